@@ -41,6 +41,7 @@ import (
 	"github.com/grafana/alloy/internal/service/labelstore"
 	"github.com/grafana/alloy/internal/service/livedebugging"
 	otel_service "github.com/grafana/alloy/internal/service/otel"
+	profilingservice "github.com/grafana/alloy/internal/service/profiling"
 	remotecfgservice "github.com/grafana/alloy/internal/service/remotecfg"
 	uiservice "github.com/grafana/alloy/internal/service/ui"
 	"github.com/grafana/alloy/internal/static/config/instrumentation"
@@ -277,6 +278,11 @@ func (fr *alloyRun) Run(configPath string) error {
 
 	liveDebuggingService := livedebugging.New()
 
+	profilingService := profilingservice.New(profilingservice.Options{
+		Logger:  log.With(l, "service", "profiling"),
+		Metrics: reg,
+	})
+
 	uiService := uiservice.New(uiservice.Options{
 		UIPrefix:        fr.uiPrefix,
 		CallbackManager: liveDebuggingService.Data().(livedebugging.CallbackManager),
@@ -302,6 +308,7 @@ func (fr *alloyRun) Run(configPath string) error {
 			labelService,
 			liveDebuggingService,
 			otelService,
+			profilingService,
 			remoteCfgService,
 			uiService,
 		},
@@ -7,6 +7,7 @@ import (
 	_ "github.com/grafana/alloy/internal/component/discovery/azure"                          // Import discovery.azure
 	_ "github.com/grafana/alloy/internal/component/discovery/consul"                         // Import discovery.consul
 	_ "github.com/grafana/alloy/internal/component/discovery/consulagent"                    // Import discovery.consulagent
+	_ "github.com/grafana/alloy/internal/component/discovery/containerd"                     // Import discovery.containerd
 	_ "github.com/grafana/alloy/internal/component/discovery/digitalocean"                   // Import discovery.digitalocean
 	_ "github.com/grafana/alloy/internal/component/discovery/dns"                            // Import discovery.dns
 	_ "github.com/grafana/alloy/internal/component/discovery/docker"                         // Import discovery.docker
@@ -17,6 +18,7 @@ import (
 	_ "github.com/grafana/alloy/internal/component/discovery/hetzner"                        // Import discovery.hetzner
 	_ "github.com/grafana/alloy/internal/component/discovery/http"                           // Import discovery.http
 	_ "github.com/grafana/alloy/internal/component/discovery/ionos"                          // Import discovery.ionos
+	_ "github.com/grafana/alloy/internal/component/discovery/join"                           // Import discovery.join
 	_ "github.com/grafana/alloy/internal/component/discovery/kubelet"                        // Import discovery.kubelet
 	_ "github.com/grafana/alloy/internal/component/discovery/kubernetes"                     // Import discovery.kubernetes
 	_ "github.com/grafana/alloy/internal/component/discovery/kuma"                           // Import discovery.kuma
@@ -36,9 +38,11 @@ import (
 	_ "github.com/grafana/alloy/internal/component/faro/receiver"                            // Import faro.receiver
 	_ "github.com/grafana/alloy/internal/component/local/file"                               // Import local.file
 	_ "github.com/grafana/alloy/internal/component/local/file_match"                         // Import local.file_match
+	_ "github.com/grafana/alloy/internal/component/local/file_tree"                          // Import local.file_tree
 	_ "github.com/grafana/alloy/internal/component/loki/echo"                                // Import loki.echo
 	_ "github.com/grafana/alloy/internal/component/loki/process"                             // Import loki.process
 	_ "github.com/grafana/alloy/internal/component/loki/relabel"                             // Import loki.relabel
+	_ "github.com/grafana/alloy/internal/component/loki/rules/file"                          // Import loki.rules.file
 	_ "github.com/grafana/alloy/internal/component/loki/rules/kubernetes"                    // Import loki.rules.kubernetes
 	_ "github.com/grafana/alloy/internal/component/loki/source/api"                          // Import loki.source.api
 	_ "github.com/grafana/alloy/internal/component/loki/source/aws_firehose"                 // Import loki.source.awsfirehose
@@ -57,6 +61,8 @@ import (
 	_ "github.com/grafana/alloy/internal/component/loki/source/syslog"                       // Import loki.source.syslog
 	_ "github.com/grafana/alloy/internal/component/loki/source/windowsevent"                 // Import loki.source.windowsevent
 	_ "github.com/grafana/alloy/internal/component/loki/write"                               // Import loki.write
+	_ "github.com/grafana/alloy/internal/component/mimir/alerts/kubernetes"                  // Import mimir.alerts.kubernetes
+	_ "github.com/grafana/alloy/internal/component/mimir/rules/file"                         // Import mimir.rules.file
 	_ "github.com/grafana/alloy/internal/component/mimir/rules/kubernetes"                   // Import mimir.rules.kubernetes
 	_ "github.com/grafana/alloy/internal/component/otelcol/auth/basic"                       // Import otelcol.auth.basic
 	_ "github.com/grafana/alloy/internal/component/otelcol/auth/bearer"                      // Import otelcol.auth.bearer
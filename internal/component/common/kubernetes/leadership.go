@@ -0,0 +1,63 @@
+package kubernetes
+
+import (
+	"fmt"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/alloy/internal/runtime/logging/level"
+	"github.com/grafana/alloy/internal/service/cluster"
+	"github.com/grafana/ckit/shard"
+	"go.uber.org/atomic"
+)
+
+// StandbyMessage is the health message of a component which isn't the leader
+// of its cluster.
+const StandbyMessage = "standby: another instance of the cluster is the leader and syncs the rules"
+
+// ComponentLeadership elects a single instance of a component among the peers
+// of a cluster, so that only one of them updates an external API. The leader
+// is the peer owning the ID of the component according to the consistent
+// hashing of the cluster.
+//
+// When clustering is disabled, the cluster only contains the local instance,
+// which is always the leader.
+type ComponentLeadership struct {
+	id      string
+	logger  log.Logger
+	cluster cluster.Cluster
+	leader  atomic.Bool
+}
+
+// NewComponentLeadership creates a ComponentLeadership for the component with
+// the given ID. Update must be called before IsLeader returns true.
+func NewComponentLeadership(id string, logger log.Logger, cluster cluster.Cluster) *ComponentLeadership {
+	return &ComponentLeadership{
+		id:      id,
+		logger:  logger,
+		cluster: cluster,
+	}
+}
+
+// Update checks if this instance is the leader, stores the result, and
+// returns true if the leadership status has changed since the last time
+// Update was called.
+func (l *ComponentLeadership) Update() (bool, error) {
+	peers, err := l.cluster.Lookup(shard.StringKey(l.id), 1, shard.OpReadWrite)
+	if err != nil {
+		return false, fmt.Errorf("unable to determine leader for %s: %w", l.id, err)
+	}
+
+	if len(peers) != 1 {
+		return false, fmt.Errorf("unexpected peers from leadership check: %+v", peers)
+	}
+
+	isLeader := peers[0].Self
+	level.Info(l.logger).Log("msg", "checked leadership of component", "is_leader", isLeader)
+	return l.leader.Swap(isLeader) != isLeader, nil
+}
+
+// IsLeader returns true if this instance was the leader the last time Update
+// was called.
+func (l *ComponentLeadership) IsLeader() bool {
+	return l.leader.Load()
+}
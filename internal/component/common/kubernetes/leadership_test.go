@@ -0,0 +1,57 @@
+package kubernetes
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/ckit/peer"
+	"github.com/grafana/ckit/shard"
+	"github.com/stretchr/testify/require"
+)
+
+type fakeCluster struct {
+	peers []peer.Peer
+	err   error
+}
+
+func (f *fakeCluster) Lookup(shard.Key, int, shard.Op) ([]peer.Peer, error) {
+	return f.peers, f.err
+}
+
+func (f *fakeCluster) Peers() []peer.Peer {
+	return f.peers
+}
+
+func TestComponentLeadership(t *testing.T) {
+	cluster := &fakeCluster{peers: []peer.Peer{{Name: "self", Self: true}}}
+	l := NewComponentLeadership("mimir.rules.kubernetes.default", log.NewNopLogger(), cluster)
+	require.False(t, l.IsLeader(), "leadership must be checked before being acquired")
+
+	changed, err := l.Update()
+	require.NoError(t, err)
+	require.True(t, changed)
+	require.True(t, l.IsLeader())
+
+	changed, err = l.Update()
+	require.NoError(t, err)
+	require.False(t, changed)
+
+	// Another peer takes over.
+	cluster.peers = []peer.Peer{{Name: "other"}}
+	changed, err = l.Update()
+	require.NoError(t, err)
+	require.True(t, changed)
+	require.False(t, l.IsLeader())
+
+	// Errors leave the leadership status unchanged.
+	cluster.err = errors.New("lookup failed")
+	_, err = l.Update()
+	require.ErrorContains(t, err, "lookup failed")
+	require.False(t, l.IsLeader())
+
+	cluster.err = nil
+	cluster.peers = nil
+	_, err = l.Update()
+	require.ErrorContains(t, err, "unexpected peers")
+}
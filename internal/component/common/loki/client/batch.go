@@ -9,6 +9,7 @@ import (
 	"github.com/gogo/protobuf/proto"
 	"github.com/golang/snappy"
 	"github.com/prometheus/common/model"
+	promql_parser "github.com/prometheus/prometheus/promql/parser"
 	"golang.org/x/exp/slices"
 
 	"github.com/grafana/loki/v3/pkg/logproto"
@@ -186,6 +187,26 @@ func (b *batch) createPushRequest() (*logproto.PushRequest, int) {
 	return &req, entriesCount
 }
 
+// entries returns the entries of the batch, with the labels of their stream.
+// The tenant ID label isn't restored, as it's not part of the streams.
+func (b *batch) entries() ([]loki.Entry, error) {
+	var entries []loki.Entry
+	for _, stream := range b.streams {
+		ls, err := promql_parser.ParseMetric(stream.Labels)
+		if err != nil {
+			return nil, fmt.Errorf("error parsing stream labels %q: %w", stream.Labels, err)
+		}
+		for _, e := range stream.Entries {
+			lbs := make(model.LabelSet, len(ls)+1)
+			for _, l := range ls {
+				lbs[model.LabelName(l.Name)] = model.LabelValue(l.Value)
+			}
+			entries = append(entries, loki.Entry{Labels: lbs, Entry: e})
+		}
+	}
+	return entries, nil
+}
+
 // countForSegment tracks that one data item has been read from a certain WAL segment.
 func (b *batch) countForSegment(segmentNum int) {
 	if curr, ok := b.segmentCounter[segmentNum]; ok {
@@ -44,10 +44,24 @@ const (
 	ReasonRateLimited   = "rate_limited"
 	ReasonStreamLimited = "stream_limited"
 	ReasonLineTooLong   = "line_too_long"
+
+	ReasonStructuredMetadataTooLarge = "structured_metadata_too_large"
+	ReasonStructuredMetadataTooMany  = "structured_metadata_too_many"
+
+	ReasonTenantQueueFull = "tenant_queue_full"
 )
 
 var Reasons = []string{ReasonGeneric, ReasonRateLimited, ReasonStreamLimited, ReasonLineTooLong}
 
+// StructuredMetadataReasons are the reasons for which entries are dropped or
+// mutated by the structured metadata policy of an endpoint. Their counters are
+// only initialized when the endpoint has a policy.
+var StructuredMetadataReasons = []string{ReasonStructuredMetadataTooLarge, ReasonStructuredMetadataTooMany}
+
+// tenantQueueReasons are the reasons for which the tenant queues drop
+// batches.
+var tenantQueueReasons = []string{ReasonGeneric, ReasonRateLimited, ReasonTenantQueueFull}
+
 var userAgent = useragent.Get()
 
 type Metrics struct {
@@ -60,6 +74,11 @@ type Metrics struct {
 	mutatedBytes                 *prometheus.CounterVec
 	requestDuration              *prometheus.HistogramVec
 	batchRetries                 *prometheus.CounterVec
+	tenantQueueBatches           *prometheus.GaugeVec
+	tenantRateLimited            *prometheus.CounterVec
+	tenantSentBytes              *prometheus.CounterVec
+	tenantSentEntries            *prometheus.CounterVec
+	tenantDroppedBatches         *prometheus.CounterVec
 	countersWithHost             []*prometheus.CounterVec
 	countersWithHostTenant       []*prometheus.CounterVec
 	countersWithHostTenantReason []*prometheus.CounterVec
@@ -104,6 +123,26 @@ func NewMetrics(reg prometheus.Registerer) *Metrics {
 		Name: "loki_write_batch_retries_total",
 		Help: "Number of times batches has had to be retried.",
 	}, []string{HostLabel, TenantLabel})
+	m.tenantQueueBatches = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "loki_write_tenant_queue_batches",
+		Help: "Number of batches waiting in the queue of a tenant.",
+	}, []string{HostLabel, TenantLabel})
+	m.tenantRateLimited = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "loki_write_tenant_rate_limited_total",
+		Help: "Number of times batches of a tenant were delayed by the tenant rate limit.",
+	}, []string{HostLabel, TenantLabel})
+	m.tenantSentBytes = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "loki_write_tenant_sent_bytes_total",
+		Help: "Number of bytes sent for a tenant.",
+	}, []string{HostLabel, TenantLabel})
+	m.tenantSentEntries = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "loki_write_tenant_sent_entries_total",
+		Help: "Number of log entries sent to the ingester for a tenant.",
+	}, []string{HostLabel, TenantLabel})
+	m.tenantDroppedBatches = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "loki_write_tenant_dropped_batches_total",
+		Help: "Number of batches of a tenant dropped by the tenant queues.",
+	}, []string{HostLabel, TenantLabel, ReasonLabel})
 
 	m.countersWithHost = []*prometheus.CounterVec{
 		m.encodedBytes, m.sentBytes, m.sentEntries,
@@ -127,6 +166,11 @@ func NewMetrics(reg prometheus.Registerer) *Metrics {
 		m.mutatedBytes = util.MustRegisterOrGet(reg, m.mutatedBytes).(*prometheus.CounterVec)
 		m.requestDuration = util.MustRegisterOrGet(reg, m.requestDuration).(*prometheus.HistogramVec)
 		m.batchRetries = util.MustRegisterOrGet(reg, m.batchRetries).(*prometheus.CounterVec)
+		m.tenantQueueBatches = util.MustRegisterOrGet(reg, m.tenantQueueBatches).(*prometheus.GaugeVec)
+		m.tenantRateLimited = util.MustRegisterOrGet(reg, m.tenantRateLimited).(*prometheus.CounterVec)
+		m.tenantSentBytes = util.MustRegisterOrGet(reg, m.tenantSentBytes).(*prometheus.CounterVec)
+		m.tenantSentEntries = util.MustRegisterOrGet(reg, m.tenantSentEntries).(*prometheus.CounterVec)
+		m.tenantDroppedBatches = util.MustRegisterOrGet(reg, m.tenantDroppedBatches).(*prometheus.CounterVec)
 	}
 
 	return &m
@@ -160,6 +204,16 @@ type client struct {
 	maxStreams          int
 	maxLineSize         int
 	maxLineSizeTruncate bool
+
+	structuredMetadataPolicy *structuredMetadataPolicy
+
+	// tenantQueues sends the batches when per-tenant queues are enabled.
+	tenantQueues *tenantScheduler
+
+	// requeue, if set, receives the entries of the batches which still fail
+	// to be sent after the last retry, instead of dropping them. It must be
+	// set before entries are sent to the client.
+	requeue func([]loki.Entry)
 }
 
 // Tripperware can wrap a roundtripper.
@@ -193,6 +247,8 @@ func newClient(metrics *Metrics, cfg Config, maxStreams, maxLineSize int, maxLin
 		maxStreams:          maxStreams,
 		maxLineSize:         maxLineSize,
 		maxLineSizeTruncate: maxLineSizeTruncate,
+
+		structuredMetadataPolicy: newStructuredMetadataPolicy(cfg.StructuredMetadata),
 	}
 	if cfg.Name != "" {
 		c.name = cfg.Name
@@ -210,6 +266,10 @@ func newClient(metrics *Metrics, cfg Config, maxStreams, maxLineSize int, maxLin
 
 	c.client.Timeout = cfg.Timeout
 
+	if cfg.TenantQueues.Enabled {
+		c.tenantQueues = newTenantScheduler(ctx, cfg.TenantQueues, cfg, metrics, c.logger, c.send, nil)
+	}
+
 	// Initialize counters to 0 so the metrics are exported before the first
 	// occurrence of incrementing to avoid missing metrics.
 	for _, counter := range c.metrics.countersWithHost {
@@ -242,6 +302,18 @@ func (c *client) initBatchMetrics(tenantID string) {
 		for _, reason := range Reasons {
 			counter.WithLabelValues(c.cfg.URL.Host, tenantID, reason).Add(0)
 		}
+		if c.structuredMetadataPolicy != nil {
+			for _, reason := range StructuredMetadataReasons {
+				counter.WithLabelValues(c.cfg.URL.Host, tenantID, reason).Add(0)
+			}
+		}
+	}
+	if c.tenantQueues != nil {
+		c.metrics.droppedBytes.WithLabelValues(c.cfg.URL.Host, tenantID, ReasonTenantQueueFull).Add(0)
+		c.metrics.droppedEntries.WithLabelValues(c.cfg.URL.Host, tenantID, ReasonTenantQueueFull).Add(0)
+		for _, reason := range tenantQueueReasons {
+			c.metrics.tenantDroppedBatches.WithLabelValues(c.cfg.URL.Host, tenantID, reason).Add(0)
+		}
 	}
 
 	for _, counter := range c.metrics.countersWithHostTenant {
@@ -272,6 +344,19 @@ func (c *client) run() {
 		for tenantID, batch := range batches {
 			c.sendBatch(tenantID, batch)
 		}
+		if c.tenantQueues != nil {
+			// Once cancelled by StopNow, the queued batches are sent without
+			// retries. Otherwise, retries could delay the shutdown for as long
+			// as the backoff of every tenant, so draining is bounded by the
+			// drain timeout of the queue.
+			ctx := context.Background()
+			if c.cfg.Queue.DrainTimeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, c.cfg.Queue.DrainTimeout)
+				defer cancel()
+			}
+			c.tenantQueues.drain(ctx)
+		}
 
 		c.wg.Done()
 	}()
@@ -298,6 +383,11 @@ func (c *client) run() {
 				e.Line = e.Line[:c.maxLineSize]
 			}
 
+			// Either drop or mutate the log entry because its structured metadata exceeds the limits of the endpoint.
+			if !c.structuredMetadataPolicy.enforce(&e.Entry, c.metrics, c.cfg.URL.Host, tenantID) {
+				break
+			}
+
 			batch, ok := batches[tenantID]
 
 			// If the batch doesn't exist yet, we create a new one with the entry
@@ -351,6 +441,11 @@ func batchIsRateLimited(status int) bool {
 }
 
 func (c *client) sendBatch(tenantID string, batch *batch) {
+	if c.tenantQueues != nil {
+		c.tenantQueues.enqueue(tenantID, batch)
+		return
+	}
+
 	buf, entriesCount, err := batch.encode()
 	if err != nil {
 		level.Error(c.logger).Log("msg", "error encoding batch", "error", err)
@@ -399,6 +494,14 @@ func (c *client) sendBatch(tenantID string, batch *batch) {
 	}
 
 	if err != nil {
+		// Only batches which failed with retryable errors are requeued, as the
+		// others would fail again.
+		if c.requeue != nil && (status <= 0 || batchIsRateLimited(status) || status/100 == 5) {
+			if c.requeueBatch(tenantID, batch, status, err) {
+				return
+			}
+		}
+
 		level.Error(c.logger).Log("msg", "final error sending batch", "status", status, "tenant", tenantID, "error", err)
 		// If the reason for the last retry error was rate limiting, count the drops as such, even if the previous errors
 		// were for a different reason
@@ -411,6 +514,30 @@ func (c *client) sendBatch(tenantID string, batch *batch) {
 	}
 }
 
+// setRequeue implements requeuingClient.
+func (c *client) setRequeue(requeue func([]loki.Entry)) {
+	c.requeue = requeue
+}
+
+// requeueBatch hands the entries of batch, which failed to be sent, to
+// c.requeue. It returns false if the entries can't be recovered from batch.
+func (c *client) requeueBatch(tenantID string, batch *batch, status int, err error) bool {
+	entries, decodeErr := batch.entries()
+	if decodeErr != nil {
+		level.Error(c.logger).Log("msg", "error requeuing batch", "tenant", tenantID, "error", decodeErr)
+		return false
+	}
+	if tenantID != c.cfg.TenantID {
+		for _, e := range entries {
+			e.Labels[ReservedLabelTenantID] = model.LabelValue(tenantID)
+		}
+	}
+
+	level.Warn(c.logger).Log("msg", "final error sending batch, requeuing it", "status", status, "tenant", tenantID, "entries", len(entries), "error", err)
+	c.requeue(entries)
+	return true
+}
+
 func (c *client) send(ctx context.Context, tenantID string, buf []byte) (int, error) {
 	ctx, cancel := context.WithTimeout(ctx, c.cfg.Timeout)
 	defer cancel()
@@ -47,6 +47,17 @@ type Config struct {
 
 	// Queue controls configuration parameters specific to the queue client
 	Queue QueueConfig
+
+	// StructuredMetadata is the policy applied to the structured metadata of
+	// entries before they're batched.
+	StructuredMetadata StructuredMetadataConfig
+
+	// TenantQueues configures the per-tenant queues used to send batches.
+	TenantQueues TenantQueueConfig
+
+	// Spool configures the spool used to queue entries on disk when they can't
+	// be sent as fast as they're received.
+	Spool SpoolConfig
 }
 
 // QueueConfig holds configurations for the queue-based remote-write client.
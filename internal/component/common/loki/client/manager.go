@@ -93,6 +93,7 @@ func NewManager(metrics *Metrics, logger log.Logger, limits limit.Config, reg pr
 	walWatcherMetrics := wal.NewWatcherMetrics(reg)
 	walMarkerMetrics := internal.NewMarkerMetrics(reg)
 	queueClientMetrics := NewQueueClientMetrics(reg)
+	spoolMetrics := NewSpoolMetrics(reg)
 
 	if len(clientCfgs) == 0 {
 		return nil, fmt.Errorf("at least one client config must be provided")
@@ -146,6 +147,15 @@ func NewManager(metrics *Metrics, logger log.Logger, limits limit.Config, reg pr
 				return nil, fmt.Errorf("error starting client: %w", err)
 			}
 
+			if cfg.Spool.Enabled {
+				spooled, err := NewSpooled(client, cfg.Spool, spoolDir(cfg), spoolMetrics, log.With(logger, "client", clientName))
+				if err != nil {
+					client.Stop()
+					return nil, fmt.Errorf("error starting client spool: %w", err)
+				}
+				client = spooled
+			}
+
 			clients = append(clients, client)
 
 			pairs = append(pairs, watcherClientPair{
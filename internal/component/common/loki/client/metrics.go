@@ -35,3 +35,86 @@ func (m *QueueClientMetrics) CurryWithId(id string) *QueueClientMetrics {
 		}),
 	}
 }
+
+type SpoolMetrics struct {
+	diskBytes      *prometheus.GaugeVec
+	diskEntries    *prometheus.GaugeVec
+	memoryEntries  *prometheus.GaugeVec
+	writtenEntries *prometheus.CounterVec
+	droppedEntries *prometheus.CounterVec
+}
+
+func NewSpoolMetrics(reg prometheus.Registerer) *SpoolMetrics {
+	m := &SpoolMetrics{
+		diskBytes: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: "loki_write",
+				Name:      "spool_bytes",
+				Help:      "Size of the spool on disk",
+			},
+			[]string{"id"},
+		),
+		diskEntries: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: "loki_write",
+				Name:      "spool_entries",
+				Help:      "Number of entries in the spool on disk waiting to be sent",
+			},
+			[]string{"id"},
+		),
+		memoryEntries: prometheus.NewGaugeVec(
+			prometheus.GaugeOpts{
+				Namespace: "loki_write",
+				Name:      "spool_memory_entries",
+				Help:      "Number of entries queued in memory waiting to be sent",
+			},
+			[]string{"id"},
+		),
+		writtenEntries: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: "loki_write",
+				Name:      "spool_written_entries_total",
+				Help:      "Number of entries written to the spool on disk",
+			},
+			[]string{"id"},
+		),
+		droppedEntries: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Namespace: "loki_write",
+				Name:      "spool_dropped_entries_total",
+				Help:      "Number of entries dropped because the spool was full or couldn't be written or read",
+			},
+			[]string{"id", ReasonLabel},
+		),
+	}
+
+	if reg != nil {
+		m.diskBytes = util.MustRegisterOrGet(reg, m.diskBytes).(*prometheus.GaugeVec)
+		m.diskEntries = util.MustRegisterOrGet(reg, m.diskEntries).(*prometheus.GaugeVec)
+		m.memoryEntries = util.MustRegisterOrGet(reg, m.memoryEntries).(*prometheus.GaugeVec)
+		m.writtenEntries = util.MustRegisterOrGet(reg, m.writtenEntries).(*prometheus.CounterVec)
+		m.droppedEntries = util.MustRegisterOrGet(reg, m.droppedEntries).(*prometheus.CounterVec)
+	}
+
+	return m
+}
+
+// spoolClientMetrics are the metrics of the spool of a client.
+type spoolClientMetrics struct {
+	diskBytes      prometheus.Gauge
+	diskEntries    prometheus.Gauge
+	memoryEntries  prometheus.Gauge
+	writtenEntries prometheus.Counter
+	droppedEntries *prometheus.CounterVec
+}
+
+func (m *SpoolMetrics) withId(id string) *spoolClientMetrics {
+	labels := prometheus.Labels{"id": id}
+	return &spoolClientMetrics{
+		diskBytes:      m.diskBytes.With(labels),
+		diskEntries:    m.diskEntries.With(labels),
+		memoryEntries:  m.memoryEntries.With(labels),
+		writtenEntries: m.writtenEntries.With(labels),
+		droppedEntries: m.droppedEntries.MustCurryWith(labels),
+	}
+}
@@ -180,6 +180,12 @@ type queueClient struct {
 	maxLineSizeTruncate bool
 	quit                chan struct{}
 	markerHandler       MarkerHandler
+
+	structuredMetadataPolicy *structuredMetadataPolicy
+
+	// tenantQueues sends the batches instead of sendQueue when per-tenant
+	// queues are enabled.
+	tenantQueues *tenantScheduler
 }
 
 // NewQueue creates a new queueClient.
@@ -214,6 +220,8 @@ func newQueueClient(metrics *Metrics, qcMetrics *QueueClientMetrics, cfg Config,
 		maxStreams:          maxStreams,
 		maxLineSize:         maxLineSize,
 		maxLineSizeTruncate: maxLineSizeTruncate,
+
+		structuredMetadataPolicy: newStructuredMetadataPolicy(cfg.StructuredMetadata),
 	}
 
 	// The buffered channel size is calculated using the configured capacity, which is the worst case number of bytes
@@ -233,6 +241,13 @@ func newQueueClient(metrics *Metrics, qcMetrics *QueueClientMetrics, cfg Config,
 
 	c.client.Timeout = cfg.Timeout
 
+	if cfg.TenantQueues.Enabled {
+		c.tenantQueues = newTenantScheduler(ctx, cfg.TenantQueues, cfg, metrics, c.logger, c.send, func(b *batch) {
+			// mark segment data for that batch as sent, even if the send operation failed
+			b.reportAsSentData(c.markerHandler)
+		})
+	}
+
 	// Initialize counters to 0 so the metrics are exported before the first
 	// occurrence of incrementing to avoid missing metrics.
 	for _, counter := range c.metrics.countersWithHost {
@@ -251,6 +266,18 @@ func (c *queueClient) initBatchMetrics(tenantID string) {
 		for _, reason := range Reasons {
 			counter.WithLabelValues(c.cfg.URL.Host, tenantID, reason).Add(0)
 		}
+		if c.structuredMetadataPolicy != nil {
+			for _, reason := range StructuredMetadataReasons {
+				counter.WithLabelValues(c.cfg.URL.Host, tenantID, reason).Add(0)
+			}
+		}
+	}
+	if c.tenantQueues != nil {
+		c.metrics.droppedBytes.WithLabelValues(c.cfg.URL.Host, tenantID, ReasonTenantQueueFull).Add(0)
+		c.metrics.droppedEntries.WithLabelValues(c.cfg.URL.Host, tenantID, ReasonTenantQueueFull).Add(0)
+		for _, reason := range tenantQueueReasons {
+			c.metrics.tenantDroppedBatches.WithLabelValues(c.cfg.URL.Host, tenantID, reason).Add(0)
+		}
 	}
 
 	for _, counter := range c.metrics.countersWithHostTenant {
@@ -322,6 +349,11 @@ func (c *queueClient) appendSingleEntry(segmentNum int, lbs model.LabelSet, e lo
 		e.Line = e.Line[:c.maxLineSize]
 	}
 
+	// Either drop or mutate the log entry because its structured metadata exceeds the limits of the endpoint.
+	if !c.structuredMetadataPolicy.enforce(&e, c.metrics, c.cfg.URL.Host, tenantID) {
+		return
+	}
+
 	// TODO: can I make this locking more fine grained?
 	c.batchesMtx.Lock()
 
@@ -344,7 +376,7 @@ func (c *queueClient) appendSingleEntry(segmentNum int, lbs model.LabelSet, e lo
 	// If adding the entry to the batch will increase the size over the max
 	// size allowed, we do send the current batch and then create a new one
 	if batch.sizeBytesAfter(e) > c.cfg.BatchSize {
-		c.sendQueue.enqueue(queuedBatch{
+		c.enqueue(queuedBatch{
 			TenantID: tenantID,
 			Batch:    batch,
 		})
@@ -424,7 +456,7 @@ func (c *queueClient) runSendOldBatches() {
 
 			// enqueue batches that were marked as too old
 			for _, qb := range batchesToFlush {
-				c.sendQueue.enqueue(qb)
+				c.enqueue(qb)
 			}
 
 			batchesToFlush = batchesToFlush[:0] // renew slide
@@ -432,6 +464,16 @@ func (c *queueClient) runSendOldBatches() {
 	}
 }
 
+// enqueue adds a batch ready to be sent to the queue of its tenant if
+// per-tenant queues are enabled, or to the send queue otherwise.
+func (c *queueClient) enqueue(qb queuedBatch) {
+	if c.tenantQueues != nil {
+		c.tenantQueues.enqueue(qb.TenantID, qb.Batch)
+		return
+	}
+	c.sendQueue.enqueue(qb)
+}
+
 // enqueuePendingBatches will go over the pending batches, and enqueue them in the send queue. If the context's
 // deadline is exceeded in any enqueue operation, this routine exits.
 func (c *queueClient) enqueuePendingBatches(ctx context.Context) {
@@ -439,6 +481,10 @@ func (c *queueClient) enqueuePendingBatches(ctx context.Context) {
 	defer c.batchesMtx.Unlock()
 
 	for tenantID, batch := range c.batches {
+		if c.tenantQueues != nil {
+			c.tenantQueues.enqueue(tenantID, batch)
+			continue
+		}
 		if !c.sendQueue.enqueueWithCancel(ctx, queuedBatch{
 			TenantID: tenantID,
 			Batch:    batch,
@@ -587,6 +633,9 @@ func (c *queueClient) Stop() {
 
 	// drain sendQueue with timeout in context
 	c.sendQueue.closeAndDrain(ctx)
+	if c.tenantQueues != nil {
+		c.tenantQueues.drain(ctx)
+	}
 
 	// stop request after drain times out or exits
 	c.cancel()
@@ -601,6 +650,9 @@ func (c *queueClient) StopNow() {
 	close(c.quit)
 	c.sendQueue.closeNow()
 	c.wg.Wait()
+	if c.tenantQueues != nil {
+		c.tenantQueues.stop()
+	}
 	c.markerHandler.Stop()
 }
 
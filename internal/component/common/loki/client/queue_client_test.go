@@ -41,6 +41,8 @@ type testCase struct {
 	batchWait   time.Duration
 	queueConfig QueueConfig
 
+	tenantQueues TenantQueueConfig
+
 	// expects
 	expectedRWReqsCount int64
 }
@@ -89,6 +91,21 @@ func TestQueueClient(t *testing.T) {
 			},
 			expectedRWReqsCount: 1, // expect all entries to be sent in a single batch (100 * < 10B per line) < 1MiB
 		},
+		"many lines and series, per-tenant queues": {
+			numLines:  100,
+			numSeries: 10,
+			batchSize: 10,
+			batchWait: time.Millisecond * 50,
+			queueConfig: QueueConfig{
+				Capacity:     100,
+				DrainTimeout: time.Second,
+			},
+			tenantQueues: TenantQueueConfig{
+				Enabled:            true,
+				Capacity:           100,
+				MaxConcurrentSends: 2,
+			},
+		},
 	} {
 		t.Run(name, func(t *testing.T) {
 			reg := prometheus.NewRegistry()
@@ -132,6 +149,7 @@ func TestQueueClient(t *testing.T) {
 				Timeout:        1 * time.Second,
 				TenantID:       "",
 				Queue:          tc.queueConfig,
+				TenantQueues:   tc.tenantQueues,
 			}
 
 			logger := log.NewLogfmtLogger(os.Stdout)
@@ -0,0 +1,306 @@
+package client
+
+import (
+	"fmt"
+	"path/filepath"
+	"sync"
+
+	"github.com/go-kit/log"
+
+	"github.com/grafana/alloy/internal/component/common/loki"
+	"github.com/grafana/alloy/internal/runtime/logging/level"
+)
+
+// Actions taken when the spool of a client is full.
+const (
+	SpoolFullActionDropNewest = "drop_newest"
+	SpoolFullActionDropOldest = "drop_oldest"
+)
+
+// Reasons for which entries are dropped by the spool of a client.
+const (
+	SpoolDropReasonFull  = "spool_full"
+	SpoolDropReasonError = "spool_error"
+)
+
+// Defaults of SpoolConfig.
+const (
+	SpoolQueueSize   = 10000
+	SpoolMaxSize     = 1 << 30
+	SpoolSegmentSize = 16 << 20
+)
+
+// SpoolConfig configures the spool of a client. Unlike the WAL, which writes
+// every entry to disk, the spool only writes entries to disk when they can't
+// be handed to the client as fast as they're received, which is the case when
+// the endpoint is failing and the client retries sending a batch.
+type SpoolConfig struct {
+	Enabled bool
+
+	// Dir is the folder in which the spool of each client is stored.
+	Dir string
+
+	// QueueSize is the number of entries queued in memory before they're
+	// written to disk.
+	QueueSize int
+
+	// MaxSize is the maximum size in bytes of the spool on disk.
+	MaxSize int
+
+	// SegmentSize is the size in bytes at which a new spool segment is
+	// started.
+	SegmentSize int
+
+	// FullAction is the action taken when the spool is full. Either the newest
+	// entries, which don't fit in the spool, or the oldest ones are dropped.
+	FullAction string
+}
+
+// requeuingClient is implemented by clients which can hand back the entries
+// of the batches they fail to send after their last retry, instead of
+// dropping them.
+type requeuingClient interface {
+	setRequeue(requeue func([]loki.Entry))
+}
+
+// spooledClient hands the entries it receives to a client through an
+// in-memory queue, and spools them to disk when the queue is full. Spooled
+// entries are handed to the client in order once the queue is empty.
+//
+// Entries are removed from the spool once they're handed to the client. If the
+// client is a requeuingClient, the entries of the batches it fails to send
+// are spooled again, before the entries not handed to it yet.
+type spooledClient struct {
+	client  Client
+	logger  log.Logger
+	metrics *spoolClientMetrics
+	cfg     SpoolConfig
+
+	entries chan loki.Entry
+	once    sync.Once
+
+	// mut protects memory, disk and failed. Entries are only queued in memory
+	// while the disk spool is empty, so that the entries in memory are always
+	// older than the ones on disk.
+	mut    sync.Mutex
+	memory []loki.Entry
+	disk   *diskSpool
+	// failed holds the entries of the batches the client failed to send,
+	// until they're spooled.
+	failed []loki.Entry
+
+	notify chan struct{}
+	quit   chan struct{}
+
+	receiveWG sync.WaitGroup
+	forwardWG sync.WaitGroup
+}
+
+// NewSpooled wraps client with a spool stored in the folder dir.
+func NewSpooled(client Client, cfg SpoolConfig, dir string, metrics *SpoolMetrics, logger log.Logger) (Client, error) {
+	return newSpooledClient(client, cfg, dir, metrics.withId(client.Name()), logger)
+}
+
+func newSpooledClient(client Client, cfg SpoolConfig, dir string, metrics *spoolClientMetrics, logger log.Logger) (*spooledClient, error) {
+	if err := validateSpoolConfig(cfg); err != nil {
+		return nil, err
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = SpoolQueueSize
+	}
+
+	disk, err := openDiskSpool(logger, metrics, dir, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &spooledClient{
+		client:  client,
+		logger:  logger,
+		metrics: metrics,
+		cfg:     cfg,
+		entries: make(chan loki.Entry),
+		disk:    disk,
+		notify:  make(chan struct{}, 1),
+		quit:    make(chan struct{}),
+	}
+
+	if r, ok := client.(requeuingClient); ok {
+		r.setRequeue(c.requeue)
+	}
+
+	c.receiveWG.Add(1)
+	go c.runReceive()
+	c.forwardWG.Add(1)
+	go c.runForward()
+
+	// Entries spooled before a restart are sent right away.
+	c.signal()
+	return c, nil
+}
+
+func (c *spooledClient) signal() {
+	select {
+	case c.notify <- struct{}{}:
+	default:
+	}
+}
+
+func (c *spooledClient) runReceive() {
+	defer c.receiveWG.Done()
+
+	for e := range c.entries {
+		c.mut.Lock()
+		if c.disk.empty() && len(c.memory) < c.cfg.QueueSize {
+			c.memory = append(c.memory, e)
+			c.metrics.memoryEntries.Set(float64(len(c.memory)))
+		} else if err := c.disk.append(e); err != nil {
+			level.Error(c.logger).Log("msg", "error spooling entry", "err", err)
+		}
+		c.mut.Unlock()
+
+		c.signal()
+	}
+}
+
+func (c *spooledClient) runForward() {
+	defer c.forwardWG.Done()
+
+	for {
+		e, fromDisk, ok := c.next()
+		if !ok {
+			select {
+			case <-c.notify:
+				continue
+			case <-c.quit:
+				return
+			}
+		}
+
+		select {
+		case c.client.Chan() <- e:
+			c.commit(fromDisk)
+		case <-c.quit:
+			// The entry wasn't committed, so it's still queued or spooled.
+			return
+		}
+	}
+}
+
+// next returns the next entry to hand to the client, without removing it.
+func (c *spooledClient) next() (e loki.Entry, fromDisk bool, ok bool) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	if len(c.failed) > 0 {
+		if err := c.spoolQueued(); err != nil {
+			level.Error(c.logger).Log("msg", "error spooling entries of failed batches", "err", err)
+		}
+	}
+	if len(c.memory) > 0 {
+		return c.memory[0], false, true
+	}
+	e, ok, err := c.disk.peek()
+	if err != nil {
+		level.Error(c.logger).Log("msg", "error reading spooled entry", "err", err)
+	}
+	return e, true, ok
+}
+
+// commit removes the entry returned by next, once it was handed to the
+// client.
+func (c *spooledClient) commit(fromDisk bool) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	if fromDisk {
+		c.disk.advance()
+		return
+	}
+	c.memory[0] = loki.Entry{}
+	c.memory = c.memory[1:]
+	c.metrics.memoryEntries.Set(float64(len(c.memory)))
+}
+
+// requeue queues entries which the client failed to send. They're spooled by
+// the forwarder before it hands the next entry to the client, so that the
+// entry being handed to it isn't moved meanwhile.
+func (c *spooledClient) requeue(entries []loki.Entry) {
+	c.mut.Lock()
+	c.failed = append(c.failed, entries...)
+	c.mut.Unlock()
+
+	c.signal()
+}
+
+// spoolQueued spools the entries of the failed batches and the entries queued
+// in memory, before the spooled ones. The entries of the failed batches are
+// older than the others, so they come first. c.mut must be held.
+func (c *spooledClient) spoolQueued() error {
+	entries := append(c.failed, c.memory...)
+	c.failed, c.memory = nil, nil
+	c.metrics.memoryEntries.Set(0)
+	return c.disk.prepend(entries)
+}
+
+func (c *spooledClient) Chan() chan<- loki.Entry {
+	return c.entries
+}
+
+// close stops handing entries to the client, and stops the client with
+// stopClient. The entries queued in memory, and those of the batches the
+// client fails to send while it stops, are spooled so that they're sent first
+// once the spool is opened again.
+func (c *spooledClient) close(stopClient func()) {
+	c.once.Do(func() { close(c.entries) })
+	c.receiveWG.Wait()
+	close(c.quit)
+	c.forwardWG.Wait()
+
+	stopClient()
+
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	if err := c.spoolQueued(); err != nil {
+		level.Error(c.logger).Log("msg", "error spooling queued entries", "err", err)
+	}
+	if err := c.disk.close(); err != nil {
+		level.Error(c.logger).Log("msg", "error closing spool", "err", err)
+	}
+}
+
+// Stop the client, spooling the entries which weren't sent.
+func (c *spooledClient) Stop() {
+	c.close(c.client.Stop)
+}
+
+// StopNow stops the client without retries, spooling the entries which
+// weren't sent.
+func (c *spooledClient) StopNow() {
+	c.close(c.client.StopNow)
+}
+
+func (c *spooledClient) Name() string {
+	return c.client.Name()
+}
+
+// spoolDir returns the folder of the spool of the client of cfg. It doesn't
+// depend on the rest of the configuration of the client unless it has no
+// name, so that the spool is kept when the configuration changes.
+func spoolDir(cfg Config) string {
+	name := cfg.Name
+	if name == "" {
+		name = asSha256(cfg.URL.String())
+	}
+	return filepath.Join(cfg.Spool.Dir, name)
+}
+
+// validateSpoolConfig returns an error if cfg can't be used.
+func validateSpoolConfig(cfg SpoolConfig) error {
+	switch cfg.FullAction {
+	case "", SpoolFullActionDropNewest, SpoolFullActionDropOldest:
+	default:
+		return fmt.Errorf("invalid spool full action %q", cfg.FullAction)
+	}
+	return nil
+}
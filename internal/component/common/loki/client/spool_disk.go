@@ -0,0 +1,566 @@
+package client
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/loki/v3/pkg/logproto"
+	"github.com/natefinch/atomic"
+	"github.com/prometheus/common/model"
+	promql_parser "github.com/prometheus/prometheus/promql/parser"
+
+	"github.com/grafana/alloy/internal/component/common/loki"
+	"github.com/grafana/alloy/internal/runtime/logging/level"
+)
+
+const (
+	spoolSegmentSuffix    = ".spool"
+	spoolPositionFileName = "position"
+
+	spoolFolderMode os.FileMode = 0o700
+	spoolFileMode   os.FileMode = 0o600
+
+	// spoolRecordHeaderSize is the size of the length and CRC32 checksum
+	// written before each record.
+	spoolRecordHeaderSize = 8
+
+	// spoolMaxRecordSize bounds the length read from the header of a record,
+	// so that a corrupted header doesn't make it read the whole segment.
+	spoolMaxRecordSize = 256 << 20
+)
+
+var (
+	spoolCastagnoliTable = crc32.MakeTable(crc32.Castagnoli)
+
+	errSpoolCorruptedRecord = errors.New("corrupted spool record")
+)
+
+// spoolSegment is a file of a diskSpool.
+type spoolSegment struct {
+	index   int64
+	size    int64
+	entries int
+}
+
+// diskSpool is a FIFO of entries stored in segment files, which are read in
+// the order of their index. It isn't safe for concurrent use.
+type diskSpool struct {
+	logger      log.Logger
+	metrics     *spoolClientMetrics
+	dir         string
+	maxSize     int64
+	segmentSize int64
+	fullAction  string
+
+	segments []*spoolSegment
+	// nextIndex is the index of the next segment. Indexes aren't reused, so
+	// that a stale position file never applies to another segment.
+	nextIndex int64
+	// size is the size of all the segments, and unread the number of entries
+	// not read yet.
+	size   int64
+	unread int
+
+	// writer appends to the last segment.
+	writerFile *os.File
+	writer     *bufio.Writer
+
+	// reader reads the first segment, of which read entries were read up to
+	// readOffset.
+	readerFile *os.File
+	reader     *bufio.Reader
+	readOffset int64
+	read       int
+
+	// peeked is the next entry, read but not advanced past yet.
+	peeked     *loki.Entry
+	peekedSize int64
+}
+
+// openDiskSpool opens the spool stored in dir, resuming from the position at
+// which it was closed.
+func openDiskSpool(logger log.Logger, metrics *spoolClientMetrics, dir string, cfg SpoolConfig) (*diskSpool, error) {
+	if err := os.MkdirAll(dir, spoolFolderMode); err != nil {
+		return nil, fmt.Errorf("error creating spool folder %q: %w", dir, err)
+	}
+
+	d := &diskSpool{
+		logger:      logger,
+		metrics:     metrics,
+		dir:         dir,
+		maxSize:     int64(cfg.MaxSize),
+		segmentSize: int64(cfg.SegmentSize),
+		fullAction:  cfg.FullAction,
+	}
+	if d.segmentSize <= 0 {
+		d.segmentSize = SpoolSegmentSize
+	}
+
+	files, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("error listing spool folder %q: %w", dir, err)
+	}
+	for _, f := range files {
+		name := f.Name()
+		if f.IsDir() || !strings.HasSuffix(name, spoolSegmentSuffix) {
+			continue
+		}
+		index, err := strconv.ParseInt(strings.TrimSuffix(name, spoolSegmentSuffix), 10, 64)
+		if err != nil {
+			continue
+		}
+		d.segments = append(d.segments, &spoolSegment{index: index})
+	}
+	sort.Slice(d.segments, func(i, j int) bool { return d.segments[i].index < d.segments[j].index })
+
+	loaded := d.segments[:0]
+	for _, s := range d.segments {
+		entries, size, err := scanSpoolSegment(d.segmentPath(s), -1)
+		if err != nil {
+			// Records are written in full, so a corrupted record is the last
+			// one of the segment, written when the process crashed.
+			level.Warn(d.logger).Log("msg", "truncating corrupted spool segment", "segment", d.segmentPath(s), "err", err)
+			if err := os.Truncate(d.segmentPath(s), size); err != nil {
+				return nil, fmt.Errorf("error truncating spool segment: %w", err)
+			}
+		}
+		if entries == 0 {
+			_ = os.Remove(d.segmentPath(s))
+			continue
+		}
+		s.entries, s.size = entries, size
+		d.size += size
+		d.unread += entries
+		loaded = append(loaded, s)
+	}
+	d.segments = loaded
+	if len(d.segments) > 0 {
+		d.nextIndex = d.segments[len(d.segments)-1].index + 1
+	}
+
+	d.loadPosition()
+	d.updateMetrics()
+	return d, nil
+}
+
+func (d *diskSpool) segmentPath(s *spoolSegment) string {
+	return filepath.Join(d.dir, strconv.FormatInt(s.index, 10)+spoolSegmentSuffix)
+}
+
+func (d *diskSpool) positionPath() string {
+	return filepath.Join(d.dir, spoolPositionFileName)
+}
+
+// loadPosition skips the entries of the first segment which were read before
+// the spool was closed. The position file is removed once loaded, so that if
+// the process crashes, entries are read again rather than skipped.
+func (d *diskSpool) loadPosition() {
+	bs, err := os.ReadFile(d.positionPath())
+	if err != nil {
+		return
+	}
+	_ = os.Remove(d.positionPath())
+	if len(d.segments) == 0 {
+		return
+	}
+
+	var index, offset int64
+	if _, err := fmt.Sscanf(string(bs), "%d %d", &index, &offset); err != nil {
+		level.Warn(d.logger).Log("msg", "could not decode spool position file", "file", d.positionPath(), "err", err)
+		return
+	}
+	head := d.segments[0]
+	if index != head.index || offset <= 0 || offset > head.size {
+		return
+	}
+	read, size, err := scanSpoolSegment(d.segmentPath(head), offset)
+	if err != nil || size != offset {
+		return
+	}
+	d.read, d.readOffset = read, offset
+	d.unread -= read
+}
+
+// empty returns true if all the entries of the spool were read.
+func (d *diskSpool) empty() bool {
+	return d.unread == 0
+}
+
+// append writes e at the end of the spool. If the spool is full, either e or
+// the oldest entries are dropped depending on the full action.
+func (d *diskSpool) append(e loki.Entry) error {
+	rec, err := encodeSpoolRecord(e)
+	if err != nil {
+		d.metrics.droppedEntries.WithLabelValues(SpoolDropReasonError).Inc()
+		return err
+	}
+
+	if d.maxSize > 0 && d.size+int64(len(rec)) > d.maxSize {
+		if d.fullAction == SpoolFullActionDropOldest {
+			for len(d.segments) > 0 && d.size+int64(len(rec)) > d.maxSize {
+				dropped := d.segments[0].entries - d.read
+				if d.peeked != nil {
+					// The peeked entry is being sent.
+					dropped--
+				}
+				if err := d.removeHead(); err != nil {
+					return err
+				}
+				d.metrics.droppedEntries.WithLabelValues(SpoolDropReasonFull).Add(float64(dropped))
+			}
+		}
+		if d.size+int64(len(rec)) > d.maxSize {
+			d.metrics.droppedEntries.WithLabelValues(SpoolDropReasonFull).Inc()
+			return nil
+		}
+	}
+
+	last := len(d.segments) - 1
+	if d.writer == nil || (d.segments[last].size > 0 && d.segments[last].size+int64(len(rec)) > d.segmentSize) {
+		if err := d.cut(); err != nil {
+			d.metrics.droppedEntries.WithLabelValues(SpoolDropReasonError).Inc()
+			return err
+		}
+		last = len(d.segments) - 1
+	}
+	if _, err := d.writer.Write(rec); err != nil {
+		d.metrics.droppedEntries.WithLabelValues(SpoolDropReasonError).Inc()
+		return fmt.Errorf("error writing to spool segment: %w", err)
+	}
+
+	s := d.segments[last]
+	s.size += int64(len(rec))
+	s.entries++
+	d.size += int64(len(rec))
+	d.unread++
+	d.metrics.writtenEntries.Inc()
+	d.updateMetrics()
+	return nil
+}
+
+// cut starts a new segment to write to.
+func (d *diskSpool) cut() error {
+	if err := d.closeWriter(); err != nil {
+		return err
+	}
+
+	s := &spoolSegment{index: d.nextIndex}
+	d.nextIndex++
+	f, err := os.OpenFile(d.segmentPath(s), os.O_CREATE|os.O_WRONLY|os.O_APPEND, spoolFileMode)
+	if err != nil {
+		return fmt.Errorf("error creating spool segment: %w", err)
+	}
+	d.segments = append(d.segments, s)
+	d.writerFile, d.writer = f, bufio.NewWriter(f)
+	return nil
+}
+
+// openWriter reopens the writer of the last segment, if any.
+func (d *diskSpool) openWriter() error {
+	if len(d.segments) == 0 {
+		return nil
+	}
+	f, err := os.OpenFile(d.segmentPath(d.segments[len(d.segments)-1]), os.O_WRONLY|os.O_APPEND, spoolFileMode)
+	if err != nil {
+		return fmt.Errorf("error opening spool segment: %w", err)
+	}
+	d.writerFile, d.writer = f, bufio.NewWriter(f)
+	return nil
+}
+
+func (d *diskSpool) closeWriter() error {
+	if d.writer == nil {
+		return nil
+	}
+	err := d.writer.Flush()
+	if closeErr := d.writerFile.Close(); err == nil {
+		err = closeErr
+	}
+	d.writerFile, d.writer = nil, nil
+	return err
+}
+
+func (d *diskSpool) closeReader() {
+	if d.readerFile != nil {
+		_ = d.readerFile.Close()
+	}
+	d.readerFile, d.reader = nil, nil
+}
+
+// peek returns the next entry of the spool without advancing past it, and
+// false if the spool is empty.
+func (d *diskSpool) peek() (loki.Entry, bool, error) {
+	if d.peeked != nil {
+		return *d.peeked, true, nil
+	}
+
+	for len(d.segments) > 0 {
+		head := d.segments[0]
+		if d.read == head.entries {
+			// The segment was read, including when it's the one written to.
+			if err := d.removeHead(); err != nil {
+				return loki.Entry{}, false, err
+			}
+			continue
+		}
+
+		if len(d.segments) == 1 && d.writer != nil {
+			if err := d.writer.Flush(); err != nil {
+				return loki.Entry{}, false, fmt.Errorf("error writing to spool segment: %w", err)
+			}
+		}
+		if d.reader == nil {
+			f, err := os.Open(d.segmentPath(head))
+			if err != nil {
+				return loki.Entry{}, false, fmt.Errorf("error opening spool segment: %w", err)
+			}
+			if _, err := f.Seek(d.readOffset, io.SeekStart); err != nil {
+				_ = f.Close()
+				return loki.Entry{}, false, fmt.Errorf("error opening spool segment: %w", err)
+			}
+			d.readerFile, d.reader = f, bufio.NewReader(f)
+		}
+
+		payload, err := readSpoolRecord(d.reader)
+		if err == nil {
+			var e loki.Entry
+			e, err = decodeSpoolRecord(payload)
+			if err == nil {
+				d.peeked = &e
+				d.peekedSize = int64(spoolRecordHeaderSize + len(payload))
+				return e, true, nil
+			}
+		}
+
+		// The rest of the segment can't be read.
+		level.Error(d.logger).Log("msg", "dropping the rest of a corrupted spool segment", "segment", d.segmentPath(head), "err", err)
+		d.metrics.droppedEntries.WithLabelValues(SpoolDropReasonError).Add(float64(head.entries - d.read))
+		if err := d.removeHead(); err != nil {
+			return loki.Entry{}, false, err
+		}
+	}
+	return loki.Entry{}, false, nil
+}
+
+// advance moves past the peeked entry. It does nothing if the peeked entry
+// was dropped in the meantime.
+func (d *diskSpool) advance() {
+	if d.peeked == nil {
+		return
+	}
+	d.peeked = nil
+	d.readOffset += d.peekedSize
+	d.read++
+	d.unread--
+	d.updateMetrics()
+}
+
+// removeHead deletes the first segment.
+func (d *diskSpool) removeHead() error {
+	head := d.segments[0]
+	if len(d.segments) == 1 {
+		if err := d.closeWriter(); err != nil {
+			level.Warn(d.logger).Log("msg", "error closing spool segment", "err", err)
+		}
+	}
+	d.closeReader()
+	if err := os.Remove(d.segmentPath(head)); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("error removing spool segment: %w", err)
+	}
+
+	d.segments = d.segments[1:]
+	d.size -= head.size
+	d.unread -= head.entries - d.read
+	d.read, d.readOffset = 0, 0
+	d.peeked = nil
+	d.updateMetrics()
+	return nil
+}
+
+// prepend writes entries before the unread entries of the spool. The unread
+// entries of the first segment are rewritten after them, so that the entries
+// read already aren't read again. The size of the spool isn't limited here,
+// as entries are only prepended from the bounded in-memory queue and from
+// the batches the client failed to send, which are bounded by the batch
+// size.
+func (d *diskSpool) prepend(entries []loki.Entry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+
+	var (
+		buf   bytes.Buffer
+		added int
+	)
+	for _, e := range entries {
+		rec, err := encodeSpoolRecord(e)
+		if err != nil {
+			d.metrics.droppedEntries.WithLabelValues(SpoolDropReasonError).Inc()
+			continue
+		}
+		buf.Write(rec)
+		added++
+	}
+	if added == 0 {
+		return nil
+	}
+
+	if len(d.segments) == 0 {
+		if err := d.cut(); err != nil {
+			return err
+		}
+	}
+
+	head := d.segments[0]
+	headIsTail := len(d.segments) == 1
+	if headIsTail {
+		if err := d.closeWriter(); err != nil {
+			return err
+		}
+	}
+	d.closeReader()
+
+	contents, err := os.ReadFile(d.segmentPath(head))
+	if err != nil {
+		return fmt.Errorf("error reading spool segment: %w", err)
+	}
+	buf.Write(contents[d.readOffset:])
+	if err := atomic.WriteFile(d.segmentPath(head), &buf); err != nil {
+		return fmt.Errorf("error writing spool segment: %w", err)
+	}
+
+	d.size += int64(buf.Len()) - head.size
+	head.size = int64(buf.Len())
+	head.entries = head.entries - d.read + added
+	d.unread += added
+	d.read, d.readOffset = 0, 0
+	d.peeked = nil
+	d.metrics.writtenEntries.Add(float64(added))
+	d.updateMetrics()
+
+	if headIsTail {
+		return d.openWriter()
+	}
+	return nil
+}
+
+// close flushes the spool to disk, and records the position of the next
+// entry to read.
+func (d *diskSpool) close() error {
+	err := d.closeWriter()
+	d.closeReader()
+
+	if len(d.segments) == 0 {
+		if rmErr := os.Remove(d.positionPath()); rmErr != nil && !os.IsNotExist(rmErr) && err == nil {
+			err = rmErr
+		}
+		return err
+	}
+	position := fmt.Sprintf("%d %d", d.segments[0].index, d.readOffset)
+	if wErr := atomic.WriteFile(d.positionPath(), strings.NewReader(position)); wErr != nil && err == nil {
+		err = wErr
+	}
+	return err
+}
+
+func (d *diskSpool) updateMetrics() {
+	d.metrics.diskBytes.Set(float64(d.size))
+	d.metrics.diskEntries.Set(float64(d.unread))
+}
+
+// scanSpoolSegment counts the records of the segment at path, up to the offset
+// stopAt if not negative. It returns the size of the valid records, and an
+// error if the segment is corrupted after them.
+func scanSpoolSegment(path string, stopAt int64) (entries int, size int64, err error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	for stopAt < 0 || size < stopAt {
+		payload, err := readSpoolRecord(r)
+		if err == io.EOF {
+			return entries, size, nil
+		}
+		if err != nil {
+			return entries, size, err
+		}
+		entries++
+		size += int64(spoolRecordHeaderSize + len(payload))
+	}
+	return entries, size, nil
+}
+
+// encodeSpoolRecord encodes e as a record, made of the length and CRC32
+// checksum of the protobuf encoded stream of e, followed by it.
+func encodeSpoolRecord(e loki.Entry) ([]byte, error) {
+	stream := logproto.Stream{
+		Labels:  e.Labels.String(),
+		Entries: []logproto.Entry{e.Entry},
+	}
+	payload, err := stream.Marshal()
+	if err != nil {
+		return nil, fmt.Errorf("error encoding spool record: %w", err)
+	}
+
+	rec := make([]byte, spoolRecordHeaderSize+len(payload))
+	binary.BigEndian.PutUint32(rec[0:4], uint32(len(payload)))
+	binary.BigEndian.PutUint32(rec[4:8], crc32.Checksum(payload, spoolCastagnoliTable))
+	copy(rec[spoolRecordHeaderSize:], payload)
+	return rec, nil
+}
+
+// readSpoolRecord reads the payload of the next record of r. It returns
+// io.EOF if r has no more records.
+func readSpoolRecord(r *bufio.Reader) ([]byte, error) {
+	var header [spoolRecordHeaderSize]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return nil, errSpoolCorruptedRecord
+		}
+		return nil, err
+	}
+
+	length := binary.BigEndian.Uint32(header[0:4])
+	if length > spoolMaxRecordSize {
+		return nil, errSpoolCorruptedRecord
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(r, payload); err != nil {
+		return nil, errSpoolCorruptedRecord
+	}
+	if crc32.Checksum(payload, spoolCastagnoliTable) != binary.BigEndian.Uint32(header[4:8]) {
+		return nil, errSpoolCorruptedRecord
+	}
+	return payload, nil
+}
+
+func decodeSpoolRecord(payload []byte) (loki.Entry, error) {
+	var stream logproto.Stream
+	if err := stream.Unmarshal(payload); err != nil || len(stream.Entries) != 1 {
+		return loki.Entry{}, errSpoolCorruptedRecord
+	}
+	ls, err := promql_parser.ParseMetric(stream.Labels)
+	if err != nil {
+		return loki.Entry{}, errSpoolCorruptedRecord
+	}
+
+	lbs := make(model.LabelSet, len(ls))
+	for _, l := range ls {
+		lbs[model.LabelName(l.Name)] = model.LabelValue(l.Value)
+	}
+	return loki.Entry{Labels: lbs, Entry: stream.Entries[0]}, nil
+}
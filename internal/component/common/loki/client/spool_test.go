@@ -0,0 +1,337 @@
+package client
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/dskit/backoff"
+	"github.com/grafana/dskit/flagext"
+	"github.com/grafana/loki/v3/pkg/logproto"
+	"github.com/grafana/loki/v3/pkg/util"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/alloy/internal/component/common/loki"
+	"github.com/grafana/alloy/internal/component/common/loki/wal"
+	lokiflag "github.com/grafana/loki/v3/pkg/util/flagext"
+)
+
+func newSpoolTestEntry(i int) loki.Entry {
+	return loki.Entry{
+		Labels: model.LabelSet{"app": "test"},
+		Entry:  logproto.Entry{Timestamp: time.Unix(int64(i)+1, 0).UTC(), Line: fmt.Sprintf("line %d", i)},
+	}
+}
+
+// readDiskSpool reads all the entries of d.
+func readDiskSpool(t *testing.T, d *diskSpool) []string {
+	var lines []string
+	for {
+		e, ok, err := d.peek()
+		require.NoError(t, err)
+		if !ok {
+			return lines
+		}
+		d.advance()
+		lines = append(lines, e.Line)
+	}
+}
+
+func TestDiskSpool(t *testing.T) {
+	dir := t.TempDir()
+	metrics := NewSpoolMetrics(prometheus.NewRegistry()).withId("test")
+	cfg := SpoolConfig{SegmentSize: 100}
+
+	d, err := openDiskSpool(log.NewNopLogger(), metrics, dir, cfg)
+	require.NoError(t, err)
+	require.True(t, d.empty())
+	for i := 0; i < 10; i++ {
+		require.NoError(t, d.append(newSpoolTestEntry(i)))
+	}
+	require.Greater(t, len(d.segments), 1, "segments should be cut at the segment size")
+
+	e, ok, err := d.peek()
+	require.NoError(t, err)
+	require.True(t, ok)
+	require.Equal(t, newSpoolTestEntry(0), e)
+	d.advance()
+	for i := 1; i < 4; i++ {
+		_, _, err := d.peek()
+		require.NoError(t, err)
+		d.advance()
+	}
+
+	// Entries are prepended before the unread ones, and the spool resumes
+	// from where it was closed.
+	require.NoError(t, d.prepend([]loki.Entry{newSpoolTestEntry(100), newSpoolTestEntry(101)}))
+	require.NoError(t, d.close())
+
+	d, err = openDiskSpool(log.NewNopLogger(), metrics, dir, cfg)
+	require.NoError(t, err)
+	require.Equal(t, 8, d.unread)
+	require.Equal(t, []string{"line 100", "line 101", "line 4", "line 5", "line 6", "line 7", "line 8", "line 9"}, readDiskSpool(t, d))
+	require.True(t, d.empty())
+	require.NoError(t, d.close())
+
+	files, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	require.Empty(t, files, "read segments and the position should be removed")
+}
+
+func TestDiskSpool_Full(t *testing.T) {
+	recordSize := func() int {
+		rec, err := encodeSpoolRecord(newSpoolTestEntry(0))
+		require.NoError(t, err)
+		return len(rec)
+	}()
+
+	for name, tc := range map[string]struct {
+		action   string
+		expected []string
+	}{
+		"drop newest": {
+			action:   SpoolFullActionDropNewest,
+			expected: []string{"line 0", "line 1", "line 2", "line 3"},
+		},
+		"drop oldest": {
+			action:   SpoolFullActionDropOldest,
+			expected: []string{"line 2", "line 3", "line 4", "line 5"},
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			reg := prometheus.NewRegistry()
+			metrics := NewSpoolMetrics(reg)
+			d, err := openDiskSpool(log.NewNopLogger(), metrics.withId("test"), t.TempDir(), SpoolConfig{
+				MaxSize:     4 * recordSize,
+				SegmentSize: 2 * recordSize,
+				FullAction:  tc.action,
+			})
+			require.NoError(t, err)
+
+			for i := 0; i < 6; i++ {
+				require.NoError(t, d.append(newSpoolTestEntry(i)))
+			}
+			require.Equal(t, tc.expected, readDiskSpool(t, d))
+			require.Equal(t, 2.0, testutil.ToFloat64(metrics.droppedEntries.WithLabelValues("test", SpoolDropReasonFull)))
+		})
+	}
+}
+
+func TestDiskSpool_CorruptedSegment(t *testing.T) {
+	dir := t.TempDir()
+	metrics := NewSpoolMetrics(prometheus.NewRegistry()).withId("test")
+
+	d, err := openDiskSpool(log.NewNopLogger(), metrics, dir, SpoolConfig{})
+	require.NoError(t, err)
+	for i := 0; i < 3; i++ {
+		require.NoError(t, d.append(newSpoolTestEntry(i)))
+	}
+	require.NoError(t, d.close())
+
+	// Simulate a crash while writing the last record.
+	path := filepath.Join(dir, "0"+spoolSegmentSuffix)
+	info, err := os.Stat(path)
+	require.NoError(t, err)
+	require.NoError(t, os.Truncate(path, info.Size()-3))
+
+	d, err = openDiskSpool(log.NewNopLogger(), metrics, dir, SpoolConfig{})
+	require.NoError(t, err)
+	require.Equal(t, []string{"line 0", "line 1"}, readDiskSpool(t, d))
+}
+
+// gatedClient is a client which only receives entries when the test reads
+// them.
+type gatedClient struct {
+	entries chan loki.Entry
+}
+
+func (c *gatedClient) Chan() chan<- loki.Entry { return c.entries }
+func (c *gatedClient) Stop()                   {}
+func (c *gatedClient) StopNow()                {}
+func (c *gatedClient) Name() string            { return "gated" }
+
+func TestSpooledClient(t *testing.T) {
+	dir := t.TempDir()
+	reg := prometheus.NewRegistry()
+	metrics := NewSpoolMetrics(reg)
+	cfg := SpoolConfig{Enabled: true, QueueSize: 3}
+
+	inner := &gatedClient{entries: make(chan loki.Entry)}
+	c, err := newSpooledClient(inner, cfg, dir, metrics.withId("gated"), log.NewNopLogger())
+	require.NoError(t, err)
+
+	// The client doesn't receive anything, so the first entries fill the
+	// queue, and the next ones are spooled.
+	for i := 0; i < 6; i++ {
+		c.Chan() <- newSpoolTestEntry(i)
+	}
+	require.Eventually(t, func() bool {
+		return testutil.ToFloat64(metrics.writtenEntries.WithLabelValues("gated")) == 3
+	}, 5*time.Second, 5*time.Millisecond)
+
+	for i := 0; i < 4; i++ {
+		require.Equal(t, fmt.Sprintf("line %d", i), (<-inner.entries).Line)
+	}
+
+	// The entries which weren't handed to the client are spooled when it's
+	// stopped, and handed to the next one in order.
+	c.Stop()
+	c, err = newSpooledClient(inner, cfg, dir, metrics.withId("gated"), log.NewNopLogger())
+	require.NoError(t, err)
+	c.Chan() <- newSpoolTestEntry(6)
+	for i := 4; i < 7; i++ {
+		require.Equal(t, fmt.Sprintf("line %d", i), (<-inner.entries).Line)
+	}
+	c.Stop()
+}
+
+func TestSpooledClient_EndpointRecovers(t *testing.T) {
+	var (
+		failing atomic.Bool
+		mut     sync.Mutex
+		lines   []string
+	)
+	failing.Store(true)
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if failing.Load() {
+			rw.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		var pushReq logproto.PushRequest
+		if err := util.ParseProtoReader(req.Context(), req.Body, int(req.ContentLength), 1<<20, &pushReq, util.RawSnappy); err != nil {
+			rw.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		mut.Lock()
+		for _, s := range pushReq.Streams {
+			for _, e := range s.Entries {
+				lines = append(lines, e.Line)
+			}
+		}
+		mut.Unlock()
+		rw.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	serverURL := flagext.URLValue{}
+	require.NoError(t, serverURL.Set(server.URL))
+
+	reg := prometheus.NewRegistry()
+	cfg := Config{
+		URL:            serverURL,
+		BatchWait:      10 * time.Millisecond,
+		BatchSize:      100,
+		BackoffConfig:  backoff.Config{MinBackoff: 5 * time.Millisecond, MaxBackoff: 5 * time.Millisecond, MaxRetries: 0},
+		ExternalLabels: lokiflag.LabelSet{},
+		Timeout:        time.Second,
+		Spool:          SpoolConfig{Enabled: true, Dir: t.TempDir(), QueueSize: 5, FullAction: SpoolFullActionDropNewest},
+	}
+	m, err := NewManager(NewMetrics(reg), log.NewNopLogger(), testLimitsConfig, reg, wal.Config{}, NilNotifier, cfg)
+	require.NoError(t, err)
+
+	var expected []string
+	for i := 0; i < 50; i++ {
+		e := newSpoolTestEntry(i)
+		m.Chan() <- e
+		expected = append(expected, e.Line)
+	}
+	spoolMetrics := NewSpoolMetrics(reg)
+	require.Eventually(t, func() bool {
+		return testutil.ToFloat64(spoolMetrics.writtenEntries.WithLabelValues(GetClientName(cfg))) > 0
+	}, 5*time.Second, 5*time.Millisecond, "entries should be spooled while the endpoint is failing")
+
+	failing.Store(false)
+	require.Eventually(t, func() bool {
+		mut.Lock()
+		defer mut.Unlock()
+		return len(lines) == len(expected)
+	}, 10*time.Second, 10*time.Millisecond)
+	m.Stop()
+
+	require.Equal(t, expected, lines, "entries should be sent in order")
+	require.Equal(t, 0.0, testutil.ToFloat64(spoolMetrics.diskEntries.WithLabelValues(GetClientName(cfg))))
+}
+
+func TestSpooledClient_RequeuesFailedBatches(t *testing.T) {
+	var (
+		failing atomic.Bool
+		mut     sync.Mutex
+		lines   []string
+	)
+	failing.Store(true)
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, req *http.Request) {
+		if failing.Load() {
+			rw.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		var pushReq logproto.PushRequest
+		if err := util.ParseProtoReader(req.Context(), req.Body, int(req.ContentLength), 1<<20, &pushReq, util.RawSnappy); err != nil {
+			rw.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		mut.Lock()
+		for _, s := range pushReq.Streams {
+			for _, e := range s.Entries {
+				lines = append(lines, e.Line)
+			}
+		}
+		mut.Unlock()
+		rw.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	serverURL := flagext.URLValue{}
+	require.NoError(t, serverURL.Set(server.URL))
+
+	reg := prometheus.NewRegistry()
+	metrics := NewMetrics(reg)
+	cfg := Config{
+		URL:            serverURL,
+		BatchWait:      10 * time.Millisecond,
+		BatchSize:      100,
+		BackoffConfig:  backoff.Config{MinBackoff: 5 * time.Millisecond, MaxBackoff: 5 * time.Millisecond, MaxRetries: 1},
+		ExternalLabels: lokiflag.LabelSet{},
+		Timeout:        time.Second,
+		Spool:          SpoolConfig{Enabled: true, Dir: t.TempDir(), QueueSize: 5, FullAction: SpoolFullActionDropNewest},
+	}
+	m, err := NewManager(metrics, log.NewNopLogger(), testLimitsConfig, reg, wal.Config{}, NilNotifier, cfg)
+	require.NoError(t, err)
+
+	var expected []string
+	for i := 0; i < 20; i++ {
+		e := newSpoolTestEntry(i)
+		m.Chan() <- e
+		expected = append(expected, e.Line)
+	}
+	spoolMetrics := NewSpoolMetrics(reg)
+	require.Eventually(t, func() bool {
+		return testutil.ToFloat64(spoolMetrics.writtenEntries.WithLabelValues(GetClientName(cfg))) >= float64(len(expected))
+	}, 5*time.Second, 5*time.Millisecond, "entries of failed batches should be spooled again")
+
+	// The batches failing while the client stops are spooled as well, and sent
+	// once the spool is opened again.
+	m.StopNow()
+	require.Equal(t, 0.0, testutil.ToFloat64(metrics.droppedEntries.WithLabelValues(serverURL.Host, "", ReasonGeneric)))
+
+	failing.Store(false)
+	m, err = NewManager(metrics, log.NewNopLogger(), testLimitsConfig, reg, wal.Config{}, NilNotifier, cfg)
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		mut.Lock()
+		defer mut.Unlock()
+		return len(lines) == len(expected)
+	}, 10*time.Second, 10*time.Millisecond)
+	m.Stop()
+
+	require.ElementsMatch(t, expected, lines)
+}
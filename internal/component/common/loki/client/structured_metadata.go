@@ -0,0 +1,165 @@
+package client
+
+import (
+	"github.com/grafana/loki/pkg/push"
+	"github.com/grafana/loki/v3/pkg/logproto"
+)
+
+// Actions taken on entries whose structured metadata exceeds the limits of a
+// StructuredMetadataConfig.
+const (
+	StructuredMetadataActionDrop     = "drop"
+	StructuredMetadataActionTruncate = "truncate"
+)
+
+// StructuredMetadataConfig holds the policy applied to the structured metadata
+// of entries before they're added to a batch, so that entries rejected by the
+// limits of Loki don't fail the whole batch.
+type StructuredMetadataConfig struct {
+	// MaxKeys is the maximum number of structured metadata pairs of an entry,
+	// zero means no limit.
+	MaxKeys int
+
+	// MaxBytes is the maximum size of the names and values of the structured
+	// metadata of an entry, zero means no limit.
+	MaxBytes int
+
+	// Action is taken on entries exceeding MaxKeys or MaxBytes. Entries are
+	// either dropped, or their structured metadata is truncated to fit.
+	Action string
+
+	// Allow is the list of structured metadata names to keep, empty means all
+	// of them.
+	Allow []string
+
+	// Deny is the list of structured metadata names to remove.
+	Deny []string
+}
+
+// structuredMetadataPolicy applies a StructuredMetadataConfig to entries.
+type structuredMetadataPolicy struct {
+	cfg   StructuredMetadataConfig
+	allow map[string]struct{}
+	deny  map[string]struct{}
+}
+
+// newStructuredMetadataPolicy returns the policy of cfg, or nil if cfg
+// doesn't change any entry.
+func newStructuredMetadataPolicy(cfg StructuredMetadataConfig) *structuredMetadataPolicy {
+	if cfg.MaxKeys <= 0 && cfg.MaxBytes <= 0 && len(cfg.Allow) == 0 && len(cfg.Deny) == 0 {
+		return nil
+	}
+	p := &structuredMetadataPolicy{cfg: cfg}
+	if len(cfg.Allow) > 0 {
+		p.allow = make(map[string]struct{}, len(cfg.Allow))
+		for _, name := range cfg.Allow {
+			p.allow[name] = struct{}{}
+		}
+	}
+	if len(cfg.Deny) > 0 {
+		p.deny = make(map[string]struct{}, len(cfg.Deny))
+		for _, name := range cfg.Deny {
+			p.deny[name] = struct{}{}
+		}
+	}
+	return p
+}
+
+// structuredMetadataResult describes how a structuredMetadataPolicy changed
+// an entry.
+type structuredMetadataResult struct {
+	// reason is the reason for which the entry was dropped or mutated, empty
+	// if the entry is within the limits.
+	reason string
+	// dropped is true if the entry must be dropped.
+	dropped bool
+	// removedBytes is the size of the structured metadata removed from the
+	// entry to fit the limits.
+	removedBytes int
+}
+
+// apply filters the structured metadata of e with the allow and deny lists,
+// and enforces the limits of the policy on what remains. The structured
+// metadata of e is replaced rather than modified in place, as entries are
+// shared between the clients of the endpoints.
+func (p *structuredMetadataPolicy) apply(e *logproto.Entry) structuredMetadataResult {
+	if p == nil || len(e.StructuredMetadata) == 0 {
+		return structuredMetadataResult{}
+	}
+
+	filtered := e.StructuredMetadata
+	if p.allow != nil || p.deny != nil {
+		filtered = make(push.LabelsAdapter, 0, len(e.StructuredMetadata))
+		for _, m := range e.StructuredMetadata {
+			if _, ok := p.allow[m.Name]; p.allow != nil && !ok {
+				continue
+			}
+			if _, ok := p.deny[m.Name]; ok {
+				continue
+			}
+			filtered = append(filtered, m)
+		}
+	}
+
+	var reason string
+	size := structuredMetadataSize(filtered)
+	switch {
+	case p.cfg.MaxBytes > 0 && size > p.cfg.MaxBytes:
+		reason = ReasonStructuredMetadataTooLarge
+	case p.cfg.MaxKeys > 0 && len(filtered) > p.cfg.MaxKeys:
+		reason = ReasonStructuredMetadataTooMany
+	}
+	if reason == "" {
+		e.StructuredMetadata = filtered
+		return structuredMetadataResult{}
+	}
+	if p.cfg.Action == StructuredMetadataActionDrop {
+		return structuredMetadataResult{reason: reason, dropped: true}
+	}
+
+	// Keep the first pairs which fit in the limits.
+	var (
+		truncated = make(push.LabelsAdapter, 0, len(filtered))
+		kept      int
+	)
+	for _, m := range filtered {
+		if p.cfg.MaxKeys > 0 && len(truncated) == p.cfg.MaxKeys {
+			break
+		}
+		if p.cfg.MaxBytes > 0 && kept+len(m.Name)+len(m.Value) > p.cfg.MaxBytes {
+			break
+		}
+		truncated = append(truncated, m)
+		kept += len(m.Name) + len(m.Value)
+	}
+	e.StructuredMetadata = truncated
+	return structuredMetadataResult{reason: reason, removedBytes: size - kept}
+}
+
+// enforce applies the policy to e and records the dropped or mutated entries
+// in metrics. It returns false if e must be dropped.
+func (p *structuredMetadataPolicy) enforce(e *logproto.Entry, metrics *Metrics, host, tenantID string) bool {
+	res := p.apply(e)
+	switch {
+	case res.reason == "":
+		return true
+	case res.dropped:
+		metrics.droppedEntries.WithLabelValues(host, tenantID, res.reason).Inc()
+		metrics.droppedBytes.WithLabelValues(host, tenantID, res.reason).Add(float64(entrySize(*e)))
+		return false
+	default:
+		metrics.mutatedEntries.WithLabelValues(host, tenantID, res.reason).Inc()
+		metrics.mutatedBytes.WithLabelValues(host, tenantID, res.reason).Add(float64(res.removedBytes))
+		return true
+	}
+}
+
+// structuredMetadataSize returns the size of structured metadata as computed
+// by Loki for its limits, which is the size of its names and values.
+func structuredMetadataSize(metadata push.LabelsAdapter) int {
+	size := 0
+	for _, m := range metadata {
+		size += len(m.Name) + len(m.Value)
+	}
+	return size
+}
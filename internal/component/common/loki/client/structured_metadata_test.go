@@ -0,0 +1,98 @@
+package client
+
+import (
+	"testing"
+	"time"
+
+	"github.com/grafana/loki/pkg/push"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/loki/v3/pkg/logproto"
+)
+
+func TestStructuredMetadataPolicy(t *testing.T) {
+	metadata := push.LabelsAdapter{
+		{Name: "trace_id", Value: "0242ac120002"},
+		{Name: "user", Value: "frank"},
+		{Name: "pod_uid", Value: "6b2f7d7c"},
+	}
+
+	tests := map[string]struct {
+		cfg      StructuredMetadataConfig
+		expected push.LabelsAdapter
+		result   structuredMetadataResult
+	}{
+		"no limits": {
+			cfg:      StructuredMetadataConfig{},
+			expected: metadata,
+		},
+		"within limits": {
+			cfg:      StructuredMetadataConfig{MaxKeys: 3, MaxBytes: 100},
+			expected: metadata,
+		},
+		"allow": {
+			cfg:      StructuredMetadataConfig{Allow: []string{"trace_id", "pod_uid"}},
+			expected: push.LabelsAdapter{metadata[0], metadata[2]},
+		},
+		"deny": {
+			cfg:      StructuredMetadataConfig{Deny: []string{"user"}},
+			expected: push.LabelsAdapter{metadata[0], metadata[2]},
+		},
+		"deny within limits": {
+			cfg:      StructuredMetadataConfig{MaxKeys: 2, Action: StructuredMetadataActionDrop, Deny: []string{"user"}},
+			expected: push.LabelsAdapter{metadata[0], metadata[2]},
+		},
+		"truncate too many": {
+			cfg:      StructuredMetadataConfig{MaxKeys: 2, Action: StructuredMetadataActionTruncate},
+			expected: metadata[:2],
+			result:   structuredMetadataResult{reason: ReasonStructuredMetadataTooMany, removedBytes: len("pod_uid6b2f7d7c")},
+		},
+		"truncate too large": {
+			cfg:      StructuredMetadataConfig{MaxKeys: 2, MaxBytes: 25, Action: StructuredMetadataActionTruncate},
+			expected: metadata[:1],
+			result:   structuredMetadataResult{reason: ReasonStructuredMetadataTooLarge, removedBytes: len("userfrankpod_uid6b2f7d7c")},
+		},
+		"drop too many": {
+			cfg:      StructuredMetadataConfig{MaxKeys: 2, Action: StructuredMetadataActionDrop},
+			expected: metadata,
+			result:   structuredMetadataResult{reason: ReasonStructuredMetadataTooMany, dropped: true},
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			e := logproto.Entry{Timestamp: time.Now(), Line: "hello", StructuredMetadata: metadata}
+			res := newStructuredMetadataPolicy(tc.cfg).apply(&e)
+			require.Equal(t, tc.result, res)
+			require.Equal(t, tc.expected, e.StructuredMetadata)
+		})
+	}
+
+	// The structured metadata of the entry is shared with other clients, so
+	// it must not be modified in place.
+	require.Equal(t, "pod_uid", metadata[2].Name)
+}
+
+func TestStructuredMetadataPolicy_Metrics(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	metrics := NewMetrics(reg)
+
+	drop := newStructuredMetadataPolicy(StructuredMetadataConfig{MaxKeys: 1, Action: StructuredMetadataActionDrop})
+	truncate := newStructuredMetadataPolicy(StructuredMetadataConfig{MaxBytes: 10, Action: StructuredMetadataActionTruncate})
+
+	newEntry := func() logproto.Entry {
+		return logproto.Entry{Line: "hello", StructuredMetadata: push.LabelsAdapter{{Name: "user", Value: "frank"}, {Name: "team", Value: "core"}}}
+	}
+
+	e := newEntry()
+	require.False(t, drop.enforce(&e, metrics, "localhost", "tenant"))
+	e = newEntry()
+	require.True(t, truncate.enforce(&e, metrics, "localhost", "tenant"))
+	require.Equal(t, push.LabelsAdapter{{Name: "user", Value: "frank"}}, e.StructuredMetadata)
+
+	require.Equal(t, 1.0, testutil.ToFloat64(metrics.droppedEntries.WithLabelValues("localhost", "tenant", ReasonStructuredMetadataTooMany)))
+	require.Equal(t, float64(entrySize(newEntry())), testutil.ToFloat64(metrics.droppedBytes.WithLabelValues("localhost", "tenant", ReasonStructuredMetadataTooMany)))
+	require.Equal(t, 1.0, testutil.ToFloat64(metrics.mutatedEntries.WithLabelValues("localhost", "tenant", ReasonStructuredMetadataTooLarge)))
+	require.Equal(t, float64(len("teamcore")), testutil.ToFloat64(metrics.mutatedBytes.WithLabelValues("localhost", "tenant", ReasonStructuredMetadataTooLarge)))
+}
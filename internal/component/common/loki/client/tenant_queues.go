@@ -0,0 +1,460 @@
+package client
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/dskit/backoff"
+	"golang.org/x/time/rate"
+
+	"github.com/grafana/alloy/internal/runtime/logging/level"
+)
+
+// Defaults of TenantQueueConfig.
+const (
+	TenantQueueCapacity           = 10
+	TenantQueueMaxConcurrentSends = 4
+	TenantQueueIdleTimeout        = 5 * time.Minute
+)
+
+// TenantQueueConfig configures the per-tenant queues of a client. When
+// enabled, the batches of each tenant are queued and sent independently of the
+// other tenants, so that a tenant which is rate limited or failing doesn't
+// delay the logs of the others.
+type TenantQueueConfig struct {
+	Enabled bool
+
+	// Capacity is the maximum number of batches queued for each tenant.
+	// Batches of a tenant whose queue is full are dropped.
+	Capacity int
+
+	// MaxConcurrentSends is the maximum number of batches sent concurrently,
+	// across all tenants.
+	MaxConcurrentSends int
+
+	// RateLimit is the maximum number of bytes per second sent for each
+	// tenant, zero means no limit.
+	RateLimit int
+
+	// RateLimitOverrides overrides RateLimit for specific tenants.
+	RateLimitOverrides map[string]int
+
+	// IdleTimeout is how long the queue of a tenant is kept once it's empty.
+	// The queue is created again when the tenant sends new batches.
+	IdleTimeout time.Duration
+}
+
+// pendingBatch is a batch waiting in the queue of a tenant.
+type pendingBatch struct {
+	batch   *batch
+	buf     []byte
+	entries int
+}
+
+// tenantQueue holds the batches of a tenant waiting to be sent, and the state
+// deciding when the next one can be sent.
+type tenantQueue struct {
+	id      string
+	batches []*pendingBatch
+	limiter *rate.Limiter
+	backoff *backoff.Backoff
+
+	// sending is true while the head batch is being sent. Only one batch of a
+	// tenant is sent at a time, so that batches are sent in order.
+	sending bool
+	// notBefore is the time before which the head batch mustn't be sent,
+	// either because of backoff or of the rate limit.
+	notBefore time.Time
+	// lastActive is the last time a batch was queued or done.
+	lastActive time.Time
+}
+
+// tenantScheduler sends the batches of the tenant queues of a client. Workers
+// pick tenants in turn, skipping tenants which are backing off or rate
+// limited, so that every tenant gets a fair share of the sends.
+type tenantScheduler struct {
+	cfg       TenantQueueConfig
+	clientCfg Config
+	metrics   *Metrics
+	logger    log.Logger
+
+	// send sends an encoded batch for a tenant.
+	send func(ctx context.Context, tenantID string, buf []byte) (int, error)
+	// done is called once a batch was sent or given up on, if not nil.
+	done func(b *batch)
+
+	// ctx stops retries when cancelled.
+	ctx context.Context
+
+	mut      sync.Mutex
+	tenants  map[string]*tenantQueue
+	order    []*tenantQueue
+	next     int
+	draining bool
+
+	wake chan struct{}
+	quit chan struct{}
+	wg   sync.WaitGroup
+}
+
+func newTenantScheduler(ctx context.Context, cfg TenantQueueConfig, clientCfg Config, metrics *Metrics, logger log.Logger, send func(context.Context, string, []byte) (int, error), done func(*batch)) *tenantScheduler {
+	if cfg.Capacity <= 0 {
+		cfg.Capacity = TenantQueueCapacity
+	}
+	if cfg.MaxConcurrentSends <= 0 {
+		cfg.MaxConcurrentSends = TenantQueueMaxConcurrentSends
+	}
+	if cfg.IdleTimeout <= 0 {
+		cfg.IdleTimeout = TenantQueueIdleTimeout
+	}
+
+	s := &tenantScheduler{
+		cfg:       cfg,
+		clientCfg: clientCfg,
+		metrics:   metrics,
+		logger:    logger,
+		send:      send,
+		done:      done,
+		ctx:       ctx,
+		tenants:   map[string]*tenantQueue{},
+		wake:      make(chan struct{}, 1),
+		quit:      make(chan struct{}),
+	}
+	s.wg.Add(cfg.MaxConcurrentSends)
+	for i := 0; i < cfg.MaxConcurrentSends; i++ {
+		go s.run()
+	}
+	return s
+}
+
+// enqueue adds b to the queue of tenantID. It never blocks: if the queue of
+// the tenant is full, b is dropped.
+func (s *tenantScheduler) enqueue(tenantID string, b *batch) {
+	s.mut.Lock()
+	tq := s.tenant(tenantID)
+	if len(tq.batches) >= s.cfg.Capacity {
+		s.mut.Unlock()
+
+		level.Warn(s.logger).Log("msg", "dropping batch because the tenant queue is full", "tenant", tenantID)
+		_, entries := b.createPushRequest()
+		s.dropped(tenantID, ReasonTenantQueueFull, float64(b.sizeBytes()), entries)
+		if s.done != nil {
+			s.done(b)
+		}
+		return
+	}
+	tq.batches = append(tq.batches, &pendingBatch{batch: b})
+	tq.lastActive = time.Now()
+	s.metrics.tenantQueueBatches.WithLabelValues(s.clientCfg.URL.Host, tenantID).Set(float64(len(tq.batches)))
+	s.mut.Unlock()
+
+	s.signal()
+}
+
+// tenant returns the queue of tenantID, creating it if needed. s.mut must be
+// held.
+func (s *tenantScheduler) tenant(tenantID string) *tenantQueue {
+	if tq, ok := s.tenants[tenantID]; ok {
+		return tq
+	}
+
+	tq := &tenantQueue{
+		id:      tenantID,
+		backoff: backoff.New(s.ctx, s.clientCfg.BackoffConfig),
+	}
+	limit := s.cfg.RateLimit
+	if override, ok := s.cfg.RateLimitOverrides[tenantID]; ok {
+		limit = override
+	}
+	if limit > 0 {
+		tq.limiter = rate.NewLimiter(rate.Limit(limit), limit)
+	}
+	s.tenants[tenantID] = tq
+	s.order = append(s.order, tq)
+	return tq
+}
+
+// signal wakes up a worker.
+func (s *tenantScheduler) signal() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (s *tenantScheduler) run() {
+	defer s.wg.Done()
+
+	for {
+		tq, pb, wait, exit := s.pick()
+		if exit {
+			// Let the other workers notice that there's nothing left to do.
+			s.signal()
+			return
+		}
+		if tq != nil {
+			s.sendBatch(tq, pb)
+			continue
+		}
+
+		var timer *time.Timer
+		var timeout <-chan time.Time
+		if wait > 0 {
+			timer = time.NewTimer(wait)
+			timeout = timer.C
+		}
+		select {
+		case <-s.wake:
+		case <-timeout:
+		case <-s.quit:
+		}
+		if timer != nil {
+			timer.Stop()
+		}
+	}
+}
+
+// pick returns the next tenant and batch to send. If no batch can be sent
+// yet, it returns how long to wait for one to be ready, zero meaning until
+// woken up. exit is true once the worker must stop.
+func (s *tenantScheduler) pick() (tq *tenantQueue, pb *pendingBatch, wait time.Duration, exit bool) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	select {
+	case <-s.quit:
+		return nil, nil, 0, true
+	default:
+	}
+
+	var (
+		now     = time.Now()
+		pending bool
+		// Once stopped without retries, queued batches are sent right away.
+		immediate = s.ctx.Err() != nil
+	)
+	// Wake up in time to evict the queues which become idle.
+	if d := s.evictIdle(now); d > 0 {
+		wait = d
+	}
+	for i := 0; i < len(s.order); i++ {
+		idx := (s.next + i) % len(s.order)
+		candidate := s.order[idx]
+		if candidate.sending {
+			pending = true
+			continue
+		}
+		if len(candidate.batches) == 0 {
+			continue
+		}
+		pending = true
+
+		head := candidate.batches[0]
+		if d := candidate.notBefore.Sub(now); d > 0 && !immediate {
+			if wait == 0 || d < wait {
+				wait = d
+			}
+			continue
+		}
+		if d := s.reserve(candidate, head, now); d > 0 && !immediate {
+			if wait == 0 || d < wait {
+				wait = d
+			}
+			continue
+		}
+
+		candidate.sending = true
+		s.next = (idx + 1) % len(s.order)
+		return candidate, head, 0, false
+	}
+
+	return nil, nil, wait, s.draining && !pending
+}
+
+// evictIdle removes the queues which have been empty for the idle timeout,
+// so that tenants which stopped sending logs don't keep their queue forever.
+// It returns how long to wait for the next queue to become idle, zero meaning
+// no queue is empty. s.mut must be held.
+func (s *tenantScheduler) evictIdle(now time.Time) time.Duration {
+	var (
+		next time.Duration
+		kept = s.order[:0]
+	)
+	for _, tq := range s.order {
+		if tq.sending || len(tq.batches) > 0 {
+			kept = append(kept, tq)
+			continue
+		}
+		if d := tq.lastActive.Add(s.cfg.IdleTimeout).Sub(now); d > 0 {
+			if next == 0 || d < next {
+				next = d
+			}
+			kept = append(kept, tq)
+			continue
+		}
+		delete(s.tenants, tq.id)
+		s.metrics.tenantQueueBatches.DeleteLabelValues(s.clientCfg.URL.Host, tq.id)
+	}
+	clear(s.order[len(kept):])
+	s.order = kept
+	if len(s.order) > 0 {
+		s.next %= len(s.order)
+	} else {
+		s.next = 0
+	}
+	return next
+}
+
+// reserve takes the size of pb from the rate limit of tq, and returns how long
+// to wait before pb can be sent if the rate limit is exceeded. s.mut must be
+// held.
+func (s *tenantScheduler) reserve(tq *tenantQueue, pb *pendingBatch, now time.Time) time.Duration {
+	if tq.limiter == nil {
+		return 0
+	}
+	n := pb.batch.sizeBytes()
+	if n > tq.limiter.Burst() {
+		// Batches larger than the rate limit are sent alone, once the tokens
+		// of a whole second are available.
+		n = tq.limiter.Burst()
+	}
+	r := tq.limiter.ReserveN(now, n)
+	d := r.DelayFrom(now)
+	if d > 0 {
+		r.CancelAt(now)
+		tq.notBefore = now.Add(d)
+		s.metrics.tenantRateLimited.WithLabelValues(s.clientCfg.URL.Host, tq.id).Inc()
+	}
+	return d
+}
+
+// sendBatch sends the head batch pb of tq once, and either removes it from
+// the queue or schedules its retry.
+func (s *tenantScheduler) sendBatch(tq *tenantQueue, pb *pendingBatch) {
+	host := s.clientCfg.URL.Host
+
+	if pb.buf == nil {
+		buf, entries, err := pb.batch.encode()
+		if err != nil {
+			level.Error(s.logger).Log("msg", "error encoding batch", "error", err)
+			s.finish(tq, pb, true)
+			return
+		}
+		pb.buf, pb.entries = buf, entries
+		s.metrics.encodedBytes.WithLabelValues(host).Add(float64(len(buf)))
+	}
+	bufBytes := float64(len(pb.buf))
+
+	start := time.Now()
+	// send uses `timeout` internally, so `context.Background` is good enough.
+	status, err := s.send(context.Background(), tq.id, pb.buf)
+	s.metrics.requestDuration.WithLabelValues(strconv.Itoa(status), host).Observe(time.Since(start).Seconds())
+
+	switch {
+	case s.clientCfg.DropRateLimitedBatches && batchIsRateLimited(status):
+		level.Warn(s.logger).Log("msg", "dropping batch due to rate limiting applied at ingester", "tenant", tq.id)
+		s.dropped(tq.id, ReasonRateLimited, bufBytes, pb.entries)
+		s.finish(tq, pb, true)
+		return
+
+	case err == nil:
+		s.metrics.sentBytes.WithLabelValues(host).Add(bufBytes)
+		s.metrics.sentEntries.WithLabelValues(host).Add(float64(pb.entries))
+		s.metrics.tenantSentBytes.WithLabelValues(host, tq.id).Add(bufBytes)
+		s.metrics.tenantSentEntries.WithLabelValues(host, tq.id).Add(float64(pb.entries))
+		s.finish(tq, pb, true)
+		return
+
+	// Only retry 429s, 500s and connection-level errors.
+	case status <= 0 || batchIsRateLimited(status) || status/100 == 5:
+		s.mut.Lock()
+		delay := tq.backoff.NextDelay()
+		retry := tq.backoff.Ongoing()
+		if retry {
+			tq.notBefore = time.Now().Add(delay)
+		}
+		s.mut.Unlock()
+
+		if retry {
+			level.Warn(s.logger).Log("msg", "error sending batch, will retry", "status", status, "tenant", tq.id, "error", err)
+			s.metrics.batchRetries.WithLabelValues(host, tq.id).Inc()
+			s.finish(tq, pb, false)
+			return
+		}
+	}
+
+	level.Error(s.logger).Log("msg", "final error sending batch", "status", status, "tenant", tq.id, "error", err)
+	// If the reason for the last retry error was rate limiting, count the drops as such, even if the previous errors
+	// were for a different reason
+	dropReason := ReasonGeneric
+	if batchIsRateLimited(status) {
+		dropReason = ReasonRateLimited
+	}
+	s.dropped(tq.id, dropReason, bufBytes, pb.entries)
+	s.finish(tq, pb, true)
+}
+
+// dropped records that a batch of tenantID was dropped for reason.
+func (s *tenantScheduler) dropped(tenantID, reason string, bytes float64, entries int) {
+	host := s.clientCfg.URL.Host
+	s.metrics.droppedBytes.WithLabelValues(host, tenantID, reason).Add(bytes)
+	s.metrics.droppedEntries.WithLabelValues(host, tenantID, reason).Add(float64(entries))
+	s.metrics.tenantDroppedBatches.WithLabelValues(host, tenantID, reason).Inc()
+}
+
+// finish releases tq after pb was sent. If remove is true, pb is removed from
+// the queue, and the backoff of tq is reset for the next batch.
+func (s *tenantScheduler) finish(tq *tenantQueue, pb *pendingBatch, remove bool) {
+	s.mut.Lock()
+	tq.sending = false
+	tq.lastActive = time.Now()
+	if remove {
+		tq.batches = tq.batches[1:]
+		tq.backoff.Reset()
+		s.metrics.tenantQueueBatches.WithLabelValues(s.clientCfg.URL.Host, tq.id).Set(float64(len(tq.batches)))
+	}
+	s.mut.Unlock()
+
+	if remove && s.done != nil {
+		s.done(pb.batch)
+	}
+	s.signal()
+}
+
+// drain waits for the queued batches to be sent, or for ctx to be done. The
+// workers are stopped either way.
+func (s *tenantScheduler) drain(ctx context.Context) {
+	s.mut.Lock()
+	s.draining = true
+	s.mut.Unlock()
+	s.signal()
+
+	stopped := make(chan struct{})
+	go func() {
+		s.wg.Wait()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+	case <-ctx.Done():
+		level.Warn(s.logger).Log("msg", "timeout exceeded while draining tenant queues")
+		s.stop()
+	}
+}
+
+// stop stops the workers once they're done with the batches they're sending,
+// without sending the queued batches.
+func (s *tenantScheduler) stop() {
+	s.mut.Lock()
+	select {
+	case <-s.quit:
+	default:
+		close(s.quit)
+	}
+	s.mut.Unlock()
+	s.wg.Wait()
+}
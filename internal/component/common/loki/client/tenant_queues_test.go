@@ -0,0 +1,286 @@
+package client
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/dskit/backoff"
+	"github.com/grafana/dskit/flagext"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/alloy/internal/component/common/loki"
+	"github.com/grafana/loki/v3/pkg/logproto"
+	lokiflag "github.com/grafana/loki/v3/pkg/util/flagext"
+)
+
+// fakeLoki is a Loki push endpoint rate limiting some tenants.
+type fakeLoki struct {
+	mut         sync.Mutex
+	rateLimited map[string]bool
+	received    map[string][]time.Time
+}
+
+func (f *fakeLoki) ServeHTTP(rw http.ResponseWriter, req *http.Request) {
+	tenantID := req.Header.Get("X-Scope-OrgID")
+
+	f.mut.Lock()
+	f.received[tenantID] = append(f.received[tenantID], time.Now())
+	rateLimited := f.rateLimited[tenantID]
+	f.mut.Unlock()
+
+	if rateLimited {
+		rw.WriteHeader(http.StatusTooManyRequests)
+		return
+	}
+	rw.WriteHeader(http.StatusNoContent)
+}
+
+func (f *fakeLoki) requests(tenantID string) []time.Time {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+	return append([]time.Time(nil), f.received[tenantID]...)
+}
+
+func TestClient_TenantQueues(t *testing.T) {
+	fake := &fakeLoki{rateLimited: map[string]bool{"noisy": true}, received: map[string][]time.Time{}}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+
+	serverURL := flagext.URLValue{}
+	require.NoError(t, serverURL.Set(server.URL))
+
+	reg := prometheus.NewRegistry()
+	c, err := New(NewMetrics(reg), Config{
+		URL:       serverURL,
+		BatchWait: 10 * time.Millisecond,
+		BatchSize: 5,
+		// Without per-tenant queues, the backoff of the noisy tenant would
+		// delay the logs of the quiet tenant by at least a minute.
+		BackoffConfig:  backoff.Config{MinBackoff: time.Minute, MaxBackoff: time.Minute, MaxRetries: 5},
+		ExternalLabels: lokiflag.LabelSet{},
+		Timeout:        time.Second,
+		TenantQueues:   TenantQueueConfig{Enabled: true, Capacity: 10, MaxConcurrentSends: 2},
+	}, 0, 0, false, log.NewNopLogger())
+	require.NoError(t, err)
+
+	newEntry := func(tenantID, line string) loki.Entry {
+		return loki.Entry{
+			Labels: model.LabelSet{ReservedLabelTenantID: model.LabelValue(tenantID)},
+			Entry:  logproto.Entry{Timestamp: time.Now(), Line: line},
+		}
+	}
+	c.Chan() <- newEntry("noisy", "line1")
+	c.Chan() <- newEntry("noisy", "line2")
+	require.Eventually(t, func() bool { return len(fake.requests("noisy")) == 1 }, 5*time.Second, 5*time.Millisecond)
+
+	c.Chan() <- newEntry("quiet", "line3")
+	c.Chan() <- newEntry("quiet", "line4")
+	require.Eventually(t, func() bool { return len(fake.requests("quiet")) == 2 }, 5*time.Second, 5*time.Millisecond)
+
+	// The queued batches of the noisy tenant are sent once, without retries.
+	c.StopNow()
+	require.Len(t, fake.requests("noisy"), 3)
+
+	expectedMetrics := strings.ReplaceAll(`
+# HELP loki_write_dropped_entries_total Number of log entries dropped because failed to be sent to the ingester after all retries.
+# TYPE loki_write_dropped_entries_total counter
+loki_write_dropped_entries_total{host="__HOST__",reason="ingester_error",tenant="noisy"} 0
+loki_write_dropped_entries_total{host="__HOST__",reason="ingester_error",tenant="quiet"} 0
+loki_write_dropped_entries_total{host="__HOST__",reason="line_too_long",tenant="noisy"} 0
+loki_write_dropped_entries_total{host="__HOST__",reason="line_too_long",tenant="quiet"} 0
+loki_write_dropped_entries_total{host="__HOST__",reason="rate_limited",tenant="noisy"} 2
+loki_write_dropped_entries_total{host="__HOST__",reason="rate_limited",tenant="quiet"} 0
+loki_write_dropped_entries_total{host="__HOST__",reason="stream_limited",tenant="noisy"} 0
+loki_write_dropped_entries_total{host="__HOST__",reason="stream_limited",tenant="quiet"} 0
+loki_write_dropped_entries_total{host="__HOST__",reason="tenant_queue_full",tenant="noisy"} 0
+loki_write_dropped_entries_total{host="__HOST__",reason="tenant_queue_full",tenant="quiet"} 0
+# HELP loki_write_tenant_sent_entries_total Number of log entries sent to the ingester for a tenant.
+# TYPE loki_write_tenant_sent_entries_total counter
+loki_write_tenant_sent_entries_total{host="__HOST__",tenant="quiet"} 2
+# HELP loki_write_batch_retries_total Number of times batches has had to be retried.
+# TYPE loki_write_batch_retries_total counter
+loki_write_batch_retries_total{host="__HOST__",tenant="noisy"} 1
+loki_write_batch_retries_total{host="__HOST__",tenant="quiet"} 0
+# HELP loki_write_tenant_dropped_batches_total Number of batches of a tenant dropped by the tenant queues.
+# TYPE loki_write_tenant_dropped_batches_total counter
+loki_write_tenant_dropped_batches_total{host="__HOST__",reason="ingester_error",tenant="noisy"} 0
+loki_write_tenant_dropped_batches_total{host="__HOST__",reason="ingester_error",tenant="quiet"} 0
+loki_write_tenant_dropped_batches_total{host="__HOST__",reason="rate_limited",tenant="noisy"} 2
+loki_write_tenant_dropped_batches_total{host="__HOST__",reason="rate_limited",tenant="quiet"} 0
+loki_write_tenant_dropped_batches_total{host="__HOST__",reason="tenant_queue_full",tenant="noisy"} 0
+loki_write_tenant_dropped_batches_total{host="__HOST__",reason="tenant_queue_full",tenant="quiet"} 0
+`, "__HOST__", serverURL.Host)
+	require.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(expectedMetrics), "loki_write_dropped_entries_total", "loki_write_tenant_sent_entries_total", "loki_write_batch_retries_total", "loki_write_tenant_dropped_batches_total"))
+}
+
+func TestClient_TenantQueuesDrainTimeout(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, _ *http.Request) {
+		requests.Add(1)
+		rw.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer server.Close()
+
+	serverURL := flagext.URLValue{}
+	require.NoError(t, serverURL.Set(server.URL))
+
+	c, err := New(NewMetrics(prometheus.NewRegistry()), Config{
+		URL:            serverURL,
+		BatchWait:      10 * time.Millisecond,
+		BatchSize:      5,
+		BackoffConfig:  backoff.Config{MinBackoff: time.Minute, MaxBackoff: time.Minute, MaxRetries: 5},
+		ExternalLabels: lokiflag.LabelSet{},
+		Timeout:        time.Second,
+		Queue:          QueueConfig{DrainTimeout: 100 * time.Millisecond},
+		TenantQueues:   TenantQueueConfig{Enabled: true, Capacity: 10, MaxConcurrentSends: 2},
+	}, 0, 0, false, log.NewNopLogger())
+	require.NoError(t, err)
+
+	c.Chan() <- loki.Entry{
+		Labels: model.LabelSet{ReservedLabelTenantID: "tenant"},
+		Entry:  logproto.Entry{Timestamp: time.Now(), Line: "line"},
+	}
+	require.Eventually(t, func() bool { return requests.Load() == 1 }, 5*time.Second, 5*time.Millisecond)
+
+	// The batch backing off isn't retried for a minute, but stopping the
+	// client only waits for the drain timeout.
+	stopped := make(chan struct{})
+	go func() {
+		c.Stop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(10 * time.Second):
+		require.FailNow(t, "stopping the client didn't respect the drain timeout")
+	}
+	require.Equal(t, int32(1), requests.Load())
+}
+
+func TestTenantScheduler_RateLimit(t *testing.T) {
+	var (
+		mut  sync.Mutex
+		sent = map[string][]time.Time{}
+	)
+	send := func(_ context.Context, tenantID string, _ []byte) (int, error) {
+		mut.Lock()
+		defer mut.Unlock()
+		sent[tenantID] = append(sent[tenantID], time.Now())
+		return http.StatusNoContent, nil
+	}
+
+	reg := prometheus.NewRegistry()
+	metrics := NewMetrics(reg)
+	cfg := Config{}
+	require.NoError(t, cfg.URL.Set("http://localhost:3100/loki/api/v1/push"))
+	s := newTenantScheduler(context.Background(), TenantQueueConfig{
+		RateLimit: 1000,
+		// Zero disables the rate limit of a tenant.
+		RateLimitOverrides: map[string]int{"unlimited": 0},
+	}, cfg, metrics, log.NewNopLogger(), send, nil)
+
+	line := strings.Repeat("a", 600)
+	for _, tenantID := range []string{"limited", "unlimited"} {
+		for i := 0; i < 2; i++ {
+			s.enqueue(tenantID, newBatch(0, loki.Entry{Entry: logproto.Entry{Timestamp: time.Now(), Line: line}}))
+		}
+	}
+	s.drain(context.Background())
+
+	require.Len(t, sent["limited"], 2)
+	require.Len(t, sent["unlimited"], 2)
+	// The second batch of the limited tenant waits for its share of the
+	// rate limit, while the unlimited tenant is sent right away.
+	require.GreaterOrEqual(t, sent["limited"][1].Sub(sent["limited"][0]), 150*time.Millisecond)
+	require.Less(t, sent["unlimited"][1].Sub(sent["unlimited"][0]), 150*time.Millisecond)
+	require.Equal(t, 1.0, testutil.ToFloat64(metrics.tenantRateLimited.WithLabelValues("localhost:3100", "limited")))
+	require.Equal(t, 1, testutil.CollectAndCount(metrics.tenantRateLimited))
+}
+
+func TestTenantScheduler_QueueFull(t *testing.T) {
+	var (
+		release = make(chan struct{})
+		started = make(chan struct{}, 1)
+	)
+	send := func(_ context.Context, _ string, _ []byte) (int, error) {
+		started <- struct{}{}
+		<-release
+		return http.StatusNoContent, nil
+	}
+
+	var (
+		mut  sync.Mutex
+		done int
+	)
+	reg := prometheus.NewRegistry()
+	metrics := NewMetrics(reg)
+	cfg := Config{}
+	require.NoError(t, cfg.URL.Set("http://localhost:3100/loki/api/v1/push"))
+	s := newTenantScheduler(context.Background(), TenantQueueConfig{Capacity: 1}, cfg, metrics, log.NewNopLogger(), send, func(*batch) {
+		mut.Lock()
+		done++
+		mut.Unlock()
+	})
+
+	newTestBatch := func() *batch {
+		return newBatch(0, loki.Entry{Entry: logproto.Entry{Timestamp: time.Now(), Line: "line"}})
+	}
+	s.enqueue("tenant", newTestBatch())
+	<-started
+
+	// The queue of the tenant is full until the batch being sent is done.
+	s.enqueue("tenant", newTestBatch())
+	require.Equal(t, 1.0, testutil.ToFloat64(metrics.droppedEntries.WithLabelValues("localhost:3100", "tenant", ReasonTenantQueueFull)))
+	require.Equal(t, 1.0, testutil.ToFloat64(metrics.tenantDroppedBatches.WithLabelValues("localhost:3100", "tenant", ReasonTenantQueueFull)))
+	require.Equal(t, 1.0, testutil.ToFloat64(metrics.tenantQueueBatches.WithLabelValues("localhost:3100", "tenant")))
+
+	close(release)
+	s.drain(context.Background())
+
+	require.Equal(t, 2, done)
+	require.Equal(t, 0.0, testutil.ToFloat64(metrics.tenantQueueBatches.WithLabelValues("localhost:3100", "tenant")))
+}
+
+func TestTenantScheduler_EvictsIdleQueues(t *testing.T) {
+	send := func(_ context.Context, _ string, _ []byte) (int, error) {
+		return http.StatusNoContent, nil
+	}
+
+	metrics := NewMetrics(prometheus.NewRegistry())
+	cfg := Config{}
+	require.NoError(t, cfg.URL.Set("http://localhost:3100/loki/api/v1/push"))
+	s := newTenantScheduler(context.Background(), TenantQueueConfig{IdleTimeout: 50 * time.Millisecond}, cfg, metrics, log.NewNopLogger(), send, nil)
+	defer s.stop()
+
+	numQueues := func() int {
+		s.mut.Lock()
+		defer s.mut.Unlock()
+		return len(s.order)
+	}
+	for _, tenantID := range []string{"a", "b"} {
+		s.enqueue(tenantID, newBatch(0, loki.Entry{Entry: logproto.Entry{Timestamp: time.Now(), Line: "line"}}))
+	}
+	require.Equal(t, 2, numQueues())
+
+	// The queues are evicted once empty for the idle timeout, without
+	// further batches.
+	require.Eventually(t, func() bool { return numQueues() == 0 }, 5*time.Second, 5*time.Millisecond)
+	s.mut.Lock()
+	require.Empty(t, s.tenants)
+	s.mut.Unlock()
+	require.Equal(t, 0, testutil.CollectAndCount(metrics.tenantQueueBatches))
+
+	// A tenant sending again gets a new queue.
+	s.enqueue("a", newBatch(0, loki.Entry{Entry: logproto.Entry{Timestamp: time.Now(), Line: "line"}}))
+	require.Equal(t, 1, numQueues())
+}
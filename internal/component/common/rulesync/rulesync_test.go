@@ -0,0 +1,256 @@
+package rulesync
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/alloy/internal/component"
+	"github.com/grafana/alloy/internal/component/common/kubernetes"
+	"github.com/grafana/alloy/internal/component/discovery"
+	"github.com/prometheus/prometheus/model/rulefmt"
+	"github.com/stretchr/testify/require"
+)
+
+const ruleFile = `
+groups:
+  - name: group1
+    rules:
+      - record: job:up:sum
+        expr: sum by (job) (up)
+  - name: group2
+    rules:
+      - alert: Down
+        expr: up == 0
+`
+
+func TestReadSources(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "team-a.yaml"), []byte(ruleFile), 0o644))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "other.yaml"), []byte(ruleFile), 0o644))
+
+	sources, err := ReadSources(
+		[]discovery.Target{
+			discovery.NewTargetFromMap(map[string]string{PathLabel: filepath.Join(dir, "team-a.yaml")}),
+			discovery.NewTargetFromMap(map[string]string{PathLabel: filepath.Join(dir, "other.yaml"), NamespaceLabel: "team-b"}),
+		},
+		map[string]string{"team-c": ruleFile},
+	)
+	require.NoError(t, err)
+
+	namespaces := make(map[string]string)
+	for _, src := range sources {
+		namespaces[src.Name] = src.Namespace
+		require.Equal(t, ruleFile, string(src.Content))
+	}
+	require.Equal(t, map[string]string{
+		filepath.Join(dir, "team-a.yaml"): "team-a",
+		filepath.Join(dir, "other.yaml"):  "team-b",
+		"team-c":                          "team-c",
+	}, namespaces)
+
+	_, err = ReadSources([]discovery.Target{discovery.NewTargetFromMap(map[string]string{PathLabel: filepath.Join(dir, "missing.yaml")})}, nil)
+	require.ErrorContains(t, err, "reading rule file")
+}
+
+func TestDesiredState(t *testing.T) {
+	prefix := func(ns string) string { return "alloy/" + ns }
+
+	desired, err := DesiredState([]Source{
+		{Name: "a.yaml", Namespace: "a", Content: []byte(ruleFile)},
+		{Name: "b", Namespace: "b", Content: []byte(ruleFile)},
+	}, prefix, true)
+	require.NoError(t, err)
+	require.Len(t, desired["alloy/a"], 2)
+	require.Len(t, desired["alloy/b"], 2)
+
+	// The same group can't be defined twice in a namespace.
+	_, err = DesiredState([]Source{
+		{Name: "a.yaml", Namespace: "a", Content: []byte(ruleFile)},
+		{Name: "other/a.yaml", Namespace: "a", Content: []byte(ruleFile)},
+	}, prefix, true)
+	require.ErrorContains(t, err, `rule group "group1" of namespace "alloy/a" is defined in both a.yaml and other/a.yaml`)
+}
+
+func TestParseRuleGroups(t *testing.T) {
+	logQL := `
+groups:
+  - name: logs
+    rules:
+      - alert: Errors
+        expr: sum(rate({app="foo"} |= "error" [5m])) > 0
+`
+	_, err := ParseRuleGroups([]byte(logQL), true)
+	require.Error(t, err, "LogQL isn't valid PromQL")
+
+	groups, err := ParseRuleGroups([]byte(logQL), false)
+	require.NoError(t, err)
+	require.Len(t, groups, 1)
+
+	_, err = ParseRuleGroups([]byte("groups:\n  - name: a\n  - name: a\n"), false)
+	require.ErrorContains(t, err, `duplicate rule group name "a"`)
+
+	_, err = ParseRuleGroups([]byte("groups:\n  - name: a\n    rules:\n      - expr: up\n"), false)
+	require.ErrorContains(t, err, "exactly one of record or alert must be set")
+
+	_, err = ParseRuleGroups([]byte("unknown: true\n"), false)
+	require.Error(t, err)
+
+	groups, err = ParseRuleGroups(nil, false)
+	require.NoError(t, err)
+	require.Empty(t, groups)
+}
+
+func TestSyncer(t *testing.T) {
+	client := newFakeClient()
+	client.rules["unmanaged"] = parseGroups(t, ruleFile)
+	client.rules["alloy/stale"] = parseGroups(t, ruleFile)
+
+	s := NewSyncer(client, log.NewNopLogger(), func(ns string) bool { return ns != "unmanaged" })
+
+	desired, err := DesiredState([]Source{{Name: "a", Namespace: "a", Content: []byte(ruleFile)}}, func(ns string) string { return "alloy/" + ns }, true)
+	require.NoError(t, err)
+
+	changes, err := s.Sync(context.Background(), desired)
+	require.NoError(t, err)
+	require.Equal(t, Changes{Added: 2, Removed: 2}, changes)
+
+	require.Len(t, client.rules["unmanaged"], 2, "unmanaged namespaces must be left as is")
+	require.NotContains(t, client.rules, "alloy/stale")
+	require.Equal(t, desired["alloy/a"], client.rules["alloy/a"])
+
+	// Syncing again is a no-op.
+	changes, err = s.Sync(context.Background(), desired)
+	require.NoError(t, err)
+	require.Equal(t, Changes{}, changes)
+
+	// Changed groups are updated.
+	desired["alloy/a"] = parseGroups(t, "groups:\n  - name: group1\n    rules:\n      - record: job:up:max\n        expr: max by (job) (up)\n")
+	changes, err = s.Sync(context.Background(), desired)
+	require.NoError(t, err)
+	require.Equal(t, Changes{Updated: 1, Removed: 1}, changes)
+
+	status := s.Status()
+	require.Empty(t, status.Error)
+	require.Equal(t, kubernetes.RuleGroupsByNamespace(desired), status.Actual)
+
+	// Errors of the ruler are reported.
+	client.err = errors.New("ruler unavailable")
+	_, err = s.Sync(context.Background(), desired)
+	require.ErrorContains(t, err, "ruler unavailable")
+	require.Contains(t, s.Status().Error, "ruler unavailable")
+}
+
+func TestRunner(t *testing.T) {
+	client := newFakeClient()
+
+	newRunner := func(componentID string, contents map[string]string) *Runner {
+		r := NewRunner(log.NewNopLogger(), NewMetrics("test", "Test"))
+		r.Update(Config{
+			Client:       client,
+			SyncInterval: time.Minute,
+			Namespacer:   NewNamespacer("alloy", componentID, "/"),
+			ValidateExpr: true,
+			Contents:     contents,
+		})
+		return r
+	}
+
+	// Components using the same prefix don't delete each other's rule groups.
+	a := newRunner("rules.file.a", map[string]string{"team": ruleFile})
+	b := newRunner("rules.file.b", map[string]string{"team": ruleFile})
+	a.sync(context.Background())
+	b.sync(context.Background())
+	a.sync(context.Background())
+	require.Len(t, client.rules["alloy/rules.file.a/team"], 2)
+	require.Len(t, client.rules["alloy/rules.file.b/team"], 2)
+	require.Equal(t, component.HealthTypeHealthy, a.CurrentHealth().Health)
+
+	require.Equal(t, []NamespaceStatus{{
+		Name:                 "alloy/rules.file.a/team",
+		NumRuleGroups:        2,
+		NumDesiredRuleGroups: 2,
+	}}, a.Status().Namespaces())
+
+	// Invalid rule files leave the ruler unchanged.
+	a.Update(Config{
+		Client:       client,
+		SyncInterval: time.Minute,
+		Namespacer:   NewNamespacer("alloy", "rules.file.a", "/"),
+		Contents:     map[string]string{"team": "groups: [}"},
+	})
+	a.sync(context.Background())
+	require.Len(t, client.rules["alloy/rules.file.a/team"], 2)
+	require.Equal(t, component.HealthTypeUnhealthy, a.CurrentHealth().Health)
+}
+
+func parseGroups(t *testing.T, content string) []rulefmt.RuleGroup {
+	t.Helper()
+	groups, err := ParseRuleGroups([]byte(content), true)
+	require.NoError(t, err)
+	return groups
+}
+
+type fakeClient struct {
+	mut   sync.Mutex
+	rules map[string][]rulefmt.RuleGroup
+	err   error
+}
+
+var _ Client = (*fakeClient)(nil)
+
+func newFakeClient() *fakeClient {
+	return &fakeClient{rules: make(map[string][]rulefmt.RuleGroup)}
+}
+
+func (c *fakeClient) CreateRuleGroup(_ context.Context, namespace string, rg rulefmt.RuleGroup) error {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	if c.err != nil {
+		return c.err
+	}
+	c.deleteLocked(namespace, rg.Name)
+	c.rules[namespace] = append(c.rules[namespace], rg)
+	return nil
+}
+
+func (c *fakeClient) DeleteRuleGroup(_ context.Context, namespace, groupName string) error {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	if c.err != nil {
+		return c.err
+	}
+	c.deleteLocked(namespace, groupName)
+	return nil
+}
+
+func (c *fakeClient) deleteLocked(namespace, groupName string) {
+	groups := c.rules[namespace]
+	for i, g := range groups {
+		if g.Name == groupName {
+			c.rules[namespace] = append(groups[:i:i], groups[i+1:]...)
+			break
+		}
+	}
+	if len(c.rules[namespace]) == 0 {
+		delete(c.rules, namespace)
+	}
+}
+
+func (c *fakeClient) ListRules(_ context.Context, _ string) (map[string][]rulefmt.RuleGroup, error) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	if c.err != nil {
+		return nil, c.err
+	}
+	out := make(map[string][]rulefmt.RuleGroup, len(c.rules))
+	for ns, groups := range c.rules {
+		out[ns] = append([]rulefmt.RuleGroup(nil), groups...)
+	}
+	return out, nil
+}
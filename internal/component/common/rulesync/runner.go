@@ -0,0 +1,281 @@
+package rulesync
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/dskit/instrument"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/grafana/alloy/internal/component"
+	"github.com/grafana/alloy/internal/component/discovery"
+	"github.com/grafana/alloy/internal/runtime/logging/level"
+)
+
+// syncTimeout is the maximum duration of a sync with the ruler.
+const syncTimeout = 30 * time.Second
+
+// Namespacer maps the namespaces of rule files to the namespaces of the ruler
+// owned by a component.
+//
+// The namespaces of the ruler are made of a prefix, the ID of the component
+// and the namespace of the rule file, joined by a separator. Including the ID
+// of the component prevents components using the same prefix from deleting
+// each other's rule groups.
+type Namespacer struct {
+	prefix string
+}
+
+// NewNamespacer creates a Namespacer for the component with the ID
+// componentID.
+func NewNamespacer(prefix, componentID, separator string) Namespacer {
+	return Namespacer{prefix: prefix + separator + componentID + separator}
+}
+
+// Namespace returns the namespace of the ruler of the rule groups of
+// namespace.
+func (n Namespacer) Namespace(namespace string) string {
+	return n.prefix + namespace
+}
+
+// IsManaged returns true if the namespace of the ruler is owned by the
+// component. Other namespaces are left as is.
+func (n Namespacer) IsManaged(namespace string) bool {
+	return strings.HasPrefix(namespace, n.prefix)
+}
+
+// Metrics are the metrics of a Runner.
+type Metrics struct {
+	syncsTotal       prometheus.Counter
+	syncsFailed      prometheus.Counter
+	ruleGroupChanges *prometheus.CounterVec
+
+	// ClientTiming is the histogram of the durations of the requests of the
+	// ruler client.
+	ClientTiming *prometheus.HistogramVec
+}
+
+// NewMetrics creates the metrics of a Runner. Their names start with
+// subsystem, and their help refers to the ruler called ruler.
+func NewMetrics(subsystem, ruler string) *Metrics {
+	return &Metrics{
+		syncsTotal: prometheus.NewCounter(prometheus.CounterOpts{
+			Subsystem: subsystem,
+			Name:      "syncs_total",
+			Help:      fmt.Sprintf("Total number of syncs of the rule groups with the %s Ruler.", ruler),
+		}),
+		syncsFailed: prometheus.NewCounter(prometheus.CounterOpts{
+			Subsystem: subsystem,
+			Name:      "syncs_failed_total",
+			Help:      fmt.Sprintf("Total number of syncs of the rule groups with the %s Ruler which failed.", ruler),
+		}),
+		ruleGroupChanges: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Subsystem: subsystem,
+			Name:      "rule_group_changes_total",
+			Help:      fmt.Sprintf("Total number of rule groups changed in the %s Ruler, partitioned by kind of change.", ruler),
+		}, []string{"kind"}),
+		ClientTiming: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Subsystem: subsystem,
+			Name:      strings.ToLower(ruler) + "_client_request_duration_seconds",
+			Help:      fmt.Sprintf("Duration of requests to the %s API.", ruler),
+			Buckets:   instrument.DefBuckets,
+		}, instrument.HistogramCollectorBuckets),
+	}
+}
+
+// Register registers the metrics to r.
+func (m *Metrics) Register(r prometheus.Registerer) error {
+	for _, c := range []prometheus.Collector{
+		m.syncsTotal,
+		m.syncsFailed,
+		m.ruleGroupChanges,
+		m.ClientTiming,
+	} {
+		if err := r.Register(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Config configures a Runner.
+type Config struct {
+	Client       Client
+	SyncInterval time.Duration
+	Namespacer   Namespacer
+	// ValidateExpr indicates whether rule expressions must be valid PromQL
+	// expressions.
+	ValidateExpr bool
+
+	// Targets are rule files, usually exported by local.file_match.
+	Targets []discovery.Target
+	// Contents are the contents of rule files keyed by namespace.
+	Contents map[string]string
+}
+
+// Runner periodically syncs the rule groups read from files or strings with a
+// ruler. It implements the behavior shared by the rules.file components.
+type Runner struct {
+	log     log.Logger
+	metrics *Metrics
+	updates chan struct{}
+
+	mut    sync.RWMutex
+	config Config
+	syncer *Syncer
+
+	healthMut sync.RWMutex
+	health    component.Health
+}
+
+// NewRunner creates a Runner. Update must be called before Run.
+func NewRunner(logger log.Logger, metrics *Metrics) *Runner {
+	return &Runner{
+		log:     logger,
+		metrics: metrics,
+		updates: make(chan struct{}, 1),
+	}
+}
+
+// Update updates the configuration of the Runner and schedules a sync.
+func (r *Runner) Update(config Config) {
+	r.mut.Lock()
+	r.config = config
+	r.syncer = NewSyncer(config.Client, r.log, config.Namespacer.IsManaged)
+	r.mut.Unlock()
+
+	select {
+	case r.updates <- struct{}{}:
+	default: // update already scheduled
+	}
+}
+
+// Run syncs the rule groups every sync interval and after every update,
+// until ctx is canceled.
+func (r *Runner) Run(ctx context.Context) error {
+	ticker := time.NewTicker(r.current().SyncInterval)
+	defer ticker.Stop()
+
+	for {
+		r.sync(ctx)
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-r.updates:
+			ticker.Reset(r.current().SyncInterval)
+		case <-ticker.C:
+		}
+	}
+}
+
+func (r *Runner) current() Config {
+	r.mut.RLock()
+	defer r.mut.RUnlock()
+	return r.config
+}
+
+// sync reads the rule files and syncs their rule groups with the ruler.
+func (r *Runner) sync(ctx context.Context) {
+	r.mut.RLock()
+	config, syncer := r.config, r.syncer
+	r.mut.RUnlock()
+
+	ctx, cancel := context.WithTimeout(ctx, syncTimeout)
+	defer cancel()
+
+	r.metrics.syncsTotal.Inc()
+	changes, err := syncRules(ctx, config, syncer)
+	r.metrics.ruleGroupChanges.WithLabelValues("add").Add(float64(changes.Added))
+	r.metrics.ruleGroupChanges.WithLabelValues("update").Add(float64(changes.Updated))
+	r.metrics.ruleGroupChanges.WithLabelValues("remove").Add(float64(changes.Removed))
+	if err != nil {
+		r.metrics.syncsFailed.Inc()
+		level.Error(r.log).Log("msg", "failed to sync rule groups", "err", err)
+		r.setHealth(component.Health{
+			Health:     component.HealthTypeUnhealthy,
+			Message:    err.Error(),
+			UpdateTime: time.Now(),
+		})
+		return
+	}
+	r.setHealth(component.Health{
+		Health:     component.HealthTypeHealthy,
+		UpdateTime: time.Now(),
+	})
+}
+
+func syncRules(ctx context.Context, config Config, syncer *Syncer) (Changes, error) {
+	sources, err := ReadSources(config.Targets, config.Contents)
+	if err != nil {
+		syncer.Fail(err)
+		return Changes{}, err
+	}
+
+	desired, err := DesiredState(sources, config.Namespacer.Namespace, config.ValidateExpr)
+	if err != nil {
+		syncer.Fail(err)
+		return Changes{}, err
+	}
+
+	return syncer.Sync(ctx, desired)
+}
+
+func (r *Runner) setHealth(h component.Health) {
+	r.healthMut.Lock()
+	defer r.healthMut.Unlock()
+	r.health = h
+}
+
+// CurrentHealth returns the health of the Runner, based on the result of the
+// last sync.
+func (r *Runner) CurrentHealth() component.Health {
+	r.healthMut.RLock()
+	defer r.healthMut.RUnlock()
+	return r.health
+}
+
+// Status reports the result of the last sync.
+func (r *Runner) Status() Status {
+	r.mut.RLock()
+	syncer := r.syncer
+	r.mut.RUnlock()
+	return syncer.Status()
+}
+
+// NamespaceStatus counts the rule groups of a namespace of the ruler.
+type NamespaceStatus struct {
+	Name                 string
+	NumRuleGroups        int
+	NumDesiredRuleGroups int
+}
+
+// Namespaces returns the actual and desired rule groups counts of the
+// namespaces of s, sorted by name.
+func (s Status) Namespaces() []NamespaceStatus {
+	names := make(map[string]struct{})
+	for ns := range s.Actual {
+		names[ns] = struct{}{}
+	}
+	for ns := range s.Desired {
+		names[ns] = struct{}{}
+	}
+
+	out := make([]NamespaceStatus, 0, len(names))
+	for ns := range names {
+		out = append(out, NamespaceStatus{
+			Name:                 ns,
+			NumRuleGroups:        len(s.Actual[ns]),
+			NumDesiredRuleGroups: len(s.Desired[ns]),
+		})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		return out[i].Name < out[j].Name
+	})
+	return out
+}
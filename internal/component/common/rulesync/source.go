@@ -0,0 +1,145 @@
+// Package rulesync syncs rule groups read from files or strings to the ruler
+// API of Mimir or Loki.
+package rulesync
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/grafana/alloy/internal/component/common/kubernetes"
+	"github.com/grafana/alloy/internal/component/discovery"
+	"github.com/hashicorp/go-multierror"
+	"github.com/prometheus/prometheus/model/rulefmt"
+	"gopkg.in/yaml.v3" // Used for prometheus rulefmt compatibility instead of gopkg.in/yaml.v2
+)
+
+const (
+	// PathLabel is the label of a target holding the path of a rule file.
+	PathLabel = "__path__"
+	// NamespaceLabel is the label of a target overriding the namespace of the
+	// rule groups of a file.
+	NamespaceLabel = "namespace"
+)
+
+// Source is a rule file read from a target or from a string.
+type Source struct {
+	// Name identifies the source: the path of a file, or the key of a string.
+	Name string
+	// Namespace is the ruler namespace of the rule groups of the source, before
+	// the namespace prefix of the component is applied.
+	Namespace string
+	Content   []byte
+}
+
+// ReadSources reads the rule files of targets and combines them with
+// contents, keyed by namespace. The namespace of a file is the value of the
+// namespace label of its target, or its base name without extension.
+func ReadSources(targets []discovery.Target, contents map[string]string) ([]Source, error) {
+	sources := make([]Source, 0, len(targets)+len(contents))
+
+	for _, target := range targets {
+		path, ok := target.Get(PathLabel)
+		if !ok || path == "" {
+			return nil, fmt.Errorf("target %v has no %s label", target, PathLabel)
+		}
+		buf, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("reading rule file: %w", err)
+		}
+
+		namespace, _ := target.Get(NamespaceLabel)
+		if namespace == "" {
+			namespace = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		}
+		sources = append(sources, Source{Name: path, Namespace: namespace, Content: buf})
+	}
+
+	for namespace, content := range contents {
+		sources = append(sources, Source{Name: namespace, Namespace: namespace, Content: []byte(content)})
+	}
+
+	sort.Slice(sources, func(i, j int) bool { return sources[i].Name < sources[j].Name })
+	return sources, nil
+}
+
+// DesiredState parses the rule groups of sources and indexes them by the
+// ruler namespace returned by namespaceFor. Expressions are only validated
+// as PromQL if validateExpr is true.
+//
+// An error is returned if any source is invalid, so that the rule groups of
+// a source with a syntax error aren't deleted from the ruler.
+func DesiredState(sources []Source, namespaceFor func(string) string, validateExpr bool) (kubernetes.RuleGroupsByNamespace, error) {
+	desired := make(kubernetes.RuleGroupsByNamespace)
+	seen := make(map[string]map[string]string) // Namespace -> group name -> source name.
+
+	for _, src := range sources {
+		if src.Namespace == "" || strings.Contains(src.Namespace, "/") {
+			return nil, fmt.Errorf("invalid namespace %q for %s, namespaces must not be empty or contain a slash", src.Namespace, src.Name)
+		}
+		groups, err := ParseRuleGroups(src.Content, validateExpr)
+		if err != nil {
+			return nil, fmt.Errorf("parsing rule groups of %s: %w", src.Name, err)
+		}
+
+		namespace := namespaceFor(src.Namespace)
+		if seen[namespace] == nil {
+			seen[namespace] = make(map[string]string)
+		}
+		for _, group := range groups {
+			if other, ok := seen[namespace][group.Name]; ok {
+				return nil, fmt.Errorf("rule group %q of namespace %q is defined in both %s and %s", group.Name, namespace, other, src.Name)
+			}
+			seen[namespace][group.Name] = src.Name
+		}
+		desired[namespace] = append(desired[namespace], groups...)
+	}
+
+	return desired, nil
+}
+
+// ParseRuleGroups parses a rule file in the Prometheus rule file format. If
+// validateExpr is false, expressions aren't parsed, which allows LogQL
+// expressions, and only the structure of the file is validated.
+func ParseRuleGroups(buf []byte, validateExpr bool) ([]rulefmt.RuleGroup, error) {
+	if validateExpr {
+		groups, errs := rulefmt.Parse(buf)
+		if len(errs) > 0 {
+			return nil, multierror.Append(nil, errs...)
+		}
+		return groups.Groups, nil
+	}
+
+	var groups rulefmt.RuleGroups
+	dec := yaml.NewDecoder(bytes.NewReader(buf))
+	dec.KnownFields(true)
+	if err := dec.Decode(&groups); err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+
+	names := make(map[string]struct{}, len(groups.Groups))
+	for _, group := range groups.Groups {
+		if group.Name == "" {
+			return nil, fmt.Errorf("rule group name must not be empty")
+		}
+		if _, ok := names[group.Name]; ok {
+			return nil, fmt.Errorf("duplicate rule group name %q", group.Name)
+		}
+		names[group.Name] = struct{}{}
+
+		for _, rule := range group.Rules {
+			if (rule.Record.Value == "") == (rule.Alert.Value == "") {
+				return nil, fmt.Errorf("rule group %q: exactly one of record or alert must be set", group.Name)
+			}
+			if rule.Expr.Value == "" {
+				return nil, fmt.Errorf("rule group %q: expr must not be empty", group.Name)
+			}
+		}
+	}
+	return groups.Groups, nil
+}
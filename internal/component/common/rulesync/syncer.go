@@ -0,0 +1,186 @@
+package rulesync
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/alloy/internal/component/common/kubernetes"
+	"github.com/grafana/alloy/internal/runtime/logging/level"
+	"github.com/hashicorp/go-multierror"
+	"github.com/prometheus/prometheus/model/rulefmt"
+)
+
+// Client is the part of the Mimir and Loki ruler clients used to sync rule
+// groups.
+type Client interface {
+	CreateRuleGroup(ctx context.Context, namespace string, rg rulefmt.RuleGroup) error
+	DeleteRuleGroup(ctx context.Context, namespace, groupName string) error
+	ListRules(ctx context.Context, namespace string) (map[string][]rulefmt.RuleGroup, error)
+}
+
+// Syncer applies the difference between a desired state and the rule groups
+// of the ruler. Only the namespaces for which isManaged returns true are
+// changed.
+type Syncer struct {
+	client    Client
+	logger    log.Logger
+	isManaged func(namespace string) bool
+
+	mut      sync.RWMutex
+	actual   kubernetes.RuleGroupsByNamespace
+	desired  kubernetes.RuleGroupsByNamespace
+	lastSync time.Time
+	lastErr  error
+	changes  Changes
+}
+
+// Changes counts the rule groups changed by a sync.
+type Changes struct {
+	Added, Updated, Removed int
+}
+
+// NewSyncer creates a Syncer.
+func NewSyncer(client Client, logger log.Logger, isManaged func(namespace string) bool) *Syncer {
+	return &Syncer{
+		client:    client,
+		logger:    logger,
+		isManaged: isManaged,
+	}
+}
+
+// Sync creates, updates and deletes rule groups in the managed namespaces of
+// the ruler so that they match desired. It returns the rule groups which were
+// changed, even if an error occurred.
+func (s *Syncer) Sync(ctx context.Context, desired kubernetes.RuleGroupsByNamespace) (Changes, error) {
+	changes, err := s.sync(ctx, desired)
+
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	s.desired = desired
+	s.lastSync = time.Now()
+	s.lastErr = err
+	s.changes = changes
+	return changes, err
+}
+
+// Fail records that the desired state couldn't be determined. The ruler is
+// left unchanged.
+func (s *Syncer) Fail(err error) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	s.lastSync = time.Now()
+	s.lastErr = err
+	s.changes = Changes{}
+}
+
+func (s *Syncer) sync(ctx context.Context, desired kubernetes.RuleGroupsByNamespace) (Changes, error) {
+	var changes Changes
+	if err := s.refresh(ctx); err != nil {
+		return changes, err
+	}
+
+	var result error
+	for namespace, diffs := range kubernetes.DiffRuleState(desired, s.Actual()) {
+		for _, diff := range diffs {
+			if err := s.apply(ctx, namespace, diff, &changes); err != nil {
+				result = multierror.Append(result, err)
+				break
+			}
+		}
+	}
+
+	// Refresh the state of the ruler after applying changes.
+	if err := s.refresh(ctx); err != nil {
+		result = multierror.Append(result, err)
+	}
+	return changes, result
+}
+
+func (s *Syncer) apply(ctx context.Context, namespace string, diff kubernetes.RuleGroupDiff, changes *Changes) error {
+	switch diff.Kind {
+	case kubernetes.RuleGroupDiffKindAdd:
+		if err := s.client.CreateRuleGroup(ctx, namespace, diff.Desired); err != nil {
+			return err
+		}
+		changes.Added++
+		level.Info(s.logger).Log("msg", "added rule group", "namespace", namespace, "group", diff.Desired.Name)
+	case kubernetes.RuleGroupDiffKindRemove:
+		if err := s.client.DeleteRuleGroup(ctx, namespace, diff.Actual.Name); err != nil {
+			return err
+		}
+		changes.Removed++
+		level.Info(s.logger).Log("msg", "removed rule group", "namespace", namespace, "group", diff.Actual.Name)
+	case kubernetes.RuleGroupDiffKindUpdate:
+		if err := s.client.CreateRuleGroup(ctx, namespace, diff.Desired); err != nil {
+			return err
+		}
+		changes.Updated++
+		level.Info(s.logger).Log("msg", "updated rule group", "namespace", namespace, "group", diff.Desired.Name)
+	default:
+		level.Error(s.logger).Log("msg", "unknown rule group diff kind", "kind", diff.Kind)
+	}
+	return nil
+}
+
+// refresh lists the rule groups of the managed namespaces of the ruler.
+func (s *Syncer) refresh(ctx context.Context) error {
+	rulesByNamespace, err := s.client.ListRules(ctx, "")
+	if err != nil {
+		return fmt.Errorf("listing rules: %w", err)
+	}
+	for ns := range rulesByNamespace {
+		if !s.isManaged(ns) {
+			delete(rulesByNamespace, ns)
+		}
+	}
+
+	s.mut.Lock()
+	s.actual = rulesByNamespace
+	s.mut.Unlock()
+	return nil
+}
+
+// Actual returns the rule groups of the managed namespaces of the ruler, as
+// of the last sync.
+func (s *Syncer) Actual() kubernetes.RuleGroupsByNamespace {
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+	return copyState(s.actual)
+}
+
+// Status reports the result of the last sync.
+func (s *Syncer) Status() Status {
+	s.mut.RLock()
+	defer s.mut.RUnlock()
+
+	st := Status{
+		LastSync: s.lastSync,
+		Changes:  s.changes,
+		Desired:  copyState(s.desired),
+		Actual:   copyState(s.actual),
+	}
+	if s.lastErr != nil {
+		st.Error = s.lastErr.Error()
+	}
+	return st
+}
+
+// Status is the result of the last sync of a Syncer.
+type Status struct {
+	LastSync time.Time
+	Error    string
+	Changes  Changes
+	Desired  kubernetes.RuleGroupsByNamespace
+	Actual   kubernetes.RuleGroupsByNamespace
+}
+
+func copyState(state kubernetes.RuleGroupsByNamespace) kubernetes.RuleGroupsByNamespace {
+	out := make(kubernetes.RuleGroupsByNamespace, len(state))
+	for ns, groups := range state {
+		out[ns] = groups
+	}
+	return out
+}
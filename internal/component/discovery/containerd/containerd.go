@@ -0,0 +1,334 @@
+// Package containerd implements a discovery.containerd component.
+package containerd
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/discovery/targetgroup"
+	"github.com/prometheus/prometheus/util/strutil"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	runtimeapi "k8s.io/cri-api/pkg/apis/runtime/v1"
+
+	"github.com/grafana/alloy/internal/component"
+	"github.com/grafana/alloy/internal/component/discovery"
+	"github.com/grafana/alloy/internal/featuregate"
+	"github.com/grafana/alloy/internal/runtime/logging/level"
+)
+
+const (
+	defaultAddress         = "unix:///run/containerd/containerd.sock"
+	defaultRefreshInterval = 5 * time.Second
+	defaultRequestTimeout  = 10 * time.Second
+
+	metaLabelPrefix               = model.MetaLabelPrefix + "containerd_"
+	presentValue                  = model.LabelValue("true")
+	podIDLabel                    = metaLabelPrefix + "pod_id"
+	podNameLabel                  = metaLabelPrefix + "pod_name"
+	podNamespaceLabel             = metaLabelPrefix + "pod_namespace"
+	podUIDLabel                   = metaLabelPrefix + "pod_uid"
+	podStateLabel                 = metaLabelPrefix + "pod_state"
+	podIPLabel                    = metaLabelPrefix + "pod_ip"
+	podRuntimeHandlerLabel        = metaLabelPrefix + "pod_runtime_handler"
+	podLabelPrefix                = metaLabelPrefix + "pod_label_"
+	podLabelPresentPrefix         = metaLabelPrefix + "pod_labelpresent_"
+	podAnnotationPrefix           = metaLabelPrefix + "pod_annotation_"
+	podAnnotationPresentPrefix    = metaLabelPrefix + "pod_annotationpresent_"
+	containerIDLabel              = metaLabelPrefix + "container_id"
+	containerNameLabel            = metaLabelPrefix + "container_name"
+	containerAttemptLabel         = metaLabelPrefix + "container_attempt"
+	containerImageLabel           = metaLabelPrefix + "container_image"
+	containerImageRefLabel        = metaLabelPrefix + "container_image_ref"
+	containerStateLabel           = metaLabelPrefix + "container_state"
+	containerLogPathLabel         = metaLabelPrefix + "container_log_path"
+	containerLabelPrefix          = metaLabelPrefix + "container_label_"
+	containerLabelPresentPrefix   = metaLabelPrefix + "container_labelpresent_"
+	containerAnnotationPrefix     = metaLabelPrefix + "container_annotation_"
+	containerAnnotationPresPrefix = metaLabelPrefix + "container_annotationpresent_"
+
+	// pathLabel is the label used by loki.source.file to find the file to
+	// tail.
+	pathLabel = "__path__"
+	// containerIDPrivateLabel is the label used by discovery.process and
+	// pyroscope to match processes with containers.
+	containerIDPrivateLabel = "__container_id__"
+)
+
+func init() {
+	component.Register(component.Registration{
+		Name:      "discovery.containerd",
+		Stability: featuregate.StabilityExperimental,
+		Args:      Arguments{},
+		Exports:   discovery.Exports{},
+		Build: func(opts component.Options, args component.Arguments) (component.Component, error) {
+			return New(opts, args.(Arguments))
+		},
+	})
+}
+
+// Arguments configures the discovery.containerd component.
+type Arguments struct {
+	Address         string        `alloy:"address,attr,optional"`
+	RefreshInterval time.Duration `alloy:"refresh_interval,attr,optional"`
+	RequestTimeout  time.Duration `alloy:"request_timeout,attr,optional"`
+	Namespaces      []string      `alloy:"namespaces,attr,optional"`
+}
+
+// SetToDefault implements syntax.Defaulter.
+func (args *Arguments) SetToDefault() {
+	*args = Arguments{
+		Address:         defaultAddress,
+		RefreshInterval: defaultRefreshInterval,
+		RequestTimeout:  defaultRequestTimeout,
+	}
+}
+
+// Validate implements syntax.Validator.
+func (args *Arguments) Validate() error {
+	if args.Address == "" {
+		return fmt.Errorf("address must not be empty")
+	}
+	if args.RefreshInterval <= 0 {
+		return fmt.Errorf("refresh_interval must be greater than 0")
+	}
+	if args.RequestTimeout <= 0 {
+		return fmt.Errorf("request_timeout must be greater than 0")
+	}
+	return nil
+}
+
+// New returns a new instance of a discovery.containerd component.
+func New(opts component.Options, args Arguments) (*discovery.Component, error) {
+	return discovery.New(opts, args, func(args component.Arguments) (discovery.DiscovererConfig, error) {
+		newArgs := args.(Arguments)
+		return &containerdDiscoveryConfig{
+			args: newArgs,
+			opts: opts,
+		}, nil
+	})
+}
+
+// Discovery lists pod sandboxes and containers through the CRI runtime
+// service.
+type Discovery struct {
+	logger           log.Logger
+	conn             *grpc.ClientConn
+	client           runtimeapi.RuntimeServiceClient
+	requestTimeout   time.Duration
+	targetNamespaces []string
+
+	// cache of container statuses, which don't change for the fields we use
+	// once a container has been created.
+	statuses map[string]*runtimeapi.ContainerStatus
+	// cache of pod sources from the last discovery refresh
+	discoveredPodSources map[string]bool
+}
+
+// NewContainerdDiscovery creates a Discovery which connects to the CRI
+// runtime service listening on args.Address.
+func NewContainerdDiscovery(logger log.Logger, args Arguments) (*Discovery, error) {
+	conn, err := grpc.NewClient(args.Address, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		return nil, fmt.Errorf("error creating CRI client for %q: %w", args.Address, err)
+	}
+	return &Discovery{
+		logger:           logger,
+		conn:             conn,
+		client:           runtimeapi.NewRuntimeServiceClient(conn),
+		requestTimeout:   args.RequestTimeout,
+		targetNamespaces: args.Namespaces,
+		statuses:         make(map[string]*runtimeapi.ContainerStatus),
+	}, nil
+}
+
+// Close closes the connection to the CRI runtime service.
+func (d *Discovery) Close() error {
+	return d.conn.Close()
+}
+
+// Refresh lists all pod sandboxes and their containers and builds one target
+// group per pod sandbox.
+//
+// Pod sandboxes and containers which are removed while Refresh runs are
+// skipped. Other errors getting the status of a single pod sandbox or
+// container are logged, and its target is built without the status.
+func (d *Discovery) Refresh(ctx context.Context) ([]*targetgroup.Group, error) {
+	ctx, cancel := context.WithTimeout(ctx, d.requestTimeout)
+	defer cancel()
+
+	podsResp, err := d.client.ListPodSandbox(ctx, &runtimeapi.ListPodSandboxRequest{})
+	if err != nil {
+		return nil, fmt.Errorf("error listing pod sandboxes: %w", err)
+	}
+	containersResp, err := d.client.ListContainers(ctx, &runtimeapi.ListContainersRequest{})
+	if err != nil {
+		return nil, fmt.Errorf("error listing containers: %w", err)
+	}
+
+	containersByPod := make(map[string][]*runtimeapi.Container)
+	for _, c := range containersResp.Containers {
+		containersByPod[c.PodSandboxId] = append(containersByPod[c.PodSandboxId], c)
+	}
+
+	var (
+		targetGroups []*targetgroup.Group
+		discovered   = make(map[string]bool)
+		statuses     = make(map[string]*runtimeapi.ContainerStatus)
+	)
+	for _, pod := range podsResp.Items {
+		if !d.podInTargetNamespaces(pod) {
+			continue
+		}
+
+		var podIP string
+		if pod.State == runtimeapi.PodSandboxState_SANDBOX_READY {
+			resp, err := d.client.PodSandboxStatus(ctx, &runtimeapi.PodSandboxStatusRequest{PodSandboxId: pod.Id})
+			switch {
+			case status.Code(err) == codes.NotFound:
+				continue
+			case err != nil:
+				level.Warn(d.logger).Log("msg", "error getting status of pod sandbox", "pod_id", pod.Id, "err", err)
+			case resp.Status.GetNetwork() != nil:
+				podIP = resp.Status.Network.Ip
+			}
+		}
+
+		tg := &targetgroup.Group{
+			Source: podSource(pod),
+			Labels: podLabels(pod, podIP),
+		}
+		for _, c := range containersByPod[pod.Id] {
+			cs, err := d.containerStatus(ctx, c.Id)
+			switch {
+			case status.Code(err) == codes.NotFound:
+				continue
+			case err != nil:
+				// The status isn't cached, so that it's requested again on the
+				// next refresh.
+				level.Warn(d.logger).Log("msg", "error getting status of container", "container_id", c.Id, "err", err)
+			default:
+				statuses[c.Id] = cs
+			}
+			tg.Targets = append(tg.Targets, containerLabels(c, cs, podIP))
+		}
+		targetGroups = append(targetGroups, tg)
+		discovered[tg.Source] = true
+	}
+
+	// check for pods that were present in the last refresh but not in this one
+	for k := range d.discoveredPodSources {
+		if _, ok := discovered[k]; !ok {
+			// append a target group with no targets to indicate the pod was removed
+			targetGroups = append(targetGroups, &targetgroup.Group{
+				Source: k,
+			})
+		}
+	}
+	d.discoveredPodSources = discovered
+	d.statuses = statuses
+
+	return targetGroups, nil
+}
+
+// containerStatus returns the cached status of a container, or requests it
+// from the runtime if the container hasn't been seen before.
+func (d *Discovery) containerStatus(ctx context.Context, id string) (*runtimeapi.ContainerStatus, error) {
+	if status, ok := d.statuses[id]; ok {
+		return status, nil
+	}
+	resp, err := d.client.ContainerStatus(ctx, &runtimeapi.ContainerStatusRequest{ContainerId: id})
+	if err != nil {
+		return nil, err
+	}
+	return resp.Status, nil
+}
+
+func (d *Discovery) podInTargetNamespaces(pod *runtimeapi.PodSandbox) bool {
+	if len(d.targetNamespaces) == 0 {
+		return true
+	}
+	return slices.Contains(d.targetNamespaces, pod.GetMetadata().GetNamespace())
+}
+
+func podSource(pod *runtimeapi.PodSandbox) string {
+	return "pod/" + pod.Id
+}
+
+func podLabels(pod *runtimeapi.PodSandbox, podIP string) model.LabelSet {
+	ls := model.LabelSet{
+		podIDLabel:             lv(pod.Id),
+		podNameLabel:           lv(pod.GetMetadata().GetName()),
+		podNamespaceLabel:      lv(pod.GetMetadata().GetNamespace()),
+		podUIDLabel:            lv(pod.GetMetadata().GetUid()),
+		podStateLabel:          lv(podState(pod.State)),
+		podRuntimeHandlerLabel: lv(pod.RuntimeHandler),
+	}
+	if podIP != "" {
+		ls[podIPLabel] = lv(podIP)
+	}
+	addMetadata(ls, pod.Labels, podLabelPrefix, podLabelPresentPrefix)
+	addMetadata(ls, pod.Annotations, podAnnotationPrefix, podAnnotationPresentPrefix)
+	return ls
+}
+
+func containerLabels(c *runtimeapi.Container, status *runtimeapi.ContainerStatus, podIP string) model.LabelSet {
+	image := status.GetImage().GetImage()
+	if image == "" {
+		image = c.GetImage().GetImage()
+	}
+	imageRef := status.GetImageRef()
+	if imageRef == "" {
+		imageRef = c.ImageRef
+	}
+
+	ls := model.LabelSet{
+		containerIDLabel:        lv(c.Id),
+		containerNameLabel:      lv(c.GetMetadata().GetName()),
+		containerAttemptLabel:   lv(strconv.FormatUint(uint64(c.GetMetadata().GetAttempt()), 10)),
+		containerImageLabel:     lv(image),
+		containerImageRefLabel:  lv(imageRef),
+		containerStateLabel:     lv(containerState(c.State)),
+		containerIDPrivateLabel: lv(c.Id),
+	}
+	// The address is only a placeholder for components which require one;
+	// a port has to be added with discovery.relabel before scraping.
+	if podIP != "" {
+		ls[model.AddressLabel] = lv(podIP)
+	}
+	if logPath := status.GetLogPath(); logPath != "" {
+		ls[containerLogPathLabel] = lv(logPath)
+		ls[pathLabel] = lv(logPath)
+	}
+	addMetadata(ls, c.Labels, containerLabelPrefix, containerLabelPresentPrefix)
+	addMetadata(ls, c.Annotations, containerAnnotationPrefix, containerAnnotationPresPrefix)
+	return ls
+}
+
+func addMetadata(ls model.LabelSet, metadata map[string]string, prefix, presentPrefix string) {
+	for k, v := range metadata {
+		ln := strutil.SanitizeLabelName(k)
+		ls[model.LabelName(prefix+ln)] = lv(v)
+		ls[model.LabelName(presentPrefix+ln)] = presentValue
+	}
+}
+
+func podState(state runtimeapi.PodSandboxState) string {
+	return strings.ToLower(strings.TrimPrefix(state.String(), "SANDBOX_"))
+}
+
+func containerState(state runtimeapi.ContainerState) string {
+	return strings.ToLower(strings.TrimPrefix(state.String(), "CONTAINER_"))
+}
+
+func lv(s string) model.LabelValue {
+	return model.LabelValue(s)
+}
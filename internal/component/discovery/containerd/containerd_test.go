@@ -0,0 +1,270 @@
+package containerd
+
+import (
+	"context"
+	"net"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/discovery/targetgroup"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	runtimeapi "k8s.io/cri-api/pkg/apis/runtime/v1"
+
+	"github.com/grafana/alloy/syntax"
+)
+
+// fakeRuntimeService is a CRI runtime service serving a fixed set of pod
+// sandboxes and containers.
+type fakeRuntimeService struct {
+	runtimeapi.UnimplementedRuntimeServiceServer
+
+	mut            sync.Mutex
+	pods           []*runtimeapi.PodSandbox
+	podIPs         map[string]string
+	containers     []*runtimeapi.Container
+	statusRequests int
+	// statusErrs holds the errors returned when requesting the status of a
+	// pod sandbox or a container, indexed by ID.
+	statusErrs map[string]error
+}
+
+func (s *fakeRuntimeService) ListPodSandbox(context.Context, *runtimeapi.ListPodSandboxRequest) (*runtimeapi.ListPodSandboxResponse, error) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	return &runtimeapi.ListPodSandboxResponse{Items: s.pods}, nil
+}
+
+func (s *fakeRuntimeService) PodSandboxStatus(_ context.Context, req *runtimeapi.PodSandboxStatusRequest) (*runtimeapi.PodSandboxStatusResponse, error) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	if err := s.statusErrs[req.PodSandboxId]; err != nil {
+		return nil, err
+	}
+	return &runtimeapi.PodSandboxStatusResponse{Status: &runtimeapi.PodSandboxStatus{
+		Id:      req.PodSandboxId,
+		Network: &runtimeapi.PodSandboxNetworkStatus{Ip: s.podIPs[req.PodSandboxId]},
+	}}, nil
+}
+
+func (s *fakeRuntimeService) ListContainers(context.Context, *runtimeapi.ListContainersRequest) (*runtimeapi.ListContainersResponse, error) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	return &runtimeapi.ListContainersResponse{Containers: s.containers}, nil
+}
+
+func (s *fakeRuntimeService) ContainerStatus(_ context.Context, req *runtimeapi.ContainerStatusRequest) (*runtimeapi.ContainerStatusResponse, error) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	s.statusRequests++
+	if err := s.statusErrs[req.ContainerId]; err != nil {
+		return nil, err
+	}
+	return &runtimeapi.ContainerStatusResponse{Status: &runtimeapi.ContainerStatus{
+		Id:       req.ContainerId,
+		Image:    &runtimeapi.ImageSpec{Image: "docker.io/library/nginx:1.25"},
+		ImageRef: "docker.io/library/nginx@sha256:abc",
+		LogPath:  "/var/log/pods/default_nginx_1234/nginx/0.log",
+	}}, nil
+}
+
+func startFakeRuntimeService(t *testing.T, svc *fakeRuntimeService) string {
+	socket := filepath.Join(t.TempDir(), "cri.sock")
+	lis, err := net.Listen("unix", socket)
+	require.NoError(t, err)
+
+	srv := grpc.NewServer()
+	runtimeapi.RegisterRuntimeServiceServer(srv, svc)
+	go srv.Serve(lis)
+	t.Cleanup(srv.Stop)
+
+	return "unix://" + socket
+}
+
+func TestAlloyConfig(t *testing.T) {
+	var args Arguments
+	require.NoError(t, syntax.Unmarshal([]byte{}, &args))
+	require.Equal(t, "unix:///run/containerd/containerd.sock", args.Address)
+	require.Equal(t, defaultRefreshInterval, args.RefreshInterval)
+
+	err := syntax.Unmarshal([]byte(`address = ""`), &args)
+	require.ErrorContains(t, err, "address must not be empty")
+}
+
+func TestRefresh(t *testing.T) {
+	svc := &fakeRuntimeService{
+		pods: []*runtimeapi.PodSandbox{
+			{
+				Id:          "pod-1",
+				Metadata:    &runtimeapi.PodSandboxMetadata{Name: "nginx", Namespace: "default", Uid: "1234"},
+				State:       runtimeapi.PodSandboxState_SANDBOX_READY,
+				Labels:      map[string]string{"app.kubernetes.io/name": "nginx"},
+				Annotations: map[string]string{"owner": "web"},
+			},
+			{
+				Id:       "pod-2",
+				Metadata: &runtimeapi.PodSandboxMetadata{Name: "agent", Namespace: "monitoring", Uid: "5678"},
+				State:    runtimeapi.PodSandboxState_SANDBOX_NOTREADY,
+			},
+		},
+		podIPs: map[string]string{"pod-1": "10.0.0.5"},
+		containers: []*runtimeapi.Container{
+			{
+				Id:           "c1",
+				PodSandboxId: "pod-1",
+				Metadata:     &runtimeapi.ContainerMetadata{Name: "nginx", Attempt: 1},
+				Image:        &runtimeapi.ImageSpec{Image: "sha256:abc"},
+				State:        runtimeapi.ContainerState_CONTAINER_RUNNING,
+				Labels:       map[string]string{"io.kubernetes.container.name": "nginx"},
+			},
+		},
+	}
+
+	var args Arguments
+	args.SetToDefault()
+	args.Address = startFakeRuntimeService(t, svc)
+	args.Namespaces = []string{"default"}
+
+	d, err := NewContainerdDiscovery(log.NewNopLogger(), args)
+	require.NoError(t, err)
+	defer d.Close()
+
+	groups, err := d.Refresh(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, []*targetgroup.Group{{
+		Source: "pod/pod-1",
+		Labels: model.LabelSet{
+			"__meta_containerd_pod_id":                                  "pod-1",
+			"__meta_containerd_pod_name":                                "nginx",
+			"__meta_containerd_pod_namespace":                           "default",
+			"__meta_containerd_pod_uid":                                 "1234",
+			"__meta_containerd_pod_state":                               "ready",
+			"__meta_containerd_pod_ip":                                  "10.0.0.5",
+			"__meta_containerd_pod_runtime_handler":                     "",
+			"__meta_containerd_pod_label_app_kubernetes_io_name":        "nginx",
+			"__meta_containerd_pod_labelpresent_app_kubernetes_io_name": "true",
+			"__meta_containerd_pod_annotation_owner":                    "web",
+			"__meta_containerd_pod_annotationpresent_owner":             "true",
+		},
+		Targets: []model.LabelSet{{
+			"__address__":                                                           "10.0.0.5",
+			"__container_id__":                                                      "c1",
+			"__path__":                                                              "/var/log/pods/default_nginx_1234/nginx/0.log",
+			"__meta_containerd_container_id":                                        "c1",
+			"__meta_containerd_container_name":                                      "nginx",
+			"__meta_containerd_container_attempt":                                   "1",
+			"__meta_containerd_container_image":                                     "docker.io/library/nginx:1.25",
+			"__meta_containerd_container_image_ref":                                 "docker.io/library/nginx@sha256:abc",
+			"__meta_containerd_container_state":                                     "running",
+			"__meta_containerd_container_log_path":                                  "/var/log/pods/default_nginx_1234/nginx/0.log",
+			"__meta_containerd_container_label_io_kubernetes_container_name":        "nginx",
+			"__meta_containerd_container_labelpresent_io_kubernetes_container_name": "true",
+		}},
+	}}, groups)
+
+	// Container statuses are cached between refreshes.
+	_, err = d.Refresh(context.Background())
+	require.NoError(t, err)
+	require.Equal(t, 1, svc.statusRequests)
+}
+
+func TestPodDeletion(t *testing.T) {
+	svc := &fakeRuntimeService{
+		pods: []*runtimeapi.PodSandbox{
+			{Id: "pod-1", Metadata: &runtimeapi.PodSandboxMetadata{Name: "a", Namespace: "default"}, State: runtimeapi.PodSandboxState_SANDBOX_NOTREADY},
+			{Id: "pod-2", Metadata: &runtimeapi.PodSandboxMetadata{Name: "b", Namespace: "default"}, State: runtimeapi.PodSandboxState_SANDBOX_NOTREADY},
+		},
+	}
+
+	var args Arguments
+	args.SetToDefault()
+	args.Address = startFakeRuntimeService(t, svc)
+
+	d, err := NewContainerdDiscovery(log.NewNopLogger(), args)
+	require.NoError(t, err)
+	defer d.Close()
+
+	groups, err := d.Refresh(context.Background())
+	require.NoError(t, err)
+	require.Len(t, groups, 2)
+
+	svc.mut.Lock()
+	svc.pods = svc.pods[1:]
+	svc.mut.Unlock()
+
+	groups, err = d.Refresh(context.Background())
+	require.NoError(t, err)
+	require.Len(t, groups, 2)
+	require.Equal(t, "pod/pod-2", groups[0].Source)
+	// The removed pod is reported as an empty group so its targets are dropped.
+	require.Equal(t, &targetgroup.Group{Source: "pod/pod-1"}, groups[1])
+
+	groups, err = d.Refresh(context.Background())
+	require.NoError(t, err)
+	require.Len(t, groups, 1)
+}
+
+func TestRefreshStatusErrors(t *testing.T) {
+	svc := &fakeRuntimeService{
+		pods: []*runtimeapi.PodSandbox{
+			{Id: "pod-1", Metadata: &runtimeapi.PodSandboxMetadata{Name: "a", Namespace: "default"}, State: runtimeapi.PodSandboxState_SANDBOX_READY},
+			{Id: "pod-2", Metadata: &runtimeapi.PodSandboxMetadata{Name: "b", Namespace: "default"}, State: runtimeapi.PodSandboxState_SANDBOX_READY},
+			{Id: "pod-3", Metadata: &runtimeapi.PodSandboxMetadata{Name: "c", Namespace: "default"}, State: runtimeapi.PodSandboxState_SANDBOX_READY},
+		},
+		podIPs: map[string]string{"pod-1": "10.0.0.1", "pod-2": "10.0.0.2", "pod-3": "10.0.0.3"},
+		containers: []*runtimeapi.Container{
+			{Id: "ctr-1", PodSandboxId: "pod-1", Metadata: &runtimeapi.ContainerMetadata{Name: "removed"}},
+			{Id: "ctr-2", PodSandboxId: "pod-1", Metadata: &runtimeapi.ContainerMetadata{Name: "failing"}},
+			{Id: "ctr-3", PodSandboxId: "pod-1", Metadata: &runtimeapi.ContainerMetadata{Name: "nginx"}},
+			{Id: "ctr-4", PodSandboxId: "pod-3", Metadata: &runtimeapi.ContainerMetadata{Name: "nginx"}},
+		},
+		statusErrs: map[string]error{
+			"ctr-1": status.Error(codes.NotFound, "container not found"),
+			"ctr-2": status.Error(codes.Unavailable, "runtime unavailable"),
+			"pod-2": status.Error(codes.NotFound, "pod sandbox not found"),
+			"pod-3": status.Error(codes.Internal, "internal error"),
+		},
+	}
+
+	var args Arguments
+	args.SetToDefault()
+	args.Address = startFakeRuntimeService(t, svc)
+
+	d, err := NewContainerdDiscovery(log.NewNopLogger(), args)
+	require.NoError(t, err)
+	defer d.Close()
+
+	groups, err := d.Refresh(context.Background())
+	require.NoError(t, err)
+
+	// The removed pod sandbox is skipped.
+	require.Len(t, groups, 2)
+	require.Equal(t, "pod/pod-1", groups[0].Source)
+	require.Equal(t, "pod/pod-3", groups[1].Source)
+
+	// The removed container is skipped, and the container whose status
+	// failed is kept without its status.
+	require.Len(t, groups[0].Targets, 2)
+	require.Equal(t, model.LabelValue("failing"), groups[0].Targets[0][containerNameLabel])
+	require.NotContains(t, groups[0].Targets[0], model.LabelName(pathLabel))
+	require.Equal(t, model.LabelValue("nginx"), groups[0].Targets[1][containerNameLabel])
+	require.Contains(t, groups[0].Targets[1], model.LabelName(pathLabel))
+
+	// The pod sandbox whose status failed is kept without its IP.
+	require.NotContains(t, groups[1].Labels, model.LabelName(podIPLabel))
+	require.Len(t, groups[1].Targets, 1)
+
+	// Failed statuses are requested again on the next refresh.
+	svc.mut.Lock()
+	delete(svc.statusErrs, "ctr-2")
+	svc.mut.Unlock()
+
+	groups, err = d.Refresh(context.Background())
+	require.NoError(t, err)
+	require.Contains(t, groups[0].Targets[0], model.LabelName(pathLabel))
+}
@@ -0,0 +1,88 @@
+package containerd
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/prometheus/client_golang/prometheus"
+	prom_discovery "github.com/prometheus/prometheus/discovery"
+	"github.com/prometheus/prometheus/discovery/refresh"
+	"github.com/prometheus/prometheus/discovery/targetgroup"
+
+	"github.com/grafana/alloy/internal/component"
+)
+
+type containerdDiscoveryConfig struct {
+	args Arguments
+	opts component.Options
+}
+
+var _ prom_discovery.Config = (*containerdDiscoveryConfig)(nil)
+
+// Name implements discovery.DiscovererConfig.
+func (c *containerdDiscoveryConfig) Name() string {
+	return "containerd"
+}
+
+// NewDiscoverer implements discovery.DiscovererConfig.
+func (c *containerdDiscoveryConfig) NewDiscoverer(discOpts prom_discovery.DiscovererOptions) (prom_discovery.Discoverer, error) {
+	m, ok := discOpts.Metrics.(*containerdMetrics)
+	if !ok {
+		return nil, fmt.Errorf("invalid discovery metrics type")
+	}
+
+	containerdDiscovery, err := NewContainerdDiscovery(c.opts.Logger, c.args)
+	if err != nil {
+		return nil, err
+	}
+
+	return &closingDiscoverer{
+		Discoverer: refresh.NewDiscovery(refresh.Options{
+			Logger:              c.opts.Logger,
+			Mech:                "containerd",
+			Interval:            c.args.RefreshInterval,
+			RefreshF:            containerdDiscovery.Refresh,
+			MetricsInstantiator: m.refreshMetrics,
+		}),
+		discovery: containerdDiscovery,
+	}, nil
+}
+
+// NewDiscovererMetrics implements discovery.DiscovererConfig.
+func (*containerdDiscoveryConfig) NewDiscovererMetrics(_ prometheus.Registerer, rmi prom_discovery.RefreshMetricsInstantiator) prom_discovery.DiscovererMetrics {
+	return newDiscovererMetrics(rmi)
+}
+
+// closingDiscoverer closes the connection to the CRI runtime service once the
+// wrapped discoverer stops.
+type closingDiscoverer struct {
+	prom_discovery.Discoverer
+	discovery *Discovery
+}
+
+// Run implements discovery.Discoverer.
+func (d *closingDiscoverer) Run(ctx context.Context, up chan<- []*targetgroup.Group) {
+	defer d.discovery.Close()
+	d.Discoverer.Run(ctx, up)
+}
+
+var _ prom_discovery.DiscovererMetrics = (*containerdMetrics)(nil)
+
+type containerdMetrics struct {
+	refreshMetrics prom_discovery.RefreshMetricsInstantiator
+}
+
+func newDiscovererMetrics(rmi prom_discovery.RefreshMetricsInstantiator) prom_discovery.DiscovererMetrics {
+	m := &containerdMetrics{
+		refreshMetrics: rmi,
+	}
+	return m
+}
+
+// Register implements discovery.DiscovererMetrics.
+func (m *containerdMetrics) Register() error {
+	return nil
+}
+
+// Unregister implements discovery.DiscovererMetrics.
+func (m *containerdMetrics) Unregister() {}
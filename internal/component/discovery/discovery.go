@@ -2,45 +2,16 @@ package discovery
 
 import (
 	"context"
-	"sort"
-	"strings"
 	"sync"
 	"time"
 
-	"github.com/prometheus/common/model"
 	"github.com/prometheus/prometheus/discovery"
 	"github.com/prometheus/prometheus/discovery/targetgroup"
-	"github.com/prometheus/prometheus/model/labels"
 
 	"github.com/grafana/alloy/internal/component"
 	"github.com/grafana/alloy/internal/runtime/logging/level"
 )
 
-// Target refers to a singular discovered endpoint found by a discovery
-// component.
-type Target map[string]string
-
-// Labels converts Target into a set of sorted labels.
-func (t Target) Labels() labels.Labels {
-	var lset labels.Labels
-	for k, v := range t {
-		lset = append(lset, labels.Label{Name: k, Value: v})
-	}
-	sort.Sort(lset)
-	return lset
-}
-
-func (t Target) NonMetaLabels() labels.Labels {
-	var lset labels.Labels
-	for k, v := range t {
-		if !strings.HasPrefix(k, model.MetaLabelPrefix) {
-			lset = append(lset, labels.Label{Name: k, Value: v})
-		}
-	}
-	sort.Sort(lset)
-	return lset
-}
-
 // Exports holds values which are exported by all discovery components.
 type Exports struct {
 	Targets []Target `alloy:"targets,attr"`
@@ -185,8 +156,10 @@ var MaxUpdateFrequency = 5 * time.Second
 // runDiscovery is a utility for consuming and forwarding target groups from a discoverer.
 // It will handle collating targets (and clearing), as well as time based throttling of updates.
 func (c *Component) runDiscovery(ctx context.Context, d DiscovererWithMetrics) {
-	// all targets we have seen so far
-	cache := map[string]*targetgroup.Group{}
+	// all targets we have seen so far, by target group source. Targets are
+	// converted once when their group is received, so that unchanged groups
+	// don't need to be converted again on each send.
+	cache := map[string][]Target{}
 
 	ch := make(chan []*targetgroup.Group)
 	runExited := make(chan struct{})
@@ -197,20 +170,13 @@ func (c *Component) runDiscovery(ctx context.Context, d DiscovererWithMetrics) {
 
 	// function to convert and send targets in format scraper expects
 	send := func() {
-		allTargets := []Target{}
-		for _, group := range cache {
-			for _, target := range group.Targets {
-				labels := map[string]string{}
-				// first add the group labels, and then the
-				// target labels, so that target labels take precedence.
-				for k, v := range group.Labels {
-					labels[string(k)] = string(v)
-				}
-				for k, v := range target {
-					labels[string(k)] = string(v)
-				}
-				allTargets = append(allTargets, labels)
-			}
+		var count int
+		for _, targets := range cache {
+			count += len(targets)
+		}
+		allTargets := make([]Target, 0, count)
+		for _, targets := range cache {
+			allTargets = append(allTargets, targets...)
 		}
 		c.opts.OnStateChange(Exports{Targets: allTargets})
 	}
@@ -237,10 +203,20 @@ func (c *Component) runDiscovery(ctx context.Context, d DiscovererWithMetrics) {
 				if len(group.Targets) == 0 {
 					delete(cache, group.Source)
 				} else {
-					cache[group.Source] = group
+					cache[group.Source] = groupTargets(group)
 				}
 			}
 			haveUpdates = true
 		}
 	}
 }
+
+// groupTargets converts the targets of a target group. Target labels take
+// precedence over the group labels.
+func groupTargets(group *targetgroup.Group) []Target {
+	targets := make([]Target, 0, len(group.Targets))
+	for _, target := range group.Targets {
+		targets = append(targets, NewTargetFromSpecificAndBaseLabelSet(target, group.Labels))
+	}
+	return targets
+}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/grafana/ckit/peer"
 	"github.com/grafana/ckit/shard"
+	"github.com/prometheus/prometheus/model/labels"
 	"github.com/stretchr/testify/require"
 
 	"github.com/grafana/alloy/internal/service/cluster"
@@ -285,11 +286,7 @@ func BenchmarkDistributedTargets(b *testing.B) {
 }
 
 func mkTarget(kv ...string) Target {
-	target := make(Target)
-	for i := 0; i < len(kv); i += 2 {
-		target[kv[i]] = kv[i+1]
-	}
-	return target
+	return NewTargetFromLabels(labels.FromStrings(kv...))
 }
 
 func testDistTargets(lookupMap map[shard.Key][]peer.Peer) *DistributedTargets {
@@ -0,0 +1,186 @@
+// Package join implements the discovery.join component.
+package join
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/grafana/alloy/internal/component"
+	"github.com/grafana/alloy/internal/component/discovery"
+	"github.com/grafana/alloy/internal/featuregate"
+	"github.com/prometheus/prometheus/model/labels"
+)
+
+func init() {
+	component.Register(component.Registration{
+		Name:      "discovery.join",
+		Stability: featuregate.StabilityExperimental,
+		Args:      Arguments{},
+		Exports:   Exports{},
+
+		Build: func(opts component.Options, args component.Arguments) (component.Component, error) {
+			return New(opts, args.(Arguments))
+		},
+	})
+}
+
+// Supported join types.
+const (
+	JoinTypeInner = "inner"
+	JoinTypeLeft  = "left"
+)
+
+// Supported sides to prefer when both targets of a match have the same label.
+const (
+	PreferLeft  = "left"
+	PreferRight = "right"
+)
+
+// Arguments holds values which are used to configure the discovery.join component.
+type Arguments struct {
+	// Left and Right are the two lists of targets to join.
+	Left  []discovery.Target `alloy:"left,attr"`
+	Right []discovery.Target `alloy:"right,attr"`
+
+	// On is the list of labels whose values must be equal for two targets to
+	// match.
+	On []string `alloy:"on,attr"`
+
+	Type   string `alloy:"type,attr,optional"`
+	Prefer string `alloy:"prefer,attr,optional"`
+}
+
+// DefaultArguments holds the default arguments for the discovery.join
+// component.
+var DefaultArguments = Arguments{
+	Type:   JoinTypeInner,
+	Prefer: PreferLeft,
+}
+
+// SetToDefault implements syntax.Defaulter.
+func (args *Arguments) SetToDefault() {
+	*args = DefaultArguments
+}
+
+// Validate implements syntax.Validator.
+func (args *Arguments) Validate() error {
+	if len(args.On) == 0 {
+		return fmt.Errorf("on must contain at least one label")
+	}
+	switch args.Type {
+	case JoinTypeInner, JoinTypeLeft:
+	default:
+		return fmt.Errorf("unknown join type %q, must be %q or %q", args.Type, JoinTypeInner, JoinTypeLeft)
+	}
+	switch args.Prefer {
+	case PreferLeft, PreferRight:
+	default:
+		return fmt.Errorf("unknown prefer value %q, must be %q or %q", args.Prefer, PreferLeft, PreferRight)
+	}
+	return nil
+}
+
+// Exports holds values which are exported by the discovery.join component.
+type Exports struct {
+	Output []discovery.Target `alloy:"output,attr"`
+}
+
+// Component implements the discovery.join component.
+type Component struct {
+	opts component.Options
+}
+
+var _ component.Component = (*Component)(nil)
+
+// New creates a new discovery.join component.
+func New(o component.Options, args Arguments) (*Component, error) {
+	c := &Component{opts: o}
+
+	// Call to Update() to set the output once at the start
+	if err := c.Update(args); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// Run implements component.Component.
+func (c *Component) Run(ctx context.Context) error {
+	<-ctx.Done()
+	return nil
+}
+
+// Update implements component.Component.
+func (c *Component) Update(args component.Arguments) error {
+	newArgs := args.(Arguments)
+
+	c.opts.OnStateChange(Exports{
+		Output: Join(newArgs.Left, newArgs.Right, newArgs.On, newArgs.Type, newArgs.Prefer),
+	})
+
+	return nil
+}
+
+// Join joins the left and right targets which have the same values for all
+// the labels in on. Each match produces one target with the labels of both
+// targets; labels set by both targets take the value of the preferred side.
+//
+// With a left join, left targets which don't match any right target are kept
+// unchanged. A target which doesn't have all the labels in on never matches.
+func Join(left, right []discovery.Target, on []string, joinType, prefer string) []discovery.Target {
+	index := make(map[string][]discovery.Target, len(right))
+	for _, t := range right {
+		if key, ok := joinKey(t, on); ok {
+			index[key] = append(index[key], t)
+		}
+	}
+
+	output := make([]discovery.Target, 0, len(left))
+	for _, l := range left {
+		var matches []discovery.Target
+		if key, ok := joinKey(l, on); ok {
+			matches = index[key]
+		}
+		if len(matches) == 0 {
+			if joinType == JoinTypeLeft {
+				output = append(output, l)
+			}
+			continue
+		}
+		for _, r := range matches {
+			if prefer == PreferRight {
+				output = append(output, merge(l, r))
+			} else {
+				output = append(output, merge(r, l))
+			}
+		}
+	}
+	return output
+}
+
+// joinKey returns the values of the labels in on, or false if t doesn't have
+// one of them.
+func joinKey(t discovery.Target, on []string) (string, bool) {
+	var sb strings.Builder
+	for _, name := range on {
+		value, ok := t.Get(name)
+		if !ok {
+			return "", false
+		}
+		sb.WriteString(value)
+		sb.WriteByte('\xff')
+	}
+	return sb.String(), true
+}
+
+// merge returns a target with the labels of base and override, where the
+// labels of override take precedence.
+func merge(base, override discovery.Target) discovery.Target {
+	lb := labels.NewBuilder(base.Labels())
+	override.ForEachLabel(func(name, value string) bool {
+		lb.Set(name, value)
+		return true
+	})
+	return discovery.NewTargetFromLabels(lb.Labels())
+}
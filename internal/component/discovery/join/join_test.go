@@ -0,0 +1,91 @@
+package join_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/grafana/alloy/internal/component/discovery"
+	"github.com/grafana/alloy/internal/component/discovery/join"
+	"github.com/grafana/alloy/internal/runtime/componenttest"
+	"github.com/grafana/alloy/syntax"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJoin(t *testing.T) {
+	alloyArguments := `
+left = [
+	{ "__address__" = "10.0.0.1:8080", "pod" = "api",    "namespace" = "prod", "team" = "left" },
+	{ "__address__" = "10.0.0.2:8080", "pod" = "web",    "namespace" = "prod" },
+	{ "__address__" = "10.0.0.3:8080", "pod" = "worker", "namespace" = "dev" },
+	{ "__address__" = "10.0.0.4:8080", "namespace" = "prod" },
+]
+right = [
+	{ "pod" = "api", "namespace" = "prod", "team" = "checkout", "__meta_process_pid" = "1" },
+	{ "pod" = "api", "namespace" = "prod", "team" = "checkout", "__meta_process_pid" = "2" },
+	{ "pod" = "web", "namespace" = "prod", "team" = "frontend" },
+	{ "pod" = "worker", "namespace" = "prod", "team" = "batch" },
+]
+on = ["pod", "namespace"]
+`
+	var args join.Arguments
+	require.NoError(t, syntax.Unmarshal([]byte(alloyArguments), &args))
+	require.Equal(t, join.JoinTypeInner, args.Type)
+	require.Equal(t, join.PreferLeft, args.Prefer)
+
+	tc, err := componenttest.NewControllerFromID(nil, "discovery.join")
+	require.NoError(t, err)
+	go func() {
+		err = tc.Run(componenttest.TestContext(t), args)
+		require.NoError(t, err)
+	}()
+
+	require.NoError(t, tc.WaitExports(time.Second))
+	require.Equal(t, []discovery.Target{
+		discovery.NewTargetFromMap(map[string]string{"__address__": "10.0.0.1:8080", "pod": "api", "namespace": "prod", "team": "left", "__meta_process_pid": "1"}),
+		discovery.NewTargetFromMap(map[string]string{"__address__": "10.0.0.1:8080", "pod": "api", "namespace": "prod", "team": "left", "__meta_process_pid": "2"}),
+		discovery.NewTargetFromMap(map[string]string{"__address__": "10.0.0.2:8080", "pod": "web", "namespace": "prod", "team": "frontend"}),
+	}, tc.Exports().(join.Exports).Output)
+}
+
+func TestJoin_LeftPreferRight(t *testing.T) {
+	left := []discovery.Target{
+		discovery.NewTargetFromMap(map[string]string{"host": "a", "env": "left"}),
+		discovery.NewTargetFromMap(map[string]string{"host": "b", "env": "left"}),
+		discovery.NewTargetFromMap(map[string]string{"env": "left"}),
+	}
+	right := []discovery.Target{
+		discovery.NewTargetFromMap(map[string]string{"host": "a", "env": "right", "region": "eu"}),
+	}
+
+	require.Equal(t, []discovery.Target{
+		discovery.NewTargetFromMap(map[string]string{"host": "a", "env": "right", "region": "eu"}),
+		discovery.NewTargetFromMap(map[string]string{"host": "b", "env": "left"}),
+		discovery.NewTargetFromMap(map[string]string{"env": "left"}),
+	}, join.Join(left, right, []string{"host"}, join.JoinTypeLeft, join.PreferRight))
+}
+
+func TestValidate(t *testing.T) {
+	var args join.Arguments
+	err := syntax.Unmarshal([]byte(`
+left  = []
+right = []
+on    = []
+`), &args)
+	require.ErrorContains(t, err, "on must contain at least one label")
+
+	err = syntax.Unmarshal([]byte(`
+left  = []
+right = []
+on    = ["host"]
+type  = "outer"
+`), &args)
+	require.ErrorContains(t, err, `unknown join type "outer"`)
+
+	err = syntax.Unmarshal([]byte(`
+left   = []
+right  = []
+on     = ["host"]
+prefer = "both"
+`), &args)
+	require.ErrorContains(t, err, `unknown prefer value "both"`)
+}
@@ -19,6 +19,15 @@ type DiscoverConfig struct {
 	Username    bool `alloy:"username,attr,optional"`
 	UID         bool `alloy:"uid,attr,optional"`
 	ContainerID bool `alloy:"container_id,attr,optional"`
+	CgroupPath  bool `alloy:"cgroup_path,attr,optional"`
+	SystemdUnit bool `alloy:"systemd_unit,attr,optional"`
+	Kubernetes  bool `alloy:"kubernetes,attr,optional"`
+	StartTime   bool `alloy:"start_time,attr,optional"`
+	PPID        bool `alloy:"ppid,attr,optional"`
+	ListenPorts bool `alloy:"listen_ports,attr,optional"`
+
+	// Environment lists the environment variables whose values are exposed.
+	Environment []string `alloy:"environment,attr,optional"`
 }
 
 var DefaultConfig = Arguments{
@@ -42,15 +42,15 @@ func getContainerIDFromK8S(k8sContainerID string) string {
 }
 
 func getContainerIDFromTarget(target discovery.Target) string {
-	cid, ok := target[labelProcessContainerID]
+	cid, ok := target.Get(labelProcessContainerID)
 	if ok && cid != "" {
 		return cid
 	}
-	cid, ok = target["__meta_kubernetes_pod_container_id"]
+	cid, ok = target.Get("__meta_kubernetes_pod_container_id")
 	if ok && cid != "" {
 		return getContainerIDFromK8S(cid)
 	}
-	cid, ok = target["__meta_docker_container_id"]
+	cid, ok = target.Get("__meta_docker_container_id")
 	if ok && cid != "" {
 		return cid
 	}
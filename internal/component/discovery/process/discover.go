@@ -9,10 +9,14 @@ import (
 	"os/user"
 	"path"
 	"runtime"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/go-kit/log"
 	"github.com/go-kit/log/level"
 	"github.com/grafana/alloy/internal/component/discovery"
+	"github.com/prometheus/prometheus/util/strutil"
 	gopsutil "github.com/shirou/gopsutil/v3/process"
 	"golang.org/x/sys/unix"
 )
@@ -25,6 +29,14 @@ const (
 	labelProcessUsername    = "__meta_process_username"
 	labelProcessUID         = "__meta_process_uid"
 	labelProcessContainerID = "__container_id__"
+	labelProcessCgroupPath  = "__meta_process_cgroup_path"
+	labelProcessSystemdUnit = "__meta_process_systemd_unit"
+	labelProcessPodUID      = "__meta_process_kubernetes_pod_uid"
+	labelProcessPodQoSClass = "__meta_process_kubernetes_pod_qos_class"
+	labelProcessStartTime   = "__meta_process_start_time"
+	labelProcessPPID        = "__meta_process_ppid"
+	labelProcessListenPorts = "__meta_process_listen_ports"
+	labelProcessEnvPrefix   = "__meta_process_env_"
 )
 
 type process struct {
@@ -35,6 +47,15 @@ type process struct {
 	containerID string
 	username    string
 	uid         string
+
+	cgroupPath  string
+	systemdUnit string
+	podUID      string
+	podQoSClass string
+	startTime   string
+	ppid        string
+	listenPorts string
+	env         map[string]string
 }
 
 func (p process) String() string {
@@ -51,7 +72,7 @@ func convertProcesses(ps []process) []discovery.Target {
 }
 
 func convertProcess(p process) discovery.Target {
-	t := make(discovery.Target, 5)
+	t := make(map[string]string, 15+len(p.env))
 	t[labelProcessID] = p.pid
 	if p.exe != "" {
 		t[labelProcessExe] = p.exe
@@ -71,7 +92,29 @@ func convertProcess(p process) discovery.Target {
 	if p.uid != "" {
 		t[labelProcessUID] = p.uid
 	}
-	return t
+	if p.cgroupPath != "" {
+		t[labelProcessCgroupPath] = p.cgroupPath
+	}
+	if p.systemdUnit != "" {
+		t[labelProcessSystemdUnit] = p.systemdUnit
+	}
+	if p.podUID != "" {
+		t[labelProcessPodUID] = p.podUID
+		t[labelProcessPodQoSClass] = p.podQoSClass
+	}
+	if p.startTime != "" {
+		t[labelProcessStartTime] = p.startTime
+	}
+	if p.ppid != "" {
+		t[labelProcessPPID] = p.ppid
+	}
+	if p.listenPorts != "" {
+		t[labelProcessListenPorts] = p.listenPorts
+	}
+	for name, value := range p.env {
+		t[labelProcessEnvPrefix+strutil.SanitizeLabelName(name)] = value
+	}
+	return discovery.NewTargetFromMap(t)
 }
 
 func discover(l log.Logger, cfg *DiscoverConfig) ([]process, error) {
@@ -80,6 +123,15 @@ func discover(l log.Logger, cfg *DiscoverConfig) ([]process, error) {
 		return nil, fmt.Errorf("failed to list processes: %w", err)
 	}
 	res := make([]process, 0, len(processes))
+
+	var bootTime time.Time
+	if cfg.StartTime {
+		bootTime, err = hostProcFS.bootTime()
+		if err != nil {
+			return nil, fmt.Errorf("failed to get boot time: %w", err)
+		}
+	}
+
 	loge := func(pid int, e error) {
 		if errors.Is(e, unix.ESRCH) {
 			return
@@ -139,7 +191,7 @@ func discover(l log.Logger, cfg *DiscoverConfig) ([]process, error) {
 				continue
 			}
 		}
-		res = append(res, process{
+		proc := process{
 			pid:         spid,
 			exe:         exe,
 			cwd:         cwd,
@@ -147,12 +199,75 @@ func discover(l log.Logger, cfg *DiscoverConfig) ([]process, error) {
 			containerID: containerID,
 			username:    username,
 			uid:         uid,
-		})
+		}
+		if err := hostProcFS.discoverMetadata(spid, cfg, bootTime, &proc); err != nil {
+			loge(int(p.Pid), err)
+		}
+		res = append(res, proc)
 	}
 
 	return res, nil
 }
 
+// discoverMetadata sets the optional metadata of a process enabled in cfg.
+// Metadata which can't be read is left empty, and the errors are returned.
+func (fs procFS) discoverMetadata(pid string, cfg *DiscoverConfig, bootTime time.Time, p *process) error {
+	var errs []error
+
+	if cfg.CgroupPath || cfg.SystemdUnit || cfg.Kubernetes {
+		cgroupPath, err := fs.readCgroupPath(pid)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		if cfg.CgroupPath {
+			p.cgroupPath = cgroupPath
+		}
+		if cfg.SystemdUnit {
+			p.systemdUnit = systemdUnitFromCgroup(cgroupPath)
+		}
+		if cfg.Kubernetes {
+			p.podUID, p.podQoSClass = kubernetesPodFromCgroup(cgroupPath)
+		}
+	}
+
+	if cfg.StartTime || cfg.PPID {
+		stat, err := fs.readStat(pid)
+		if err != nil {
+			errs = append(errs, err)
+		} else {
+			if cfg.StartTime {
+				startTime := bootTime.Add(time.Duration(stat.startTime) * time.Second / userHZ)
+				p.startTime = startTime.UTC().Format(time.RFC3339)
+			}
+			if cfg.PPID {
+				p.ppid = strconv.Itoa(stat.ppid)
+			}
+		}
+	}
+
+	if len(cfg.Environment) > 0 {
+		env, err := fs.readEnvironment(pid, cfg.Environment)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		p.env = env
+	}
+
+	if cfg.ListenPorts {
+		ports, err := fs.listenPorts(pid)
+		if err != nil {
+			errs = append(errs, err)
+		}
+		portStrings := make([]string, 0, len(ports))
+		for _, port := range ports {
+			portStrings = append(portStrings, strconv.Itoa(port))
+		}
+		p.listenPorts = strings.Join(portStrings, ",")
+	}
+
+	return errors.Join(errs...)
+}
+
 func getLinuxProcessContainerID(pid string) (string, error) {
 	if runtime.GOOS == "linux" {
 		cgroup, err := os.Open(path.Join("/proc", pid, "cgroup"))
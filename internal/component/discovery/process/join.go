@@ -27,14 +27,9 @@ func join(processes, containers []discovery.Target) []discovery.Target {
 			res = append(res, p)
 			continue
 		}
-		mergedTarget := make(discovery.Target, len(p)+len(container))
-		for k, v := range p {
-			mergedTarget[k] = v
-		}
-		for k, v := range container {
-			mergedTarget[k] = v
-		}
-		res = append(res, mergedTarget)
+		// The labels of the container take precedence over the ones of the
+		// process.
+		res = append(res, discovery.NewTargetFromSpecificAndBaseLabelSet(container.LabelSet(), p.LabelSet()))
 	}
 	for _, target := range cid2container {
 		res = append(res, target)
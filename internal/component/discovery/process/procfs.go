@@ -0,0 +1,275 @@
+//go:build linux
+
+package process
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// userHZ is the number of clock ticks per second used for the times of
+// /proc/<pid>/stat. It's 100 on all the architectures supported by Linux.
+const userHZ = 100
+
+var (
+	// cgroupPodUIDRe matches the UID of a Kubernetes pod in a cgroup path,
+	// for both the cgroupfs and systemd cgroup drivers.
+	cgroupPodUIDRe = regexp.MustCompile(`pod([0-9a-f]{8}[-_][0-9a-f]{4}[-_][0-9a-f]{4}[-_][0-9a-f]{4}[-_][0-9a-f]{12})`)
+
+	// systemdUnitSuffixes are the suffixes of the systemd unit types which
+	// can hold processes.
+	systemdUnitSuffixes = []string{".service", ".scope", ".socket", ".mount", ".swap"}
+)
+
+// procFS reads process metadata from a proc filesystem mounted at root.
+type procFS struct {
+	root string
+}
+
+var hostProcFS = procFS{root: "/proc"}
+
+// procStat holds the fields of /proc/<pid>/stat used by discovery.process.
+type procStat struct {
+	ppid      int
+	startTime uint64 // In clock ticks since boot.
+}
+
+// readStat parses /proc/<pid>/stat.
+func (fs procFS) readStat(pid string) (procStat, error) {
+	buf, err := os.ReadFile(filepath.Join(fs.root, pid, "stat"))
+	if err != nil {
+		return procStat{}, err
+	}
+
+	// The command name is between parentheses and may contain spaces and
+	// parentheses itself, so the other fields start after the last ')'.
+	end := bytes.LastIndexByte(buf, ')')
+	if end < 0 {
+		return procStat{}, fmt.Errorf("invalid stat file for pid %s", pid)
+	}
+	// Fields from the 3rd one (state), see proc(5).
+	fields := strings.Fields(string(buf[end+1:]))
+	if len(fields) < 20 {
+		return procStat{}, fmt.Errorf("invalid stat file for pid %s: %d fields", pid, len(fields))
+	}
+
+	ppid, err := strconv.Atoi(fields[1])
+	if err != nil {
+		return procStat{}, fmt.Errorf("invalid ppid for pid %s: %w", pid, err)
+	}
+	startTime, err := strconv.ParseUint(fields[19], 10, 64)
+	if err != nil {
+		return procStat{}, fmt.Errorf("invalid start time for pid %s: %w", pid, err)
+	}
+	return procStat{ppid: ppid, startTime: startTime}, nil
+}
+
+// bootTime returns the boot time of the system from /proc/stat.
+func (fs procFS) bootTime() (time.Time, error) {
+	f, err := os.Open(filepath.Join(fs.root, "stat"))
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		name, value, found := strings.Cut(scanner.Text(), " ")
+		if !found || name != "btime" {
+			continue
+		}
+		btime, err := strconv.ParseInt(strings.TrimSpace(value), 10, 64)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid btime: %w", err)
+		}
+		return time.Unix(btime, 0), nil
+	}
+	if err := scanner.Err(); err != nil {
+		return time.Time{}, err
+	}
+	return time.Time{}, errors.New("btime not found in stat file")
+}
+
+// readCgroupPath returns the cgroup of a process. The path of the unified
+// (cgroup v2) hierarchy is preferred, then the one of the systemd hierarchy.
+func (fs procFS) readCgroupPath(pid string) (string, error) {
+	buf, err := os.ReadFile(filepath.Join(fs.root, pid, "cgroup"))
+	if err != nil {
+		return "", err
+	}
+	return parseCgroupPath(string(buf)), nil
+}
+
+func parseCgroupPath(cgroup string) string {
+	var systemdPath, firstPath string
+	for _, line := range strings.Split(cgroup, "\n") {
+		// Lines are formatted as hierarchy-ID:controller-list:cgroup-path.
+		parts := strings.SplitN(line, ":", 3)
+		if len(parts) != 3 {
+			continue
+		}
+		switch {
+		case parts[0] == "0" && parts[1] == "":
+			return parts[2]
+		case parts[1] == "name=systemd":
+			systemdPath = parts[2]
+		case firstPath == "":
+			firstPath = parts[2]
+		}
+	}
+	if systemdPath != "" {
+		return systemdPath
+	}
+	return firstPath
+}
+
+// systemdUnitFromCgroup returns the systemd unit of a process from its cgroup
+// path, which is the innermost unit holding processes.
+func systemdUnitFromCgroup(cgroupPath string) string {
+	segments := strings.Split(cgroupPath, "/")
+	for i := len(segments) - 1; i >= 0; i-- {
+		for _, suffix := range systemdUnitSuffixes {
+			if strings.HasSuffix(segments[i], suffix) {
+				return segments[i]
+			}
+		}
+	}
+	return ""
+}
+
+// kubernetesPodFromCgroup returns the UID and QoS class of the Kubernetes pod
+// of a process from its cgroup path. Both are empty for processes which don't
+// belong to a pod.
+func kubernetesPodFromCgroup(cgroupPath string) (podUID, qosClass string) {
+	if !strings.Contains(cgroupPath, "kubepods") {
+		return "", ""
+	}
+	matches := cgroupPodUIDRe.FindAllStringSubmatch(cgroupPath, -1)
+	if len(matches) == 0 {
+		return "", ""
+	}
+	// The systemd cgroup driver replaces the dashes of the UID.
+	podUID = strings.ReplaceAll(matches[len(matches)-1][1], "_", "-")
+
+	switch {
+	case strings.Contains(cgroupPath, "besteffort"):
+		qosClass = "BestEffort"
+	case strings.Contains(cgroupPath, "burstable"):
+		qosClass = "Burstable"
+	default:
+		qosClass = "Guaranteed"
+	}
+	return podUID, qosClass
+}
+
+// readEnvironment returns the values of the environment variables of a
+// process which are in names.
+func (fs procFS) readEnvironment(pid string, names []string) (map[string]string, error) {
+	buf, err := os.ReadFile(filepath.Join(fs.root, pid, "environ"))
+	if err != nil {
+		return nil, err
+	}
+
+	wanted := make(map[string]struct{}, len(names))
+	for _, name := range names {
+		wanted[name] = struct{}{}
+	}
+
+	env := make(map[string]string)
+	for _, kv := range bytes.Split(buf, []byte{0}) {
+		name, value, found := strings.Cut(string(kv), "=")
+		if !found {
+			continue
+		}
+		if _, ok := wanted[name]; ok {
+			env[name] = value
+		}
+	}
+	return env, nil
+}
+
+// listenPorts returns the sorted TCP ports a process listens on. The sockets
+// of the process are found in /proc/<pid>/fd, and matched with the listening
+// sockets of its network namespace.
+func (fs procFS) listenPorts(pid string) ([]int, error) {
+	fdDir := filepath.Join(fs.root, pid, "fd")
+	fds, err := os.ReadDir(fdDir)
+	if err != nil {
+		return nil, err
+	}
+	inodes := make(map[string]struct{})
+	for _, fd := range fds {
+		link, err := os.Readlink(filepath.Join(fdDir, fd.Name()))
+		if err != nil {
+			// The file descriptor may have been closed since it was listed.
+			continue
+		}
+		if inode, ok := strings.CutPrefix(link, "socket:["); ok {
+			inodes[strings.TrimSuffix(inode, "]")] = struct{}{}
+		}
+	}
+	if len(inodes) == 0 {
+		return nil, nil
+	}
+
+	ports := make(map[int]struct{})
+	for _, file := range []string{"tcp", "tcp6"} {
+		err := fs.readListeningSockets(filepath.Join(fs.root, pid, "net", file), func(inode string, port int) {
+			if _, ok := inodes[inode]; ok {
+				ports[port] = struct{}{}
+			}
+		})
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, err
+		}
+	}
+
+	res := make([]int, 0, len(ports))
+	for port := range ports {
+		res = append(res, port)
+	}
+	sort.Ints(res)
+	return res, nil
+}
+
+// tcpListen is the state of listening sockets in /proc/net/tcp.
+const tcpListen = "0A"
+
+// readListeningSockets calls f for each listening socket of a /proc/net/tcp
+// file.
+func (fs procFS) readListeningSockets(path string, f func(inode string, port int)) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	scanner.Scan() // Skip the header.
+	for scanner.Scan() {
+		// sl local_address rem_address st tx_queue:rx_queue tr:tm->when retrnsmt uid timeout inode
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 10 || fields[3] != tcpListen {
+			continue
+		}
+		_, hexPort, found := strings.Cut(fields[1], ":")
+		if !found {
+			continue
+		}
+		port, err := strconv.ParseUint(hexPort, 16, 16)
+		if err != nil {
+			continue
+		}
+		f(fields[9], int(port))
+	}
+	return scanner.Err()
+}
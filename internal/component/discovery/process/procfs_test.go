@@ -0,0 +1,157 @@
+//go:build linux
+
+package process
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+// fakeProcFS creates a proc filesystem with a single process in a temporary
+// directory.
+func fakeProcFS(t *testing.T, pid string, files map[string]string, fds map[string]string) procFS {
+	root := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(root, name)
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, os.WriteFile(path, []byte(content), 0644))
+	}
+	fdDir := filepath.Join(root, pid, "fd")
+	require.NoError(t, os.MkdirAll(fdDir, 0755))
+	for fd, target := range fds {
+		require.NoError(t, os.Symlink(target, filepath.Join(fdDir, fd)))
+	}
+	return procFS{root: root}
+}
+
+const (
+	testNetTCP = `  sl  local_address rem_address   st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+   0: 00000000:1F90 00000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 1001 1 0000000000000000 100 0 0 10 0
+   1: 0100007F:0CEA 00000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 1002 1 0000000000000000 100 0 0 10 0
+   2: 0100007F:1F90 0100007F:D431 01 00000000:00000000 00:00000000 00000000     0        0 1003 1 0000000000000000 20 4 30 10 -1
+`
+	testNetTCP6 = `  sl  local_address                         remote_address                        st tx_queue rx_queue tr tm->when retrnsmt   uid  timeout inode
+   0: 00000000000000000000000000000000:23F1 00000000000000000000000000000000:0000 0A 00000000:00000000 00:00000000 00000000     0        0 1004 1 0000000000000000 100 0 0 10 0
+`
+)
+
+func TestDiscoverMetadata(t *testing.T) {
+	fs := fakeProcFS(t, "42", map[string]string{
+		"stat": "cpu  1 2 3 4\nbtime 1700000000\nprocesses 100\n",
+		"42/stat": "42 (my (weird) app) S 7 42 42 0 -1 4194560 100 0 0 0 10 5 0 0 20 0 4 0 " +
+			"12345 1000000 500 18446744073709551615 1 1 0 0 0 0 0 0 0 0 0 0 17 3 0 0 0 0 0\n",
+		"42/cgroup": "12:cpuset:/kubepods.slice/kubepods-burstable.slice\n" +
+			"1:name=systemd:/system.slice/containerd.service\n" +
+			"0::/kubepods.slice/kubepods-burstable.slice/kubepods-burstable-pod471203d1_984f_477e_9c35_db96487ffe5e.slice/" +
+			"cri-containerd-a534eb629135e43beb13213976e37bb2ab95cba4c0d1d0b4e27c6bc4d8091b83.scope\n",
+		"42/environ":  "PATH=/usr/bin\x00SERVICE_NAME=checkout\x00OTEL_SERVICE_NAME=checkout-api\x00SECRET=hunter2\x00",
+		"42/net/tcp":  testNetTCP,
+		"42/net/tcp6": testNetTCP6,
+	}, map[string]string{
+		"0": "/dev/null",
+		"3": "socket:[1001]",
+		"4": "socket:[1003]",
+		"5": "socket:[1004]",
+		"6": "pipe:[2000]",
+	})
+
+	bootTime, err := fs.bootTime()
+	require.NoError(t, err)
+	require.Equal(t, time.Unix(1700000000, 0), bootTime)
+
+	cfg := &DiscoverConfig{
+		CgroupPath:  true,
+		SystemdUnit: true,
+		Kubernetes:  true,
+		StartTime:   true,
+		PPID:        true,
+		ListenPorts: true,
+		Environment: []string{"SERVICE_NAME", "OTEL_SERVICE_NAME", "MISSING"},
+	}
+	p := process{pid: "42"}
+	require.NoError(t, fs.discoverMetadata("42", cfg, bootTime, &p))
+
+	target := convertProcess(p)
+	require.Equal(t, map[string]string{
+		labelProcessID: "42",
+		labelProcessCgroupPath: "/kubepods.slice/kubepods-burstable.slice/kubepods-burstable-pod471203d1_984f_477e_9c35_db96487ffe5e.slice/" +
+			"cri-containerd-a534eb629135e43beb13213976e37bb2ab95cba4c0d1d0b4e27c6bc4d8091b83.scope",
+		labelProcessSystemdUnit:                     "cri-containerd-a534eb629135e43beb13213976e37bb2ab95cba4c0d1d0b4e27c6bc4d8091b83.scope",
+		labelProcessPodUID:                          "471203d1-984f-477e-9c35-db96487ffe5e",
+		labelProcessPodQoSClass:                     "Burstable",
+		labelProcessStartTime:                       "2023-11-14T22:15:23Z",
+		labelProcessPPID:                            "7",
+		labelProcessListenPorts:                     "8080,9201",
+		labelProcessEnvPrefix + "SERVICE_NAME":      "checkout",
+		labelProcessEnvPrefix + "OTEL_SERVICE_NAME": "checkout-api",
+	}, target.AsMap())
+}
+
+func TestDiscoverMetadata_Disabled(t *testing.T) {
+	// Nothing is read when no metadata is enabled, so missing files aren't
+	// errors.
+	fs := procFS{root: t.TempDir()}
+	p := process{pid: "42"}
+	require.NoError(t, fs.discoverMetadata("42", &DiscoverConfig{}, time.Time{}, &p))
+	require.Equal(t, process{pid: "42"}, p)
+
+	err := fs.discoverMetadata("42", &DiscoverConfig{PPID: true}, time.Time{}, &p)
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestCgroupMetadata(t *testing.T) {
+	testcases := []struct {
+		cgroup         string
+		expectedPath   string
+		expectedUnit   string
+		expectedPodUID string
+		expectedQoS    string
+	}{
+		{
+			cgroup:       "0::/system.slice/sshd.service\n",
+			expectedPath: "/system.slice/sshd.service",
+			expectedUnit: "sshd.service",
+		},
+		{
+			cgroup:       "0::/user.slice/user-1000.slice/session-2.scope\n",
+			expectedPath: "/user.slice/user-1000.slice/session-2.scope",
+			expectedUnit: "session-2.scope",
+		},
+		{
+			// cgroup v1 with the cgroupfs driver.
+			cgroup: "11:memory:/kubepods/besteffort/pod88f6f4e3-59c0-4ce8-9ecf-391c8b5a60ad/656959d9ee87\n" +
+				"1:name=systemd:/kubepods/besteffort/pod88f6f4e3-59c0-4ce8-9ecf-391c8b5a60ad/656959d9ee87\n",
+			expectedPath:   "/kubepods/besteffort/pod88f6f4e3-59c0-4ce8-9ecf-391c8b5a60ad/656959d9ee87",
+			expectedPodUID: "88f6f4e3-59c0-4ce8-9ecf-391c8b5a60ad",
+			expectedQoS:    "BestEffort",
+		},
+		{
+			cgroup: "0::/kubepods.slice/kubepods-podb57320a0_e7eb_4ac8_a791_4c4472796867.slice/" +
+				"crio-0ecc7949cbaf17e883264ea1055f60b184a7cb264fd759c4a692e1155086fe2d.scope\n",
+			expectedPath: "/kubepods.slice/kubepods-podb57320a0_e7eb_4ac8_a791_4c4472796867.slice/" +
+				"crio-0ecc7949cbaf17e883264ea1055f60b184a7cb264fd759c4a692e1155086fe2d.scope",
+			expectedUnit:   "crio-0ecc7949cbaf17e883264ea1055f60b184a7cb264fd759c4a692e1155086fe2d.scope",
+			expectedPodUID: "b57320a0-e7eb-4ac8-a791-4c4472796867",
+			expectedQoS:    "Guaranteed",
+		},
+		{
+			cgroup:       "0::/\n",
+			expectedPath: "/",
+		},
+	}
+	for _, tc := range testcases {
+		t.Run(tc.expectedPath, func(t *testing.T) {
+			cgroupPath := parseCgroupPath(tc.cgroup)
+			require.Equal(t, tc.expectedPath, cgroupPath)
+			require.Equal(t, tc.expectedUnit, systemdUnitFromCgroup(cgroupPath))
+
+			podUID, qos := kubernetesPodFromCgroup(cgroupPath)
+			require.Equal(t, tc.expectedPodUID, podUID)
+			require.Equal(t, tc.expectedQoS, qos)
+		})
+	}
+}
@@ -2,13 +2,13 @@ package relabel
 
 import (
 	"context"
+	"reflect"
 	"sync"
 
 	"github.com/grafana/alloy/internal/component"
 	alloy_relabel "github.com/grafana/alloy/internal/component/common/relabel"
 	"github.com/grafana/alloy/internal/component/discovery"
 	"github.com/grafana/alloy/internal/featuregate"
-	"github.com/prometheus/prometheus/model/labels"
 	"github.com/prometheus/prometheus/model/relabel"
 )
 
@@ -46,6 +46,17 @@ type Component struct {
 
 	mut sync.RWMutex
 	rcs []*relabel.Config
+
+	// cache holds the results of the last update by target hash, so that only
+	// the targets which changed since then are relabeled again.
+	cache map[uint64][]relabelResult
+}
+
+// relabelResult is the result of relabeling a target.
+type relabelResult struct {
+	input  discovery.Target
+	output discovery.Target
+	keep   bool
 }
 
 var _ component.Component = (*Component)(nil)
@@ -75,17 +86,25 @@ func (c *Component) Update(args component.Arguments) error {
 
 	newArgs := args.(Arguments)
 
-	targets := make([]discovery.Target, 0, len(newArgs.Targets))
 	relabelConfigs := alloy_relabel.ComponentToPromRelabelConfigs(newArgs.RelabelConfigs)
+	if !reflect.DeepEqual(c.rcs, relabelConfigs) {
+		// The cached results are only valid for the rules they were computed with.
+		c.cache = nil
+	}
 	c.rcs = relabelConfigs
 
+	var (
+		targets  = make([]discovery.Target, 0, len(newArgs.Targets))
+		newCache = make(map[uint64][]relabelResult, len(newArgs.Targets))
+	)
 	for _, t := range newArgs.Targets {
-		lset := componentMapToPromLabels(t)
-		lset, keep := relabel.Process(lset, relabelConfigs...)
-		if keep {
-			targets = append(targets, promLabelsToComponent(lset))
+		res := c.relabel(t, newCache)
+		if res.keep {
+			targets = append(targets, res.output)
 		}
 	}
+	// Dropping the old cache evicts the results of the targets which are gone.
+	c.cache = newCache
 
 	c.opts.OnStateChange(Exports{
 		Output: targets,
@@ -95,20 +114,36 @@ func (c *Component) Update(args component.Arguments) error {
 	return nil
 }
 
-func componentMapToPromLabels(ls discovery.Target) labels.Labels {
-	res := make([]labels.Label, 0, len(ls))
-	for k, v := range ls {
-		res = append(res, labels.Label{Name: k, Value: v})
-	}
+// relabel returns the result of relabeling t, reusing the cached result if t
+// was already relabeled by the previous update. The result is stored in
+// newCache.
+func (c *Component) relabel(t discovery.Target, newCache map[uint64][]relabelResult) relabelResult {
+	hash := t.Hash()
 
+	res, found := findResult(newCache[hash], t)
+	if found {
+		// Duplicate target in this update.
+		return res
+	}
+	res, found = findResult(c.cache[hash], t)
+	if !found {
+		lset, keep := relabel.Process(t.Labels(), c.rcs...)
+		res = relabelResult{input: t, keep: keep}
+		if keep {
+			res.output = discovery.NewTargetFromLabels(lset)
+		}
+	}
+	newCache[hash] = append(newCache[hash], res)
 	return res
 }
 
-func promLabelsToComponent(ls labels.Labels) discovery.Target {
-	res := make(map[string]string, len(ls))
-	for _, l := range ls {
-		res[l.Name] = l.Value
+// findResult finds the result for t among results of targets with the same
+// hash.
+func findResult(results []relabelResult, t discovery.Target) (relabelResult, bool) {
+	for _, res := range results {
+		if res.input.Equals(t) {
+			return res, true
+		}
 	}
-
-	return res
+	return relabelResult{}, false
 }
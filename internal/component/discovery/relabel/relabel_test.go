@@ -56,7 +56,7 @@ rule {
 }
 `
 	expectedOutput := []discovery.Target{
-		map[string]string{"__address__": "localhost", "app": "backend", "destination": "localhost/one", "meta_bar": "bar", "meta_foo": "foo", "name": "one"},
+		discovery.NewTargetFromMap(map[string]string{"__address__": "localhost", "app": "backend", "destination": "localhost/one", "meta_bar": "bar", "meta_foo": "foo", "name": "one"}),
 	}
 
 	var args relabel.Arguments
@@ -74,6 +74,64 @@ rule {
 	require.NotNil(t, tc.Exports().(relabel.Exports).Rules)
 }
 
+func TestIncrementalUpdates(t *testing.T) {
+	var args relabel.Arguments
+	require.NoError(t, syntax.Unmarshal([]byte(`
+targets = [
+	{ "__address__" = "localhost:1", "app" = "backend" },
+	{ "__address__" = "localhost:2", "app" = "frontend" },
+]
+
+rule {
+	source_labels = ["app"]
+	target_label  = "service"
+}`), &args))
+
+	tc, err := componenttest.NewControllerFromID(nil, "discovery.relabel")
+	require.NoError(t, err)
+	go func() {
+		err = tc.Run(componenttest.TestContext(t), args)
+		require.NoError(t, err)
+	}()
+	require.NoError(t, tc.WaitExports(time.Second))
+	require.Equal(t, []discovery.Target{
+		discovery.NewTargetFromMap(map[string]string{"__address__": "localhost:1", "app": "backend", "service": "backend"}),
+		discovery.NewTargetFromMap(map[string]string{"__address__": "localhost:2", "app": "frontend", "service": "frontend"}),
+	}, tc.Exports().(relabel.Exports).Output)
+
+	// Unchanged targets keep their output, changed and new targets are
+	// relabeled and removed targets are dropped.
+	args.Targets = []discovery.Target{
+		discovery.NewTargetFromMap(map[string]string{"__address__": "localhost:1", "app": "backend"}),
+		discovery.NewTargetFromMap(map[string]string{"__address__": "localhost:2", "app": "db"}),
+		discovery.NewTargetFromMap(map[string]string{"__address__": "localhost:3", "app": "cache"}),
+		discovery.NewTargetFromMap(map[string]string{"__address__": "localhost:3", "app": "cache"}),
+	}
+	require.NoError(t, tc.Update(args))
+	require.Equal(t, []discovery.Target{
+		discovery.NewTargetFromMap(map[string]string{"__address__": "localhost:1", "app": "backend", "service": "backend"}),
+		discovery.NewTargetFromMap(map[string]string{"__address__": "localhost:2", "app": "db", "service": "db"}),
+		discovery.NewTargetFromMap(map[string]string{"__address__": "localhost:3", "app": "cache", "service": "cache"}),
+		discovery.NewTargetFromMap(map[string]string{"__address__": "localhost:3", "app": "cache", "service": "cache"}),
+	}, tc.Exports().(relabel.Exports).Output)
+
+	// Changing the rules relabels all the targets.
+	targets := args.Targets
+	require.NoError(t, syntax.Unmarshal([]byte(`
+targets = []
+
+rule {
+	source_labels = ["app"]
+	regex         = "backend"
+	action        = "keep"
+}`), &args))
+	args.Targets = targets
+	require.NoError(t, tc.Update(args))
+	require.Equal(t, []discovery.Target{
+		discovery.NewTargetFromMap(map[string]string{"__address__": "localhost:1", "app": "backend"}),
+	}, tc.Exports().(relabel.Exports).Output)
+}
+
 func TestRuleGetter(t *testing.T) {
 	originalCfg := `
 targets = []
@@ -0,0 +1,171 @@
+package discovery
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/labels"
+
+	"github.com/grafana/alloy/syntax"
+)
+
+// Target refers to a singular discovered endpoint found by a discovery
+// component.
+//
+// Targets are immutable: they are passed by value between components without
+// copying their labels, so they must never be modified once created. In Alloy
+// syntax, a Target behaves like an object whose fields are its labels.
+type Target struct {
+	labels labels.Labels
+}
+
+var (
+	_ syntax.Capsule                = Target{}
+	_ syntax.ConvertibleIntoCapsule = Target{}
+	_ syntax.Unmarshaler            = (*Target)(nil)
+)
+
+// EmptyTarget is a Target without any label.
+var EmptyTarget = Target{labels: labels.EmptyLabels()}
+
+// NewTargetFromLabels creates a Target from a set of labels. lset must not be
+// modified afterwards.
+func NewTargetFromLabels(lset labels.Labels) Target {
+	return Target{labels: lset}
+}
+
+// NewTargetFromMap creates a Target from a map of labels.
+func NewTargetFromMap(m map[string]string) Target {
+	return Target{labels: labels.FromMap(m)}
+}
+
+// NewTargetFromLabelSet creates a Target from a label set.
+func NewTargetFromLabelSet(ls model.LabelSet) Target {
+	return NewTargetFromSpecificAndBaseLabelSet(ls, nil)
+}
+
+// NewTargetFromSpecificAndBaseLabelSet creates a Target from the labels
+// specific to a target and the labels of its target group. The specific labels
+// take precedence over the group labels with the same name.
+//
+// The label names and values are shared with the label sets instead of being
+// copied, so targets of the same group don't duplicate the group labels.
+func NewTargetFromSpecificAndBaseLabelSet(own, group model.LabelSet) Target {
+	b := labels.NewScratchBuilder(len(own) + len(group))
+	for name, value := range group {
+		if _, overridden := own[name]; !overridden {
+			b.Add(string(name), string(value))
+		}
+	}
+	for name, value := range own {
+		b.Add(string(name), string(value))
+	}
+	b.Sort()
+	return Target{labels: b.Labels()}
+}
+
+// Get returns the value of the label name, and whether the Target has it.
+func (t Target) Get(name string) (string, bool) {
+	if !t.labels.Has(name) {
+		return "", false
+	}
+	return t.labels.Get(name), true
+}
+
+// Len returns the number of labels of the Target.
+func (t Target) Len() int {
+	return t.labels.Len()
+}
+
+// ForEachLabel calls f for each label of the Target, sorted by name, until f
+// returns false.
+func (t Target) ForEachLabel(f func(name, value string) bool) {
+	t.labels.Validate(func(l labels.Label) error {
+		if !f(l.Name, l.Value) {
+			return errStopIteration
+		}
+		return nil
+	})
+}
+
+var errStopIteration = fmt.Errorf("stop iteration")
+
+// AsMap returns a new map holding the labels of the Target.
+func (t Target) AsMap() map[string]string {
+	return t.labels.Map()
+}
+
+// Labels returns the sorted labels of the Target, which must not be modified.
+func (t Target) Labels() labels.Labels {
+	return t.labels
+}
+
+// NonMetaLabels returns the labels of the Target, excluding the labels starting
+// with the meta label prefix.
+func (t Target) NonMetaLabels() labels.Labels {
+	b := labels.NewBuilder(t.labels)
+	t.labels.Range(func(l labels.Label) {
+		if strings.HasPrefix(l.Name, model.MetaLabelPrefix) {
+			b.Del(l.Name)
+		}
+	})
+	return b.Labels()
+}
+
+// LabelSet returns the labels of the Target as a new label set.
+func (t Target) LabelSet() model.LabelSet {
+	ls := make(model.LabelSet, t.labels.Len())
+	t.labels.Range(func(l labels.Label) {
+		ls[model.LabelName(l.Name)] = model.LabelValue(l.Value)
+	})
+	return ls
+}
+
+// Hash returns a hash of the labels of the Target.
+func (t Target) Hash() uint64 {
+	return t.labels.Hash()
+}
+
+// Equals returns whether two targets have the same labels.
+func (t Target) Equals(other Target) bool {
+	return labels.Equal(t.labels, other.labels)
+}
+
+// String returns the labels of the Target in the Prometheus format.
+func (t Target) String() string {
+	return t.labels.String()
+}
+
+// AlloyCapsule marks Target as a capsule, so that it's passed as is through
+// Alloy expressions instead of being converted into an object.
+func (t Target) AlloyCapsule() {}
+
+// ConvertInto implements syntax.ConvertibleIntoCapsule. Converting into a map
+// allows targets to be used as objects in Alloy expressions.
+func (t Target) ConvertInto(dst interface{}) error {
+	switch dst := dst.(type) {
+	case *map[string]string:
+		*dst = t.AsMap()
+		return nil
+	case *map[string]interface{}:
+		m := make(map[string]interface{}, t.labels.Len())
+		t.labels.Range(func(l labels.Label) {
+			m[l.Name] = l.Value
+		})
+		*dst = m
+		return nil
+	}
+	return syntax.ErrNoConversion
+}
+
+// UnmarshalAlloy implements syntax.Unmarshaler, so that objects of strings,
+// such as targets written in the configuration file, decode into targets.
+func (t *Target) UnmarshalAlloy(f func(v interface{}) error) error {
+	var m map[string]string
+	if err := f(&m); err != nil {
+		return err
+	}
+	*t = NewTargetFromMap(m)
+	return nil
+}
@@ -0,0 +1,72 @@
+package discovery
+
+import (
+	"testing"
+
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/alloy/syntax"
+	"github.com/grafana/alloy/syntax/parser"
+	"github.com/grafana/alloy/syntax/token/builder"
+	"github.com/grafana/alloy/syntax/vm"
+)
+
+func TestNewTargetFromSpecificAndBaseLabelSet(t *testing.T) {
+	target := NewTargetFromSpecificAndBaseLabelSet(
+		model.LabelSet{"__address__": "localhost:9090", "job": "own"},
+		model.LabelSet{"job": "group", "region": "eu", "__meta_zone": "a"},
+	)
+
+	require.Equal(t, labels.FromStrings("__address__", "localhost:9090", "__meta_zone", "a", "job", "own", "region", "eu"), target.Labels())
+	require.Equal(t, labels.FromStrings("__address__", "localhost:9090", "job", "own", "region", "eu"), target.NonMetaLabels())
+	require.Equal(t, 4, target.Len())
+
+	job, ok := target.Get("job")
+	require.True(t, ok)
+	require.Equal(t, "own", job)
+	_, ok = target.Get("missing")
+	require.False(t, ok)
+
+	require.True(t, target.Equals(NewTargetFromMap(map[string]string{"__address__": "localhost:9090", "__meta_zone": "a", "job": "own", "region": "eu"})))
+}
+
+func TestTarget_Syntax(t *testing.T) {
+	type Arguments struct {
+		Targets []Target `alloy:"targets,attr"`
+	}
+
+	var args Arguments
+	require.NoError(t, syntax.Unmarshal([]byte(`
+		targets = [{ "__address__" = "localhost:9090", "job" = "test" }]
+	`), &args))
+	require.Equal(t, []Target{
+		NewTargetFromMap(map[string]string{"__address__": "localhost:9090", "job": "test"}),
+	}, args.Targets)
+
+	// Targets can be used like objects in expressions.
+	expr, err := parser.ParseExpression(`targets[0]["job"] + "/" + targets[0].__address__`)
+	require.NoError(t, err)
+	var actual string
+	require.NoError(t, vm.New(expr).Evaluate(&vm.Scope{
+		Variables: map[string]interface{}{"targets": args.Targets},
+	}, &actual))
+	require.Equal(t, "test/localhost:9090", actual)
+
+	// Targets decode into maps, and are encoded as objects.
+	var m map[string]string
+	expr, err = parser.ParseExpression(`target`)
+	require.NoError(t, err)
+	require.NoError(t, vm.New(expr).Evaluate(&vm.Scope{
+		Variables: map[string]interface{}{"target": args.Targets[0]},
+	}, &m))
+	require.Equal(t, map[string]string{"__address__": "localhost:9090", "job": "test"}, m)
+
+	f := builder.NewFile()
+	f.Body().AppendFrom(args)
+	require.Equal(t, `targets = [{
+	__address__ = "localhost:9090",
+	job         = "test",
+}]`, string(f.Bytes()))
+}
@@ -225,7 +225,9 @@ func TestMultiLabels(t *testing.T) {
 		"foo":   "bar",
 		"fruit": "apple",
 	})
-	c.args.PathTargets[0]["newlabel"] = "test"
+	newTarget := c.args.PathTargets[0].AsMap()
+	newTarget["newlabel"] = "test"
+	c.args.PathTargets[0] = discovery.NewTargetFromMap(newTarget)
 	ct := context.Background()
 	ct, ccl := context.WithTimeout(ct, 40*time.Second)
 	defer ccl()
@@ -249,14 +251,14 @@ func createComponent(t *testing.T, dir string, paths []string, excluded []string
 func createComponentWithLabels(t *testing.T, dir string, paths []string, excluded []string, labels map[string]string) *Component {
 	tPaths := make([]discovery.Target, 0)
 	for i, p := range paths {
-		tar := discovery.Target{"__path__": p}
+		tar := map[string]string{"__path__": p}
 		for k, v := range labels {
 			tar[k] = v
 		}
 		if i < len(excluded) {
 			tar["__path_exclude__"] = excluded[i]
 		}
-		tPaths = append(tPaths, tar)
+		tPaths = append(tPaths, discovery.NewTargetFromMap(tar))
 	}
 	c, err := New(component.Options{
 		ID:       "test",
@@ -279,7 +281,7 @@ func createComponentWithLabels(t *testing.T, dir string, paths []string, exclude
 
 func contains(sources []discovery.Target, match string) bool {
 	for _, s := range sources {
-		p := s["__path__"]
+		p, _ := s.Get("__path__")
 		if strings.Contains(p, match) {
 			return true
 		}
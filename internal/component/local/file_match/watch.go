@@ -51,21 +51,20 @@ func (w *watch) getPaths() ([]discovery.Target, error) {
 		if fi.IsDir() {
 			continue
 		}
-		dt := discovery.Target{}
-		for dk, v := range w.target {
-			dt[dk] = v
-		}
+		dt := w.target.AsMap()
 		dt["__path__"] = abs
-		allMatchingPaths = append(allMatchingPaths, dt)
+		allMatchingPaths = append(allMatchingPaths, discovery.NewTargetFromMap(dt))
 	}
 
 	return allMatchingPaths, nil
 }
 
 func (w *watch) getPath() string {
-	return w.target["__path__"]
+	path, _ := w.target.Get("__path__")
+	return path
 }
 
 func (w *watch) getExcludePath() string {
-	return w.target["__path_exclude__"]
+	path, _ := w.target.Get("__path_exclude__")
+	return path
 }
@@ -0,0 +1,392 @@
+package file_tree
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bmatcuk/doublestar"
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/grafana/alloy/internal/component"
+	"github.com/grafana/alloy/internal/featuregate"
+	filedetector "github.com/grafana/alloy/internal/filedetector"
+	"github.com/grafana/alloy/internal/runtime/logging/level"
+	"github.com/grafana/alloy/syntax/alloytypes"
+)
+
+// waitReadPeriod holds the time to wait before reading the tree while the
+// local.file_tree component is running.
+//
+// This coalesces the events of files written together, such as a
+// configuration management run, and avoids exporting partial writes.
+const waitReadPeriod time.Duration = 100 * time.Millisecond
+
+func init() {
+	component.Register(component.Registration{
+		Name:      "local.file_tree",
+		Stability: featuregate.StabilityExperimental,
+		Args:      Arguments{},
+		Exports:   Exports{},
+
+		Build: func(opts component.Options, args component.Arguments) (component.Component, error) {
+			return New(opts, args.(Arguments))
+		},
+	})
+}
+
+// Arguments holds values which are used to configure the local.file_tree
+// component.
+type Arguments struct {
+	// Path indicates the directory to watch.
+	Path string `alloy:"path,attr"`
+	// Include holds the patterns of the relative paths of the files to export.
+	Include []string `alloy:"include,attr,optional"`
+	// Exclude holds the patterns of the relative paths of the files to ignore,
+	// even if they match Include.
+	Exclude []string `alloy:"exclude,attr,optional"`
+	// Type indicates how to detect changes to the directory.
+	Type filedetector.Detector `alloy:"detector,attr,optional"`
+	// PollFrequency determines the frequency to check for changes when Type is
+	// Poll.
+	PollFrequency time.Duration `alloy:"poll_frequency,attr,optional"`
+	// IsSecret marks the files as holding secret values which should not be
+	// displayed to the user.
+	IsSecret bool `alloy:"is_secret,attr,optional"`
+}
+
+// DefaultArguments provides the default arguments for the local.file_tree
+// component.
+var DefaultArguments = Arguments{
+	Include:       []string{"**"},
+	Type:          filedetector.DetectorFSNotify,
+	PollFrequency: time.Minute,
+}
+
+// SetToDefault implements syntax.Defaulter.
+func (a *Arguments) SetToDefault() {
+	*a = DefaultArguments
+	a.Include = slices.Clone(DefaultArguments.Include)
+}
+
+// Validate implements syntax.Validator.
+func (a *Arguments) Validate() error {
+	if a.PollFrequency <= 0 {
+		return fmt.Errorf("poll_frequency must be greater than 0")
+	}
+	return nil
+}
+
+// Exports holds values which are exported by the local.file_tree component.
+type Exports struct {
+	// Files holds the content of the files, indexed by their path relative to
+	// the watched directory.
+	Files map[string]alloytypes.OptionalSecret `alloy:"files,attr"`
+}
+
+// Component implements the local.file_tree component.
+type Component struct {
+	opts component.Options
+
+	mut         sync.Mutex
+	args        Arguments
+	latestFiles map[string]string
+	detector    io.Closer
+
+	healthMut sync.RWMutex
+	health    component.Health
+
+	// reloadCh is a buffered channel which is written to when the watched tree
+	// should be reloaded by the component.
+	reloadCh     chan struct{}
+	lastAccessed prometheus.Gauge
+	filesCount   prometheus.Gauge
+}
+
+var (
+	_ component.Component       = (*Component)(nil)
+	_ component.HealthComponent = (*Component)(nil)
+)
+
+// New creates a new local.file_tree component.
+func New(o component.Options, args Arguments) (*Component, error) {
+	c := &Component{
+		opts: o,
+
+		reloadCh: make(chan struct{}, 1),
+		lastAccessed: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "local_file_tree_timestamp_last_accessed_unix_seconds",
+			Help: "The last successful access in unix seconds",
+		}),
+		filesCount: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "local_file_tree_files",
+			Help: "Number of files exported",
+		}),
+	}
+
+	for _, collector := range []prometheus.Collector{c.lastAccessed, c.filesCount} {
+		if err := o.Registerer.Register(collector); err != nil {
+			return nil, err
+		}
+	}
+	// Perform an update which will immediately set our exports to the initial
+	// contents of the tree.
+	if err := c.Update(args); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// Run implements component.Component.
+func (c *Component) Run(ctx context.Context) error {
+	defer func() {
+		c.mut.Lock()
+		defer c.mut.Unlock()
+
+		if c.detector != nil {
+			if err := c.detector.Close(); err != nil {
+				level.Error(c.opts.Logger).Log("msg", "failed to shut down detector", "err", err)
+			}
+			c.detector = nil
+		}
+	}()
+
+	// Run may be called again after exiting while the component still exists,
+	// in which case the detector has to be recreated.
+	c.mut.Lock()
+	_ = c.configureDetector()
+	c.mut.Unlock()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-c.reloadCh:
+			time.Sleep(waitReadPeriod)
+
+			// We ignore the error here from readTree since readTree will log errors
+			// and also report the error as the health of the component.
+			c.mut.Lock()
+			_ = c.readTree()
+			c.mut.Unlock()
+		}
+	}
+}
+
+// readTree reads the files of the tree and exports them if they changed since
+// the last read. mut must be held when called.
+func (c *Component) readTree() error {
+	files, err := c.walk()
+	if err != nil {
+		c.setHealth(component.Health{
+			Health:     component.HealthTypeUnhealthy,
+			Message:    fmt.Sprintf("failed to read directory: %s", err),
+			UpdateTime: time.Now(),
+		})
+		level.Error(c.opts.Logger).Log("msg", "failed to read directory", "path", c.args.Path, "err", err)
+		return err
+	}
+	c.lastAccessed.SetToCurrentTime()
+	c.filesCount.Set(float64(len(files)))
+
+	// Polling rereads the tree even when nothing changed; only notify
+	// dependants of actual changes.
+	if c.latestFiles == nil || !maps.Equal(files, c.latestFiles) {
+		c.latestFiles = files
+
+		exports := Exports{Files: make(map[string]alloytypes.OptionalSecret, len(files))}
+		for path, content := range files {
+			exports.Files[path] = alloytypes.OptionalSecret{
+				IsSecret: c.args.IsSecret,
+				Value:    content,
+			}
+		}
+		c.opts.OnStateChange(exports)
+	}
+
+	c.setHealth(component.Health{
+		Health:     component.HealthTypeHealthy,
+		Message:    fmt.Sprintf("read %d files", len(files)),
+		UpdateTime: time.Now(),
+	})
+	return nil
+}
+
+// walk returns the content of the files of the tree matching the include and
+// exclude patterns, indexed by their slash-separated relative path.
+//
+// Hidden files and directories are skipped. This ignores the temporary files
+// of editors and the internal directories of Kubernetes volumes, whose
+// files are also exposed through symbolic links at the root.
+func (c *Component) walk() (map[string]string, error) {
+	files := make(map[string]string)
+
+	// WalkDir doesn't follow symbolic links, including the root.
+	root, err := filepath.EvalSymlinks(c.args.Path)
+	if err != nil {
+		return nil, err
+	}
+
+	err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if path == root {
+			if !d.IsDir() {
+				return fmt.Errorf("%s is not a directory", path)
+			}
+			return nil
+		}
+		if strings.HasPrefix(d.Name(), ".") {
+			if d.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		// Symbolic links to files are followed, not the ones to directories.
+		if d.Type()&fs.ModeSymlink != 0 {
+			fi, err := os.Stat(path)
+			if errors.Is(err, fs.ErrNotExist) {
+				// Dangling link.
+				return nil
+			} else if err != nil {
+				return err
+			}
+			if !fi.Mode().IsRegular() {
+				return nil
+			}
+		} else if !d.Type().IsRegular() {
+			return nil
+		}
+
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if ok, err := c.matches(rel); err != nil || !ok {
+			return err
+		}
+
+		bb, err := os.ReadFile(path)
+		if errors.Is(err, fs.ErrNotExist) {
+			// The file was removed while walking the tree.
+			return nil
+		} else if err != nil {
+			return err
+		}
+		files[rel] = string(bb)
+		return nil
+	})
+
+	return files, err
+}
+
+// matches returns true if the relative path matches at least one include
+// pattern and no exclude pattern.
+func (c *Component) matches(rel string) (bool, error) {
+	for _, pattern := range c.args.Exclude {
+		if ok, err := doublestar.Match(pattern, rel); err != nil {
+			return false, fmt.Errorf("invalid exclude pattern %q: %w", pattern, err)
+		} else if ok {
+			return false, nil
+		}
+	}
+	for _, pattern := range c.args.Include {
+		if ok, err := doublestar.Match(pattern, rel); err != nil {
+			return false, fmt.Errorf("invalid include pattern %q: %w", pattern, err)
+		} else if ok {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// Update implements component.Component.
+func (c *Component) Update(args component.Arguments) error {
+	newArgs := args.(Arguments)
+
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	c.args = newArgs
+
+	// Force an immediate read of the tree to report any potential errors
+	// early, and always export it since the arguments may have changed
+	// whether the files are secret.
+	c.latestFiles = nil
+	if err := c.readTree(); err != nil {
+		return fmt.Errorf("failed to read directory: %w", err)
+	}
+
+	// The path or the detector may have changed; recreate the detector.
+	if c.detector != nil {
+		if err := c.detector.Close(); err != nil {
+			level.Error(c.opts.Logger).Log("msg", "failed to shut down old detector", "err", err)
+		}
+		c.detector = nil
+	}
+
+	return c.configureDetector()
+}
+
+// configureDetector configures the detector if one isn't set. mut must be held
+// when called.
+func (c *Component) configureDetector() error {
+	if c.detector != nil {
+		// Already have a detector; don't do anything.
+		return nil
+	}
+
+	var err error
+
+	reloadTree := func() {
+		select {
+		case c.reloadCh <- struct{}{}:
+		default:
+			// no-op: a reload is already queued so we don't need to queue a second
+			// one.
+		}
+	}
+
+	switch c.args.Type {
+	case filedetector.DetectorPoll:
+		c.detector = filedetector.NewPoller(filedetector.PollerOptions{
+			Filename:      c.args.Path,
+			ReloadFile:    reloadTree,
+			PollFrequency: c.args.PollFrequency,
+		})
+	case filedetector.DetectorFSNotify:
+		c.detector, err = filedetector.NewFSNotify(filedetector.FSNotifyOptions{
+			Logger:        c.opts.Logger,
+			Filename:      c.args.Path,
+			ReloadFile:    reloadTree,
+			PollFrequency: c.args.PollFrequency,
+			Recursive:     true,
+		})
+	}
+
+	return err
+}
+
+// CurrentHealth implements component.HealthComponent.
+func (c *Component) CurrentHealth() component.Health {
+	c.healthMut.RLock()
+	defer c.healthMut.RUnlock()
+	return c.health
+}
+
+func (c *Component) setHealth(h component.Health) {
+	c.healthMut.Lock()
+	defer c.healthMut.Unlock()
+	c.health = h
+}
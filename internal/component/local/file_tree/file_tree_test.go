@@ -0,0 +1,178 @@
+package file_tree_test
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/grafana/alloy/internal/component/local/file_tree"
+	filedetector "github.com/grafana/alloy/internal/filedetector"
+	"github.com/grafana/alloy/internal/runtime/componenttest"
+	"github.com/grafana/alloy/syntax/alloytypes"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFileTree(t *testing.T) {
+	t.Run("Polling change detector", func(t *testing.T) {
+		runFileTreeTests(t, filedetector.DetectorPoll)
+	})
+
+	t.Run("Event change detector", func(t *testing.T) {
+		runFileTreeTests(t, filedetector.DetectorFSNotify)
+	})
+}
+
+func writeFile(t *testing.T, path, content string) {
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0775))
+	require.NoError(t, os.WriteFile(path, []byte(content), 0664))
+}
+
+func exports(files map[string]string) file_tree.Exports {
+	out := file_tree.Exports{Files: make(map[string]alloytypes.OptionalSecret)}
+	for path, content := range files {
+		out.Files[path] = alloytypes.OptionalSecret{Value: content}
+	}
+	return out
+}
+
+// runFileTreeTests will run a suite of tests with the configured update type.
+func runFileTreeTests(t *testing.T, ut filedetector.Detector) {
+	newSuiteController := func(t *testing.T, dir string) *componenttest.Controller {
+		writeFile(t, filepath.Join(dir, "a.yaml"), "a")
+		writeFile(t, filepath.Join(dir, "sub", "b.yaml"), "b")
+		writeFile(t, filepath.Join(dir, "sub", "b.yaml.bak"), "excluded")
+		writeFile(t, filepath.Join(dir, ".hidden"), "hidden")
+
+		tc, err := componenttest.NewControllerFromID(nil, "local.file_tree")
+		require.NoError(t, err)
+		go func() {
+			err := tc.Run(componenttest.TestContext(t), file_tree.Arguments{
+				Path:    dir,
+				Include: []string{"**"},
+				Exclude: []string{"**/*.bak"},
+				Type:    ut,
+
+				// Pick a polling frequency which is fast enough so that tests finish
+				// quickly but not so frequent such that Go struggles to schedule the
+				// goroutines of the tests on slower machines.
+				PollFrequency: 50 * time.Millisecond,
+			})
+			require.NoError(t, err)
+		}()
+
+		// Swallow the initial exports notification.
+		require.NoError(t, tc.WaitExports(time.Second))
+		require.Equal(t, exports(map[string]string{
+			"a.yaml":     "a",
+			"sub/b.yaml": "b",
+		}), tc.Exports())
+		return tc
+	}
+
+	t.Run("Updates to files are detected", func(t *testing.T) {
+		dir := t.TempDir()
+		sc := newSuiteController(t, dir)
+
+		writeFile(t, filepath.Join(dir, "sub", "b.yaml"), "new b")
+
+		require.NoError(t, sc.WaitExports(time.Second))
+		require.Equal(t, exports(map[string]string{
+			"a.yaml":     "a",
+			"sub/b.yaml": "new b",
+		}), sc.Exports())
+	})
+
+	t.Run("Files in new directories are detected", func(t *testing.T) {
+		dir := t.TempDir()
+		sc := newSuiteController(t, dir)
+
+		writeFile(t, filepath.Join(dir, "new", "deeper", "c.yaml"), "c")
+
+		require.Eventually(t, func() bool {
+			_, ok := sc.Exports().(file_tree.Exports).Files["new/deeper/c.yaml"]
+			return ok
+		}, 2*time.Second, 10*time.Millisecond)
+	})
+
+	t.Run("Deleted files are detected", func(t *testing.T) {
+		dir := t.TempDir()
+		sc := newSuiteController(t, dir)
+
+		require.NoError(t, os.Remove(filepath.Join(dir, "a.yaml")))
+
+		require.NoError(t, sc.WaitExports(time.Second))
+		require.Equal(t, exports(map[string]string{
+			"sub/b.yaml": "b",
+		}), sc.Exports())
+	})
+}
+
+func TestFileTree_Secret(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "password"), "hunter2")
+
+	tc, err := componenttest.NewControllerFromID(nil, "local.file_tree")
+	require.NoError(t, err)
+	go func() {
+		err := tc.Run(componenttest.TestContext(t), file_tree.Arguments{
+			Path:          dir,
+			Include:       []string{"**"},
+			Type:          filedetector.DetectorPoll,
+			PollFrequency: 1 * time.Hour,
+			IsSecret:      true,
+		})
+		require.NoError(t, err)
+	}()
+
+	require.NoError(t, tc.WaitExports(time.Second))
+	require.Equal(t, file_tree.Exports{
+		Files: map[string]alloytypes.OptionalSecret{
+			"password": {IsSecret: true, Value: "hunter2"},
+		},
+	}, tc.Exports())
+}
+
+// TestFileTree_ExistOnLoad ensures that the configured directory must exist
+// on the first load of local.file_tree.
+func TestFileTree_ExistOnLoad(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "missing")
+
+	tc, err := componenttest.NewControllerFromID(nil, "local.file_tree")
+	require.NoError(t, err)
+
+	err = tc.Run(canceledContext(), file_tree.Arguments{
+		Path:          dir,
+		Include:       []string{"**"},
+		Type:          filedetector.DetectorPoll,
+		PollFrequency: 1 * time.Hour,
+	})
+
+	var expectErr error = &fs.PathError{}
+	require.ErrorAs(t, err, &expectErr)
+}
+
+func TestFileTree_InvalidPattern(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, filepath.Join(dir, "a.yaml"), "a")
+
+	tc, err := componenttest.NewControllerFromID(nil, "local.file_tree")
+	require.NoError(t, err)
+
+	err = tc.Run(canceledContext(), file_tree.Arguments{
+		Path:          dir,
+		Include:       []string{"[a-"},
+		Type:          filedetector.DetectorPoll,
+		PollFrequency: 1 * time.Hour,
+	})
+	require.ErrorContains(t, err, `invalid include pattern "[a-"`)
+}
+
+// canceledContext creates a context which is already canceled.
+func canceledContext() context.Context {
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	return ctx
+}
@@ -0,0 +1,172 @@
+package process
+
+import (
+	"context"
+	"math"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/expfmt"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/value"
+
+	"github.com/grafana/alloy/internal/component/loki/process/metric"
+	alloyprom "github.com/grafana/alloy/internal/component/prometheus"
+	"github.com/grafana/alloy/internal/runtime/logging/level"
+)
+
+// metricsForwarder appends the series of the stage.metrics collectors of the
+// current pipeline to the metrics_forward_to appendables.
+type metricsForwarder struct {
+	logger log.Logger
+
+	mut      sync.Mutex
+	fanout   *alloyprom.Fanout
+	registry *prometheus.Registry
+	// sent holds the series appended by the last forward, by label hash, so
+	// that stale markers can be appended once they disappear.
+	sent map[uint64]labels.Labels
+}
+
+func newMetricsForwarder(logger log.Logger) *metricsForwarder {
+	return &metricsForwarder{
+		logger:   logger,
+		registry: prometheus.NewRegistry(),
+		sent:     make(map[uint64]labels.Labels),
+	}
+}
+
+// SetRegistry sets the registry holding the stage.metrics collectors of the
+// current pipeline. The series of the previous registry are marked as stale by
+// the next forward.
+func (f *metricsForwarder) SetRegistry(registry *prometheus.Registry) {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+	f.registry = registry
+}
+
+// SetFanout sets where series are forwarded to.
+func (f *metricsForwarder) SetFanout(fanout *alloyprom.Fanout) {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+	f.fanout = fanout
+}
+
+// Forward appends the current value of every forwarded series with timestamp
+// ts, and a stale marker for every series which was forwarded last time but
+// no longer exists, for example because it expired after max_idle_duration.
+func (f *metricsForwarder) Forward(ctx context.Context, ts time.Time) error {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+	if f.fanout == nil {
+		return nil
+	}
+
+	// Gather returns whatever it could collect on errors, such as two
+	// stage.metrics blocks defining the same metric.
+	families, err := f.registry.Gather()
+	if err != nil {
+		level.Warn(f.logger).Log("msg", "failed to gather all metrics to forward", "err", err)
+	}
+	samples, err := expfmt.ExtractSamples(&expfmt.DecodeOptions{Timestamp: model.TimeFromUnixNano(ts.UnixNano())}, families...)
+	if err != nil {
+		level.Warn(f.logger).Log("msg", "failed to extract some samples to forward", "err", err)
+	}
+
+	var (
+		app     = f.fanout.Appender(ctx)
+		tsMilli = ts.UnixMilli()
+		current = make(map[uint64]labels.Labels, len(samples))
+	)
+	for _, s := range samples {
+		lbls := metricToLabels(s.Metric)
+		current[lbls.Hash()] = lbls
+		if _, err := app.Append(0, lbls, tsMilli, float64(s.Value)); err != nil {
+			_ = app.Rollback()
+			return err
+		}
+	}
+	for hash, lbls := range f.sent {
+		if _, ok := current[hash]; ok {
+			continue
+		}
+		if _, err := app.Append(0, lbls, tsMilli, math.Float64frombits(value.StaleNaN)); err != nil {
+			_ = app.Rollback()
+			return err
+		}
+	}
+	if err := app.Commit(); err != nil {
+		return err
+	}
+	f.sent = current
+	return nil
+}
+
+// StaleAll appends a stale marker for every series forwarded last time.
+func (f *metricsForwarder) StaleAll(ctx context.Context, ts time.Time) error {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+	if f.fanout == nil || len(f.sent) == 0 {
+		return nil
+	}
+
+	app := f.fanout.Appender(ctx)
+	for _, lbls := range f.sent {
+		if _, err := app.Append(0, lbls, ts.UnixMilli(), math.Float64frombits(value.StaleNaN)); err != nil {
+			_ = app.Rollback()
+			return err
+		}
+	}
+	if err := app.Commit(); err != nil {
+		return err
+	}
+	f.sent = make(map[uint64]labels.Labels)
+	return nil
+}
+
+func metricToLabels(m model.Metric) labels.Labels {
+	b := labels.NewScratchBuilder(len(m))
+	for name, val := range m {
+		b.Add(string(name), string(val))
+	}
+	b.Sort()
+	return b.Labels()
+}
+
+// forwardingRegisterer registers collectors on the wrapped Registerer, and
+// additionally registers the collectors of stage.metrics on forwarded.
+type forwardingRegisterer struct {
+	prometheus.Registerer
+	forwarded *prometheus.Registry
+}
+
+// Register implements prometheus.Registerer.
+func (r *forwardingRegisterer) Register(c prometheus.Collector) error {
+	if err := r.Registerer.Register(c); err != nil {
+		return err
+	}
+	switch c.(type) {
+	case *metric.Counters, *metric.Gauges, *metric.Histograms:
+		// These collectors are unchecked, so registering them can't fail.
+		return r.forwarded.Register(c)
+	}
+	return nil
+}
+
+// MustRegister implements prometheus.Registerer.
+func (r *forwardingRegisterer) MustRegister(cs ...prometheus.Collector) {
+	for _, c := range cs {
+		if err := r.Register(c); err != nil {
+			panic(err)
+		}
+	}
+}
+
+// Unregister implements prometheus.Registerer.
+func (r *forwardingRegisterer) Unregister(c prometheus.Collector) bool {
+	r.forwarded.Unregister(c)
+	return r.Registerer.Unregister(c)
+}
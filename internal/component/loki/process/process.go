@@ -5,13 +5,21 @@ package process
 
 import (
 	"context"
+	"fmt"
 	"reflect"
 	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/prometheus/storage"
 
 	"github.com/grafana/alloy/internal/component"
 	"github.com/grafana/alloy/internal/component/common/loki"
 	"github.com/grafana/alloy/internal/component/loki/process/stages"
+	alloyprom "github.com/grafana/alloy/internal/component/prometheus"
 	"github.com/grafana/alloy/internal/featuregate"
+	"github.com/grafana/alloy/internal/runtime/logging/level"
+	"github.com/grafana/alloy/internal/service/labelstore"
 )
 
 // TODO(thampiotr): We should reconsider which parts of this component should be exported and which should
@@ -36,6 +44,27 @@ func init() {
 type Arguments struct {
 	ForwardTo []loki.LogsReceiver  `alloy:"forward_to,attr"`
 	Stages    []stages.StageConfig `alloy:"stage,enum,optional"`
+
+	MetricsForwardTo       []storage.Appendable `alloy:"metrics_forward_to,attr,optional"`
+	MetricsForwardInterval time.Duration        `alloy:"metrics_forward_interval,attr,optional"`
+}
+
+// DefaultArguments holds the default values of the loki.process arguments.
+var DefaultArguments = Arguments{
+	MetricsForwardInterval: time.Minute,
+}
+
+// SetToDefault implements syntax.Defaulter.
+func (a *Arguments) SetToDefault() {
+	*a = DefaultArguments
+}
+
+// Validate implements syntax.Validator.
+func (a *Arguments) Validate() error {
+	if a.MetricsForwardInterval <= 0 {
+		return fmt.Errorf("metrics_forward_interval must be greater than 0")
+	}
+	return nil
 }
 
 // Exports exposes the receiver that can be used to send log entries to
@@ -61,12 +90,17 @@ type Component struct {
 
 	fanoutMut sync.RWMutex
 	fanout    []loki.LogsReceiver
+
+	metricsForwarder       *metricsForwarder
+	metricsFanout          *alloyprom.Fanout
+	metricsForwardInterval time.Duration
 }
 
 // New creates a new loki.process component.
 func New(o component.Options, args Arguments) (*Component, error) {
 	c := &Component{
-		opts: o,
+		opts:             o,
+		metricsForwarder: newMetricsForwarder(o.Logger),
 	}
 
 	// Create and immediately export the receiver which remains the same for
@@ -94,9 +128,10 @@ func (c *Component) Run(ctx context.Context) error {
 		c.mut.RUnlock()
 	}()
 	wg := &sync.WaitGroup{}
-	wg.Add(2)
+	wg.Add(3)
 	go c.handleIn(ctx, wg)
 	go c.handleOut(ctx, wg)
+	go c.forwardMetrics(ctx, wg)
 
 	wg.Wait()
 	return nil
@@ -111,6 +146,10 @@ func (c *Component) Update(args component.Arguments) error {
 	c.fanout = newArgs.ForwardTo
 	c.fanoutMut.Unlock()
 
+	if err := c.updateMetricsFanout(newArgs); err != nil {
+		return err
+	}
+
 	// Then update the pipeline itself.
 	c.mut.Lock()
 	defer c.mut.Unlock()
@@ -119,14 +158,20 @@ func (c *Component) Update(args component.Arguments) error {
 	// first load. This will allow a component with no stages to function
 	// properly.
 	if stagesChanged(c.stages, newArgs.Stages) || c.stages == nil {
-		if c.entryHandler != nil {
-			c.entryHandler.Stop()
-		}
-
-		pipeline, err := stages.NewPipeline(c.opts.Logger, newArgs.Stages, &c.opts.ID, c.opts.Registerer)
+		// The collectors of stage.metrics are also registered on a
+		// registry of their own, which is forwarded to metrics_forward_to.
+		metricsRegistry := prometheus.NewRegistry()
+		registerer := &forwardingRegisterer{Registerer: c.opts.Registerer, forwarded: metricsRegistry}
+		pipeline, err := stages.NewPipeline(c.opts.Logger, newArgs.Stages, &c.opts.ID, registerer)
 		if err != nil {
 			return err
 		}
+		// The previous pipeline is only stopped once the new one was built,
+		// so that it keeps processing entries if the new stages are invalid.
+		if c.entryHandler != nil {
+			c.entryHandler.Stop()
+		}
+		c.metricsForwarder.SetRegistry(metricsRegistry)
 		c.entryHandler = loki.NewEntryHandler(c.processOut, func() {})
 		c.processIn = pipeline.Wrap(c.entryHandler).Chan()
 		c.stages = newArgs.Stages
@@ -135,6 +180,56 @@ func (c *Component) Update(args component.Arguments) error {
 	return nil
 }
 
+// updateMetricsFanout updates where the series of stage.metrics are
+// forwarded to. The label store is only looked up once metrics_forward_to is
+// set, so that the component runs without it otherwise.
+func (c *Component) updateMetricsFanout(args Arguments) error {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	c.metricsForwardInterval = args.MetricsForwardInterval
+	if c.metricsForwardInterval <= 0 {
+		c.metricsForwardInterval = DefaultArguments.MetricsForwardInterval
+	}
+	if c.metricsFanout == nil {
+		if len(args.MetricsForwardTo) == 0 {
+			return nil
+		}
+		data, err := c.opts.GetServiceData(labelstore.ServiceName)
+		if err != nil {
+			return err
+		}
+		c.metricsFanout = alloyprom.NewFanout(args.MetricsForwardTo, c.opts.ID, c.opts.Registerer, data.(labelstore.LabelStore))
+		c.metricsForwarder.SetFanout(c.metricsFanout)
+		return nil
+	}
+	c.metricsFanout.UpdateChildren(args.MetricsForwardTo)
+	return nil
+}
+
+// forwardMetrics appends the series of stage.metrics to metrics_forward_to
+// on every metrics_forward_interval, and marks them as stale on exit.
+func (c *Component) forwardMetrics(ctx context.Context, wg *sync.WaitGroup) {
+	defer wg.Done()
+	for {
+		c.mut.RLock()
+		interval := c.metricsForwardInterval
+		c.mut.RUnlock()
+
+		select {
+		case <-ctx.Done():
+			if err := c.metricsForwarder.StaleAll(context.Background(), time.Now()); err != nil {
+				level.Warn(c.opts.Logger).Log("msg", "failed to mark forwarded metrics as stale", "err", err)
+			}
+			return
+		case <-time.After(interval):
+			if err := c.metricsForwarder.Forward(ctx, time.Now()); err != nil {
+				level.Warn(c.opts.Logger).Log("msg", "failed to forward metrics", "err", err)
+			}
+		}
+	}
+}
+
 func (c *Component) handleIn(ctx context.Context, wg *sync.WaitGroup) {
 	defer wg.Done()
 	for {
@@ -5,12 +5,16 @@ package process
 import (
 	"context"
 	"os"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/grafana/loki/v3/pkg/logproto"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/value"
+	"github.com/prometheus/prometheus/storage"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/atomic"
 	"go.uber.org/goleak"
@@ -20,7 +24,9 @@ import (
 	"github.com/grafana/alloy/internal/component/discovery"
 	"github.com/grafana/alloy/internal/component/loki/process/stages"
 	lsf "github.com/grafana/alloy/internal/component/loki/source/file"
+	alloyprom "github.com/grafana/alloy/internal/component/prometheus"
 	"github.com/grafana/alloy/internal/runtime/componenttest"
+	"github.com/grafana/alloy/internal/service/labelstore"
 	"github.com/grafana/alloy/internal/util"
 	"github.com/grafana/alloy/syntax"
 )
@@ -355,7 +361,7 @@ stage.static_labels {
 
 	go func() {
 		err := ctrl.Run(context.Background(), lsf.Arguments{
-			Targets: []discovery.Target{{"__path__": f.Name(), "somelbl": "somevalue"}},
+			Targets: []discovery.Target{discovery.NewTargetFromMap(map[string]string{"__path__": f.Name(), "somelbl": "somevalue"})},
 			ForwardTo: []loki.LogsReceiver{
 				tc1.Exports().(Exports).Receiver,
 				tc2.Exports().(Exports).Receiver,
@@ -487,3 +493,166 @@ func TestDeadlockWithFrequentUpdates(t *testing.T) {
 	time.Sleep(1 * time.Second)
 	require.WithinDuration(t, time.Now(), lastSend.Load().(time.Time), 300*time.Millisecond)
 }
+
+func TestUpdateWithInvalidLookupTable(t *testing.T) {
+	stg := func(table string) []stages.StageConfig {
+		type cfg struct {
+			Stages []stages.StageConfig `alloy:"stage,enum"`
+		}
+		var stagesCfg cfg
+		err := syntax.Unmarshal([]byte(`
+			stage.regex {
+				expression = "^(?P<service>\\S+)"
+			}
+			stage.lookup {
+				source = "service"
+				table  = "`+table+`"
+				labels = ["team"]
+			}`), &stagesCfg)
+		require.NoError(t, err)
+		return stagesCfg.Stages
+	}
+
+	ch := loki.NewLogsReceiver()
+	opts := component.Options{
+		Logger:        util.TestAlloyLogger(t),
+		Registerer:    prometheus.NewRegistry(),
+		OnStateChange: func(e component.Exports) {},
+	}
+	c, err := New(opts, Arguments{
+		ForwardTo: []loki.LogsReceiver{ch},
+		Stages:    stg(`service,team\napi,core\n`),
+	})
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go c.Run(ctx)
+
+	// The table is missing its key column, so the previous table is kept.
+	err = c.Update(Arguments{
+		ForwardTo: []loki.LogsReceiver{ch},
+		Stages:    stg(`name,team\napi,payments\n`),
+	})
+	require.ErrorContains(t, err, `key column "service" is missing`)
+
+	c.receiver.Chan() <- loki.Entry{
+		Labels: model.LabelSet{"app": "foo"},
+		Entry:  logproto.Entry{Timestamp: time.Now(), Line: "api request served"},
+	}
+	select {
+	case entry := <-ch.Chan():
+		require.Equal(t, model.LabelSet{"app": "foo", "team": "core"}, entry.Labels)
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "failed waiting for log line")
+	}
+}
+
+func TestMetricsForwarding(t *testing.T) {
+	stg := `
+	stage.metrics {
+		metric.counter {
+			name              = "lines_total"
+			description       = "Lines processed"
+			match_all         = true
+			action            = "inc"
+			max_idle_duration = "1s"
+		}
+	}`
+	type cfg struct {
+		Stages []stages.StageConfig `alloy:"stage,enum"`
+	}
+	var stagesCfg cfg
+	require.NoError(t, syntax.Unmarshal([]byte(stg), &stagesCfg))
+
+	type sample struct {
+		labels labels.Labels
+		value  float64
+	}
+	var (
+		mut     sync.Mutex
+		samples []sample
+	)
+	ls := labelstore.New(nil, prometheus.NewRegistry())
+	appendable := alloyprom.NewInterceptor(nil, ls, alloyprom.WithAppendHook(func(ref storage.SeriesRef, l labels.Labels, _ int64, v float64, _ storage.Appender) (storage.SeriesRef, error) {
+		mut.Lock()
+		defer mut.Unlock()
+		samples = append(samples, sample{labels: l, value: v})
+		return ref, nil
+	}))
+	takeSamples := func() []sample {
+		mut.Lock()
+		defer mut.Unlock()
+		res := samples
+		samples = nil
+		return res
+	}
+
+	ch1 := loki.NewLogsReceiver()
+	opts := component.Options{
+		ID:            "loki.process.test",
+		Logger:        util.TestAlloyLogger(t),
+		Registerer:    prometheus.NewRegistry(),
+		OnStateChange: func(e component.Exports) {},
+		GetServiceData: func(name string) (interface{}, error) {
+			require.Equal(t, labelstore.ServiceName, name)
+			return ls, nil
+		},
+	}
+	args := DefaultArguments
+	args.ForwardTo = []loki.LogsReceiver{ch1}
+	args.Stages = stagesCfg.Stages
+	args.MetricsForwardTo = []storage.Appendable{appendable}
+	args.MetricsForwardInterval = time.Hour
+
+	c, err := New(opts, args)
+	require.NoError(t, err)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go c.Run(ctx)
+
+	c.receiver.Chan() <- loki.Entry{
+		Labels: model.LabelSet{"app": "foo"},
+		Entry:  logproto.Entry{Timestamp: time.Now(), Line: "hello"},
+	}
+	select {
+	case <-ch1.Chan():
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "failed waiting for log line")
+	}
+
+	require.NoError(t, c.metricsForwarder.Forward(ctx, time.Now()))
+	require.Equal(t, []sample{{
+		labels: labels.FromStrings("__name__", "loki_process_custom_lines_total", "app", "foo"),
+		value:  1,
+	}}, takeSamples())
+
+	// Once the series expires after max_idle_duration, a stale marker is
+	// forwarded for it.
+	require.Eventually(t, func() bool {
+		require.NoError(t, c.metricsForwarder.Forward(ctx, time.Now()))
+		for _, s := range takeSamples() {
+			if value.IsStaleNaN(s.value) {
+				require.Equal(t, "foo", s.labels.Get("app"))
+				return true
+			}
+			require.Equal(t, 1.0, s.value)
+		}
+		return false
+	}, 5*time.Second, 100*time.Millisecond)
+
+	// Nothing is forwarded after the stale marker.
+	require.NoError(t, c.metricsForwarder.Forward(ctx, time.Now()))
+	require.Empty(t, takeSamples())
+}
+
+func TestArgumentsValidate(t *testing.T) {
+	var args Arguments
+	require.NoError(t, syntax.Unmarshal([]byte(`forward_to = []`), &args))
+	require.Equal(t, time.Minute, args.MetricsForwardInterval)
+
+	err := syntax.Unmarshal([]byte(`
+		forward_to               = []
+		metrics_forward_interval = "0s"
+	`), &args)
+	require.ErrorContains(t, err, "metrics_forward_interval must be greater than 0")
+}
@@ -0,0 +1,268 @@
+package stages
+
+import (
+	"encoding/binary"
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/cespare/xxhash/v2"
+	"github.com/go-kit/log"
+	"github.com/grafana/loki/v3/pkg/logproto"
+	lru "github.com/hashicorp/golang-lru/v2"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+
+	"github.com/grafana/alloy/internal/component/common/loki"
+	"github.com/grafana/alloy/internal/runtime/logging/level"
+)
+
+// Configuration errors.
+var (
+	ErrDedupInvalidWindow     = errors.New("dedup stage `window` must be greater than 0")
+	ErrDedupInvalidMaxEntries = errors.New("dedup stage `max_entries` must be greater than 0")
+)
+
+// DedupConfig contains the configuration for a dedupStage.
+type DedupConfig struct {
+	Source          string        `alloy:"source,attr,optional"`
+	Window          time.Duration `alloy:"window,attr,optional"`
+	MaxEntries      int           `alloy:"max_entries,attr,optional"`
+	Consecutive     bool          `alloy:"consecutive,attr,optional"`
+	RepeatedMessage string        `alloy:"repeated_message,attr,optional"`
+	DropReason      string        `alloy:"drop_counter_reason,attr,optional"`
+}
+
+// DefaultDedupConfig sets the default values of the dedup stage.
+var DefaultDedupConfig = DedupConfig{
+	Window:          time.Minute,
+	MaxEntries:      10000,
+	RepeatedMessage: "last message repeated $COUNT times",
+	DropReason:      "dedup_stage",
+}
+
+// SetToDefault implements syntax.Defaulter.
+func (c *DedupConfig) SetToDefault() {
+	*c = DefaultDedupConfig
+}
+
+// Validate implements syntax.Validator.
+func (c *DedupConfig) Validate() error {
+	if c.Window <= 0 {
+		return ErrDedupInvalidWindow
+	}
+	if c.MaxEntries <= 0 {
+		return ErrDedupInvalidMaxEntries
+	}
+	return nil
+}
+
+// newDedupStage creates a dedupStage from config.
+func newDedupStage(logger log.Logger, config DedupConfig, registerer prometheus.Registerer) (Stage, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	if config.DropReason == "" {
+		config.DropReason = DefaultDedupConfig.DropReason
+	}
+
+	return &dedupStage{
+		logger:    log.With(logger, "component", "stage", "type", "dedup"),
+		cfg:       config,
+		sources:   splitSource(config.Source),
+		dropCount: getDropCountMetric(registerer),
+	}, nil
+}
+
+// dedupStage drops log entries which were already seen within a time window.
+type dedupStage struct {
+	logger    log.Logger
+	cfg       DedupConfig
+	sources   []string
+	dropCount *prometheus.CounterVec
+}
+
+// dedupStream is the state of a stream when only consecutive duplicates are
+// dropped.
+type dedupStream struct {
+	key      uint64    // The key of the last entry passed through.
+	last     Entry     // The last entry dropped as a duplicate.
+	lastSeen time.Time // When the last entry with the key was received.
+	repeated int       // The number of entries dropped since the last one passed through.
+}
+
+// Run implements Stage.
+func (m *dedupStage) Run(in chan Entry) chan Entry {
+	out := make(chan Entry)
+	if m.cfg.Consecutive {
+		go m.runConsecutive(in, out)
+	} else {
+		go m.runWindow(in, out)
+	}
+	return out
+}
+
+// runWindow drops entries whose key was seen within the window.
+func (m *dedupStage) runWindow(in chan Entry, out chan Entry) {
+	defer close(out)
+
+	// The error is only returned for a non-positive size, which is validated.
+	seen, _ := lru.New[uint64, time.Time](m.cfg.MaxEntries)
+	for e := range in {
+		key, ok := m.key(e)
+		if !ok {
+			out <- e
+			continue
+		}
+
+		now := time.Now()
+		if ts, found := seen.Get(key); found && now.Sub(ts) < m.cfg.Window {
+			if Debug {
+				level.Debug(m.logger).Log("msg", "dropping duplicate entry", "first_seen", ts)
+			}
+			m.dropCount.WithLabelValues(m.cfg.DropReason).Inc()
+			continue
+		}
+		seen.Add(key, now)
+		out <- e
+	}
+}
+
+// runConsecutive drops entries whose key is the same as the previous entry of
+// their stream, and sends a summary of the dropped entries once the stream
+// moves on to another key or is idle for the window.
+func (m *dedupStage) runConsecutive(in chan Entry, out chan Entry) {
+	defer close(out)
+
+	var pending []Entry
+	streams, _ := lru.NewWithEvict[model.Fingerprint, *dedupStream](m.cfg.MaxEntries, func(_ model.Fingerprint, s *dedupStream) {
+		if summary, ok := m.summary(s); ok {
+			pending = append(pending, summary)
+		}
+	})
+	flushPending := func() {
+		for _, e := range pending {
+			out <- e
+		}
+		pending = pending[:0]
+	}
+
+	ticker := time.NewTicker(m.cfg.Window)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			now := time.Now()
+			for _, fp := range streams.Keys() {
+				if s, ok := streams.Peek(fp); ok && now.Sub(s.lastSeen) >= m.cfg.Window {
+					streams.Remove(fp)
+				}
+			}
+			flushPending()
+		case e, ok := <-in:
+			if !ok {
+				streams.Purge()
+				flushPending()
+				return
+			}
+
+			key, ok := m.key(e)
+			if !ok {
+				out <- e
+				continue
+			}
+
+			now := time.Now()
+			fp := e.Labels.Fingerprint()
+			s, found := streams.Get(fp)
+			if found && s.key == key && now.Sub(s.lastSeen) < m.cfg.Window {
+				s.last = e
+				s.lastSeen = now
+				s.repeated++
+				m.dropCount.WithLabelValues(m.cfg.DropReason).Inc()
+				continue
+			}
+			if found {
+				if summary, ok := m.summary(s); ok {
+					out <- summary
+				}
+			}
+			streams.Add(fp, &dedupStream{key: key, lastSeen: now})
+			flushPending()
+			out <- e
+		}
+	}
+}
+
+// summary returns the entry which reports the number of entries dropped from
+// a stream, if any.
+func (m *dedupStage) summary(s *dedupStream) (Entry, bool) {
+	if s.repeated == 0 || m.cfg.RepeatedMessage == "" {
+		return Entry{}, false
+	}
+	extracted := make(map[string]interface{}, len(s.last.Extracted))
+	for k, v := range s.last.Extracted {
+		extracted[k] = v
+	}
+	return Entry{
+		Extracted: extracted,
+		Entry: loki.Entry{
+			Labels: s.last.Labels.Clone(),
+			Entry: logproto.Entry{
+				Timestamp: s.last.Timestamp,
+				Line:      strings.ReplaceAll(m.cfg.RepeatedMessage, "$COUNT", strconv.Itoa(s.repeated)),
+			},
+		},
+	}, true
+}
+
+// key returns the hash identifying duplicates of e. Without source, the hash
+// covers the labels, timestamp and line of e, or only the line when dropping
+// consecutive duplicates. With source, the hash covers the extracted values,
+// and it returns false if one of them is missing.
+func (m *dedupStage) key(e Entry) (uint64, bool) {
+	h := xxhash.New()
+	if len(m.sources) == 0 {
+		if !m.cfg.Consecutive {
+			var buf [16]byte
+			binary.LittleEndian.PutUint64(buf[:8], uint64(e.Labels.Fingerprint()))
+			binary.LittleEndian.PutUint64(buf[8:], uint64(e.Timestamp.UnixNano()))
+			_, _ = h.Write(buf[:])
+		}
+		_, _ = h.WriteString(e.Line)
+		return h.Sum64(), true
+	}
+
+	for _, src := range m.sources {
+		v, ok := e.Extracted[src]
+		if !ok {
+			if Debug {
+				level.Debug(m.logger).Log("msg", "entry will not be deduplicated, the source was not found in the extracted map", "source", src)
+			}
+			return 0, false
+		}
+		s, err := getString(v)
+		if err != nil {
+			if Debug {
+				level.Debug(m.logger).Log("msg", "entry will not be deduplicated, failed to convert extracted value to string", "source", src, "err", err, "type", reflect.TypeOf(v))
+			}
+			return 0, false
+		}
+		_, _ = h.WriteString(s)
+		_, _ = h.Write([]byte{0xff})
+	}
+	return h.Sum64(), true
+}
+
+// Name implements Stage.
+func (m *dedupStage) Name() string {
+	return StageTypeDedup
+}
+
+// Cleanup implements Stage.
+func (*dedupStage) Cleanup() {
+	// no-op
+}
@@ -0,0 +1,189 @@
+package stages
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+
+	util_log "github.com/grafana/loki/v3/pkg/util/log"
+)
+
+func TestPipeline_Dedup(t *testing.T) {
+	t.Parallel()
+
+	var (
+		ts     = time.Now()
+		stream = model.LabelSet{"app": "foo"}
+		other  = model.LabelSet{"app": "bar"}
+	)
+
+	tests := map[string]struct {
+		config   string
+		entries  []Entry
+		expected []string
+	}{
+		"labels, timestamp and line": {
+			`stage.dedup {}`,
+			[]Entry{
+				newEntry(nil, stream, "hello", ts),
+				newEntry(nil, stream, "hello", ts),
+				newEntry(nil, other, "hello", ts),
+				newEntry(nil, stream, "hello", ts.Add(time.Second)),
+				newEntry(nil, stream, "world", ts),
+				newEntry(nil, stream, "hello", ts),
+			},
+			[]string{"hello", "hello", "hello", "world"},
+		},
+		"extracted values": {
+			`stage.json {
+				expressions = { "id" = "" }
+			}
+			stage.dedup {
+				source = "id"
+			}`,
+			[]Entry{
+				newEntry(nil, stream, `{"id": "1", "relay": "a"}`, ts),
+				newEntry(nil, other, `{"id": "1", "relay": "b"}`, ts.Add(time.Second)),
+				newEntry(nil, stream, `{"id": "2", "relay": "a"}`, ts),
+				newEntry(nil, stream, `{"relay": "a"}`, ts),
+				newEntry(nil, stream, `{"relay": "a"}`, ts),
+			},
+			[]string{`{"id": "1", "relay": "a"}`, `{"id": "2", "relay": "a"}`, `{"relay": "a"}`, `{"relay": "a"}`},
+		},
+		"consecutive": {
+			`stage.dedup {
+				consecutive = true
+			}`,
+			[]Entry{
+				newEntry(nil, stream, "hello", ts),
+				newEntry(nil, stream, "hello", ts.Add(time.Second)),
+				newEntry(nil, other, "hello", ts),
+				newEntry(nil, stream, "hello", ts.Add(2*time.Second)),
+				newEntry(nil, stream, "world", ts.Add(3*time.Second)),
+				newEntry(nil, stream, "hello", ts.Add(4*time.Second)),
+				newEntry(nil, stream, "hello", ts.Add(5*time.Second)),
+			},
+			[]string{"hello", "hello", "last message repeated 2 times", "world", "hello", "last message repeated 1 times"},
+		},
+		"consecutive without summary": {
+			`stage.dedup {
+				consecutive      = true
+				repeated_message = ""
+			}`,
+			[]Entry{
+				newEntry(nil, stream, "hello", ts),
+				newEntry(nil, stream, "hello", ts),
+				newEntry(nil, stream, "world", ts),
+			},
+			[]string{"hello", "world"},
+		},
+	}
+
+	for testName, testData := range tests {
+		testData := testData
+
+		t.Run(testName, func(t *testing.T) {
+			t.Parallel()
+
+			pl, err := NewPipeline(util_log.Logger, loadConfig(testData.config), nil, prometheus.NewRegistry())
+			require.NoError(t, err)
+
+			var lines []string
+			for _, e := range processEntries(pl, testData.entries...) {
+				lines = append(lines, e.Line)
+			}
+			require.Equal(t, testData.expected, lines)
+		})
+	}
+}
+
+func TestDedupStage_Summary(t *testing.T) {
+	config := `
+stage.dedup {
+	consecutive         = true
+	repeated_message    = "repeated $COUNT times"
+	drop_counter_reason = "duplicate"
+}
+`
+	reg := prometheus.NewRegistry()
+	pl, err := NewPipeline(util_log.Logger, loadConfig(config), nil, reg)
+	require.NoError(t, err)
+
+	ts := time.Now()
+	stream := model.LabelSet{"app": "foo"}
+	out := processEntries(pl,
+		newEntry(nil, stream, "hello", ts),
+		newEntry(nil, stream, "hello", ts.Add(time.Second)),
+		newEntry(nil, stream, "hello", ts.Add(2*time.Second)),
+	)
+	require.Len(t, out, 2)
+
+	// The summary has the labels and timestamp of the last duplicate.
+	require.Equal(t, "repeated 2 times", out[1].Line)
+	require.Equal(t, stream, out[1].Labels)
+	require.Equal(t, ts.Add(2*time.Second), out[1].Timestamp)
+	require.Equal(t, 2.0, testutil.ToFloat64(getDropCountMetric(reg).WithLabelValues("duplicate")))
+}
+
+func TestDedupStage_SummaryAfterWindow(t *testing.T) {
+	config := `
+stage.dedup {
+	consecutive = true
+	window      = "100ms"
+}
+`
+	pl, err := NewPipeline(util_log.Logger, loadConfig(config), nil, prometheus.NewRegistry())
+	require.NoError(t, err)
+
+	in := make(chan Entry)
+	out := pl.Run(in)
+	defer close(in)
+
+	ts := time.Now()
+	in <- newEntry(nil, nil, "hello", ts)
+	require.Equal(t, "hello", (<-out).Line)
+	in <- newEntry(nil, nil, "hello", ts)
+
+	// The summary is sent once the stream is idle for the window, without
+	// waiting for the next entry.
+	select {
+	case e := <-out:
+		require.Equal(t, "last message repeated 1 times", e.Line)
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "failed waiting for the summary")
+	}
+}
+
+func TestDedupStage_MaxEntries(t *testing.T) {
+	config := `
+stage.dedup {
+	max_entries = 1
+}
+`
+	pl, err := NewPipeline(util_log.Logger, loadConfig(config), nil, prometheus.NewRegistry())
+	require.NoError(t, err)
+
+	// The first line is evicted by the second, so its duplicate isn't dropped.
+	ts := time.Now()
+	out := processEntries(pl,
+		newEntry(nil, nil, "a", ts),
+		newEntry(nil, nil, "b", ts),
+		newEntry(nil, nil, "a", ts),
+		newEntry(nil, nil, "a", ts),
+	)
+	require.Len(t, out, 3)
+}
+
+func TestDedupConfig_Validate(t *testing.T) {
+	cfg := DefaultDedupConfig
+	cfg.Window = 0
+	require.ErrorIs(t, cfg.Validate(), ErrDedupInvalidWindow)
+
+	cfg = DefaultDedupConfig
+	cfg.MaxEntries = 0
+	require.ErrorIs(t, cfg.Validate(), ErrDedupInvalidMaxEntries)
+}
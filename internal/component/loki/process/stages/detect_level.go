@@ -0,0 +1,385 @@
+package stages
+
+import (
+	"encoding/json"
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/go-kit/log"
+	"github.com/go-logfmt/logfmt"
+	"github.com/grafana/loki/v3/pkg/logproto"
+	"github.com/prometheus/common/model"
+
+	"github.com/grafana/alloy/internal/runtime/logging/level"
+)
+
+// Configuration errors.
+var (
+	ErrDetectLevelInvalidTarget      = errors.New("detect_level stage `target` must be a valid label name")
+	ErrDetectLevelInvalidDestination = errors.New("detect_level stage `destination` must be \"structured_metadata\" or \"label\"")
+	ErrDetectLevelInvalidMapping     = errors.New("detect_level stage `mapping` values must not be empty")
+)
+
+// Destinations of the level detected by the detect_level stage.
+const (
+	DetectLevelDestinationStructuredMetadata = "structured_metadata"
+	DetectLevelDestinationLabel              = "label"
+)
+
+// Normalized log levels.
+const (
+	LogLevelTrace    = "trace"
+	LogLevelDebug    = "debug"
+	LogLevelInfo     = "info"
+	LogLevelWarn     = "warn"
+	LogLevelError    = "error"
+	LogLevelCritical = "critical"
+	LogLevelFatal    = "fatal"
+	LogLevelUnknown  = "unknown"
+)
+
+// Names of the structured metadata holding the severity of OTLP logs.
+const (
+	otlpSeverityText   = "severity_text"
+	otlpSeverityNumber = "severity_number"
+)
+
+// DetectLevelConfig configures a processing stage that infers a normalized
+// log level from the content of log entries.
+type DetectLevelConfig struct {
+	Target       string            `alloy:"target,attr,optional"`
+	Destination  string            `alloy:"destination,attr,optional"`
+	Keys         []string          `alloy:"keys,attr,optional"`
+	Mapping      map[string]string `alloy:"mapping,attr,optional"`
+	DefaultLevel string            `alloy:"default_level,attr,optional"`
+}
+
+// DefaultDetectLevelConfig sets the default values of the detect_level stage.
+var DefaultDetectLevelConfig = DetectLevelConfig{
+	Target:       "detected_level",
+	Destination:  DetectLevelDestinationStructuredMetadata,
+	Keys:         []string{"level", "lvl", "severity", "loglevel"},
+	DefaultLevel: LogLevelUnknown,
+}
+
+// SetToDefault implements syntax.Defaulter.
+func (c *DetectLevelConfig) SetToDefault() {
+	*c = DefaultDetectLevelConfig
+}
+
+// Validate implements syntax.Validator.
+func (c *DetectLevelConfig) Validate() error {
+	if !model.LabelName(c.Target).IsValid() {
+		return ErrDetectLevelInvalidTarget
+	}
+	if c.Destination != DetectLevelDestinationStructuredMetadata && c.Destination != DetectLevelDestinationLabel {
+		return ErrDetectLevelInvalidDestination
+	}
+	for _, v := range c.Mapping {
+		if v == "" {
+			return ErrDetectLevelInvalidMapping
+		}
+	}
+	return nil
+}
+
+// levelAliases maps the lowercase spellings of log levels used by common
+// logging libraries to their normalized level.
+var levelAliases = map[string]string{
+	"trace": LogLevelTrace, "trc": LogLevelTrace,
+	"debug": LogLevelDebug, "dbg": LogLevelDebug, "debu": LogLevelDebug,
+	"info": LogLevelInfo, "inf": LogLevelInfo, "information": LogLevelInfo, "informational": LogLevelInfo, "notice": LogLevelInfo,
+	"warn": LogLevelWarn, "warning": LogLevelWarn, "wrn": LogLevelWarn,
+	"error": LogLevelError, "err": LogLevelError, "erro": LogLevelError, "eror": LogLevelError,
+	"critical": LogLevelCritical, "crit": LogLevelCritical, "alert": LogLevelCritical, "emerg": LogLevelCritical, "emergency": LogLevelCritical,
+	"fatal": LogLevelFatal, "fata": LogLevelFatal, "ftl": LogLevelFatal, "panic": LogLevelFatal, "pnc": LogLevelFatal,
+}
+
+// levelKeywords are the words searched for in unstructured log lines. Short
+// spellings are left out, as they're too likely to appear in the text.
+var levelKeywords = map[string]bool{
+	"trace": true, "debug": true, "info": true, "warn": true, "warning": true,
+	"error": true, "err": true, "critical": true, "fatal": true, "panic": true,
+}
+
+// klogLevels maps the severity prefix of klog lines, such as
+// "E1019 12:00:00.000000 1 main.go:10] msg", to normalized levels.
+var (
+	klogPrefix = regexp.MustCompile(`^([IWEF])\d{4} \d{2}:\d{2}:\d{2}`)
+	klogLevels = map[string]string{
+		"I": LogLevelInfo,
+		"W": LogLevelWarn,
+		"E": LogLevelError,
+		"F": LogLevelFatal,
+	}
+)
+
+// maxBracketPrefixLength bounds how far into a line bracketed levels, such as
+// "[ERROR]" or "<warn>", are searched for.
+const maxBracketPrefixLength = 128
+
+// newDetectLevelStage creates a detectLevelStage from config.
+func newDetectLevelStage(logger log.Logger, config DetectLevelConfig) (Stage, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	mapping := make(map[string]string, len(config.Mapping))
+	for k, v := range config.Mapping {
+		mapping[strings.ToLower(strings.TrimSpace(k))] = v
+	}
+	return &detectLevelStage{
+		logger:  log.With(logger, "component", "stage", "type", "detect_level"),
+		cfg:     config,
+		mapping: mapping,
+	}, nil
+}
+
+// detectLevelStage infers a normalized log level from key-value pairs,
+// bracketed prefixes and keywords of log entries.
+type detectLevelStage struct {
+	logger  log.Logger
+	cfg     DetectLevelConfig
+	mapping map[string]string
+}
+
+// Run implements Stage.
+func (s *detectLevelStage) Run(in chan Entry) chan Entry {
+	return RunWith(in, func(e Entry) Entry {
+		s.process(&e)
+		return e
+	})
+}
+
+func (s *detectLevelStage) process(e *Entry) {
+	// A level which is already set is left untouched.
+	if _, ok := e.Labels[model.LabelName(s.cfg.Target)]; ok {
+		return
+	}
+	for _, m := range e.StructuredMetadata {
+		if m.Name == s.cfg.Target {
+			return
+		}
+	}
+
+	lvl := s.detect(e)
+	if lvl == "" {
+		lvl = s.cfg.DefaultLevel
+	}
+	if lvl == "" {
+		return
+	}
+	if Debug {
+		level.Debug(s.logger).Log("msg", "detected log level", "level", lvl)
+	}
+
+	switch s.cfg.Destination {
+	case DetectLevelDestinationLabel:
+		if !model.LabelValue(lvl).IsValid() {
+			return
+		}
+		if e.Labels == nil {
+			e.Labels = model.LabelSet{}
+		}
+		e.Labels[model.LabelName(s.cfg.Target)] = model.LabelValue(lvl)
+	default:
+		e.StructuredMetadata = append(e.StructuredMetadata, logproto.LabelAdapter{Name: s.cfg.Target, Value: lvl})
+	}
+}
+
+// detect returns the level of e, or an empty string if it couldn't be
+// detected. The severity of OTLP logs takes precedence over the configured
+// keys, which take precedence over the content of the line.
+func (s *detectLevelStage) detect(e *Entry) string {
+	for _, m := range e.StructuredMetadata {
+		if m.Name == otlpSeverityText {
+			if lvl, ok := s.normalize(m.Value); ok {
+				return lvl
+			}
+		}
+	}
+	for _, m := range e.StructuredMetadata {
+		if m.Name == otlpSeverityNumber {
+			if lvl, ok := otlpSeverityLevel(m.Value); ok {
+				return lvl
+			}
+		}
+	}
+
+	for _, key := range s.cfg.Keys {
+		for name, value := range e.Labels {
+			if strings.EqualFold(string(name), key) {
+				if lvl, ok := s.normalize(string(value)); ok {
+					return lvl
+				}
+			}
+		}
+		for _, m := range e.StructuredMetadata {
+			if strings.EqualFold(m.Name, key) {
+				if lvl, ok := s.normalize(m.Value); ok {
+					return lvl
+				}
+			}
+		}
+		for name, value := range e.Extracted {
+			if !strings.EqualFold(name, key) {
+				continue
+			}
+			if str, err := getString(value); err == nil {
+				if lvl, ok := s.normalize(str); ok {
+					return lvl
+				}
+			}
+		}
+	}
+
+	if lvl, ok := s.detectFromFields(e.Line); ok {
+		return lvl
+	}
+	if lvl, ok := s.detectFromPrefix(e.Line); ok {
+		return lvl
+	}
+	return s.detectFromKeywords(e.Line)
+}
+
+// detectFromFields looks up the configured keys in the top-level fields of a
+// JSON or logfmt line.
+func (s *detectLevelStage) detectFromFields(line string) (string, bool) {
+	trimmed := strings.TrimSpace(line)
+	if strings.HasPrefix(trimmed, "{") {
+		var fields map[string]interface{}
+		if err := json.Unmarshal([]byte(trimmed), &fields); err != nil {
+			return "", false
+		}
+		for _, key := range s.cfg.Keys {
+			for name, value := range fields {
+				if !strings.EqualFold(name, key) {
+					continue
+				}
+				str, err := getString(value)
+				if err != nil {
+					continue
+				}
+				if lvl, ok := s.normalize(str); ok {
+					return lvl, true
+				}
+			}
+		}
+		return "", false
+	}
+
+	// Decoding errors are ignored, as unstructured lines are also scanned for
+	// key-value pairs.
+	decoder := logfmt.NewDecoder(strings.NewReader(trimmed))
+	fields := make(map[string]string)
+	for decoder.ScanRecord() {
+		for decoder.ScanKeyval() {
+			if len(decoder.Value()) > 0 {
+				fields[strings.ToLower(string(decoder.Key()))] = string(decoder.Value())
+			}
+		}
+	}
+	for _, key := range s.cfg.Keys {
+		if value, ok := fields[strings.ToLower(key)]; ok {
+			if lvl, ok := s.normalize(value); ok {
+				return lvl, true
+			}
+		}
+	}
+	return "", false
+}
+
+// detectFromPrefix detects the level of lines starting with a klog prefix, or
+// holding a bracketed level such as "[ERROR]" or "<warn>" near their start.
+func (s *detectLevelStage) detectFromPrefix(line string) (string, bool) {
+	if m := klogPrefix.FindStringSubmatch(line); m != nil {
+		return klogLevels[m[1]], true
+	}
+
+	prefix := line
+	if len(prefix) > maxBracketPrefixLength {
+		prefix = prefix[:maxBracketPrefixLength]
+	}
+	for i := 0; i < len(prefix); i++ {
+		var closing byte
+		switch prefix[i] {
+		case '[':
+			closing = ']'
+		case '<':
+			closing = '>'
+		default:
+			continue
+		}
+		end := strings.IndexByte(prefix[i+1:], closing)
+		if end < 0 {
+			continue
+		}
+		if lvl, ok := s.normalize(prefix[i+1 : i+1+end]); ok {
+			return lvl, true
+		}
+	}
+	return "", false
+}
+
+// detectFromKeywords returns the level of the first level keyword of line.
+func (s *detectLevelStage) detectFromKeywords(line string) string {
+	for _, word := range strings.FieldsFunc(line, func(r rune) bool { return !unicode.IsLetter(r) }) {
+		if !levelKeywords[strings.ToLower(word)] {
+			continue
+		}
+		if lvl, ok := s.normalize(word); ok {
+			return lvl
+		}
+	}
+	return ""
+}
+
+// normalize maps value to a log level, using the configured mapping before
+// the built-in aliases.
+func (s *detectLevelStage) normalize(value string) (string, bool) {
+	key := strings.ToLower(strings.TrimSpace(value))
+	if key == "" {
+		return "", false
+	}
+	if lvl, ok := s.mapping[key]; ok {
+		return lvl, true
+	}
+	lvl, ok := levelAliases[key]
+	return lvl, ok
+}
+
+// otlpSeverityLevel maps an OTLP severity number to a log level.
+func otlpSeverityLevel(value string) (string, bool) {
+	n, err := strconv.Atoi(strings.TrimSpace(value))
+	if err != nil {
+		return "", false
+	}
+	switch {
+	case n >= 1 && n <= 4:
+		return LogLevelTrace, true
+	case n >= 5 && n <= 8:
+		return LogLevelDebug, true
+	case n >= 9 && n <= 12:
+		return LogLevelInfo, true
+	case n >= 13 && n <= 16:
+		return LogLevelWarn, true
+	case n >= 17 && n <= 20:
+		return LogLevelError, true
+	case n >= 21 && n <= 24:
+		return LogLevelFatal, true
+	default:
+		return "", false
+	}
+}
+
+// Name implements Stage.
+func (s *detectLevelStage) Name() string {
+	return StageTypeDetectLevel
+}
+
+// Cleanup implements Stage.
+func (*detectLevelStage) Cleanup() {
+	// no-op
+}
@@ -0,0 +1,112 @@
+package stages
+
+import (
+	"testing"
+	"time"
+
+	"github.com/grafana/loki/v3/pkg/logproto"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+
+	util_log "github.com/grafana/loki/v3/pkg/util/log"
+)
+
+func TestDetectLevelStage(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		line      string
+		labels    model.LabelSet
+		metadata  []logproto.LabelAdapter
+		extracted map[string]interface{}
+		expected  string
+	}{
+		"json":                    {line: `{"msg": "request served", "Level": "WARNING"}`, expected: "warn"},
+		"json unmapped number":    {line: `{"msg": "request served", "level": 30}`, expected: "unknown"},
+		"logfmt":                  {line: `ts=2024-10-19T12:00:00Z lvl=dbg msg="cache miss, error ignored"`, expected: "debug"},
+		"label":                   {line: "request served", labels: model.LabelSet{"severity": "Err"}, expected: "error"},
+		"extracted":               {line: "request served", extracted: map[string]interface{}{"level": "fatal"}, expected: "fatal"},
+		"otlp severity text":      {line: "level=info", metadata: []logproto.LabelAdapter{{Name: "severity_text", Value: "ERROR"}}, expected: "error"},
+		"otlp severity number":    {line: "level=info", metadata: []logproto.LabelAdapter{{Name: "severity_number", Value: "14"}}, expected: "warn"},
+		"bracketed prefix":        {line: "2024-10-19 12:00:00 [ERROR] could not connect, retrying with info", expected: "error"},
+		"angle bracketed prefix":  {line: "<crit> disk full", expected: "critical"},
+		"klog":                    {line: "W1019 12:00:00.000000       1 main.go:10] slow request", expected: "warn"},
+		"keyword":                 {line: "Connection refused: Error while dialing", expected: "error"},
+		"keyword as part of word": {line: "informative message about errors", expected: "unknown"},
+		"no level":                {line: "request served", expected: "unknown"},
+	}
+
+	for testName, testData := range tests {
+		testData := testData
+
+		t.Run(testName, func(t *testing.T) {
+			t.Parallel()
+
+			s, err := newDetectLevelStage(util_log.Logger, DefaultDetectLevelConfig)
+			require.NoError(t, err)
+
+			e := newEntry(testData.extracted, testData.labels, testData.line, time.Now())
+			e.StructuredMetadata = testData.metadata
+			out := processEntries(s, e)[0]
+
+			expected := append(append([]logproto.LabelAdapter{}, testData.metadata...), logproto.LabelAdapter{Name: "detected_level", Value: testData.expected})
+			require.Equal(t, expected, []logproto.LabelAdapter(out.StructuredMetadata))
+			require.Equal(t, testData.line, out.Line)
+		})
+	}
+}
+
+func TestPipeline_DetectLevel(t *testing.T) {
+	config := `
+stage.detect_level {
+	target        = "level"
+	destination   = "label"
+	keys          = ["level", "priority"]
+	mapping       = { "30" = "info", "50" = "error", "warning" = "warning" }
+	default_level = ""
+}
+`
+	pl, err := NewPipeline(util_log.Logger, loadConfig(config), nil, prometheus.NewRegistry())
+	require.NoError(t, err)
+
+	ts := time.Now()
+	out := processEntries(pl,
+		newEntry(nil, model.LabelSet{"app": "foo"}, `{"msg": "done", "priority": 50}`, ts),
+		newEntry(nil, model.LabelSet{"app": "foo"}, `{"msg": "done", "level": "30"}`, ts),
+		newEntry(nil, model.LabelSet{"app": "foo"}, `Warning: disk almost full`, ts),
+		newEntry(nil, model.LabelSet{"app": "foo", "level": "debug"}, `[error] already labelled`, ts),
+		newEntry(nil, model.LabelSet{"app": "foo"}, `request served`, ts),
+	)
+	require.Len(t, out, 5)
+	require.Equal(t, model.LabelSet{"app": "foo", "level": "error"}, out[0].Labels)
+	require.Equal(t, model.LabelSet{"app": "foo", "level": "info"}, out[1].Labels)
+	require.Equal(t, model.LabelSet{"app": "foo", "level": "warning"}, out[2].Labels)
+	require.Equal(t, model.LabelSet{"app": "foo", "level": "debug"}, out[3].Labels)
+	require.Equal(t, model.LabelSet{"app": "foo"}, out[4].Labels)
+}
+
+func TestDetectLevelConfig_Validate(t *testing.T) {
+	tests := map[string]struct {
+		config DetectLevelConfig
+		err    error
+	}{
+		"invalid target": {
+			config: DetectLevelConfig{Target: "detected-level", Destination: DetectLevelDestinationLabel},
+			err:    ErrDetectLevelInvalidTarget,
+		},
+		"invalid destination": {
+			config: DetectLevelConfig{Target: "level", Destination: "extracted"},
+			err:    ErrDetectLevelInvalidDestination,
+		},
+		"empty mapping value": {
+			config: DetectLevelConfig{Target: "level", Destination: DetectLevelDestinationLabel, Mapping: map[string]string{"E": ""}},
+			err:    ErrDetectLevelInvalidMapping,
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			require.ErrorIs(t, tc.config.Validate(), tc.err)
+		})
+	}
+}
@@ -0,0 +1,283 @@
+package stages
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/loki/v3/pkg/logproto"
+	"github.com/prometheus/common/model"
+
+	"github.com/grafana/alloy/internal/runtime/logging/level"
+)
+
+// Configuration errors.
+var (
+	ErrLookupEmptySource   = errors.New("lookup stage `source` must not be empty")
+	ErrLookupInvalidFormat = errors.New("lookup stage `format` must be \"csv\" or \"json\"")
+	ErrLookupInvalidTable  = errors.New("could not parse lookup table")
+)
+
+// Formats of the table of the lookup stage.
+const (
+	LookupFormatCSV  = "csv"
+	LookupFormatJSON = "json"
+)
+
+// LookupConfig configures a processing stage that enriches log entries with
+// the columns of the row of a table matching an extracted value.
+type LookupConfig struct {
+	Source             string   `alloy:"source,attr"`
+	Table              string   `alloy:"table,attr"`
+	Format             string   `alloy:"format,attr,optional"`
+	KeyColumn          string   `alloy:"key_column,attr,optional"`
+	Columns            []string `alloy:"columns,attr,optional"`
+	Labels             []string `alloy:"labels,attr,optional"`
+	StructuredMetadata []string `alloy:"structured_metadata,attr,optional"`
+}
+
+// DefaultLookupConfig sets the default values of the lookup stage.
+var DefaultLookupConfig = LookupConfig{
+	Format: LookupFormatCSV,
+}
+
+// SetToDefault implements syntax.Defaulter.
+func (c *LookupConfig) SetToDefault() {
+	*c = DefaultLookupConfig
+}
+
+// Validate implements syntax.Validator.
+func (c *LookupConfig) Validate() error {
+	if c.Source == "" {
+		return ErrLookupEmptySource
+	}
+	if c.Format != LookupFormatCSV && c.Format != LookupFormatJSON {
+		return ErrLookupInvalidFormat
+	}
+	for _, name := range append(append([]string{}, c.Labels...), c.StructuredMetadata...) {
+		if !model.LabelName(name).IsValid() {
+			return fmt.Errorf("lookup stage column %q is not a valid label name", name)
+		}
+	}
+	return nil
+}
+
+// lookupTable is a table of the lookup stage, indexed by the value of its key
+// column.
+type lookupTable struct {
+	columns []string
+	rows    map[string]map[string]string
+}
+
+// newLookupStage creates a lookupStage from config. The table is parsed once,
+// so that a new table replaces the previous one when the stage is created
+// again on Update.
+func newLookupStage(logger log.Logger, config LookupConfig) (Stage, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	key := config.KeyColumn
+	if key == "" {
+		key = config.Source
+	}
+
+	var (
+		table *lookupTable
+		err   error
+	)
+	switch config.Format {
+	case LookupFormatCSV:
+		table, err = parseCSVLookupTable(config.Table, key)
+	case LookupFormatJSON:
+		table, err = parseJSONLookupTable(config.Table, key)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%v: %w", ErrLookupInvalidTable, err)
+	}
+
+	columns := config.Columns
+	if len(columns) == 0 {
+		for _, c := range table.columns {
+			if c != key {
+				columns = append(columns, c)
+			}
+		}
+	}
+	known := make(map[string]bool, len(table.columns))
+	for _, c := range table.columns {
+		known[c] = true
+	}
+	for _, c := range append(append(append([]string{}, columns...), config.Labels...), config.StructuredMetadata...) {
+		if !known[c] {
+			return nil, fmt.Errorf("lookup stage column %q is not a column of the table", c)
+		}
+	}
+
+	return &lookupStage{
+		logger:  log.With(logger, "component", "stage", "type", "lookup"),
+		cfg:     config,
+		table:   table,
+		columns: columns,
+	}, nil
+}
+
+// parseCSVLookupTable parses a CSV table whose first record is the header.
+func parseCSVLookupTable(content, key string) (*lookupTable, error) {
+	r := csv.NewReader(strings.NewReader(content))
+	r.TrimLeadingSpace = true
+	header, err := r.Read()
+	if err == io.EOF {
+		return nil, errors.New("the table is empty")
+	} else if err != nil {
+		return nil, err
+	}
+
+	var records []map[string]string
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+		row := make(map[string]string, len(header))
+		for i, c := range header {
+			row[c] = record[i]
+		}
+		records = append(records, row)
+	}
+	return newLookupTable(header, records, key)
+}
+
+// parseJSONLookupTable parses a JSON array of objects, whose keys are the
+// columns of the table. Values which aren't strings are converted to their
+// JSON representation.
+func parseJSONLookupTable(content, key string) (*lookupTable, error) {
+	var objects []map[string]interface{}
+	if err := json.Unmarshal([]byte(content), &objects); err != nil {
+		return nil, err
+	}
+
+	var (
+		columns []string
+		seen    = make(map[string]bool)
+		records = make([]map[string]string, 0, len(objects))
+	)
+	for _, obj := range objects {
+		row := make(map[string]string, len(obj))
+		for c, v := range obj {
+			if !seen[c] {
+				seen[c] = true
+				columns = append(columns, c)
+			}
+			s, err := getString(v)
+			if err != nil {
+				b, err := json.Marshal(v)
+				if err != nil {
+					return nil, err
+				}
+				s = string(b)
+			}
+			row[c] = s
+		}
+		records = append(records, row)
+	}
+	sort.Strings(columns)
+	return newLookupTable(columns, records, key)
+}
+
+func newLookupTable(columns []string, records []map[string]string, key string) (*lookupTable, error) {
+	table := &lookupTable{
+		columns: columns,
+		rows:    make(map[string]map[string]string, len(records)),
+	}
+	for _, row := range records {
+		k, ok := row[key]
+		if !ok {
+			return nil, fmt.Errorf("key column %q is missing", key)
+		}
+		if _, ok := table.rows[k]; ok {
+			return nil, fmt.Errorf("duplicate key %q", k)
+		}
+		table.rows[k] = row
+	}
+	return table, nil
+}
+
+// lookupStage enriches log entries with the row of a table matching an
+// extracted value.
+type lookupStage struct {
+	logger  log.Logger
+	cfg     LookupConfig
+	table   *lookupTable
+	columns []string
+}
+
+// Run implements Stage.
+func (s *lookupStage) Run(in chan Entry) chan Entry {
+	return RunWith(in, func(e Entry) Entry {
+		s.process(&e)
+		return e
+	})
+}
+
+func (s *lookupStage) process(e *Entry) {
+	v, ok := e.Extracted[s.cfg.Source]
+	if !ok {
+		if Debug {
+			level.Debug(s.logger).Log("msg", "source does not exist in the set of extracted values", "source", s.cfg.Source)
+		}
+		return
+	}
+	key, err := getString(v)
+	if err != nil {
+		if Debug {
+			level.Debug(s.logger).Log("msg", "failed to convert source value to string", "source", s.cfg.Source, "err", err, "type", reflect.TypeOf(v))
+		}
+		return
+	}
+	row, ok := s.table.rows[key]
+	if !ok {
+		if Debug {
+			level.Debug(s.logger).Log("msg", "no row of the table matches the source value", "source", s.cfg.Source, "value", key)
+		}
+		return
+	}
+
+	for _, c := range s.columns {
+		if value, ok := row[c]; ok {
+			e.Extracted[c] = value
+		}
+	}
+	for _, c := range s.cfg.Labels {
+		value, ok := row[c]
+		if !ok || value == "" || !model.LabelValue(value).IsValid() {
+			continue
+		}
+		if e.Labels == nil {
+			e.Labels = model.LabelSet{}
+		}
+		e.Labels[model.LabelName(c)] = model.LabelValue(value)
+	}
+	for _, c := range s.cfg.StructuredMetadata {
+		if value, ok := row[c]; ok {
+			e.StructuredMetadata = append(e.StructuredMetadata, logproto.LabelAdapter{Name: c, Value: value})
+		}
+	}
+}
+
+// Name implements Stage.
+func (s *lookupStage) Name() string {
+	return StageTypeLookup
+}
+
+// Cleanup implements Stage.
+func (*lookupStage) Cleanup() {
+	// no-op
+}
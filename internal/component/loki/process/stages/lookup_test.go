@@ -0,0 +1,158 @@
+package stages
+
+import (
+	"testing"
+	"time"
+
+	"github.com/grafana/loki/v3/pkg/logproto"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+
+	util_log "github.com/grafana/loki/v3/pkg/util/log"
+)
+
+const (
+	testLookupCSV = `service, team, slack
+api, core, #core-oncall
+billing, payments, #payments
+`
+	testLookupJSON = `[
+  {"host": "db-1", "rack": "r1", "datacenter": "eu-west", "tier": 1},
+  {"host": "db-2", "rack": "r2", "datacenter": "eu-west", "tier": 2}
+]`
+)
+
+func TestPipeline_Lookup(t *testing.T) {
+	t.Parallel()
+
+	tests := map[string]struct {
+		config            string
+		entry             string
+		expectedExtracted map[string]interface{}
+		expectedLabels    model.LabelSet
+		expectedMetadata  []logproto.LabelAdapter
+	}{
+		"csv": {
+			config: `
+stage.json {
+	expressions = { service = "" }
+}
+stage.lookup {
+	source              = "service"
+	table               = ` + "`" + testLookupCSV + "`" + `
+	labels              = ["team"]
+	structured_metadata = ["slack"]
+}`,
+			entry:             `{"service": "api"}`,
+			expectedExtracted: map[string]interface{}{"service": "api", "team": "core", "slack": "#core-oncall"},
+			expectedLabels:    model.LabelSet{"team": "core"},
+			expectedMetadata:  []logproto.LabelAdapter{{Name: "slack", Value: "#core-oncall"}},
+		},
+		"json with key column and columns": {
+			config: `
+stage.json {
+	expressions = { hostname = "" }
+}
+stage.lookup {
+	source     = "hostname"
+	format     = "json"
+	key_column = "host"
+	columns    = ["datacenter", "tier"]
+	table      = ` + "`" + testLookupJSON + "`" + `
+}`,
+			entry:             `{"hostname": "db-2"}`,
+			expectedExtracted: map[string]interface{}{"hostname": "db-2", "datacenter": "eu-west", "tier": "2"},
+			expectedLabels:    model.LabelSet{},
+		},
+		"no match": {
+			config: `
+stage.json {
+	expressions = { service = "" }
+}
+stage.lookup {
+	source = "service"
+	table  = ` + "`" + testLookupCSV + "`" + `
+	labels = ["team"]
+}`,
+			entry:             `{"service": "unknown"}`,
+			expectedExtracted: map[string]interface{}{"service": "unknown"},
+			expectedLabels:    model.LabelSet{},
+		},
+		"missing source": {
+			config: `
+stage.lookup {
+	source = "service"
+	table  = ` + "`" + testLookupCSV + "`" + `
+}`,
+			entry:             `{"service": "api"}`,
+			expectedExtracted: map[string]interface{}{},
+			expectedLabels:    model.LabelSet{},
+		},
+	}
+
+	for testName, testData := range tests {
+		testData := testData
+
+		t.Run(testName, func(t *testing.T) {
+			t.Parallel()
+
+			pl, err := NewPipeline(util_log.Logger, loadConfig(testData.config), nil, prometheus.NewRegistry())
+			require.NoError(t, err)
+			out := processEntries(pl, newEntry(nil, nil, testData.entry, time.Now()))[0]
+			require.Equal(t, testData.expectedExtracted, out.Extracted)
+			require.Equal(t, testData.expectedLabels, out.Labels)
+			require.Equal(t, testData.expectedMetadata, []logproto.LabelAdapter(out.StructuredMetadata))
+		})
+	}
+}
+
+func TestLookupConfig_Errors(t *testing.T) {
+	tests := map[string]struct {
+		config LookupConfig
+		err    string
+	}{
+		"empty source": {
+			config: LookupConfig{Format: LookupFormatCSV, Table: testLookupCSV},
+			err:    ErrLookupEmptySource.Error(),
+		},
+		"invalid format": {
+			config: LookupConfig{Source: "service", Format: "yaml", Table: testLookupCSV},
+			err:    ErrLookupInvalidFormat.Error(),
+		},
+		"invalid label name": {
+			config: LookupConfig{Source: "service", Format: LookupFormatCSV, Table: testLookupCSV, Labels: []string{"team-name"}},
+			err:    `lookup stage column "team-name" is not a valid label name`,
+		},
+		"empty table": {
+			config: LookupConfig{Source: "service", Format: LookupFormatCSV},
+			err:    "could not parse lookup table: the table is empty",
+		},
+		"wrong number of fields": {
+			config: LookupConfig{Source: "service", Format: LookupFormatCSV, Table: "service,team\napi\n"},
+			err:    "wrong number of fields",
+		},
+		"missing key column": {
+			config: LookupConfig{Source: "name", Format: LookupFormatCSV, Table: testLookupCSV},
+			err:    `key column "name" is missing`,
+		},
+		"duplicate key": {
+			config: LookupConfig{Source: "service", Format: LookupFormatCSV, Table: "service,team\napi,a\napi,b\n"},
+			err:    `duplicate key "api"`,
+		},
+		"unknown column": {
+			config: LookupConfig{Source: "service", Format: LookupFormatCSV, Table: testLookupCSV, Columns: []string{"owner"}},
+			err:    `lookup stage column "owner" is not a column of the table`,
+		},
+		"invalid json": {
+			config: LookupConfig{Source: "host", Format: LookupFormatJSON, Table: `{"host": "db-1"}`},
+			err:    ErrLookupInvalidTable.Error(),
+		},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := newLookupStage(util_log.Logger, tc.config)
+			require.ErrorContains(t, err, tc.err)
+		})
+	}
+}
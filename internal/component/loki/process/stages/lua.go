@@ -0,0 +1,546 @@
+package stages
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/loki/v3/pkg/logproto"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+	lua "github.com/yuin/gopher-lua"
+	"github.com/yuin/gopher-lua/parse"
+
+	"github.com/grafana/alloy/internal/runtime/logging/level"
+)
+
+// Configuration errors.
+var (
+	ErrLuaEmptyScript       = errors.New("lua stage `script` must not be empty")
+	ErrLuaInvalidTimeout    = errors.New("lua stage `timeout` must be greater than 0")
+	ErrLuaInvalidOnError    = errors.New("lua stage `on_error` must be one of \"pass\", \"drop\" or \"label\"")
+	ErrLuaCouldNotCompile   = errors.New("could not compile lua script")
+	ErrLuaProcessUndefined  = errors.New("lua script must define a `process` function")
+	ErrLuaCouldNotLoadState = errors.New("could not run lua script")
+)
+
+// Error policies of the lua stage.
+const (
+	LuaOnErrorPass  = "pass"
+	LuaOnErrorDrop  = "drop"
+	LuaOnErrorLabel = "label"
+)
+
+// Values of the error label set by the lua stage.
+const (
+	luaErrorValue        = "LuaErr"
+	luaTimeoutErrorValue = "LuaTimeoutErr"
+)
+
+// luaUnsafeGlobals are the functions of the base library which give access to
+// the file system or to the process, and are removed from the scripts.
+var luaUnsafeGlobals = []string{"collectgarbage", "dofile", "load", "loadfile", "loadstring", "module", "print", "require"}
+
+// Limits of the Lua state, which bound the memory used by deep recursion and
+// by the library functions which can build large strings in a single call.
+// The memory used by other operations, such as concatenating strings with
+// .. in a loop, is only bounded by the timeout of the stage.
+const (
+	// luaCallStackSize is the maximum depth of nested function calls.
+	luaCallStackSize = 200
+	// luaRegistrySize and luaRegistryMaxSize are the initial and maximum
+	// number of values on the stack of the state, which grows by
+	// luaRegistryGrowStep values at a time.
+	luaRegistrySize     = 1024
+	luaRegistryMaxSize  = 64 * 1024
+	luaRegistryGrowStep = 1024
+	// luaMaxStringLength is the maximum length of the strings returned by
+	// string.rep and table.concat.
+	luaMaxStringLength = 1 << 20
+)
+
+// LuaConfig configures a processing stage that runs a Lua script on each log
+// entry.
+type LuaConfig struct {
+	Script     string        `alloy:"script,attr"`
+	Timeout    time.Duration `alloy:"timeout,attr,optional"`
+	OnError    string        `alloy:"on_error,attr,optional"`
+	ErrorLabel string        `alloy:"error_label,attr,optional"`
+	DropReason string        `alloy:"drop_counter_reason,attr,optional"`
+}
+
+// DefaultLuaConfig sets the default values of the lua stage.
+var DefaultLuaConfig = LuaConfig{
+	Timeout:    100 * time.Millisecond,
+	OnError:    LuaOnErrorPass,
+	ErrorLabel: "lua_error",
+	DropReason: "lua_stage",
+}
+
+// SetToDefault implements syntax.Defaulter.
+func (c *LuaConfig) SetToDefault() {
+	*c = DefaultLuaConfig
+}
+
+// Validate implements syntax.Validator.
+func (c *LuaConfig) Validate() error {
+	if strings.TrimSpace(c.Script) == "" {
+		return ErrLuaEmptyScript
+	}
+	if c.Timeout <= 0 {
+		return ErrLuaInvalidTimeout
+	}
+	switch c.OnError {
+	case LuaOnErrorPass, LuaOnErrorDrop:
+	case LuaOnErrorLabel:
+		if !model.LabelName(c.ErrorLabel).IsValid() {
+			return fmt.Errorf("lua stage `error_label` %q is not a valid label name", c.ErrorLabel)
+		}
+	default:
+		return ErrLuaInvalidOnError
+	}
+	return nil
+}
+
+// newLuaStage creates a luaStage from config. The script is compiled and run
+// once, and its process function is then called for each entry.
+func newLuaStage(logger log.Logger, config LuaConfig, registerer prometheus.Registerer) (Stage, error) {
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	if config.DropReason == "" {
+		config.DropReason = DefaultLuaConfig.DropReason
+	}
+
+	chunk, err := parse.Parse(strings.NewReader(config.Script), StageTypeLua)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %w", ErrLuaCouldNotCompile, err)
+	}
+	proto, err := lua.Compile(chunk, StageTypeLua)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %w", ErrLuaCouldNotCompile, err)
+	}
+
+	L := newLuaState()
+	ctx, cancel := context.WithTimeout(context.Background(), config.Timeout)
+	defer cancel()
+	L.SetContext(ctx)
+	L.Push(L.NewFunctionFromProto(proto))
+	err = L.PCall(0, 0, nil)
+	L.RemoveContext()
+	if err != nil {
+		L.Close()
+		return nil, fmt.Errorf("%v: %w", ErrLuaCouldNotLoadState, err)
+	}
+	fn, ok := L.GetGlobal("process").(*lua.LFunction)
+	if !ok {
+		L.Close()
+		return nil, ErrLuaProcessUndefined
+	}
+
+	return &luaStage{
+		logger:    log.With(logger, "component", "stage", "type", "lua"),
+		cfg:       config,
+		state:     L,
+		process:   fn,
+		errors:    getLuaErrorsMetric(registerer),
+		dropCount: getDropCountMetric(registerer),
+	}, nil
+}
+
+// newLuaState returns a Lua state with the base, table, string and math
+// libraries, without access to the file system or to the process.
+//
+// The state is shared by all the entries, so global variables set by the
+// script persist between calls of its process function.
+func newLuaState() *lua.LState {
+	L := lua.NewState(lua.Options{
+		SkipOpenLibs:     true,
+		CallStackSize:    luaCallStackSize,
+		RegistrySize:     luaRegistrySize,
+		RegistryMaxSize:  luaRegistryMaxSize,
+		RegistryGrowStep: luaRegistryGrowStep,
+	})
+	for _, lib := range []struct {
+		name string
+		fn   lua.LGFunction
+	}{
+		{lua.BaseLibName, lua.OpenBase},
+		{lua.TabLibName, lua.OpenTable},
+		{lua.StringLibName, lua.OpenString},
+		{lua.MathLibName, lua.OpenMath},
+	} {
+		L.Push(L.NewFunction(lib.fn))
+		L.Push(lua.LString(lib.name))
+		L.Call(1, 0)
+	}
+	for _, name := range luaUnsafeGlobals {
+		L.SetGlobal(name, lua.LNil)
+	}
+	// string.rep and table.concat can build strings of any length in a single
+	// call.
+	if strTbl, ok := L.GetGlobal(lua.StringLibName).(*lua.LTable); ok {
+		strTbl.RawSetString("rep", L.NewFunction(luaStringRep))
+	}
+	if tblTbl, ok := L.GetGlobal(lua.TabLibName).(*lua.LTable); ok {
+		tblTbl.RawSetString("concat", L.NewFunction(luaTableConcat))
+	}
+	return L
+}
+
+// luaStringRep implements string.rep(s, n [, sep]), failing if the result is
+// longer than luaMaxStringLength.
+func luaStringRep(L *lua.LState) int {
+	str := L.CheckString(1)
+	n := L.CheckInt(2)
+	sep := L.OptString(3, "")
+	if n <= 0 {
+		L.Push(lua.LString(""))
+		return 1
+	}
+	if size := int64(len(str)+len(sep))*int64(n) - int64(len(sep)); size > luaMaxStringLength {
+		L.RaiseError("string.rep result is too large (%d bytes, limit is %d)", size, luaMaxStringLength)
+		return 0
+	}
+	parts := make([]string, n)
+	for i := range parts {
+		parts[i] = str
+	}
+	L.Push(lua.LString(strings.Join(parts, sep)))
+	return 1
+}
+
+// luaTableConcat implements table.concat(list [, sep [, i [, j]]]), failing
+// if the result is longer than luaMaxStringLength.
+func luaTableConcat(L *lua.LState) int {
+	tbl := L.CheckTable(1)
+	sep := L.OptString(2, "")
+	n := tbl.Len()
+	i := L.OptInt(3, 1)
+	j := L.OptInt(4, n)
+	if L.GetTop() == 3 && (i > n || i < 1) {
+		L.Push(lua.LString(""))
+		return 1
+	}
+	i, j = max(min(i, n), 1), min(j, n)
+
+	var b strings.Builder
+	for k := i; k <= j; k++ {
+		v := tbl.RawGetInt(k)
+		if !lua.LVCanConvToString(v) {
+			L.RaiseError("invalid value (%s) at index %d in table for concat", v.Type().String(), k)
+			return 0
+		}
+		str := lua.LVAsString(v)
+		if k > i {
+			str = sep + str
+		}
+		if size := b.Len() + len(str); size > luaMaxStringLength {
+			L.RaiseError("table.concat result is too large (more than %d bytes, limit is %d)", size, luaMaxStringLength)
+			return 0
+		}
+		b.WriteString(str)
+	}
+	L.Push(lua.LString(b.String()))
+	return 1
+}
+
+// luaStage runs a Lua script on each log entry.
+type luaStage struct {
+	logger    log.Logger
+	cfg       LuaConfig
+	errors    *prometheus.CounterVec
+	dropCount *prometheus.CounterVec
+
+	mut     sync.Mutex
+	state   *lua.LState
+	process *lua.LFunction
+}
+
+// Run implements Stage.
+func (s *luaStage) Run(in chan Entry) chan Entry {
+	out := make(chan Entry)
+	go func() {
+		defer close(out)
+		for e := range in {
+			keep, err := s.run(&e)
+			if err != nil {
+				keep = s.handleError(&e, err)
+			}
+			if !keep {
+				s.dropCount.WithLabelValues(s.cfg.DropReason).Inc()
+				continue
+			}
+			out <- e
+		}
+	}()
+	return out
+}
+
+// run calls the process function of the script with e, and updates e with
+// the changes made by the script. It returns false if the script returned
+// false to drop the entry. The entry is left unchanged on errors.
+func (s *luaStage) run(e *Entry) (bool, error) {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+
+	ctx, cancel := context.WithTimeout(context.Background(), s.cfg.Timeout)
+	defer cancel()
+	s.state.SetContext(ctx)
+	defer s.state.RemoveContext()
+
+	tbl := s.entryTable(e)
+	if err := s.state.CallByParam(lua.P{Fn: s.process, NRet: 1, Protect: true}, tbl); err != nil {
+		if ctx.Err() != nil {
+			return false, ctx.Err()
+		}
+		return false, err
+	}
+	ret := s.state.Get(-1)
+	s.state.Pop(1)
+	if ret == lua.LFalse {
+		return false, nil
+	}
+	return true, s.updateEntry(e, tbl)
+}
+
+// handleError applies the error policy to e, and returns whether it's kept.
+func (s *luaStage) handleError(e *Entry, err error) bool {
+	value, reason := luaErrorValue, "error"
+	if errors.Is(err, context.DeadlineExceeded) {
+		value, reason = luaTimeoutErrorValue, "timeout"
+	}
+	s.errors.WithLabelValues(reason).Inc()
+	if Debug {
+		level.Debug(s.logger).Log("msg", "failed to run lua script", "err", err)
+	}
+
+	switch s.cfg.OnError {
+	case LuaOnErrorDrop:
+		return false
+	case LuaOnErrorLabel:
+		if e.Labels == nil {
+			e.Labels = model.LabelSet{}
+		}
+		e.Labels[model.LabelName(s.cfg.ErrorLabel)] = model.LabelValue(value)
+	}
+	return true
+}
+
+// entryTable returns the table passed to the process function for e.
+func (s *luaStage) entryTable(e *Entry) *lua.LTable {
+	L := s.state
+
+	labels := L.CreateTable(0, len(e.Labels))
+	for name, value := range e.Labels {
+		labels.RawSetString(string(name), lua.LString(value))
+	}
+	metadata := L.CreateTable(0, len(e.StructuredMetadata))
+	for _, l := range e.StructuredMetadata {
+		metadata.RawSetString(l.Name, lua.LString(l.Value))
+	}
+	extracted := L.CreateTable(0, len(e.Extracted))
+	for k, v := range e.Extracted {
+		extracted.RawSetString(k, toLuaValue(L, v))
+	}
+
+	tbl := L.CreateTable(0, 6)
+	tbl.RawSetString("line", lua.LString(e.Line))
+	tbl.RawSetString("timestamp", lua.LNumber(e.Timestamp.Unix()))
+	tbl.RawSetString("timestamp_nanos", lua.LNumber(e.Timestamp.Nanosecond()))
+	tbl.RawSetString("labels", labels)
+	tbl.RawSetString("structured_metadata", metadata)
+	tbl.RawSetString("extracted", extracted)
+	return tbl
+}
+
+// updateEntry updates e with the content of tbl after the process function
+// returned.
+func (s *luaStage) updateEntry(e *Entry, tbl *lua.LTable) error {
+	line, ok := tbl.RawGetString("line").(lua.LString)
+	if !ok {
+		return errors.New("entry.line must be a string")
+	}
+	sec, err := luaInteger(tbl, "timestamp")
+	if err != nil {
+		return err
+	}
+	nanos, err := luaInteger(tbl, "timestamp_nanos")
+	if err != nil {
+		return err
+	}
+	if nanos < 0 || nanos >= int64(time.Second) {
+		return fmt.Errorf("entry.timestamp_nanos must be between 0 and %d", int64(time.Second)-1)
+	}
+	labels, err := luaStringTable(tbl, "labels")
+	if err != nil {
+		return err
+	}
+	metadata, err := luaStringTable(tbl, "structured_metadata")
+	if err != nil {
+		return err
+	}
+	extractedTbl, ok := tbl.RawGetString("extracted").(*lua.LTable)
+	if !ok {
+		return errors.New("entry.extracted must be a table")
+	}
+
+	newLabels := make(model.LabelSet, len(labels))
+	for name, value := range labels {
+		l, v := model.LabelName(name), model.LabelValue(value)
+		if !l.IsValid() || !v.IsValid() {
+			return fmt.Errorf("invalid label %s=%q", name, value)
+		}
+		newLabels[l] = v
+	}
+	newMetadata := make([]logproto.LabelAdapter, 0, len(metadata))
+	for name, value := range metadata {
+		if !model.LabelName(name).IsValid() {
+			return fmt.Errorf("invalid structured metadata name %q", name)
+		}
+		newMetadata = append(newMetadata, logproto.LabelAdapter{Name: name, Value: value})
+	}
+	sort.Slice(newMetadata, func(i, j int) bool { return newMetadata[i].Name < newMetadata[j].Name })
+
+	newExtracted := make(map[string]interface{})
+	extractedTbl.ForEach(func(k, v lua.LValue) {
+		newExtracted[k.String()] = fromLuaValue(v)
+	})
+
+	e.Line = string(line)
+	if sec != e.Timestamp.Unix() || nanos != int64(e.Timestamp.Nanosecond()) {
+		e.Timestamp = time.Unix(sec, nanos)
+	}
+	e.Labels = newLabels
+	if len(newMetadata) > 0 || len(e.StructuredMetadata) > 0 {
+		e.StructuredMetadata = newMetadata
+	}
+	e.Extracted = newExtracted
+	return nil
+}
+
+// luaInteger returns the field key of tbl as an integer.
+func luaInteger(tbl *lua.LTable, key string) (int64, error) {
+	n, ok := tbl.RawGetString(key).(lua.LNumber)
+	if !ok || math.Trunc(float64(n)) != float64(n) || math.Abs(float64(n)) > 1<<53 {
+		return 0, fmt.Errorf("entry.%s must be an integer", key)
+	}
+	return int64(n), nil
+}
+
+// luaStringTable returns the field key of tbl as a map of strings.
+func luaStringTable(tbl *lua.LTable, key string) (map[string]string, error) {
+	t, ok := tbl.RawGetString(key).(*lua.LTable)
+	if !ok {
+		return nil, fmt.Errorf("entry.%s must be a table", key)
+	}
+	res := make(map[string]string)
+	var err error
+	t.ForEach(func(k, v lua.LValue) {
+		switch v.(type) {
+		case lua.LString, lua.LNumber, lua.LBool:
+			res[k.String()] = v.String()
+		default:
+			err = fmt.Errorf("entry.%s.%s must be a string", key, k.String())
+		}
+	})
+	return res, err
+}
+
+// toLuaValue converts a value of the extracted map to a Lua value.
+func toLuaValue(L *lua.LState, v interface{}) lua.LValue {
+	switch v := v.(type) {
+	case nil:
+		return lua.LNil
+	case bool:
+		return lua.LBool(v)
+	case string:
+		return lua.LString(v)
+	case float64:
+		return lua.LNumber(v)
+	case float32:
+		return lua.LNumber(v)
+	case int:
+		return lua.LNumber(v)
+	case int64:
+		return lua.LNumber(v)
+	case int32:
+		return lua.LNumber(v)
+	case uint:
+		return lua.LNumber(v)
+	case uint64:
+		return lua.LNumber(v)
+	case uint32:
+		return lua.LNumber(v)
+	case map[string]interface{}:
+		t := L.CreateTable(0, len(v))
+		for k, item := range v {
+			t.RawSetString(k, toLuaValue(L, item))
+		}
+		return t
+	case []interface{}:
+		t := L.CreateTable(len(v), 0)
+		for _, item := range v {
+			t.Append(toLuaValue(L, item))
+		}
+		return t
+	default:
+		s, err := getString(v)
+		if err != nil {
+			return lua.LNil
+		}
+		return lua.LString(s)
+	}
+}
+
+// fromLuaValue converts a Lua value to a value of the extracted map. Tables
+// with a sequence are converted to slices, and other tables to maps.
+func fromLuaValue(v lua.LValue) interface{} {
+	switch v := v.(type) {
+	case lua.LBool:
+		return bool(v)
+	case lua.LString:
+		return string(v)
+	case lua.LNumber:
+		return float64(v)
+	case *lua.LTable:
+		if n := v.MaxN(); n > 0 {
+			res := make([]interface{}, 0, n)
+			for i := 1; i <= n; i++ {
+				res = append(res, fromLuaValue(v.RawGetInt(i)))
+			}
+			return res
+		}
+		res := make(map[string]interface{})
+		v.ForEach(func(k, item lua.LValue) {
+			res[k.String()] = fromLuaValue(item)
+		})
+		return res
+	default:
+		return nil
+	}
+}
+
+// Name implements Stage.
+func (s *luaStage) Name() string {
+	return StageTypeLua
+}
+
+// Cleanup implements Stage.
+func (s *luaStage) Cleanup() {
+	s.mut.Lock()
+	defer s.mut.Unlock()
+	s.state.Close()
+}
+
+func getLuaErrorsMetric(registerer prometheus.Registerer) *prometheus.CounterVec {
+	return registerCounterVec(registerer, "loki_process", "lua_errors_total",
+		"A count of all errors of lua stages, by reason",
+		[]string{"reason"})
+}
@@ -0,0 +1,243 @@
+package stages
+
+import (
+	"testing"
+	"time"
+
+	"github.com/grafana/loki/v3/pkg/logproto"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/require"
+
+	util_log "github.com/grafana/loki/v3/pkg/util/log"
+)
+
+func TestPipeline_Lua(t *testing.T) {
+	config := `
+stage.json {
+	expressions = { level = "", user = "" }
+}
+stage.lua {
+	script = ` + "`" + `
+function process(entry)
+  if entry.extracted.level == "debug" then
+    return false
+  end
+  entry.labels.level = entry.extracted.level
+  entry.labels.filename = nil
+  entry.structured_metadata.user = entry.extracted.user
+  entry.extracted.user_upper = string.upper(entry.extracted.user)
+  entry.line = "[" .. entry.extracted.level .. "] " .. entry.line
+end
+` + "`" + `
+}
+stage.output {
+	source = "user_upper"
+}
+`
+	pl, err := NewPipeline(util_log.Logger, loadConfig(config), nil, prometheus.NewRegistry())
+	require.NoError(t, err)
+
+	ts := time.Now()
+	lbls := model.LabelSet{"app": "foo", "filename": "/var/log/foo.log"}
+	out := processEntries(pl,
+		newEntry(nil, lbls.Clone(), `{"level": "info", "user": "frank"}`, ts),
+		newEntry(nil, lbls.Clone(), `{"level": "debug", "user": "frank"}`, ts),
+	)
+	require.Len(t, out, 1)
+	require.Equal(t, "FRANK", out[0].Line)
+	require.Equal(t, model.LabelSet{"app": "foo", "level": "info"}, out[0].Labels)
+	require.Equal(t, []logproto.LabelAdapter{{Name: "user", Value: "frank"}}, []logproto.LabelAdapter(out[0].StructuredMetadata))
+	require.Equal(t, ts, out[0].Timestamp)
+}
+
+func TestLuaStage_Timestamp(t *testing.T) {
+	config := `
+stage.lua {
+	script = ` + "`" + `
+function process(entry)
+  entry.extracted.seconds = entry.timestamp
+  entry.extracted.nanos = entry.timestamp_nanos
+  entry.timestamp = entry.timestamp - 1
+  entry.timestamp_nanos = 500000000
+  entry.extracted.count = #entry.extracted.items
+end
+` + "`" + `
+}
+`
+	pl, err := NewPipeline(util_log.Logger, loadConfig(config), nil, prometheus.NewRegistry())
+	require.NoError(t, err)
+
+	ts := time.Unix(1700000000, 123456789)
+	extracted := map[string]interface{}{"items": []interface{}{"a", "b", "c"}}
+	out := processEntries(pl, newEntry(extracted, nil, "hello", ts))
+	require.Len(t, out, 1)
+	require.Equal(t, time.Unix(1699999999, 500000000).UnixNano(), out[0].Timestamp.UnixNano())
+	require.Equal(t, 1700000000.0, out[0].Extracted["seconds"])
+	require.Equal(t, 123456789.0, out[0].Extracted["nanos"])
+	require.Equal(t, 3.0, out[0].Extracted["count"])
+	require.Equal(t, []interface{}{"a", "b", "c"}, out[0].Extracted["items"])
+}
+
+func TestLuaStage_Errors(t *testing.T) {
+	script := `
+function process(entry)
+  if entry.line == "loop" then
+    while true do end
+  end
+  error("boom")
+end
+`
+	tests := map[string]struct {
+		onError       string
+		line          string
+		expectedLabel model.LabelValue
+		reason        string
+		dropped       bool
+	}{
+		"pass":          {onError: LuaOnErrorPass, line: "hello", reason: "error"},
+		"drop":          {onError: LuaOnErrorDrop, line: "hello", reason: "error", dropped: true},
+		"label":         {onError: LuaOnErrorLabel, line: "hello", reason: "error", expectedLabel: "LuaErr"},
+		"label timeout": {onError: LuaOnErrorLabel, line: "loop", reason: "timeout", expectedLabel: "LuaTimeoutErr"},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			reg := prometheus.NewRegistry()
+			cfg := DefaultLuaConfig
+			cfg.Script = script
+			cfg.OnError = tc.onError
+			cfg.Timeout = 50 * time.Millisecond
+			s, err := newLuaStage(util_log.Logger, cfg, reg)
+			require.NoError(t, err)
+			defer s.Cleanup()
+
+			out := processEntries(s, newEntry(nil, model.LabelSet{"app": "foo"}, tc.line, time.Now()))
+			require.Equal(t, 1.0, testutil.ToFloat64(getLuaErrorsMetric(reg).WithLabelValues(tc.reason)))
+			if tc.dropped {
+				require.Empty(t, out)
+				require.Equal(t, 1.0, testutil.ToFloat64(getDropCountMetric(reg).WithLabelValues("lua_stage")))
+				return
+			}
+			require.Len(t, out, 1)
+			require.Equal(t, tc.line, out[0].Line)
+			require.Equal(t, tc.expectedLabel, out[0].Labels["lua_error"])
+		})
+	}
+}
+
+func TestLuaStage_Limits(t *testing.T) {
+	tests := map[string]string{
+		"invalid timestamp":       `entry.timestamp = entry.timestamp + 0.5`,
+		"invalid timestamp nanos": `entry.timestamp_nanos = 1e9`,
+		"deep recursion":          `local function f(n) return f(n + 1) + 1 end f(0)`,
+		"large stack":             `local t = {} for i = 1, 100000 do t[i] = i end unpack(t)`,
+		"large string.rep":        `entry.line = string.rep("x", 1e7)`,
+		"large string.rep method": `entry.line = ("xy"):rep(1e6, ",")`,
+		"large table.concat":      `local t = {} for i = 1, 2000 do t[i] = string.rep("x", 1000) end entry.line = table.concat(t)`,
+	}
+	for name, body := range tests {
+		t.Run(name, func(t *testing.T) {
+			reg := prometheus.NewRegistry()
+			cfg := DefaultLuaConfig
+			cfg.Script = "function process(entry)\n" + body + "\nend"
+			cfg.OnError = LuaOnErrorLabel
+			cfg.Timeout = time.Second
+			s, err := newLuaStage(util_log.Logger, cfg, reg)
+			require.NoError(t, err)
+			defer s.Cleanup()
+
+			out := processEntries(s, newEntry(nil, model.LabelSet{"app": "foo"}, "hello", time.Now()))
+			require.Len(t, out, 1)
+			require.Equal(t, "hello", out[0].Line)
+			require.Equal(t, model.LabelValue("LuaErr"), out[0].Labels["lua_error"])
+			require.Equal(t, 1.0, testutil.ToFloat64(getLuaErrorsMetric(reg).WithLabelValues("error")))
+		})
+	}
+}
+
+func TestLuaStage_StringRep(t *testing.T) {
+	cfg := DefaultLuaConfig
+	cfg.Script = `
+function process(entry)
+  entry.line = string.rep("ab", 3, "-") .. ("x"):rep(2) .. string.rep("y", 0)
+end
+`
+	s, err := newLuaStage(util_log.Logger, cfg, prometheus.NewRegistry())
+	require.NoError(t, err)
+	defer s.Cleanup()
+
+	out := processEntries(s, newEntry(nil, nil, "hello", time.Now()))
+	require.Len(t, out, 1)
+	require.Equal(t, "ab-ab-abxx", out[0].Line)
+}
+
+func TestLuaStage_TableConcat(t *testing.T) {
+	cfg := DefaultLuaConfig
+	cfg.Script = `
+function process(entry)
+  local t = {"a", 1, "c"}
+  entry.line = table.concat(t, "-") .. table.concat(t) .. table.concat(t, ",", 2) .. table.concat(t, ",", 2, 2) .. table.concat(t, ",", 4)
+end
+`
+	s, err := newLuaStage(util_log.Logger, cfg, prometheus.NewRegistry())
+	require.NoError(t, err)
+	defer s.Cleanup()
+
+	out := processEntries(s, newEntry(nil, nil, "hello", time.Now()))
+	require.Len(t, out, 1)
+	require.Equal(t, "a-1-ca1c1,c1", out[0].Line)
+}
+
+// Concatenating strings with .. isn't limited, but the script is stopped
+// once it runs for longer than its timeout.
+func TestLuaStage_ConcatenationTimeout(t *testing.T) {
+	reg := prometheus.NewRegistry()
+	cfg := DefaultLuaConfig
+	cfg.Script = `
+function process(entry)
+  local s = entry.line
+  while true do
+    s = s .. "x"
+  end
+end
+`
+	cfg.OnError = LuaOnErrorLabel
+	cfg.Timeout = 50 * time.Millisecond
+	s, err := newLuaStage(util_log.Logger, cfg, reg)
+	require.NoError(t, err)
+	defer s.Cleanup()
+
+	out := processEntries(s, newEntry(nil, model.LabelSet{"app": "foo"}, "hello", time.Now()))
+	require.Len(t, out, 1)
+	require.Equal(t, "hello", out[0].Line)
+	require.Equal(t, model.LabelValue("LuaTimeoutErr"), out[0].Labels["lua_error"])
+	require.Equal(t, 1.0, testutil.ToFloat64(getLuaErrorsMetric(reg).WithLabelValues("timeout")))
+}
+
+func TestLuaConfig_Errors(t *testing.T) {
+	tests := map[string]struct {
+		script string
+		err    string
+	}{
+		"empty script":      {script: " ", err: ErrLuaEmptyScript.Error()},
+		"syntax error":      {script: "function process(", err: ErrLuaCouldNotCompile.Error()},
+		"missing process":   {script: "x = 1", err: ErrLuaProcessUndefined.Error()},
+		"top-level error":   {script: `error("boom")`, err: ErrLuaCouldNotLoadState.Error()},
+		"no file system":    {script: `io.open("/etc/passwd")`, err: ErrLuaCouldNotLoadState.Error()},
+		"no process access": {script: `os.exit(1)`, err: ErrLuaCouldNotLoadState.Error()},
+	}
+	for name, tc := range tests {
+		t.Run(name, func(t *testing.T) {
+			cfg := DefaultLuaConfig
+			cfg.Script = tc.script
+			_, err := newLuaStage(util_log.Logger, cfg, prometheus.NewRegistry())
+			require.ErrorContains(t, err, tc.err)
+		})
+	}
+
+	cfg := DefaultLuaConfig
+	cfg.Script = "function process(entry) end"
+	cfg.OnError = "retry"
+	require.ErrorIs(t, cfg.Validate(), ErrLuaInvalidOnError)
+}
@@ -0,0 +1,111 @@
+package stages
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/alloy/internal/runtime/logging/level"
+	"github.com/grafana/loki/v3/pkg/logql/log/pattern"
+	"github.com/prometheus/common/model"
+)
+
+// Config Errors.
+var (
+	ErrPatternRequired         = errors.New("pattern is required")
+	ErrCouldNotCompilePattern  = errors.New("could not compile pattern")
+	ErrEmptyPatternStageSource = errors.New("empty source")
+)
+
+// PatternConfig configures a processing stage that uses a LogQL pattern
+// expression to extract values from log lines into the shared values map.
+type PatternConfig struct {
+	Pattern string  `alloy:"pattern,attr"`
+	Source  *string `alloy:"source,attr,optional"`
+}
+
+// validatePatternConfig validates the config and returns a pattern matcher.
+func validatePatternConfig(c PatternConfig) (*pattern.Matcher, error) {
+	if c.Pattern == "" {
+		return nil, ErrPatternRequired
+	}
+
+	if c.Source != nil && *c.Source == "" {
+		return nil, ErrEmptyPatternStageSource
+	}
+
+	matcher, err := pattern.New(c.Pattern)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %w", ErrCouldNotCompilePattern, err)
+	}
+
+	return matcher, nil
+}
+
+// patternStage sets extracted data using a LogQL pattern expression.
+type patternStage struct {
+	config  *PatternConfig
+	matcher *pattern.Matcher
+	logger  log.Logger
+}
+
+// newPatternStage creates a newPatternStage
+func newPatternStage(logger log.Logger, config PatternConfig) (Stage, error) {
+	matcher, err := validatePatternConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	return toStage(&patternStage{
+		config:  &config,
+		matcher: matcher,
+		logger:  log.With(logger, "component", "stage", "type", "pattern"),
+	}), nil
+}
+
+// Process implements Stage
+func (r *patternStage) Process(labels model.LabelSet, extracted map[string]interface{}, t *time.Time, entry *string) {
+	// If a source key is provided, the pattern stage should process it
+	// from the extracted map, otherwise should fall back to the entry
+	input := entry
+
+	if r.config.Source != nil {
+		if _, ok := extracted[*r.config.Source]; !ok {
+			level.Debug(r.logger).Log("msg", "source does not exist in the set of extracted values", "source", *r.config.Source)
+			return
+		}
+
+		value, err := getString(extracted[*r.config.Source])
+		if err != nil {
+			level.Debug(r.logger).Log("msg", "failed to convert source value to string", "source", *r.config.Source, "err", err, "type", reflect.TypeOf(extracted[*r.config.Source]))
+			return
+		}
+
+		input = &value
+	}
+
+	if input == nil {
+		level.Debug(r.logger).Log("msg", "cannot parse a nil entry")
+		return
+	}
+
+	// Like the LogQL pattern parser, a line which only matches the beginning
+	// of the pattern populates the captures it reached.
+	captures := r.matcher.Matches([]byte(*input))
+	if captures == nil {
+		level.Debug(r.logger).Log("msg", "pattern did not match", "input", *input, "pattern", r.config.Pattern)
+		return
+	}
+
+	names := r.matcher.Names()
+	for i, capture := range captures {
+		extracted[names[i]] = string(capture)
+	}
+	level.Debug(r.logger).Log("msg", "extracted data debug in pattern stage", "extracted data", fmt.Sprintf("%v", extracted))
+}
+
+// Name implements Stage
+func (r *patternStage) Name() string {
+	return StageTypePattern
+}
@@ -0,0 +1,206 @@
+package stages
+
+import (
+	"testing"
+	"time"
+
+	"github.com/grafana/alloy/internal/util"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testPatternAlloyMultiStageWithSource = `
+stage.pattern {
+    pattern = "<ip> <identd> <user> [<timestamp>] \"<action> <path> <protocol>\" <status> <size> \"<referer>\" \"<useragent>\""
+}
+stage.pattern {
+    pattern = "HTTP/<protocol_version>"
+    source  = "protocol"
+}
+`
+
+func TestPipeline_Pattern(t *testing.T) {
+	t.Parallel()
+
+	logger := util.TestAlloyLogger(t)
+	pl, err := NewPipeline(logger, loadConfig(testPatternAlloyMultiStageWithSource), nil, prometheus.DefaultRegisterer)
+	require.NoError(t, err)
+
+	out := processEntries(pl, newEntry(nil, nil, testRegexLogLine, time.Now()))[0]
+	assert.Equal(t, map[string]interface{}{
+		"ip":               "11.11.11.11",
+		"identd":           "-",
+		"user":             "frank",
+		"timestamp":        "25/Jan/2000:14:00:01 -0500",
+		"action":           "GET",
+		"path":             "/1986.js",
+		"protocol":         "HTTP/1.1",
+		"protocol_version": "1.1",
+		"status":           "200",
+		"size":             "932",
+		"referer":          "-",
+		"useragent":        "Mozilla/5.0 (Windows; U; Windows NT 5.1; de; rv:1.9.1.7) Gecko/20091221 Firefox/3.5.7 GTB6",
+	}, out.Extracted)
+}
+
+func TestPatternConfig_validate(t *testing.T) {
+	t.Parallel()
+	emptySource := ""
+	tests := map[string]struct {
+		config PatternConfig
+		err    error
+	}{
+		"empty pattern": {
+			PatternConfig{},
+			ErrPatternRequired,
+		},
+		"empty source": {
+			PatternConfig{Pattern: "<foo>", Source: &emptySource},
+			ErrEmptyPatternStageSource,
+		},
+		"no capture": {
+			PatternConfig{Pattern: "foo <_>"},
+			ErrCouldNotCompilePattern,
+		},
+		"consecutive captures": {
+			PatternConfig{Pattern: "<foo><bar>"},
+			ErrCouldNotCompilePattern,
+		},
+		"valid": {
+			PatternConfig{Pattern: "<_> <foo>"},
+			nil,
+		},
+	}
+	for tName, tt := range tests {
+		tt := tt
+		t.Run(tName, func(t *testing.T) {
+			t.Parallel()
+			_, err := validatePatternConfig(tt.config)
+			if tt.err == nil {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorContains(t, err, tt.err.Error())
+		})
+	}
+}
+
+func TestPatternParser_Parse(t *testing.T) {
+	t.Parallel()
+	tests := map[string]struct {
+		config          PatternConfig
+		extracted       map[string]interface{}
+		entry           string
+		expectedExtract map[string]interface{}
+	}{
+		"unnamed captures are skipped": {
+			PatternConfig{Pattern: `<ip> - <_> [<_>] "<method> <path> <_>" <status> <_>`},
+			map[string]interface{}{},
+			regexLogFixture,
+			map[string]interface{}{
+				"ip":     "11.11.11.11",
+				"method": "GET",
+				"path":   "/1986.js",
+				"status": "200",
+			},
+		},
+		"partial match populates the captures it reached": {
+			PatternConfig{Pattern: `<method> <path> HTTP/<version>`},
+			map[string]interface{}{},
+			"GET /index.html",
+			map[string]interface{}{
+				"method": "GET",
+				"path":   "/index.html",
+			},
+		},
+		"leading literal must match the beginning": {
+			PatternConfig{Pattern: `level=<level> <_>`},
+			map[string]interface{}{},
+			"ts=1 level=info msg=hello",
+			map[string]interface{}{},
+		},
+		"successfully match pattern on extracted[source]": {
+			PatternConfig{Pattern: "HTTP/<protocol_version>", Source: &protocolStr},
+			map[string]interface{}{
+				"protocol": "HTTP/1.1",
+			},
+			regexLogFixture,
+			map[string]interface{}{
+				"protocol":         "HTTP/1.1",
+				"protocol_version": "1.1",
+			},
+		},
+		"missing extracted[source]": {
+			PatternConfig{Pattern: "HTTP/<protocol_version>", Source: &protocolStr},
+			map[string]interface{}{},
+			regexLogFixture,
+			map[string]interface{}{},
+		},
+		"invalid data type in extracted[source]": {
+			PatternConfig{Pattern: "HTTP/<protocol_version>", Source: &protocolStr},
+			map[string]interface{}{
+				"protocol": true,
+			},
+			regexLogFixture,
+			map[string]interface{}{
+				"protocol": true,
+			},
+		},
+	}
+	for tName, tt := range tests {
+		tt := tt
+		t.Run(tName, func(t *testing.T) {
+			t.Parallel()
+			logger := util.TestAlloyLogger(t)
+			p, err := New(logger, nil, StageConfig{PatternConfig: &tt.config}, nil)
+			require.NoError(t, err)
+			out := processEntries(p, newEntry(tt.extracted, nil, tt.entry, time.Now()))[0]
+			assert.Equal(t, tt.expectedExtract, out.Extracted)
+		})
+	}
+}
+
+// BenchmarkPatternStage compares the pattern stage to the regex stage
+// extracting the same fields of regexLogFixture.
+func BenchmarkPatternStage(b *testing.B) {
+	benchmarks := []struct {
+		name   string
+		config StageConfig
+	}{
+		{"pattern",
+			StageConfig{PatternConfig: &PatternConfig{
+				Pattern: `<ip> <identd> <user> [<timestamp>] "<action> <path> <protocol>" <status> <size> "<referer>" "<useragent>"`,
+			}},
+		},
+		{"regex",
+			StageConfig{RegexConfig: &RegexConfig{
+				Expression: "^(?P<ip>\\S+) (?P<identd>\\S+) (?P<user>\\S+) \\[(?P<timestamp>[\\w:/]+\\s[+\\-]\\d{4})\\] \"(?P<action>\\S+)\\s?(?P<path>\\S+)?\\s?(?P<protocol>\\S+)?\" (?P<status>\\d{3}|-) (?P<size>\\d+|-)\\s?\"?(?P<referer>[^\"]*)\"?\\s?\"?(?P<useragent>[^\"]*)?\"?$",
+			}},
+		},
+	}
+	for _, bm := range benchmarks {
+		b.Run(bm.name, func(b *testing.B) {
+			logger := util.TestAlloyLogger(b)
+			stage, err := New(logger, nil, bm.config, nil)
+			if err != nil {
+				panic(err)
+			}
+			labels := model.LabelSet{}
+			ts := time.Now()
+			extr := map[string]interface{}{}
+
+			in := make(chan Entry)
+			out := stage.Run(in)
+			go func() {
+				for range out {
+				}
+			}()
+			for i := 0; i < b.N; i++ {
+				in <- newEntry(extr, labels, regexLogFixture, ts)
+			}
+			close(in)
+		})
+	}
+}
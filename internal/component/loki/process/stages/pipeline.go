@@ -18,6 +18,8 @@ type StageConfig struct {
 	//TODO(thampiotr): sync these with new stages
 	CRIConfig             *CRIConfig             `alloy:"cri,block,optional"`
 	DecolorizeConfig      *DecolorizeConfig      `alloy:"decolorize,block,optional"`
+	DedupConfig           *DedupConfig           `alloy:"dedup,block,optional"`
+	DetectLevelConfig     *DetectLevelConfig     `alloy:"detect_level,block,optional"`
 	DockerConfig          *DockerConfig          `alloy:"docker,block,optional"`
 	DropConfig            *DropConfig            `alloy:"drop,block,optional"`
 	EventLogMessageConfig *EventLogMessageConfig `alloy:"eventlogmessage,block,optional"`
@@ -28,17 +30,21 @@ type StageConfig struct {
 	LabelsConfig          *LabelsConfig          `alloy:"labels,block,optional"`
 	LimitConfig           *LimitConfig           `alloy:"limit,block,optional"`
 	LogfmtConfig          *LogfmtConfig          `alloy:"logfmt,block,optional"`
+	LookupConfig          *LookupConfig          `alloy:"lookup,block,optional"`
+	LuaConfig             *LuaConfig             `alloy:"lua,block,optional"`
 	LuhnFilterConfig      *LuhnFilterConfig      `alloy:"luhn,block,optional"`
 	MatchConfig           *MatchConfig           `alloy:"match,block,optional"`
 	MetricsConfig         *MetricsConfig         `alloy:"metrics,block,optional"`
 	MultilineConfig       *MultilineConfig       `alloy:"multiline,block,optional"`
 	OutputConfig          *OutputConfig          `alloy:"output,block,optional"`
 	PackConfig            *PackConfig            `alloy:"pack,block,optional"`
+	PatternConfig         *PatternConfig         `alloy:"pattern,block,optional"`
 	RegexConfig           *RegexConfig           `alloy:"regex,block,optional"`
 	ReplaceConfig         *ReplaceConfig         `alloy:"replace,block,optional"`
 	StaticLabelsConfig    *StaticLabelsConfig    `alloy:"static_labels,block,optional"`
 	StructuredMetadata    *LabelsConfig          `alloy:"structured_metadata,block,optional"`
 	SamplingConfig        *SamplingConfig        `alloy:"sampling,block,optional"`
+	SecretFilterConfig    *SecretFilterConfig    `alloy:"secretfilter,block,optional"`
 	TemplateConfig        *TemplateConfig        `alloy:"template,block,optional"`
 	TenantConfig          *TenantConfig          `alloy:"tenant,block,optional"`
 	TimestampConfig       *TimestampConfig       `alloy:"timestamp,block,optional"`
@@ -0,0 +1,275 @@
+package stages
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/alloy/internal/runtime/logging/level"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/common/model"
+)
+
+// Config Errors
+var (
+	ErrEmptySecretFilterRuleName = errors.New("secretfilter rule name must not be empty")
+)
+
+const (
+	defaultSecretFilterRedactWith = "<REDACTED-SECRET:$SECRET_NAME:$SECRET_HASH>"
+
+	// secretFilterGenericRule is the name of the built-in rule which is only
+	// enabled with include_generic, as it's prone to false positives.
+	secretFilterGenericRule = "generic-api-key"
+)
+
+// SecretFilterConfig configures a processing stage that redacts secrets and
+// credentials.
+type SecretFilterConfig struct {
+	Source         string             `alloy:"source,attr,optional"`
+	RulesVersion   string             `alloy:"rules_version,attr,optional"`
+	Types          []string           `alloy:"types,attr,optional"`
+	IncludeGeneric bool               `alloy:"include_generic,attr,optional"`
+	RedactWith     string             `alloy:"redact_with,attr,optional"`
+	Allowlist      []string           `alloy:"allowlist,attr,optional"`
+	Rules          []SecretFilterRule `alloy:"rule,block,optional"`
+}
+
+// SecretFilterRule is a custom rule of the secretfilter stage.
+type SecretFilterRule struct {
+	Name        string  `alloy:"name,attr"`
+	Regex       string  `alloy:"regex,attr"`
+	SecretGroup int     `alloy:"secret_group,attr,optional"`
+	MinEntropy  float64 `alloy:"min_entropy,attr,optional"`
+}
+
+// secretRule is a compiled rule of the secretfilter stage.
+type secretRule struct {
+	name  string
+	regex *regexp.Regexp
+	// group is the index of the capture group holding the secret, 0 being the
+	// whole match.
+	group int
+	// minEntropy is the minimum Shannon entropy, in bits per character, of
+	// the secret to redact it.
+	minEntropy float64
+}
+
+// newSecretFilterStage creates a new secretfilter stage.
+func newSecretFilterStage(logger log.Logger, config SecretFilterConfig, registerer prometheus.Registerer) (Stage, error) {
+	rules, err := compileSecretRules(config)
+	if err != nil {
+		return nil, err
+	}
+
+	allowlist := make([]*regexp.Regexp, 0, len(config.Allowlist))
+	for _, expr := range config.Allowlist {
+		re, err := regexp.Compile(expr)
+		if err != nil {
+			return nil, fmt.Errorf("%v: %w", ErrCouldNotCompileRegex, err)
+		}
+		allowlist = append(allowlist, re)
+	}
+
+	redactWith := config.RedactWith
+	if redactWith == "" {
+		redactWith = defaultSecretFilterRedactWith
+	}
+
+	return toStage(&secretFilterStage{
+		cfg:        config,
+		rules:      rules,
+		allowlist:  allowlist,
+		redactWith: redactWith,
+		redacted:   getSecretsRedactedMetric(registerer),
+		logger:     log.With(logger, "component", "stage", "type", "secretfilter"),
+	}), nil
+}
+
+// compileSecretRules returns the built-in rules enabled by config followed
+// by its custom rules.
+func compileSecretRules(config SecretFilterConfig) ([]secretRule, error) {
+	version := config.RulesVersion
+	if version == "" {
+		version = latestSecretRulesVersion
+	}
+	builtin, ok := builtinSecretRules[version]
+	if !ok {
+		return nil, fmt.Errorf("unknown secretfilter rules_version %q", version)
+	}
+
+	enabled := make(map[string]bool, len(config.Types))
+	for _, name := range config.Types {
+		enabled[name] = true
+	}
+
+	var (
+		rules []secretRule
+		names = make(map[string]bool)
+	)
+	for _, r := range builtin {
+		names[r.name] = true
+		switch {
+		case len(enabled) > 0 && !enabled[r.name]:
+			continue
+		case len(enabled) == 0 && r.name == secretFilterGenericRule && !config.IncludeGeneric:
+			continue
+		}
+		rules = append(rules, r)
+	}
+	for _, name := range config.Types {
+		if !names[name] {
+			return nil, fmt.Errorf("unknown secretfilter rule type %q in rules_version %q", name, version)
+		}
+	}
+
+	for _, r := range config.Rules {
+		if r.Name == "" {
+			return nil, ErrEmptySecretFilterRuleName
+		}
+		if names[r.Name] {
+			return nil, fmt.Errorf("duplicate secretfilter rule name %q", r.Name)
+		}
+		names[r.Name] = true
+
+		re, err := regexp.Compile(r.Regex)
+		if err != nil {
+			return nil, fmt.Errorf("%v for rule %q: %w", ErrCouldNotCompileRegex, r.Name, err)
+		}
+		if r.SecretGroup < 0 || r.SecretGroup > re.NumSubexp() {
+			return nil, fmt.Errorf("secret_group %d of rule %q is out of range, the regex has %d groups", r.SecretGroup, r.Name, re.NumSubexp())
+		}
+		rules = append(rules, secretRule{
+			name:       r.Name,
+			regex:      re,
+			group:      r.SecretGroup,
+			minEntropy: r.MinEntropy,
+		})
+	}
+	return rules, nil
+}
+
+// secretFilterStage redacts secrets from log lines or extracted values.
+type secretFilterStage struct {
+	cfg        SecretFilterConfig
+	rules      []secretRule
+	allowlist  []*regexp.Regexp
+	redactWith string
+	redacted   *prometheus.CounterVec
+	logger     log.Logger
+}
+
+// Process implements Stage.
+func (s *secretFilterStage) Process(labels model.LabelSet, extracted map[string]interface{}, t *time.Time, entry *string) {
+	if s.cfg.Source == "" {
+		if entry != nil {
+			*entry = s.redact(*entry)
+		}
+		return
+	}
+
+	if _, ok := extracted[s.cfg.Source]; !ok {
+		level.Debug(s.logger).Log("msg", "source does not exist in the set of extracted values", "source", s.cfg.Source)
+		return
+	}
+	value, err := getString(extracted[s.cfg.Source])
+	if err != nil {
+		level.Debug(s.logger).Log("msg", "failed to convert source value to string", "source", s.cfg.Source, "err", err, "type", reflect.TypeOf(extracted[s.cfg.Source]))
+		return
+	}
+	extracted[s.cfg.Source] = s.redact(value)
+}
+
+// redact replaces the secrets found in input by each rule, in order.
+func (s *secretFilterStage) redact(input string) string {
+	for _, r := range s.rules {
+		matches := r.regex.FindAllStringSubmatchIndex(input, -1)
+		if matches == nil {
+			continue
+		}
+
+		var (
+			sb       strings.Builder
+			last     int
+			replaced bool
+		)
+		for _, m := range matches {
+			start, end := m[2*r.group], m[2*r.group+1]
+			if start == end {
+				// The group didn't participate in the match or is empty.
+				continue
+			}
+			secret := input[start:end]
+			if shannonEntropy(secret) < r.minEntropy || s.allowed(secret) {
+				continue
+			}
+			sb.WriteString(input[last:start])
+			sb.WriteString(s.redaction(r.name, secret))
+			last = end
+			replaced = true
+			s.redacted.WithLabelValues(r.name).Inc()
+		}
+		if !replaced {
+			continue
+		}
+		sb.WriteString(input[last:])
+		input = sb.String()
+	}
+	return input
+}
+
+// allowed reports whether secret matches one of the allowlist expressions.
+func (s *secretFilterStage) allowed(secret string) bool {
+	for _, re := range s.allowlist {
+		if re.MatchString(secret) {
+			return true
+		}
+	}
+	return false
+}
+
+// redaction returns the text replacing a secret found by the named rule.
+func (s *secretFilterStage) redaction(name, secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return strings.NewReplacer(
+		"$SECRET_NAME", name,
+		"$SECRET_HASH", hex.EncodeToString(sum[:4]),
+	).Replace(s.redactWith)
+}
+
+// shannonEntropy returns the Shannon entropy of s in bits per character.
+func shannonEntropy(s string) float64 {
+	if s == "" {
+		return 0
+	}
+	counts := make(map[rune]int)
+	var total int
+	for _, r := range s {
+		counts[r]++
+		total++
+	}
+	var entropy float64
+	for _, c := range counts {
+		p := float64(c) / float64(total)
+		entropy -= p * math.Log2(p)
+	}
+	return entropy
+}
+
+// Name implements Stage.
+func (s *secretFilterStage) Name() string {
+	return StageTypeSecretFilter
+}
+
+func getSecretsRedactedMetric(registerer prometheus.Registerer) *prometheus.CounterVec {
+	return registerCounterVec(registerer, "loki_process", "secrets_redacted_total",
+		"A count of all secrets redacted by a secretfilter stage, by rule",
+		[]string{"rule"})
+}
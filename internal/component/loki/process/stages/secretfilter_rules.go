@@ -0,0 +1,36 @@
+package stages
+
+import "regexp"
+
+// latestSecretRulesVersion is the version of the built-in secretfilter rules
+// used when rules_version isn't set.
+const latestSecretRulesVersion = "v1"
+
+// builtinSecretRules holds the built-in rule sets of the secretfilter stage
+// by version. Released rule sets must not be changed, so that pinning
+// rules_version keeps the redacted secrets stable across upgrades; add a new
+// version instead.
+var builtinSecretRules = map[string][]secretRule{
+	"v1": {
+		{name: "aws-access-key-id", regex: regexp.MustCompile(`\b((?:A3T[A-Z0-9]|AKIA|ASIA|ABIA|ACCA)[A-Z2-7]{16})\b`)},
+		{name: "aws-secret-access-key", regex: regexp.MustCompile(`(?i)aws_?secret_?(?:access_?)?key["']?\s*(?:=|:|=>)\s*["']?([A-Za-z0-9/+=]{40})\b`), group: 1, minEntropy: 3.5},
+		{name: "github-pat", regex: regexp.MustCompile(`\bghp_[0-9a-zA-Z]{36}\b`)},
+		{name: "github-fine-grained-pat", regex: regexp.MustCompile(`\bgithub_pat_[0-9a-zA-Z_]{82}\b`)},
+		{name: "github-oauth", regex: regexp.MustCompile(`\bgho_[0-9a-zA-Z]{36}\b`)},
+		{name: "github-app-token", regex: regexp.MustCompile(`\b(?:ghu|ghs)_[0-9a-zA-Z]{36}\b`)},
+		{name: "github-refresh-token", regex: regexp.MustCompile(`\bghr_[0-9a-zA-Z]{36}\b`)},
+		{name: "gitlab-pat", regex: regexp.MustCompile(`\bglpat-[0-9a-zA-Z_-]{20}\b`)},
+		{name: "slack-token", regex: regexp.MustCompile(`\bxox[baprs]-[0-9a-zA-Z-]{10,}`)},
+		{name: "slack-webhook-url", regex: regexp.MustCompile(`https://hooks\.slack\.com/(?:services|workflows)/[A-Za-z0-9+/_-]{20,}`)},
+		{name: "stripe-access-token", regex: regexp.MustCompile(`\b(?:sk|rk)_(?:test|live|prod)_[0-9a-zA-Z]{10,99}\b`)},
+		{name: "google-api-key", regex: regexp.MustCompile(`\bAIza[0-9A-Za-z_-]{35}\b`)},
+		{name: "grafana-api-key", regex: regexp.MustCompile(`\beyJrIjoi[A-Za-z0-9]{70,400}={0,2}`)},
+		{name: "grafana-cloud-api-token", regex: regexp.MustCompile(`\bglc_[A-Za-z0-9+/]{32,400}={0,2}`)},
+		{name: "grafana-service-account-token", regex: regexp.MustCompile(`\bglsa_[A-Za-z0-9]{32}_[A-Fa-f0-9]{8}\b`)},
+		{name: "npm-access-token", regex: regexp.MustCompile(`\bnpm_[a-zA-Z0-9]{36}\b`)},
+		{name: "sendgrid-api-token", regex: regexp.MustCompile(`\bSG\.[a-zA-Z0-9_-]{22}\.[a-zA-Z0-9_-]{43}\b`)},
+		{name: "jwt", regex: regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{10,}\.eyJ[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]{10,}`)},
+		{name: "private-key", regex: regexp.MustCompile(`-----BEGIN[ A-Z0-9_-]{0,100}PRIVATE KEY(?: BLOCK)?-----[\s\S]*?-----END[ A-Z0-9_-]{0,100}PRIVATE KEY(?: BLOCK)?-----`)},
+		{name: secretFilterGenericRule, regex: regexp.MustCompile(`(?i)(?:key|api|token|secret|passwd|password|auth|credential)[\w.-]{0,20}["']?\s*(?:=|:|=>|:=)\s*["']?([0-9a-zA-Z_=+/.-]{10,150})`), group: 1, minEntropy: 3.5},
+	},
+}
@@ -15,10 +15,12 @@ import (
 
 // TODO(@tpaschalis) Let's use this as the list of stages we need to port over.
 const (
-	StageTypeCRI        = "cri"
-	StageTypeDecolorize = "decolorize"
-	StageTypeDocker     = "docker"
-	StageTypeDrop       = "drop"
+	StageTypeCRI         = "cri"
+	StageTypeDecolorize  = "decolorize"
+	StageTypeDedup       = "dedup"
+	StageTypeDetectLevel = "detect_level"
+	StageTypeDocker      = "docker"
+	StageTypeDrop        = "drop"
 	//TODO(thampiotr): Add support for eventlogmessage stage
 	StageTypeEventLogMessage    = "eventlogmessage"
 	StageTypeGeoIP              = "geoip"
@@ -28,16 +30,20 @@ const (
 	StageTypeLabelDrop          = "labeldrop"
 	StageTypeLimit              = "limit"
 	StageTypeLogfmt             = "logfmt"
+	StageTypeLookup             = "lookup"
+	StageTypeLua                = "lua"
 	StageTypeLuhn               = "luhn"
 	StageTypeMatch              = "match"
 	StageTypeMetric             = "metrics"
 	StageTypeMultiline          = "multiline"
 	StageTypeOutput             = "output"
 	StageTypePack               = "pack"
+	StageTypePattern            = "pattern"
 	StageTypePipeline           = "pipeline"
 	StageTypeRegex              = "regex"
 	StageTypeReplace            = "replace"
 	StageTypeSampling           = "sampling"
+	StageTypeSecretFilter       = "secretfilter"
 	StageTypeStaticLabels       = "static_labels"
 	StageTypeStructuredMetadata = "structured_metadata"
 	StageTypeTemplate           = "template"
@@ -138,6 +144,16 @@ func New(logger log.Logger, jobName *string, cfg StageConfig, registerer prometh
 		if err != nil {
 			return nil, err
 		}
+	case cfg.LookupConfig != nil:
+		s, err = newLookupStage(logger, *cfg.LookupConfig)
+		if err != nil {
+			return nil, err
+		}
+	case cfg.LuaConfig != nil:
+		s, err = newLuaStage(logger, *cfg.LuaConfig, registerer)
+		if err != nil {
+			return nil, err
+		}
 	case cfg.LuhnFilterConfig != nil:
 		s, err = newLuhnFilterStage(*cfg.LuhnFilterConfig)
 		if err != nil {
@@ -163,6 +179,11 @@ func New(logger log.Logger, jobName *string, cfg StageConfig, registerer prometh
 		if err != nil {
 			return nil, err
 		}
+	case cfg.PatternConfig != nil:
+		s, err = newPatternStage(logger, *cfg.PatternConfig)
+		if err != nil {
+			return nil, err
+		}
 	case cfg.TimestampConfig != nil:
 		s, err = newTimestampStage(logger, *cfg.TimestampConfig)
 		if err != nil {
@@ -198,6 +219,16 @@ func New(logger log.Logger, jobName *string, cfg StageConfig, registerer prometh
 		if err != nil {
 			return nil, err
 		}
+	case cfg.DedupConfig != nil:
+		s, err = newDedupStage(logger, *cfg.DedupConfig, registerer)
+		if err != nil {
+			return nil, err
+		}
+	case cfg.DetectLevelConfig != nil:
+		s, err = newDetectLevelStage(logger, *cfg.DetectLevelConfig)
+		if err != nil {
+			return nil, err
+		}
 	case cfg.DropConfig != nil:
 		s, err = newDropStage(logger, *cfg.DropConfig, registerer)
 		if err != nil {
@@ -237,6 +268,11 @@ func New(logger log.Logger, jobName *string, cfg StageConfig, registerer prometh
 		}
 	case cfg.SamplingConfig != nil:
 		s = newSamplingStage(logger, *cfg.SamplingConfig, registerer)
+	case cfg.SecretFilterConfig != nil:
+		s, err = newSecretFilterStage(logger, *cfg.SecretFilterConfig, registerer)
+		if err != nil {
+			return nil, err
+		}
 	case cfg.EventLogMessageConfig != nil:
 		s = newEventLogMessageStage(logger, cfg.EventLogMessageConfig)
 	default:
@@ -0,0 +1,88 @@
+package file
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/grafana/alloy/internal/component"
+	"github.com/grafana/alloy/internal/component/common/rulesync"
+	"github.com/grafana/alloy/internal/featuregate"
+	lokiClient "github.com/grafana/alloy/internal/loki/client"
+)
+
+func init() {
+	component.Register(component.Registration{
+		Name:      "loki.rules.file",
+		Stability: featuregate.StabilityExperimental,
+		Args:      Arguments{},
+		Exports:   nil,
+		Build: func(o component.Options, c component.Arguments) (component.Component, error) {
+			return New(o, c.(Arguments))
+		},
+	})
+}
+
+// Component syncs rule groups read from files or strings to the Loki Ruler.
+type Component struct {
+	opts    component.Options
+	metrics *rulesync.Metrics
+	runner  *rulesync.Runner
+}
+
+var _ component.Component = (*Component)(nil)
+var _ component.DebugComponent = (*Component)(nil)
+var _ component.HealthComponent = (*Component)(nil)
+
+// New creates a new Component.
+func New(o component.Options, args Arguments) (*Component, error) {
+	m := rulesync.NewMetrics("loki_rules_file", "Loki")
+	if err := m.Register(o.Registerer); err != nil {
+		return nil, fmt.Errorf("registering metrics failed: %w", err)
+	}
+
+	c := &Component{
+		opts:    o,
+		metrics: m,
+		runner:  rulesync.NewRunner(o.Logger, m),
+	}
+	if err := c.Update(args); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *Component) Run(ctx context.Context) error {
+	return c.runner.Run(ctx)
+}
+
+func (c *Component) Update(newConfig component.Arguments) error {
+	args := newConfig.(Arguments)
+
+	httpClient := args.HTTPClientConfig.Convert()
+	client, err := lokiClient.New(c.opts.Logger, lokiClient.Config{
+		ID:               args.TenantID,
+		Address:          args.Address,
+		UseLegacyRoutes:  args.UseLegacyRoutes,
+		HTTPClientConfig: *httpClient,
+	}, c.metrics.ClientTiming)
+	if err != nil {
+		return err
+	}
+
+	c.runner.Update(rulesync.Config{
+		Client:       client,
+		SyncInterval: args.SyncInterval,
+		Namespacer:   lokiNamespacer(args.LokiNameSpacePrefix, c.opts.ID),
+		// LogQL expressions aren't valid PromQL expressions, so only the
+		// structure of the rule files is validated.
+		ValidateExpr: false,
+		Targets:      args.Targets,
+		Contents:     args.contents(),
+	})
+	return nil
+}
+
+// CurrentHealth implements component.HealthComponent.
+func (c *Component) CurrentHealth() component.Health {
+	return c.runner.CurrentHealth()
+}
@@ -0,0 +1,72 @@
+package file
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/grafana/alloy/internal/component/common/config"
+	"github.com/grafana/alloy/internal/component/common/rulesync"
+	"github.com/grafana/alloy/internal/component/discovery"
+	"github.com/grafana/alloy/syntax/alloytypes"
+)
+
+type Arguments struct {
+	Address             string                  `alloy:"address,attr"`
+	TenantID            string                  `alloy:"tenant_id,attr,optional"`
+	UseLegacyRoutes     bool                    `alloy:"use_legacy_routes,attr,optional"`
+	HTTPClientConfig    config.HTTPClientConfig `alloy:",squash"`
+	SyncInterval        time.Duration           `alloy:"sync_interval,attr,optional"`
+	LokiNameSpacePrefix string                  `alloy:"loki_namespace_prefix,attr,optional"`
+
+	// Targets are rule files, usually exported by local.file_match.
+	Targets []discovery.Target `alloy:"targets,attr,optional"`
+	// Contents are the contents of rule files keyed by namespace.
+	Contents map[string]alloytypes.OptionalSecret `alloy:"contents,attr,optional"`
+}
+
+var DefaultArguments = Arguments{
+	SyncInterval:        time.Minute,
+	LokiNameSpacePrefix: "alloy-file",
+	HTTPClientConfig:    config.DefaultHTTPClientConfig,
+}
+
+// SetToDefault implements syntax.Defaulter.
+func (args *Arguments) SetToDefault() {
+	*args = DefaultArguments
+}
+
+// Validate implements syntax.Validator.
+func (args *Arguments) Validate() error {
+	if args.SyncInterval <= 0 {
+		return fmt.Errorf("sync_interval must be greater than 0")
+	}
+	if args.LokiNameSpacePrefix == "" {
+		return fmt.Errorf("loki_namespace_prefix must not be empty")
+	}
+	for namespace := range args.Contents {
+		if namespace == "" || strings.Contains(namespace, "/") {
+			return fmt.Errorf("invalid namespace %q in contents, namespaces must not be empty or contain a slash", namespace)
+		}
+	}
+
+	// We must explicitly Validate because HTTPClientConfig is squashed and it won't run otherwise
+	return args.HTTPClientConfig.Validate()
+}
+
+// contents returns the contents of rule files keyed by namespace.
+func (args *Arguments) contents() map[string]string {
+	out := make(map[string]string, len(args.Contents))
+	for namespace, content := range args.Contents {
+		out[namespace] = content.Value
+	}
+	return out
+}
+
+// lokiNamespacer returns the Namespacer of the rule groups of the component
+// with the ID componentID. Loki namespaces look like
+// <prefix>-<component ID>-<namespace>.
+func lokiNamespacer(prefix, componentID string) rulesync.Namespacer {
+	// Set to - to separate, loki doesn't support prefixpath like mimir ruler does
+	return rulesync.NewNamespacer(prefix, componentID, "-")
+}
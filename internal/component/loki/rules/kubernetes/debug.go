@@ -4,6 +4,7 @@ import "fmt"
 
 type DebugInfo struct {
 	Error              string                   `alloy:"error,attr,optional"`
+	IsLeader           bool                     `alloy:"is_leader,attr"`
 	PrometheusRules    []DebugK8sPrometheusRule `alloy:"prometheus_rule,block,optional"`
 	LokiRuleNamespaces []DebugLokiNamespace     `alloy:"loki_rule_namespace,block,optional"`
 }
@@ -21,7 +22,16 @@ type DebugLokiNamespace struct {
 }
 
 func (c *Component) DebugInfo() interface{} {
-	var output DebugInfo
+	output := DebugInfo{IsLeader: c.leader.IsLeader()}
+
+	c.mut.RLock()
+	defer c.mut.RUnlock()
+
+	// Rules are only loaded by the leader of the cluster.
+	if !c.started {
+		return output
+	}
+
 	for ns := range c.currentState {
 		if !isManagedLokiNamespace(c.args.LokiNameSpacePrefix, ns) {
 			continue
@@ -37,7 +47,8 @@ func (c *Component) DebugInfo() interface{} {
 	managedK8sNamespaces, err := c.namespaceLister.List(c.namespaceSelector)
 	if err != nil {
 		return DebugInfo{
-			Error: fmt.Sprintf("failed to list namespaces: %v", err),
+			IsLeader: output.IsLeader,
+			Error:    fmt.Sprintf("failed to list namespaces: %v", err),
 		}
 	}
 
@@ -46,7 +57,8 @@ func (c *Component) DebugInfo() interface{} {
 		rules, err := c.ruleLister.PrometheusRules(n.Name).List(c.ruleSelector)
 		if err != nil {
 			return DebugInfo{
-				Error: fmt.Sprintf("failed to list rules: %v", err),
+				IsLeader: output.IsLeader,
+				Error:    fmt.Sprintf("failed to list rules: %v", err),
 			}
 		}
 
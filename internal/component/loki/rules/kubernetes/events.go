@@ -88,7 +88,9 @@ func (c *Component) syncLoki(ctx context.Context) error {
 		}
 	}
 
+	c.mut.Lock()
 	c.currentState = rulesByNamespace
+	c.mut.Unlock()
 
 	return nil
 }
@@ -135,6 +135,8 @@ func TestEventLoop(t *testing.T) {
 		lokiClient:        newFakeLokiClient(),
 		args:              Arguments{LokiNameSpacePrefix: "alloy"},
 		metrics:           newMetrics(),
+		leader:            &fakeLeadership{leader: true},
+		started:           true,
 	}
 	eventHandler := kubernetes.NewQueuedEventHandler(component.log, component.queue)
 
@@ -156,7 +158,15 @@ func TestEventLoop(t *testing.T) {
 	}, time.Second, 10*time.Millisecond)
 	component.queue.AddRateLimited(kubernetes.Event{Typ: eventTypeSyncLoki})
 
-	// Update the rule in kubernetes
+	// The debug info can be read while the event loop syncs with loki.
+	require.Eventually(t, func() bool {
+		info := component.DebugInfo().(DebugInfo)
+		return len(info.LokiRuleNamespaces) == 1 && len(info.PrometheusRules) == 1
+	}, time.Second, 10*time.Millisecond)
+
+	// Update the rule in kubernetes. The event loop may still read the
+	// previous version of the rule.
+	rule = rule.DeepCopy()
 	rule.Spec.Groups[0].Rules = append(rule.Spec.Groups[0].Rules, v1.Rule{
 		Alert: "alert2",
 		Expr:  intstr.FromString("expr2"),
@@ -4,6 +4,7 @@ import (
 	"time"
 
 	"github.com/grafana/alloy/internal/component"
+	commonK8s "github.com/grafana/alloy/internal/component/common/kubernetes"
 )
 
 func (c *Component) reportUnhealthy(err error) {
@@ -25,6 +26,18 @@ func (c *Component) reportHealthy() {
 	}
 }
 
+// reportStandby marks the component as healthy while another instance of the
+// cluster is the leader.
+func (c *Component) reportStandby() {
+	c.healthMut.Lock()
+	defer c.healthMut.Unlock()
+	c.health = component.Health{
+		Health:     component.HealthTypeHealthy,
+		Message:    commonK8s.StandbyMessage,
+		UpdateTime: time.Now(),
+	}
+}
+
 func (c *Component) CurrentHealth() component.Health {
 	c.healthMut.RLock()
 	defer c.healthMut.RUnlock()
@@ -12,6 +12,7 @@ import (
 	"github.com/grafana/alloy/internal/featuregate"
 	lokiClient "github.com/grafana/alloy/internal/loki/client"
 	"github.com/grafana/alloy/internal/runtime/logging/level"
+	"github.com/grafana/alloy/internal/service/cluster"
 	"github.com/grafana/dskit/backoff"
 	"github.com/grafana/dskit/instrument"
 	promListers "github.com/prometheus-operator/prometheus-operator/pkg/client/listers/monitoring/v1"
@@ -58,21 +59,30 @@ type Component struct {
 	informerStopChan  chan struct{}
 	ticker            *time.Ticker
 
-	queue         workqueue.RateLimitingInterface
-	configUpdates chan ConfigUpdate
+	queue          workqueue.RateLimitingInterface
+	configUpdates  chan ConfigUpdate
+	clusterUpdates chan struct{}
 
-	namespaceSelector labels.Selector
-	ruleSelector      labels.Selector
+	leader leadership
 
+	// mut guards started and currentState, which are read by DebugInfo while
+	// the component runs. The other fields read by DebugInfo are only changed
+	// while the component isn't started.
+	mut          sync.RWMutex
+	started      bool
 	currentState commonK8s.RuleGroupsByNamespace
 
+	namespaceSelector labels.Selector
+	ruleSelector      labels.Selector
+
 	metrics   *metrics
 	healthMut sync.RWMutex
 	health    component.Health
 }
 
 type metrics struct {
-	configUpdatesTotal prometheus.Counter
+	configUpdatesTotal  prometheus.Counter
+	clusterUpdatesTotal prometheus.Counter
 
 	eventsTotal   *prometheus.CounterVec
 	eventsFailed  *prometheus.CounterVec
@@ -84,6 +94,7 @@ type metrics struct {
 func (m *metrics) Register(r prometheus.Registerer) error {
 	r.MustRegister(
 		m.configUpdatesTotal,
+		m.clusterUpdatesTotal,
 		m.eventsTotal,
 		m.eventsFailed,
 		m.eventsRetried,
@@ -99,6 +110,11 @@ func newMetrics() *metrics {
 			Name:      "config_updates_total",
 			Help:      "Total number of times the configuration has been updated.",
 		}),
+		clusterUpdatesTotal: prometheus.NewCounter(prometheus.CounterOpts{
+			Subsystem: "loki_rules",
+			Name:      "cluster_updates_total",
+			Help:      "Total number of times the cluster has changed.",
+		}),
 		eventsTotal: prometheus.NewCounterVec(prometheus.CounterOpts{
 			Subsystem: "loki_rules",
 			Name:      "events_total",
@@ -131,6 +147,7 @@ type ConfigUpdate struct {
 var _ component.Component = (*Component)(nil)
 var _ component.DebugComponent = (*Component)(nil)
 var _ component.HealthComponent = (*Component)(nil)
+var _ cluster.Component = (*Component)(nil)
 
 func NewComponent(o component.Options, args Arguments) (*Component, error) {
 	metrics := newMetrics()
@@ -139,13 +156,20 @@ func NewComponent(o component.Options, args Arguments) (*Component, error) {
 		return nil, fmt.Errorf("registering metrics failed: %w", err)
 	}
 
+	clusterSvc, err := o.GetServiceData(cluster.ServiceName)
+	if err != nil {
+		return nil, fmt.Errorf("getting cluster service failed: %w", err)
+	}
+
 	c := &Component{
-		log:           o.Logger,
-		opts:          o,
-		args:          args,
-		configUpdates: make(chan ConfigUpdate),
-		ticker:        time.NewTicker(args.SyncInterval),
-		metrics:       metrics,
+		log:            o.Logger,
+		opts:           o,
+		args:           args,
+		configUpdates:  make(chan ConfigUpdate),
+		clusterUpdates: make(chan struct{}, 1),
+		leader:         commonK8s.NewComponentLeadership(o.ID, o.Logger, clusterSvc.(cluster.Cluster)),
+		ticker:         time.NewTicker(args.SyncInterval),
+		metrics:        metrics,
 	}
 
 	err = c.init()
@@ -166,7 +190,11 @@ func (c *Component) Run(ctx context.Context) error {
 		},
 	)
 	for {
-		if err := c.startup(ctx); err != nil {
+		// Repeatedly check if we are the leader and attempt to start the component
+		if _, err := c.leader.Update(); err != nil {
+			level.Error(c.log).Log("msg", "checking leadership during starting failed, will retry", "err", err)
+			c.reportUnhealthy(err)
+		} else if err := c.startup(ctx); err != nil {
 			level.Error(c.log).Log("msg", "starting up component failed", "err", err)
 			c.reportUnhealthy(err)
 		} else {
@@ -199,41 +227,107 @@ func (c *Component) Run(ctx context.Context) error {
 			}
 
 			update.err <- nil
+		case <-c.clusterUpdates:
+			c.metrics.clusterUpdatesTotal.Inc()
+
+			changed, err := c.leader.Update()
+			if err != nil {
+				level.Error(c.log).Log("msg", "checking leadership failed", "err", err)
+				c.reportUnhealthy(err)
+			} else if changed {
+				c.shutdown()
+				if err := c.startup(ctx); err != nil {
+					level.Error(c.log).Log("msg", "restarting component after cluster change failed", "err", err)
+					c.reportUnhealthy(err)
+				}
+			}
 		case <-ctx.Done():
 			c.shutdown()
 			return nil
 		case <-c.ticker.C:
-			c.queue.Add(commonK8s.Event{
-				Typ: eventTypeSyncLoki,
-			})
+			c.mut.RLock()
+			started := c.started
+			c.mut.RUnlock()
+
+			if started {
+				c.queue.Add(commonK8s.Event{
+					Typ: eventTypeSyncLoki,
+				})
+			} else if c.leader.IsLeader() {
+				// A previous startup failed: retry it.
+				if err := c.startup(ctx); err != nil {
+					level.Error(c.log).Log("msg", "starting up component failed, will retry", "err", err)
+					c.reportUnhealthy(err)
+				}
+			}
 		}
 	}
 }
 
-// startup launches the informers and starts the event loop.
+// startup launches the informers and starts the event loop if this instance
+// is the leader. If it is not the leader, startup does nothing. If startup
+// fails, the informers it launched are stopped again.
 func (c *Component) startup(ctx context.Context) error {
+	if !c.leader.IsLeader() {
+		level.Info(c.log).Log("msg", "skipping startup because we are not the leader")
+		c.reportStandby()
+		return nil
+	}
+
 	cfg := workqueue.RateLimitingQueueConfig{Name: "loki.rules.kubernetes"}
 	c.queue = workqueue.NewRateLimitingQueueWithConfig(workqueue.DefaultControllerRateLimiter(), cfg)
 	c.informerStopChan = make(chan struct{})
 
 	if err := c.startNamespaceInformer(); err != nil {
+		c.stopInformers()
 		return err
 	}
 	if err := c.startRuleInformer(); err != nil {
+		c.stopInformers()
 		return err
 	}
 	if err := c.syncLoki(ctx); err != nil {
+		c.stopInformers()
 		return err
 	}
+
+	c.mut.Lock()
+	c.started = true
+	c.mut.Unlock()
 	go c.eventLoop(ctx)
+	c.reportHealthy()
 	return nil
 }
 
+// stopInformers stops the informers and the queue of a startup which failed
+// before the event loop was started. Queued events are discarded.
+func (c *Component) stopInformers() {
+	close(c.informerStopChan)
+	c.queue.ShutDown()
+}
+
+// shutdown stops the informers and waits for the queued events to be
+// processed. It does nothing if the component wasn't started.
 func (c *Component) shutdown() {
+	c.mut.Lock()
+	started := c.started
+	c.started = false
+	c.mut.Unlock()
+
+	if !started {
+		return
+	}
 	close(c.informerStopChan)
 	c.queue.ShutDownWithDrain()
 }
 
+func (c *Component) NotifyClusterChange() {
+	select {
+	case c.clusterUpdates <- struct{}{}:
+	default: // update already scheduled
+	}
+}
+
 func (c *Component) Update(newConfig component.Arguments) error {
 	errChan := make(chan error)
 	c.configUpdates <- ConfigUpdate{
@@ -332,3 +426,17 @@ func (c *Component) startRuleInformer() error {
 	factory.WaitForCacheSync(c.informerStopChan)
 	return nil
 }
+
+// leadership encapsulates the logic for checking if this instance of the Component
+// is the leader among all instances to avoid conflicting updates of the Loki API.
+type leadership interface {
+	// Update checks if this component instance is still the leader, stores the result,
+	// and returns true if the leadership status has changed since the last time Update
+	// was called.
+	Update() (bool, error)
+
+	// IsLeader returns true if this component instance is the leader, false otherwise.
+	IsLeader() bool
+}
+
+var _ leadership = (*commonK8s.ComponentLeadership)(nil)
@@ -1,10 +1,19 @@
 package rules
 
 import (
+	"context"
+	"errors"
 	"testing"
 
+	"github.com/go-kit/log"
+	"github.com/grafana/alloy/internal/component"
+	commonK8s "github.com/grafana/alloy/internal/component/common/kubernetes"
 	"github.com/grafana/alloy/syntax"
+	promFake "github.com/prometheus-operator/prometheus-operator/pkg/client/versioned/fake"
+	"github.com/prometheus/prometheus/model/rulefmt"
 	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/labels"
+	k8sFake "k8s.io/client-go/kubernetes/fake"
 )
 
 func TestAlloyConfig(t *testing.T) {
@@ -33,3 +42,78 @@ func TestBadAlloyConfig(t *testing.T) {
 	err := syntax.Unmarshal([]byte(exampleAlloyConfig), &args)
 	require.ErrorContains(t, err, "at most one of basic_auth, authorization, oauth2, bearer_token & bearer_token_file must be configured")
 }
+
+type fakeLeadership struct {
+	leader bool
+}
+
+func (f *fakeLeadership) Update() (bool, error) {
+	return false, nil
+}
+
+func (f *fakeLeadership) IsLeader() bool {
+	return f.leader
+}
+
+func TestStartupOnStandby(t *testing.T) {
+	c := &Component{
+		log:    log.NewNopLogger(),
+		leader: &fakeLeadership{leader: false},
+	}
+
+	require.NoError(t, c.startup(context.Background()))
+	require.False(t, c.started)
+	require.Equal(t, component.HealthTypeHealthy, c.CurrentHealth().Health)
+	require.Equal(t, commonK8s.StandbyMessage, c.CurrentHealth().Message)
+
+	// Stopping a component which never started is a no-op.
+	c.shutdown()
+	require.Equal(t, DebugInfo{IsLeader: false}, c.DebugInfo())
+}
+
+// unavailableLokiClient fails to list rules while err is set.
+type unavailableLokiClient struct {
+	*fakeLokiClient
+	err error
+}
+
+func (m *unavailableLokiClient) ListRules(ctx context.Context, namespace string) (map[string][]rulefmt.RuleGroup, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	return m.fakeLokiClient.ListRules(ctx, namespace)
+}
+
+func TestStartupFailure(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	loki := &unavailableLokiClient{fakeLokiClient: newFakeLokiClient(), err: errors.New("loki is unavailable")}
+	c := &Component{
+		log:               log.NewNopLogger(),
+		leader:            &fakeLeadership{leader: true},
+		lokiClient:        loki,
+		k8sClient:         k8sFake.NewSimpleClientset(),
+		promClient:        promFake.NewSimpleClientset(),
+		namespaceSelector: labels.Everything(),
+		ruleSelector:      labels.Everything(),
+		metrics:           newMetrics(),
+	}
+
+	// A failed startup stops the informers and the queue it started, and
+	// leaves the component stopped.
+	require.ErrorIs(t, c.startup(ctx), loki.err)
+	require.False(t, c.started)
+	require.True(t, c.queue.ShuttingDown())
+	require.NotPanics(t, c.shutdown)
+
+	// The startup can be retried.
+	loki.err = nil
+	require.NoError(t, c.startup(ctx))
+	require.True(t, c.started)
+	require.Equal(t, component.HealthTypeHealthy, c.CurrentHealth().Health)
+
+	c.shutdown()
+	require.False(t, c.started)
+	require.True(t, c.queue.ShuttingDown())
+}
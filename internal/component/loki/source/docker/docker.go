@@ -227,10 +227,7 @@ func (c *Component) Update(args component.Arguments) error {
 
 	promTargets := make([]promTarget, len(newArgs.Targets))
 	for i, target := range newArgs.Targets {
-		var labels = make(model.LabelSet)
-		for k, v := range target {
-			labels[model.LabelName(k)] = model.LabelValue(v)
-		}
+		labels := target.LabelSet()
 		promTargets[i] = promTarget{labels: labels, fingerPrint: labels.Fingerprint()}
 	}
 
@@ -220,15 +220,15 @@ func (c *Component) Update(args component.Arguments) error {
 	}
 
 	for _, target := range newArgs.Targets {
-		path := target[pathLabel]
+		path, _ := target.Get(pathLabel)
 
 		labels := make(model.LabelSet)
-		for k, v := range target {
-			if strings.HasPrefix(k, model.ReservedLabelPrefix) {
-				continue
+		target.ForEachLabel(func(k, v string) bool {
+			if !strings.HasPrefix(k, model.ReservedLabelPrefix) {
+				labels[model.LabelName(k)] = model.LabelValue(v)
 			}
-			labels[model.LabelName(k)] = model.LabelValue(v)
-		}
+			return true
+		})
 
 		// Deduplicate targets which have the same public label set.
 		readersKey := positions.Entry{Path: path, Labels: labels.String()}
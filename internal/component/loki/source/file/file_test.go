@@ -41,10 +41,10 @@ func Test(t *testing.T) {
 
 	go func() {
 		err := ctrl.Run(ctx, Arguments{
-			Targets: []discovery.Target{{
+			Targets: []discovery.Target{discovery.NewTargetFromMap(map[string]string{
 				"__path__": f.Name(),
 				"foo":      "bar",
-			}},
+			})},
 			ForwardTo: []loki.LogsReceiver{ch1, ch2},
 		})
 		require.NoError(t, err)
@@ -91,10 +91,10 @@ func TestFileWatch(t *testing.T) {
 	ch1 := loki.NewLogsReceiver()
 
 	args := Arguments{
-		Targets: []discovery.Target{{
+		Targets: []discovery.Target{discovery.NewTargetFromMap(map[string]string{
 			"__path__": f.Name(),
 			"foo":      "bar",
-		}},
+		})},
 		ForwardTo: []loki.LogsReceiver{ch1},
 		FileWatch: FileWatch{
 			MinPollFrequency: time.Millisecond * 500,
@@ -150,10 +150,10 @@ func TestUpdate_NoLeak(t *testing.T) {
 	require.NoError(t, err)
 
 	args := Arguments{
-		Targets: []discovery.Target{{
+		Targets: []discovery.Target{discovery.NewTargetFromMap(map[string]string{
 			"__path__": f.Name(),
 			"foo":      "bar",
-		}},
+		})},
 		ForwardTo: []loki.LogsReceiver{},
 	}
 
@@ -195,8 +195,8 @@ func TestTwoTargets(t *testing.T) {
 	ch1 := loki.NewLogsReceiver()
 	args := Arguments{}
 	args.Targets = []discovery.Target{
-		{"__path__": f.Name(), "foo": "bar"},
-		{"__path__": f2.Name(), "foo": "bar2"},
+		discovery.NewTargetFromMap(map[string]string{"__path__": f.Name(), "foo": "bar"}),
+		discovery.NewTargetFromMap(map[string]string{"__path__": f2.Name(), "foo": "bar2"}),
 	}
 	args.ForwardTo = []loki.LogsReceiver{ch1}
 
@@ -265,7 +265,7 @@ func TestEncoding(t *testing.T) {
 
 	ch1 := loki.NewLogsReceiver()
 	args := Arguments{}
-	args.Targets = []discovery.Target{{"__path__": f.Name(), "lbl1": "val1"}}
+	args.Targets = []discovery.Target{discovery.NewTargetFromMap(map[string]string{"__path__": f.Name(), "lbl1": "val1"})}
 	args.Encoding = "UTF-16BE"
 	args.ForwardTo = []loki.LogsReceiver{ch1}
 
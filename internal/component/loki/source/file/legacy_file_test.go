@@ -123,9 +123,9 @@ configs:
 	args.LegacyPositionsFile = filepath.Join(positionsDir, "default.yml")
 	args.ForwardTo = []loki.LogsReceiver{ch1}
 	args.Targets = []discovery.Target{
-		{
+		discovery.NewTargetFromMap(map[string]string{
 			"__path__": tmpFile.Name(),
-		},
+		}),
 	}
 
 	// New will do the actual conversion
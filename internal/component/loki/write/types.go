@@ -18,19 +18,21 @@ import (
 
 // EndpointOptions describes an individual location to send logs to.
 type EndpointOptions struct {
-	Name              string                  `alloy:"name,attr,optional"`
-	URL               string                  `alloy:"url,attr"`
-	BatchWait         time.Duration           `alloy:"batch_wait,attr,optional"`
-	BatchSize         units.Base2Bytes        `alloy:"batch_size,attr,optional"`
-	RemoteTimeout     time.Duration           `alloy:"remote_timeout,attr,optional"`
-	Headers           map[string]string       `alloy:"headers,attr,optional"`
-	MinBackoff        time.Duration           `alloy:"min_backoff_period,attr,optional"`  // start backoff at this level
-	MaxBackoff        time.Duration           `alloy:"max_backoff_period,attr,optional"`  // increase exponentially to this level
-	MaxBackoffRetries int                     `alloy:"max_backoff_retries,attr,optional"` // give up after this many; zero means infinite retries
-	TenantID          string                  `alloy:"tenant_id,attr,optional"`
-	RetryOnHTTP429    bool                    `alloy:"retry_on_http_429,attr,optional"`
-	HTTPClientConfig  *types.HTTPClientConfig `alloy:",squash"`
-	QueueConfig       QueueConfig             `alloy:"queue_config,block,optional"`
+	Name               string                    `alloy:"name,attr,optional"`
+	URL                string                    `alloy:"url,attr"`
+	BatchWait          time.Duration             `alloy:"batch_wait,attr,optional"`
+	BatchSize          units.Base2Bytes          `alloy:"batch_size,attr,optional"`
+	RemoteTimeout      time.Duration             `alloy:"remote_timeout,attr,optional"`
+	Headers            map[string]string         `alloy:"headers,attr,optional"`
+	MinBackoff         time.Duration             `alloy:"min_backoff_period,attr,optional"`  // start backoff at this level
+	MaxBackoff         time.Duration             `alloy:"max_backoff_period,attr,optional"`  // increase exponentially to this level
+	MaxBackoffRetries  int                       `alloy:"max_backoff_retries,attr,optional"` // give up after this many; zero means infinite retries
+	TenantID           string                    `alloy:"tenant_id,attr,optional"`
+	RetryOnHTTP429     bool                      `alloy:"retry_on_http_429,attr,optional"`
+	HTTPClientConfig   *types.HTTPClientConfig   `alloy:",squash"`
+	QueueConfig        QueueConfig               `alloy:"queue_config,block,optional"`
+	StructuredMetadata StructuredMetadataOptions `alloy:"structured_metadata,block,optional"`
+	TenantQueues       TenantQueuesOptions       `alloy:"tenant_queues,block,optional"`
 }
 
 // GetDefaultEndpointOptions defines the default settings for sending logs to a
@@ -48,6 +50,14 @@ func GetDefaultEndpointOptions() EndpointOptions {
 		MaxBackoffRetries: 10,
 		HTTPClientConfig:  types.CloneDefaultHTTPClientConfig(),
 		RetryOnHTTP429:    true,
+		StructuredMetadata: StructuredMetadataOptions{
+			Action: client.StructuredMetadataActionTruncate,
+		},
+		TenantQueues: TenantQueuesOptions{
+			Capacity:           client.TenantQueueCapacity,
+			MaxConcurrentSends: client.TenantQueueMaxConcurrentSends,
+			IdleTimeout:        client.TenantQueueIdleTimeout,
+		},
 	}
 
 	return defaultEndpointOptions
@@ -87,6 +97,82 @@ func (q *QueueConfig) SetToDefault() {
 	}
 }
 
+// StructuredMetadataOptions controls the policy applied to the structured
+// metadata of log entries before they're sent to an endpoint.
+type StructuredMetadataOptions struct {
+	MaxKeys  int              `alloy:"max_keys,attr,optional"`
+	MaxBytes units.Base2Bytes `alloy:"max_bytes,attr,optional"`
+	Action   string           `alloy:"action,attr,optional"`
+	Allow    []string         `alloy:"allow,attr,optional"`
+	Deny     []string         `alloy:"deny,attr,optional"`
+}
+
+// SetToDefault implements syntax.Defaulter.
+func (s *StructuredMetadataOptions) SetToDefault() {
+	*s = StructuredMetadataOptions{
+		Action: client.StructuredMetadataActionTruncate,
+	}
+}
+
+// Validate implements syntax.Validator.
+func (s *StructuredMetadataOptions) Validate() error {
+	if s.MaxKeys < 0 {
+		return fmt.Errorf("structured_metadata max_keys must not be negative")
+	}
+	if s.MaxBytes < 0 {
+		return fmt.Errorf("structured_metadata max_bytes must not be negative")
+	}
+	switch s.Action {
+	case client.StructuredMetadataActionDrop, client.StructuredMetadataActionTruncate:
+	default:
+		return fmt.Errorf("structured_metadata action must be %q or %q, got %q", client.StructuredMetadataActionDrop, client.StructuredMetadataActionTruncate, s.Action)
+	}
+	return nil
+}
+
+// TenantQueuesOptions controls the per-tenant queues used to send logs to an
+// endpoint, so that a tenant being rate limited or failing doesn't delay the
+// logs of other tenants.
+type TenantQueuesOptions struct {
+	Enabled            bool                        `alloy:"enabled,attr,optional"`
+	Capacity           int                         `alloy:"capacity,attr,optional"`
+	MaxConcurrentSends int                         `alloy:"max_concurrent_sends,attr,optional"`
+	RateLimit          units.Base2Bytes            `alloy:"rate_limit,attr,optional"`
+	RateLimitOverrides map[string]units.Base2Bytes `alloy:"rate_limit_overrides,attr,optional"`
+	IdleTimeout        time.Duration               `alloy:"idle_timeout,attr,optional"`
+}
+
+// SetToDefault implements syntax.Defaulter.
+func (t *TenantQueuesOptions) SetToDefault() {
+	*t = TenantQueuesOptions{
+		Capacity:           client.TenantQueueCapacity,
+		MaxConcurrentSends: client.TenantQueueMaxConcurrentSends,
+		IdleTimeout:        client.TenantQueueIdleTimeout,
+	}
+}
+
+// Validate implements syntax.Validator.
+func (t *TenantQueuesOptions) Validate() error {
+	if t.Capacity <= 0 {
+		return fmt.Errorf("tenant_queues capacity must be greater than 0")
+	}
+	if t.MaxConcurrentSends <= 0 {
+		return fmt.Errorf("tenant_queues max_concurrent_sends must be greater than 0")
+	}
+	if t.RateLimit < 0 {
+		return fmt.Errorf("tenant_queues rate_limit must not be negative")
+	}
+	if t.IdleTimeout <= 0 {
+		return fmt.Errorf("tenant_queues idle_timeout must be greater than 0")
+	}
+	for tenantID, limit := range t.RateLimitOverrides {
+		if limit < 0 {
+			return fmt.Errorf("tenant_queues rate_limit_overrides for tenant %q must not be negative", tenantID)
+		}
+	}
+	return nil
+}
+
 func (args Arguments) convertClientConfigs() []client.Config {
 	var res []client.Config
 	for _, cfg := range args.Endpoints {
@@ -111,6 +197,26 @@ func (args Arguments) convertClientConfigs() []client.Config {
 				Capacity:     int(cfg.QueueConfig.Capacity),
 				DrainTimeout: cfg.QueueConfig.DrainTimeout,
 			},
+			StructuredMetadata: client.StructuredMetadataConfig{
+				MaxKeys:  cfg.StructuredMetadata.MaxKeys,
+				MaxBytes: int(cfg.StructuredMetadata.MaxBytes),
+				Action:   cfg.StructuredMetadata.Action,
+				Allow:    cfg.StructuredMetadata.Allow,
+				Deny:     cfg.StructuredMetadata.Deny,
+			},
+			TenantQueues: client.TenantQueueConfig{
+				Enabled:            cfg.TenantQueues.Enabled,
+				Capacity:           cfg.TenantQueues.Capacity,
+				MaxConcurrentSends: cfg.TenantQueues.MaxConcurrentSends,
+				RateLimit:          int(cfg.TenantQueues.RateLimit),
+				IdleTimeout:        cfg.TenantQueues.IdleTimeout,
+			},
+		}
+		if len(cfg.TenantQueues.RateLimitOverrides) > 0 {
+			cc.TenantQueues.RateLimitOverrides = make(map[string]int, len(cfg.TenantQueues.RateLimitOverrides))
+			for tenantID, limit := range cfg.TenantQueues.RateLimitOverrides {
+				cc.TenantQueues.RateLimitOverrides[tenantID] = int(limit)
+			}
 		}
 		res = append(res, cc)
 	}
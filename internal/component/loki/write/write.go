@@ -14,6 +14,8 @@ import (
 	"github.com/grafana/alloy/internal/component/common/loki/limit"
 	"github.com/grafana/alloy/internal/component/common/loki/wal"
 	"github.com/grafana/alloy/internal/featuregate"
+
+	"github.com/alecthomas/units"
 )
 
 func init() {
@@ -35,6 +37,24 @@ type Arguments struct {
 	ExternalLabels map[string]string `alloy:"external_labels,attr,optional"`
 	MaxStreams     int               `alloy:"max_streams,attr,optional"`
 	WAL            WalArguments      `alloy:"wal,block,optional"`
+	Spool          SpoolArguments    `alloy:"spool,block,optional"`
+}
+
+// Validate implements syntax.Validator.
+func (a *Arguments) Validate() error {
+	if a.WAL.Enabled && a.Spool.Enabled {
+		return fmt.Errorf("the WAL and the spool can't be enabled at the same time")
+	}
+	if a.Spool.Enabled {
+		// The tenant queues drop batches instead of blocking when they're full,
+		// so entries would never be spooled.
+		for _, e := range a.Endpoints {
+			if e.TenantQueues.Enabled {
+				return fmt.Errorf("the spool can't be enabled together with the tenant queues of endpoint %q", e.URL)
+			}
+		}
+	}
+	return nil
 }
 
 // WalArguments holds the settings for configuring the Write-Ahead Log (WAL) used
@@ -66,6 +86,41 @@ func (wa *WalArguments) SetToDefault() {
 	}
 }
 
+// SpoolArguments holds the settings for configuring the spool used to queue
+// log entries on disk when they can't be sent as fast as they're received.
+type SpoolArguments struct {
+	Enabled    bool             `alloy:"enabled,attr,optional"`
+	QueueSize  int              `alloy:"queue_size,attr,optional"`
+	MaxSize    units.Base2Bytes `alloy:"max_size,attr,optional"`
+	FullAction string           `alloy:"full_action,attr,optional"`
+}
+
+// SetToDefault implements syntax.Defaulter.
+func (sa *SpoolArguments) SetToDefault() {
+	*sa = SpoolArguments{
+		Enabled:    false,
+		QueueSize:  client.SpoolQueueSize,
+		MaxSize:    client.SpoolMaxSize,
+		FullAction: client.SpoolFullActionDropNewest,
+	}
+}
+
+// Validate implements syntax.Validator.
+func (sa *SpoolArguments) Validate() error {
+	if sa.QueueSize <= 0 {
+		return fmt.Errorf("spool queue_size must be greater than 0")
+	}
+	if sa.MaxSize <= 0 {
+		return fmt.Errorf("spool max_size must be greater than 0")
+	}
+	switch sa.FullAction {
+	case client.SpoolFullActionDropNewest, client.SpoolFullActionDropOldest:
+	default:
+		return fmt.Errorf("spool full_action must be %q or %q, got %q", client.SpoolFullActionDropNewest, client.SpoolFullActionDropOldest, sa.FullAction)
+	}
+	return nil
+}
+
 // Exports holds the receiver that is used to send log entries to the
 // loki.write component.
 type Exports struct {
@@ -171,6 +226,13 @@ func (c *Component) Update(args component.Arguments) error {
 		}
 		cfgs[i].Headers[alloyseed.LegacyHeaderName] = uid
 		cfgs[i].Headers[alloyseed.HeaderName] = uid
+		cfgs[i].Spool = client.SpoolConfig{
+			Enabled:    newArgs.Spool.Enabled,
+			Dir:        filepath.Join(c.opts.DataPath, "spool"),
+			QueueSize:  newArgs.Spool.QueueSize,
+			MaxSize:    int(newArgs.Spool.MaxSize),
+			FullAction: newArgs.Spool.FullAction,
+		}
 	}
 	walCfg := wal.Config{
 		Enabled:       newArgs.WAL.Enabled,
@@ -10,6 +10,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/alecthomas/units"
 	"github.com/grafana/loki/v3/pkg/logproto"
 	loki_util "github.com/grafana/loki/v3/pkg/util"
 	"github.com/prometheus/common/model"
@@ -17,6 +18,7 @@ import (
 	"go.uber.org/atomic"
 
 	"github.com/grafana/alloy/internal/component/common/loki"
+	"github.com/grafana/alloy/internal/component/common/loki/client"
 	"github.com/grafana/alloy/internal/component/common/loki/wal"
 	"github.com/grafana/alloy/internal/component/discovery"
 	lsf "github.com/grafana/alloy/internal/component/loki/source/file"
@@ -123,6 +125,213 @@ func TestUnmarshallWalAttrributes(t *testing.T) {
 	}
 }
 
+func TestUnmarshallStructuredMetadataAttributes(t *testing.T) {
+	type testcase struct {
+		raw           string
+		errorExpected bool
+		expected      client.StructuredMetadataConfig
+	}
+
+	for name, tc := range map[string]testcase{
+		"default config truncates": {
+			raw: "",
+			expected: client.StructuredMetadataConfig{
+				Action: client.StructuredMetadataActionTruncate,
+			},
+		},
+		"limits and lists": {
+			raw: `
+			structured_metadata {
+				max_keys  = 16
+				max_bytes = "1KiB"
+				action    = "drop"
+				deny      = ["pod_uid"]
+			}
+			`,
+			expected: client.StructuredMetadataConfig{
+				MaxKeys:  16,
+				MaxBytes: 1024,
+				Action:   client.StructuredMetadataActionDrop,
+				Deny:     []string{"pod_uid"},
+			},
+		},
+		"invalid action": {
+			raw: `
+			structured_metadata {
+				action = "reject"
+			}
+			`,
+			errorExpected: true,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			var args Arguments
+			err := syntax.Unmarshal([]byte(`
+			endpoint {
+				url = "http://0.0.0.0:11111/loki/api/v1/push"
+			`+tc.raw+`
+			}`), &args)
+			if tc.errorExpected {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, args.convertClientConfigs()[0].StructuredMetadata)
+		})
+	}
+}
+
+func TestUnmarshallTenantQueuesAttributes(t *testing.T) {
+	type testcase struct {
+		raw           string
+		errorExpected bool
+		expected      client.TenantQueueConfig
+	}
+
+	for name, tc := range map[string]testcase{
+		"default config is disabled": {
+			raw: "",
+			expected: client.TenantQueueConfig{
+				Capacity:           client.TenantQueueCapacity,
+				MaxConcurrentSends: client.TenantQueueMaxConcurrentSends,
+				IdleTimeout:        client.TenantQueueIdleTimeout,
+			},
+		},
+		"rate limits": {
+			raw: `
+			tenant_queues {
+				enabled              = true
+				capacity             = 20
+				rate_limit           = "1MiB"
+				rate_limit_overrides = { "tenant-1" = "4MiB" }
+				idle_timeout         = "1m"
+			}
+			`,
+			expected: client.TenantQueueConfig{
+				Enabled:            true,
+				Capacity:           20,
+				MaxConcurrentSends: client.TenantQueueMaxConcurrentSends,
+				RateLimit:          1024 * 1024,
+				RateLimitOverrides: map[string]int{"tenant-1": 4 * 1024 * 1024},
+				IdleTimeout:        time.Minute,
+			},
+		},
+		"invalid capacity": {
+			raw: `
+			tenant_queues {
+				enabled  = true
+				capacity = 0
+			}
+			`,
+			errorExpected: true,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			var args Arguments
+			err := syntax.Unmarshal([]byte(`
+			endpoint {
+				url = "http://0.0.0.0:11111/loki/api/v1/push"
+			`+tc.raw+`
+			}`), &args)
+			if tc.errorExpected {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, args.convertClientConfigs()[0].TenantQueues)
+		})
+	}
+}
+
+func TestUnmarshallSpoolArguments(t *testing.T) {
+	type testcase struct {
+		raw           string
+		errorExpected bool
+		expected      SpoolArguments
+	}
+
+	for name, tc := range map[string]testcase{
+		"default config": {
+			raw: `
+			spool {
+				enabled = true
+			}
+			`,
+			expected: SpoolArguments{
+				Enabled:    true,
+				QueueSize:  client.SpoolQueueSize,
+				MaxSize:    client.SpoolMaxSize,
+				FullAction: client.SpoolFullActionDropNewest,
+			},
+		},
+		"drop oldest": {
+			raw: `
+			spool {
+				enabled     = true
+				queue_size  = 100
+				max_size    = "10MiB"
+				full_action = "drop_oldest"
+			}
+			`,
+			expected: SpoolArguments{
+				Enabled:    true,
+				QueueSize:  100,
+				MaxSize:    10 * units.MiB,
+				FullAction: client.SpoolFullActionDropOldest,
+			},
+		},
+		"invalid full action": {
+			raw: `
+			spool {
+				enabled     = true
+				full_action = "block"
+			}
+			`,
+			errorExpected: true,
+		},
+		"wal and spool enabled": {
+			raw: `
+			wal {
+				enabled = true
+			}
+			spool {
+				enabled = true
+			}
+			`,
+			errorExpected: true,
+		},
+		"tenant queues and spool enabled": {
+			raw: `
+			endpoint {
+				url = "http://0.0.0.0:11112/loki/api/v1/push"
+				tenant_queues {
+					enabled = true
+				}
+			}
+			spool {
+				enabled = true
+			}
+			`,
+			errorExpected: true,
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			var args Arguments
+			err := syntax.Unmarshal([]byte(`
+			endpoint {
+				url = "http://0.0.0.0:11111/loki/api/v1/push"
+			}
+			`+tc.raw), &args)
+			if tc.errorExpected {
+				require.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, tc.expected, args.Spool)
+		})
+	}
+}
+
 func TestWriteToSingleEndpoint(t *testing.T) {
 	t.Run("wal disabled", func(t *testing.T) {
 		testSingleEndpoint(t, func(args *Arguments) {})
@@ -133,6 +342,12 @@ func TestWriteToSingleEndpoint(t *testing.T) {
 			args.WAL.Enabled = true
 		})
 	})
+
+	t.Run("spool enabled", func(t *testing.T) {
+		testSingleEndpoint(t, func(args *Arguments) {
+			args.Spool.Enabled = true
+		})
+	})
 }
 
 func testSingleEndpoint(t *testing.T, alterConfig func(arguments *Arguments)) {
@@ -272,7 +487,7 @@ func testMultipleEndpoint(t *testing.T, alterArgs func(arguments *Arguments)) {
 
 	go func() {
 		err := ctrl.Run(context.Background(), lsf.Arguments{
-			Targets: []discovery.Target{{"__path__": f.Name(), "somelbl": "somevalue"}},
+			Targets: []discovery.Target{discovery.NewTargetFromMap(map[string]string{"__path__": f.Name(), "somelbl": "somevalue"})},
 			ForwardTo: []loki.LogsReceiver{
 				tc1.Exports().(Exports).Receiver,
 				tc2.Exports().(Exports).Receiver,
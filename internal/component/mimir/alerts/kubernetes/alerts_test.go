@@ -0,0 +1,97 @@
+package alerts
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/stretchr/testify/require"
+
+	"github.com/grafana/alloy/syntax"
+)
+
+func TestAlloyConfig(t *testing.T) {
+	var exampleAlloyConfig = `
+	address = "GRAFANA_CLOUD_METRICS_URL"
+	basic_auth {
+		username = "GRAFANA_CLOUD_USER"
+		password = "GRAFANA_CLOUD_API_KEY"
+	}
+	base_config = "route:\n  receiver: default\nreceivers:\n  - name: default\n"
+	alertmanagerconfig_selector {
+		match_labels = {
+			team = "a",
+		}
+	}
+`
+
+	var args Arguments
+	err := syntax.Unmarshal([]byte(exampleAlloyConfig), &args)
+	require.NoError(t, err)
+}
+
+func TestBadAlloyConfig(t *testing.T) {
+	var exampleAlloyConfig = `
+	address = "GRAFANA_CLOUD_METRICS_URL"
+	bearer_token = "token"
+	bearer_token_file = "/path/to/file.token"
+	base_config = "route:\n  receiver: default\nreceivers:\n  - name: default\n"
+`
+
+	// Make sure the squashed HTTPClientConfig Validate function is being utilized correctly
+	var args Arguments
+	err := syntax.Unmarshal([]byte(exampleAlloyConfig), &args)
+	require.ErrorContains(t, err, "at most one of basic_auth, authorization, oauth2, bearer_token & bearer_token_file must be configured")
+}
+
+func TestInvalidBaseConfig(t *testing.T) {
+	var exampleAlloyConfig = `
+	address = "GRAFANA_CLOUD_METRICS_URL"
+	base_config = "route:\n  receiver: missing\n"
+`
+
+	var args Arguments
+	err := syntax.Unmarshal([]byte(exampleAlloyConfig), &args)
+	require.ErrorContains(t, err, `invalid base_config: undefined receiver "missing"`)
+}
+
+type fakeLeadership struct{}
+
+func (fakeLeadership) Update() (bool, error) { return false, nil }
+func (fakeLeadership) IsLeader() bool        { return true }
+
+type fakeLifecycle struct {
+	restartErr error
+}
+
+func (f *fakeLifecycle) update(Arguments)              {}
+func (f *fakeLifecycle) startup(context.Context) error { return nil }
+func (f *fakeLifecycle) restart(context.Context) error { return f.restartErr }
+func (f *fakeLifecycle) shutdown()                     {}
+func (f *fakeLifecycle) syncState()                    {}
+
+func TestUpdateReturnsRestartError(t *testing.T) {
+	c := &Component{
+		log:           log.NewNopLogger(),
+		configUpdates: make(chan ConfigUpdate),
+		ticker:        time.NewTicker(time.Hour),
+		metrics:       newMetrics(),
+	}
+	defer c.ticker.Stop()
+	state := &fakeLifecycle{restartErr: errors.New("invalid configuration")}
+	health := &fakeHealthReporter{}
+
+	done := make(chan error)
+	go func() { done <- c.iteration(context.Background(), fakeLeadership{}, state, health) }()
+
+	// The error restarting the component is returned by Update, so that it's
+	// reported as the health of the component.
+	require.ErrorIs(t, c.Update(Arguments{}), state.restartErr)
+	require.NoError(t, <-done)
+
+	healthy, err := health.get()
+	require.False(t, healthy)
+	require.ErrorIs(t, err, state.restartErr)
+}
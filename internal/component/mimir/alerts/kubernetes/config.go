@@ -0,0 +1,564 @@
+package alerts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1alpha1"
+	"github.com/prometheus/alertmanager/config"
+	"gopkg.in/yaml.v3"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// namespaceLabel is the label matched by the routes and inhibition rules
+// generated from an AlertmanagerConfig resource, so that the resource only
+// applies to alerts originating from its own namespace.
+const namespaceLabel = "namespace"
+
+// secretGetter reads the value of a key of a Kubernetes secret.
+type secretGetter interface {
+	getSecretKey(ctx context.Context, namespace string, sel corev1.SecretKeySelector) (string, error)
+}
+
+// fragment holds the parts of an Alertmanager configuration generated from a
+// single AlertmanagerConfig resource.
+type fragment struct {
+	route         map[string]interface{}
+	receivers     []interface{}
+	inhibitRules  []interface{}
+	timeIntervals []interface{}
+}
+
+// parseBaseConfig parses and validates the Alertmanager configuration the
+// AlertmanagerConfig resources get merged into.
+func parseBaseConfig(base string) (map[string]interface{}, error) {
+	if _, err := config.Load(base); err != nil {
+		return nil, err
+	}
+
+	var out map[string]interface{}
+	if err := yaml.Unmarshal([]byte(base), &out); err != nil {
+		return nil, err
+	}
+	if _, ok := out["route"].(map[string]interface{}); !ok {
+		return nil, errors.New("a root route is required")
+	}
+	return out, nil
+}
+
+// mergeConfig adds the fragments to the base configuration and returns the
+// resulting Alertmanager configuration, after validating it. The routes of
+// the fragments are evaluated before the ones of the base configuration.
+func mergeConfig(base string, fragments []*fragment) (string, error) {
+	cfg, err := parseBaseConfig(base)
+	if err != nil {
+		return "", fmt.Errorf("invalid base configuration: %w", err)
+	}
+
+	root := cfg["route"].(map[string]interface{})
+
+	var routes []interface{}
+	for _, f := range fragments {
+		if f.route != nil {
+			routes = append(routes, f.route)
+		}
+		cfg["receivers"] = appendList(cfg["receivers"], f.receivers)
+		cfg["inhibit_rules"] = appendList(cfg["inhibit_rules"], f.inhibitRules)
+		cfg["time_intervals"] = appendList(cfg["time_intervals"], f.timeIntervals)
+	}
+	root["routes"] = appendList(routes, root["routes"])
+
+	for _, key := range []string{"receivers", "inhibit_rules", "time_intervals"} {
+		if list, ok := cfg[key].([]interface{}); ok && len(list) == 0 {
+			delete(cfg, key)
+		}
+	}
+	if len(root["routes"].([]interface{})) == 0 {
+		delete(root, "routes")
+	}
+
+	out, err := yaml.Marshal(cfg)
+	if err != nil {
+		return "", err
+	}
+	if _, err := config.Load(string(out)); err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+func appendList(list interface{}, items interface{}) []interface{} {
+	out, _ := list.([]interface{})
+	more, _ := items.([]interface{})
+	return append(out, more...)
+}
+
+// converter converts an AlertmanagerConfig resource into a fragment of
+// Alertmanager configuration. Receivers and time intervals are renamed to
+// <namespace>/<name>/<original name> to avoid collisions between resources.
+type converter struct {
+	crd     *v1alpha1.AlertmanagerConfig
+	secrets secretGetter
+}
+
+func convertCRD(ctx context.Context, crd *v1alpha1.AlertmanagerConfig, secrets secretGetter) (*fragment, error) {
+	c := &converter{crd: crd, secrets: secrets}
+	out := &fragment{}
+
+	if crd.Spec.Route != nil {
+		route, err := c.convertRoute(crd.Spec.Route, true)
+		if err != nil {
+			return nil, fmt.Errorf("route: %w", err)
+		}
+		out.route = route
+	}
+
+	for _, r := range crd.Spec.Receivers {
+		receiver, err := c.convertReceiver(ctx, r)
+		if err != nil {
+			return nil, fmt.Errorf("receiver %q: %w", r.Name, err)
+		}
+		out.receivers = append(out.receivers, receiver)
+	}
+
+	for _, r := range crd.Spec.InhibitRules {
+		out.inhibitRules = append(out.inhibitRules, map[string]interface{}{
+			"source_matchers": c.namespacedMatchers(r.SourceMatch),
+			"target_matchers": c.namespacedMatchers(r.TargetMatch),
+			"equal":           r.Equal,
+		})
+	}
+
+	for _, ti := range crd.Spec.MuteTimeIntervals {
+		out.timeIntervals = append(out.timeIntervals, c.convertTimeInterval(ti))
+	}
+
+	return out, nil
+}
+
+func (c *converter) name(name string) string {
+	return fmt.Sprintf("%s/%s/%s", c.crd.Namespace, c.crd.Name, name)
+}
+
+func (c *converter) names(names []string) []string {
+	if len(names) == 0 {
+		return nil
+	}
+	out := make([]string, 0, len(names))
+	for _, name := range names {
+		out = append(out, c.name(name))
+	}
+	return out
+}
+
+// namespacedMatchers converts the matchers and prepends the namespace matcher
+// to them.
+func (c *converter) namespacedMatchers(matchers []v1alpha1.Matcher) []string {
+	namespace := v1alpha1.Matcher{Name: namespaceLabel, Value: c.crd.Namespace, MatchType: v1alpha1.MatchEqual}
+	return append([]string{namespace.String()}, convertMatchers(matchers)...)
+}
+
+func convertMatchers(matchers []v1alpha1.Matcher) []string {
+	var out []string
+	for _, m := range matchers {
+		if m.MatchType == "" {
+			// Regex is deprecated in favour of MatchType but still honoured.
+			m.MatchType = v1alpha1.MatchEqual
+			if m.Regex {
+				m.MatchType = v1alpha1.MatchRegexp
+			}
+		}
+		out = append(out, m.String())
+	}
+	return out
+}
+
+func (c *converter) convertRoute(r *v1alpha1.Route, top bool) (map[string]interface{}, error) {
+	out := map[string]interface{}{}
+	if r.Receiver != "" {
+		out["receiver"] = c.name(r.Receiver)
+	}
+	if len(r.GroupBy) > 0 {
+		out["group_by"] = r.GroupBy
+	}
+	setIfNotEmpty(out, "group_wait", r.GroupWait)
+	setIfNotEmpty(out, "group_interval", r.GroupInterval)
+	setIfNotEmpty(out, "repeat_interval", r.RepeatInterval)
+	if names := c.names(r.MuteTimeIntervals); names != nil {
+		out["mute_time_intervals"] = names
+	}
+	if names := c.names(r.ActiveTimeIntervals); names != nil {
+		out["active_time_intervals"] = names
+	}
+
+	if top {
+		// The top-level route only applies to the namespace of the resource and
+		// must not prevent other resources from matching the alerts.
+		out["matchers"] = c.namespacedMatchers(r.Matchers)
+		out["continue"] = true
+	} else {
+		if matchers := convertMatchers(r.Matchers); len(matchers) > 0 {
+			out["matchers"] = matchers
+		}
+		if r.Continue {
+			out["continue"] = true
+		}
+	}
+
+	children, err := r.ChildRoutes()
+	if err != nil {
+		return nil, err
+	}
+	var routes []interface{}
+	for i := range children {
+		child, err := c.convertRoute(&children[i], false)
+		if err != nil {
+			return nil, err
+		}
+		routes = append(routes, child)
+	}
+	if len(routes) > 0 {
+		out["routes"] = routes
+	}
+
+	return out, nil
+}
+
+func (c *converter) convertTimeInterval(ti v1alpha1.MuteTimeInterval) map[string]interface{} {
+	var intervals []interface{}
+	for _, interval := range ti.TimeIntervals {
+		out := map[string]interface{}{}
+
+		var times []interface{}
+		for _, t := range interval.Times {
+			times = append(times, map[string]interface{}{
+				"start_time": string(t.StartTime),
+				"end_time":   string(t.EndTime),
+			})
+		}
+		if len(times) > 0 {
+			out["times"] = times
+		}
+
+		var weekdays, daysOfMonth, months, years []string
+		for _, w := range interval.Weekdays {
+			weekdays = append(weekdays, string(w))
+		}
+		for _, d := range interval.DaysOfMonth {
+			if d.End == 0 {
+				daysOfMonth = append(daysOfMonth, fmt.Sprint(d.Start))
+			} else {
+				daysOfMonth = append(daysOfMonth, fmt.Sprintf("%d:%d", d.Start, d.End))
+			}
+		}
+		for _, m := range interval.Months {
+			months = append(months, string(m))
+		}
+		for _, y := range interval.Years {
+			years = append(years, string(y))
+		}
+		for key, values := range map[string][]string{
+			"weekdays":      weekdays,
+			"days_of_month": daysOfMonth,
+			"months":        months,
+			"years":         years,
+		} {
+			if len(values) > 0 {
+				out[key] = values
+			}
+		}
+
+		intervals = append(intervals, out)
+	}
+
+	return map[string]interface{}{
+		"name":           c.name(ti.Name),
+		"time_intervals": intervals,
+	}
+}
+
+func (c *converter) convertReceiver(ctx context.Context, r v1alpha1.Receiver) (map[string]interface{}, error) {
+	switch {
+	case len(r.OpsGenieConfigs) > 0:
+		return nil, errors.New("opsgenieConfigs are not supported")
+	case len(r.WeChatConfigs) > 0:
+		return nil, errors.New("wechatConfigs are not supported")
+	case len(r.VictorOpsConfigs) > 0:
+		return nil, errors.New("victoropsConfigs are not supported")
+	case len(r.PushoverConfigs) > 0:
+		return nil, errors.New("pushoverConfigs are not supported")
+	case len(r.SNSConfigs) > 0:
+		return nil, errors.New("snsConfigs are not supported")
+	case len(r.TelegramConfigs) > 0:
+		return nil, errors.New("telegramConfigs are not supported")
+	}
+
+	out := map[string]interface{}{"name": c.name(r.Name)}
+
+	var webhooks []interface{}
+	for _, w := range r.WebhookConfigs {
+		webhook, err := c.convertWebhook(ctx, w)
+		if err != nil {
+			return nil, fmt.Errorf("webhook config: %w", err)
+		}
+		webhooks = append(webhooks, webhook)
+	}
+	if len(webhooks) > 0 {
+		out["webhook_configs"] = webhooks
+	}
+
+	var slacks []interface{}
+	for _, s := range r.SlackConfigs {
+		slack, err := c.convertSlack(ctx, s)
+		if err != nil {
+			return nil, fmt.Errorf("slack config: %w", err)
+		}
+		slacks = append(slacks, slack)
+	}
+	if len(slacks) > 0 {
+		out["slack_configs"] = slacks
+	}
+
+	var pagerDuties []interface{}
+	for _, p := range r.PagerDutyConfigs {
+		pagerDuty, err := c.convertPagerDuty(ctx, p)
+		if err != nil {
+			return nil, fmt.Errorf("pagerduty config: %w", err)
+		}
+		pagerDuties = append(pagerDuties, pagerDuty)
+	}
+	if len(pagerDuties) > 0 {
+		out["pagerduty_configs"] = pagerDuties
+	}
+
+	var emails []interface{}
+	for _, e := range r.EmailConfigs {
+		email, err := c.convertEmail(ctx, e)
+		if err != nil {
+			return nil, fmt.Errorf("email config: %w", err)
+		}
+		emails = append(emails, email)
+	}
+	if len(emails) > 0 {
+		out["email_configs"] = emails
+	}
+
+	return out, nil
+}
+
+func (c *converter) convertWebhook(ctx context.Context, w v1alpha1.WebhookConfig) (map[string]interface{}, error) {
+	if w.HTTPConfig != nil {
+		return nil, errors.New("httpConfig is not supported")
+	}
+
+	out := map[string]interface{}{}
+	setSendResolved(out, w.SendResolved)
+
+	switch {
+	case w.URL != nil:
+		out["url"] = *w.URL
+	case w.URLSecret != nil:
+		url, err := c.secrets.getSecretKey(ctx, c.crd.Namespace, *w.URLSecret)
+		if err != nil {
+			return nil, err
+		}
+		out["url"] = url
+	default:
+		return nil, errors.New("one of url or urlSecret is required")
+	}
+
+	if w.MaxAlerts > 0 {
+		out["max_alerts"] = w.MaxAlerts
+	}
+	return out, nil
+}
+
+func (c *converter) convertSlack(ctx context.Context, s v1alpha1.SlackConfig) (map[string]interface{}, error) {
+	if s.HTTPConfig != nil {
+		return nil, errors.New("httpConfig is not supported")
+	}
+	if len(s.Actions) > 0 {
+		return nil, errors.New("actions are not supported")
+	}
+
+	out := map[string]interface{}{}
+	setSendResolved(out, s.SendResolved)
+
+	// Without apiURL, the slack_api_url of the global section of the base
+	// configuration is used.
+	if s.APIURL != nil {
+		url, err := c.secrets.getSecretKey(ctx, c.crd.Namespace, *s.APIURL)
+		if err != nil {
+			return nil, err
+		}
+		out["api_url"] = url
+	}
+
+	setIfNotEmpty(out, "channel", s.Channel)
+	setIfNotEmpty(out, "username", s.Username)
+	setIfNotEmpty(out, "color", s.Color)
+	setIfNotEmpty(out, "title", s.Title)
+	setIfNotEmpty(out, "title_link", s.TitleLink)
+	setIfNotEmpty(out, "pretext", s.Pretext)
+	setIfNotEmpty(out, "text", s.Text)
+	setIfNotEmpty(out, "footer", s.Footer)
+	setIfNotEmpty(out, "fallback", s.Fallback)
+	setIfNotEmpty(out, "callback_id", s.CallbackID)
+	setIfNotEmpty(out, "icon_emoji", s.IconEmoji)
+	setIfNotEmpty(out, "icon_url", s.IconURL)
+	setIfNotEmpty(out, "image_url", s.ImageURL)
+	setIfNotEmpty(out, "thumb_url", s.ThumbURL)
+	if s.ShortFields {
+		out["short_fields"] = true
+	}
+	if s.LinkNames {
+		out["link_names"] = true
+	}
+	if len(s.MrkdwnIn) > 0 {
+		out["mrkdwn_in"] = s.MrkdwnIn
+	}
+
+	var fields []interface{}
+	for _, f := range s.Fields {
+		field := map[string]interface{}{"title": f.Title, "value": f.Value}
+		if f.Short != nil {
+			field["short"] = *f.Short
+		}
+		fields = append(fields, field)
+	}
+	if len(fields) > 0 {
+		out["fields"] = fields
+	}
+
+	return out, nil
+}
+
+func (c *converter) convertPagerDuty(ctx context.Context, p v1alpha1.PagerDutyConfig) (map[string]interface{}, error) {
+	if p.HTTPConfig != nil {
+		return nil, errors.New("httpConfig is not supported")
+	}
+
+	out := map[string]interface{}{}
+	setSendResolved(out, p.SendResolved)
+
+	switch {
+	case p.RoutingKey != nil:
+		key, err := c.secrets.getSecretKey(ctx, c.crd.Namespace, *p.RoutingKey)
+		if err != nil {
+			return nil, err
+		}
+		out["routing_key"] = key
+	case p.ServiceKey != nil:
+		key, err := c.secrets.getSecretKey(ctx, c.crd.Namespace, *p.ServiceKey)
+		if err != nil {
+			return nil, err
+		}
+		out["service_key"] = key
+	default:
+		return nil, errors.New("one of routingKey or serviceKey is required")
+	}
+
+	setIfNotEmpty(out, "url", p.URL)
+	setIfNotEmpty(out, "client", p.Client)
+	setIfNotEmpty(out, "client_url", p.ClientURL)
+	setIfNotEmpty(out, "description", p.Description)
+	setIfNotEmpty(out, "severity", p.Severity)
+	setIfNotEmpty(out, "class", p.Class)
+	setIfNotEmpty(out, "group", p.Group)
+	setIfNotEmpty(out, "component", p.Component)
+	if details := keyValues(p.Details); details != nil {
+		out["details"] = details
+	}
+
+	var images []interface{}
+	for _, i := range p.PagerDutyImageConfigs {
+		image := map[string]interface{}{}
+		setIfNotEmpty(image, "src", i.Src)
+		setIfNotEmpty(image, "href", i.Href)
+		setIfNotEmpty(image, "alt", i.Alt)
+		images = append(images, image)
+	}
+	if len(images) > 0 {
+		out["images"] = images
+	}
+
+	var links []interface{}
+	for _, l := range p.PagerDutyLinkConfigs {
+		link := map[string]interface{}{}
+		setIfNotEmpty(link, "href", l.Href)
+		setIfNotEmpty(link, "text", l.Text)
+		links = append(links, link)
+	}
+	if len(links) > 0 {
+		out["links"] = links
+	}
+
+	return out, nil
+}
+
+func (c *converter) convertEmail(ctx context.Context, e v1alpha1.EmailConfig) (map[string]interface{}, error) {
+	if e.TLSConfig != nil {
+		return nil, errors.New("tlsConfig is not supported")
+	}
+	if e.To == "" {
+		return nil, errors.New("to is required")
+	}
+
+	out := map[string]interface{}{"to": e.To}
+	setSendResolved(out, e.SendResolved)
+
+	setIfNotEmpty(out, "from", e.From)
+	setIfNotEmpty(out, "hello", e.Hello)
+	setIfNotEmpty(out, "smarthost", e.Smarthost)
+	setIfNotEmpty(out, "auth_username", e.AuthUsername)
+	setIfNotEmpty(out, "auth_identity", e.AuthIdentity)
+	setIfNotEmpty(out, "html", e.HTML)
+	setIfNotEmpty(out, "text", e.Text)
+	if e.RequireTLS != nil {
+		out["require_tls"] = *e.RequireTLS
+	}
+	if headers := keyValues(e.Headers); headers != nil {
+		out["headers"] = headers
+	}
+
+	if e.AuthPassword != nil {
+		password, err := c.secrets.getSecretKey(ctx, c.crd.Namespace, *e.AuthPassword)
+		if err != nil {
+			return nil, err
+		}
+		out["auth_password"] = password
+	}
+	if e.AuthSecret != nil {
+		secret, err := c.secrets.getSecretKey(ctx, c.crd.Namespace, *e.AuthSecret)
+		if err != nil {
+			return nil, err
+		}
+		out["auth_secret"] = secret
+	}
+
+	return out, nil
+}
+
+func setIfNotEmpty(m map[string]interface{}, key, value string) {
+	if value != "" {
+		m[key] = value
+	}
+}
+
+func setSendResolved(m map[string]interface{}, sendResolved *bool) {
+	if sendResolved != nil {
+		m["send_resolved"] = *sendResolved
+	}
+}
+
+func keyValues(kvs []v1alpha1.KeyValue) map[string]string {
+	if len(kvs) == 0 {
+		return nil
+	}
+	out := make(map[string]string, len(kvs))
+	for _, kv := range kvs {
+		out[kv.Key] = kv.Value
+	}
+	return out
+}
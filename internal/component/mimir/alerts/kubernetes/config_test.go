@@ -0,0 +1,211 @@
+package alerts
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+
+	"github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1alpha1"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const testBaseConfig = `
+route:
+  receiver: default
+  routes:
+    - receiver: default
+      matchers:
+        - severity="info"
+receivers:
+  - name: default
+`
+
+type fakeSecretGetter map[string]string
+
+func (f fakeSecretGetter) getSecretKey(_ context.Context, namespace string, sel corev1.SecretKeySelector) (string, error) {
+	value, ok := f[namespace+"/"+sel.Name+"/"+sel.Key]
+	if !ok {
+		return "", fmt.Errorf("secret %s/%s not found", namespace, sel.Name)
+	}
+	return value, nil
+}
+
+func secretKey(name, key string) *corev1.SecretKeySelector {
+	return &corev1.SecretKeySelector{LocalObjectReference: corev1.LocalObjectReference{Name: name}, Key: key}
+}
+
+func testCRD() *v1alpha1.AlertmanagerConfig {
+	sendResolved := true
+	crd := &v1alpha1.AlertmanagerConfig{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: "team-a",
+			Name:      "alerts",
+		},
+		Spec: v1alpha1.AlertmanagerConfigSpec{
+			Route: &v1alpha1.Route{
+				Receiver: "webhook",
+				GroupBy:  []string{"alertname"},
+				Matchers: []v1alpha1.Matcher{{Name: "severity", Value: "critical|warning", Regex: true}},
+			},
+			Receivers: []v1alpha1.Receiver{
+				{
+					Name: "webhook",
+					WebhookConfigs: []v1alpha1.WebhookConfig{{
+						URLSecret:    secretKey("webhook", "url"),
+						SendResolved: &sendResolved,
+						MaxAlerts:    10,
+					}},
+				},
+				{
+					Name: "slack",
+					SlackConfigs: []v1alpha1.SlackConfig{{
+						APIURL:  secretKey("slack", "url"),
+						Channel: "#alerts",
+					}},
+				},
+			},
+			InhibitRules: []v1alpha1.InhibitRule{{
+				SourceMatch: []v1alpha1.Matcher{{Name: "severity", Value: "critical", MatchType: v1alpha1.MatchEqual}},
+				TargetMatch: []v1alpha1.Matcher{{Name: "severity", Value: "warning", MatchType: v1alpha1.MatchEqual}},
+				Equal:       []string{"alertname"},
+			}},
+			MuteTimeIntervals: []v1alpha1.MuteTimeInterval{{
+				Name: "weekend",
+				TimeIntervals: []v1alpha1.TimeInterval{{
+					Weekdays:    []v1alpha1.WeekdayRange{"saturday", "sunday"},
+					DaysOfMonth: []v1alpha1.DayOfMonthRange{{Start: 1, End: 7}},
+				}},
+			}},
+		},
+	}
+
+	// Child routes are stored as raw JSON.
+	childRoutes := `{"routes":[{"receiver":"slack","matchers":[{"name":"team","value":"db","matchType":"!="}],"muteTimeIntervals":["weekend"]}]}`
+	if err := json.Unmarshal([]byte(childRoutes), crd.Spec.Route); err != nil {
+		panic(err)
+	}
+	return crd
+}
+
+func TestMergeConfig(t *testing.T) {
+	secrets := fakeSecretGetter{
+		"team-a/webhook/url": "http://webhook.team-a.svc/alerts",
+		"team-a/slack/url":   "https://hooks.slack.com/services/xxx",
+	}
+
+	f, err := convertCRD(context.Background(), testCRD(), secrets)
+	require.NoError(t, err)
+
+	actual, err := mergeConfig(testBaseConfig, []*fragment{f})
+	require.NoError(t, err)
+	require.YAMLEq(t, `
+route:
+  receiver: default
+  routes:
+    - receiver: team-a/alerts/webhook
+      group_by: [alertname]
+      matchers:
+        - namespace="team-a"
+        - severity=~"critical|warning"
+      continue: true
+      routes:
+        - receiver: team-a/alerts/slack
+          matchers:
+            - team!="db"
+          mute_time_intervals:
+            - team-a/alerts/weekend
+    - receiver: default
+      matchers:
+        - severity="info"
+receivers:
+  - name: default
+  - name: team-a/alerts/webhook
+    webhook_configs:
+      - url: http://webhook.team-a.svc/alerts
+        send_resolved: true
+        max_alerts: 10
+  - name: team-a/alerts/slack
+    slack_configs:
+      - api_url: https://hooks.slack.com/services/xxx
+        channel: '#alerts'
+inhibit_rules:
+  - source_matchers:
+      - namespace="team-a"
+      - severity="critical"
+    target_matchers:
+      - namespace="team-a"
+      - severity="warning"
+    equal: [alertname]
+time_intervals:
+  - name: team-a/alerts/weekend
+    time_intervals:
+      - weekdays: [saturday, sunday]
+        days_of_month: ['1:7']
+`, actual)
+}
+
+func TestMergeConfig_NoFragments(t *testing.T) {
+	actual, err := mergeConfig(testBaseConfig, nil)
+	require.NoError(t, err)
+	require.YAMLEq(t, testBaseConfig, actual)
+}
+
+func TestConvertCRD_Errors(t *testing.T) {
+	tests := []struct {
+		name     string
+		modify   func(crd *v1alpha1.AlertmanagerConfig)
+		expected string
+	}{
+		{
+			name: "missing secret",
+			modify: func(crd *v1alpha1.AlertmanagerConfig) {
+				crd.Spec.Receivers[0].WebhookConfigs[0].URLSecret = secretKey("missing", "url")
+			},
+			expected: `receiver "webhook": webhook config: secret team-a/missing not found`,
+		},
+		{
+			name: "unsupported receiver",
+			modify: func(crd *v1alpha1.AlertmanagerConfig) {
+				crd.Spec.Receivers[1].OpsGenieConfigs = []v1alpha1.OpsGenieConfig{{}}
+			},
+			expected: `receiver "slack": opsgenieConfigs are not supported`,
+		},
+		{
+			name: "http config",
+			modify: func(crd *v1alpha1.AlertmanagerConfig) {
+				crd.Spec.Receivers[0].WebhookConfigs[0].HTTPConfig = &v1alpha1.HTTPConfig{}
+			},
+			expected: `receiver "webhook": webhook config: httpConfig is not supported`,
+		},
+	}
+
+	secrets := fakeSecretGetter{
+		"team-a/webhook/url": "http://webhook.team-a.svc/alerts",
+		"team-a/slack/url":   "https://hooks.slack.com/services/xxx",
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			crd := testCRD()
+			tc.modify(crd)
+			_, err := convertCRD(context.Background(), crd, secrets)
+			require.EqualError(t, err, tc.expected)
+		})
+	}
+}
+
+func TestMergeConfig_UndefinedReceiver(t *testing.T) {
+	crd := testCRD()
+	crd.Spec.Route.Receiver = "missing"
+
+	f, err := convertCRD(context.Background(), crd, fakeSecretGetter{
+		"team-a/webhook/url": "http://webhook.team-a.svc/alerts",
+		"team-a/slack/url":   "https://hooks.slack.com/services/xxx",
+	})
+	require.NoError(t, err)
+
+	_, err = mergeConfig(testBaseConfig, []*fragment{f})
+	require.ErrorContains(t, err, `undefined receiver "team-a/alerts/missing"`)
+}
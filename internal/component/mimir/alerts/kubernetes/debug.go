@@ -0,0 +1,51 @@
+package alerts
+
+import "fmt"
+
+type DebugInfo struct {
+	Error               string                       `alloy:"error,attr,optional"`
+	IsLeader            bool                         `alloy:"is_leader,attr"`
+	MimirConfigured     bool                         `alloy:"mimir_configured,attr"`
+	AlertmanagerConfigs []DebugK8sAlertmanagerConfig `alloy:"alertmanager_config,block,optional"`
+}
+
+type DebugK8sAlertmanagerConfig struct {
+	Namespace    string `alloy:"namespace,attr"`
+	Name         string `alloy:"name,attr"`
+	UID          string `alloy:"uid,attr"`
+	NumReceivers int    `alloy:"num_receivers,attr"`
+	Error        string `alloy:"error,attr,optional"`
+}
+
+func (c *Component) DebugInfo() interface{} {
+	output := DebugInfo{IsLeader: c.leader.IsLeader()}
+
+	// Resources are only loaded by the leader of the cluster.
+	if c.eventProcessor == nil {
+		return output
+	}
+
+	output.MimirConfigured = c.eventProcessor.getMimirState() != nil
+
+	// This should load from the informer cache, so it shouldn't fail under normal circumstances.
+	configs, err := c.eventProcessor.getKubernetesState()
+	if err != nil {
+		return DebugInfo{IsLeader: output.IsLeader, Error: fmt.Sprintf("failed to list alertmanager configs: %v", err)}
+	}
+
+	configErrors := c.eventProcessor.getConfigErrors()
+	for _, cfg := range configs {
+		info := DebugK8sAlertmanagerConfig{
+			Namespace:    cfg.Namespace,
+			Name:         cfg.Name,
+			UID:          string(cfg.UID),
+			NumReceivers: len(cfg.Spec.Receivers),
+		}
+		if err := configErrors[cfg.Namespace+"/"+cfg.Name]; err != nil {
+			info.Error = err.Error()
+		}
+		output.AlertmanagerConfigs = append(output.AlertmanagerConfigs, info)
+	}
+
+	return output
+}
@@ -0,0 +1,296 @@
+package alerts
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/alloy/internal/component/common/kubernetes"
+	"github.com/grafana/alloy/internal/mimir/client"
+	"github.com/grafana/alloy/internal/runtime/logging/level"
+	"github.com/prometheus-operator/prometheus-operator/pkg/apis/monitoring/v1alpha1"
+	promListers "github.com/prometheus-operator/prometheus-operator/pkg/client/listers/monitoring/v1alpha1"
+	"k8s.io/apimachinery/pkg/labels"
+	coreListers "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/util/workqueue"
+)
+
+const (
+	eventTypeSyncMimir kubernetes.EventType = "sync-mimir"
+)
+
+// invalidConfigsError reports AlertmanagerConfig resources which were left out
+// of the Alertmanager configuration. Retrying does not help: the resources
+// must be fixed first.
+type invalidConfigsError struct {
+	errs map[string]error
+}
+
+func (e *invalidConfigsError) Error() string {
+	keys := make([]string, 0, len(e.errs))
+	for key := range e.errs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var errs []error
+	for _, key := range keys {
+		errs = append(errs, fmt.Errorf("%s: %w", key, e.errs[key]))
+	}
+	return fmt.Sprintf("ignored %d invalid AlertmanagerConfig resources: %v", len(keys), errors.Join(errs...))
+}
+
+type eventProcessor struct {
+	queue    workqueue.RateLimitingInterface
+	stopChan chan struct{}
+	health   healthReporter
+
+	mimirClient       client.AlertmanagerInterface
+	secrets           secretGetter
+	namespaceLister   coreListers.NamespaceLister
+	configLister      promListers.AlertmanagerConfigLister
+	namespaceSelector labels.Selector
+	configSelector    labels.Selector
+	baseConfig        string
+	templateFiles     map[string]string
+
+	metrics *metrics
+	logger  log.Logger
+
+	currentState    *client.AlertmanagerConfig
+	configErrors    map[string]error
+	currentStateMtx sync.RWMutex
+}
+
+// run processes events added to the queue until the queue is shutdown.
+func (e *eventProcessor) run(ctx context.Context) {
+	for {
+		eventInterface, shutdown := e.queue.Get()
+		if shutdown {
+			level.Info(e.logger).Log("msg", "shutting down event loop")
+			return
+		}
+
+		evt := eventInterface.(kubernetes.Event)
+		e.metrics.eventsTotal.WithLabelValues(string(evt.Typ)).Inc()
+		err := e.processEvent(ctx, evt)
+
+		var invalidErr *invalidConfigsError
+		if err != nil {
+			retries := e.queue.NumRequeues(evt)
+			if retries < 5 && client.IsRecoverable(err) && !errors.As(err, &invalidErr) {
+				e.metrics.eventsRetried.WithLabelValues(string(evt.Typ)).Inc()
+				e.queue.AddRateLimited(evt)
+				level.Error(e.logger).Log(
+					"msg", "failed to process event, will retry",
+					"retries", fmt.Sprintf("%d/5", retries),
+					"err", err,
+				)
+				continue
+			} else {
+				e.metrics.eventsFailed.WithLabelValues(string(evt.Typ)).Inc()
+				level.Error(e.logger).Log(
+					"msg", "failed to process event, unrecoverable error or max retries exceeded",
+					"retries", fmt.Sprintf("%d/5", retries),
+					"err", err,
+				)
+				e.health.reportUnhealthy(err)
+			}
+		} else {
+			e.health.reportHealthy()
+		}
+
+		e.queue.Forget(evt)
+	}
+}
+
+// stop stops adding new Kubernetes events to the queue and blocks until all existing
+// events have been processed by the run loop.
+func (e *eventProcessor) stop() {
+	close(e.stopChan)
+	// Because this method blocks until the queue is empty, it's important that we don't
+	// stop the run loop and let it continue to process existing items in the queue.
+	e.queue.ShutDownWithDrain()
+}
+
+func (e *eventProcessor) processEvent(ctx context.Context, event kubernetes.Event) error {
+	defer e.queue.Done(event)
+
+	switch event.Typ {
+	case kubernetes.EventTypeResourceChanged:
+		level.Info(e.logger).Log("msg", "processing event", "type", event.Typ, "key", event.ObjectKey)
+	case eventTypeSyncMimir:
+		level.Debug(e.logger).Log("msg", "syncing current state from alertmanager")
+		err := e.syncMimir(ctx)
+		if err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("unknown event type: %s", event.Typ)
+	}
+
+	return e.reconcileState(ctx)
+}
+
+// enqueueSyncMimir requests a sync of the Alertmanager configuration from Mimir.
+// Since secrets referenced by AlertmanagerConfig resources are not watched, this
+// is also what picks up their changes.
+func (e *eventProcessor) enqueueSyncMimir() {
+	e.queue.Add(kubernetes.Event{
+		Typ: eventTypeSyncMimir,
+	})
+}
+
+func (e *eventProcessor) syncMimir(ctx context.Context) error {
+	cfg, err := e.mimirClient.GetAlertmanagerConfig(ctx)
+	if err != nil {
+		level.Error(e.logger).Log("msg", "failed to get alertmanager configuration from mimir", "err", err)
+		return err
+	}
+
+	e.currentStateMtx.Lock()
+	e.currentState = cfg
+	e.currentStateMtx.Unlock()
+
+	return nil
+}
+
+func (e *eventProcessor) reconcileState(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	desired, configErrors, err := e.desiredStateFromKubernetes(ctx)
+	if err != nil {
+		return err
+	}
+
+	e.currentStateMtx.Lock()
+	e.configErrors = configErrors
+	e.currentStateMtx.Unlock()
+
+	if current := e.getMimirState(); !alertmanagerConfigEqual(current, desired) {
+		if err := e.mimirClient.CreateAlertmanagerConfig(ctx, *desired); err != nil {
+			return err
+		}
+		level.Info(e.logger).Log("msg", "updated alertmanager configuration")
+
+		// resync mimir state after applying changes
+		if err := e.syncMimir(ctx); err != nil {
+			return err
+		}
+	}
+
+	if len(configErrors) > 0 {
+		return &invalidConfigsError{errs: configErrors}
+	}
+	return nil
+}
+
+// desiredStateFromKubernetes loads AlertmanagerConfig resources from Kubernetes
+// and merges them into the base configuration. Resources which can't be
+// converted or would make the configuration invalid are left out and returned
+// in a map indexed by <namespace>/<name>.
+func (e *eventProcessor) desiredStateFromKubernetes(ctx context.Context) (*client.AlertmanagerConfig, map[string]error, error) {
+	kubernetesState, err := e.getKubernetesState()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var (
+		fragments    []*fragment
+		configErrors = make(map[string]error)
+	)
+	for _, crd := range kubernetesState {
+		key := crd.Namespace + "/" + crd.Name
+
+		f, err := convertCRD(ctx, crd, e.secrets)
+		if err == nil {
+			// Check the resource on its own, so that a single invalid resource
+			// does not prevent the others from being applied.
+			_, err = mergeConfig(e.baseConfig, []*fragment{f})
+		}
+		if err != nil {
+			level.Warn(e.logger).Log("msg", "ignoring invalid AlertmanagerConfig", "key", key, "err", err)
+			configErrors[key] = err
+			continue
+		}
+		fragments = append(fragments, f)
+	}
+
+	cfg, err := mergeConfig(e.baseConfig, fragments)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to generate alertmanager configuration: %w", err)
+	}
+
+	return &client.AlertmanagerConfig{
+		TemplateFiles:      e.templateFiles,
+		AlertmanagerConfig: cfg,
+	}, configErrors, nil
+}
+
+func alertmanagerConfigEqual(a, b *client.AlertmanagerConfig) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	if a.AlertmanagerConfig != b.AlertmanagerConfig || len(a.TemplateFiles) != len(b.TemplateFiles) {
+		return false
+	}
+	for name, content := range a.TemplateFiles {
+		if other, ok := b.TemplateFiles[name]; !ok || other != content {
+			return false
+		}
+	}
+	return true
+}
+
+// getMimirState returns the cached Alertmanager configuration of Mimir, which
+// is nil if the tenant has none.
+func (e *eventProcessor) getMimirState() *client.AlertmanagerConfig {
+	e.currentStateMtx.RLock()
+	defer e.currentStateMtx.RUnlock()
+	return e.currentState
+}
+
+// getConfigErrors returns the AlertmanagerConfig resources ignored during the
+// last reconciliation, indexed by <namespace>/<name>.
+func (e *eventProcessor) getConfigErrors() map[string]error {
+	e.currentStateMtx.RLock()
+	defer e.currentStateMtx.RUnlock()
+
+	out := make(map[string]error, len(e.configErrors))
+	for key, err := range e.configErrors {
+		out[key] = err
+	}
+	return out
+}
+
+// getKubernetesState returns the AlertmanagerConfig resources sorted by
+// namespace and name, which is the order their routes are evaluated in.
+func (e *eventProcessor) getKubernetesState() ([]*v1alpha1.AlertmanagerConfig, error) {
+	namespaces, err := e.namespaceLister.List(e.namespaceSelector)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list namespaces: %w", err)
+	}
+
+	var out []*v1alpha1.AlertmanagerConfig
+	for _, namespace := range namespaces {
+		configs, err := e.configLister.AlertmanagerConfigs(namespace.Name).List(e.configSelector)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list alertmanager configs: %w", err)
+		}
+
+		out = append(out, configs...)
+	}
+
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Namespace != out[j].Namespace {
+			return out[i].Namespace < out[j].Namespace
+		}
+		return out[i].Name < out[j].Name
+	})
+	return out, nil
+}
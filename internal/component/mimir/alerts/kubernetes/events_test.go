@@ -0,0 +1,172 @@
+package alerts
+
+import (
+	"context"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/alloy/internal/component/common/kubernetes"
+	mimirClient "github.com/grafana/alloy/internal/mimir/client"
+	promListers "github.com/prometheus-operator/prometheus-operator/pkg/client/listers/monitoring/v1alpha1"
+	"github.com/stretchr/testify/require"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	coreListers "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+	"k8s.io/client-go/util/workqueue"
+)
+
+type fakeMimirClient struct {
+	mut     sync.RWMutex
+	config  *mimirClient.AlertmanagerConfig
+	creates int
+}
+
+var _ mimirClient.AlertmanagerInterface = &fakeMimirClient{}
+
+func (m *fakeMimirClient) GetAlertmanagerConfig(_ context.Context) (*mimirClient.AlertmanagerConfig, error) {
+	m.mut.RLock()
+	defer m.mut.RUnlock()
+	return m.config, nil
+}
+
+func (m *fakeMimirClient) CreateAlertmanagerConfig(_ context.Context, cfg mimirClient.AlertmanagerConfig) error {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+	m.config = &cfg
+	m.creates++
+	return nil
+}
+
+func (m *fakeMimirClient) get() (*mimirClient.AlertmanagerConfig, int) {
+	m.mut.RLock()
+	defer m.mut.RUnlock()
+	return m.config, m.creates
+}
+
+type fakeHealthReporter struct {
+	mut     sync.Mutex
+	healthy bool
+	err     error
+}
+
+func (f *fakeHealthReporter) reportUnhealthy(err error) {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+	f.healthy, f.err = false, err
+}
+
+func (f *fakeHealthReporter) reportHealthy() {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+	f.healthy, f.err = true, nil
+}
+
+func (f *fakeHealthReporter) get() (bool, error) {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+	return f.healthy, f.err
+}
+
+func TestEventLoop(t *testing.T) {
+	nsIndexer := cache.NewIndexer(
+		cache.DeletionHandlingMetaNamespaceKeyFunc,
+		cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc},
+	)
+	nsLister := coreListers.NewNamespaceLister(nsIndexer)
+
+	configIndexer := cache.NewIndexer(
+		cache.DeletionHandlingMetaNamespaceKeyFunc,
+		cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc},
+	)
+	configLister := promListers.NewAlertmanagerConfigLister(configIndexer)
+
+	ns := &corev1.Namespace{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: "team-a",
+		},
+	}
+	crd := testCRD()
+
+	client := &fakeMimirClient{}
+	health := &fakeHealthReporter{}
+	processor := &eventProcessor{
+		queue:    workqueue.NewRateLimitingQueue(workqueue.DefaultControllerRateLimiter()),
+		stopChan: make(chan struct{}),
+		health:   health,
+		secrets: fakeSecretGetter{
+			"team-a/webhook/url": "http://webhook.team-a.svc/alerts",
+			"team-a/slack/url":   "https://hooks.slack.com/services/xxx",
+		},
+		mimirClient:       client,
+		namespaceLister:   nsLister,
+		configLister:      configLister,
+		namespaceSelector: labels.Everything(),
+		configSelector:    labels.Everything(),
+		baseConfig:        testBaseConfig,
+		templateFiles:     map[string]string{"default.tmpl": `{{ define "x" }}x{{ end }}`},
+		metrics:           newMetrics(),
+		logger:            log.With(log.NewLogfmtLogger(os.Stdout), "ts", log.DefaultTimestampUTC),
+	}
+
+	ctx := context.Background()
+
+	// Do an initial sync of the Mimir Alertmanager state before starting the event processing loop.
+	require.NoError(t, processor.syncMimir(ctx))
+	go processor.run(ctx)
+	defer processor.stop()
+
+	eventHandler := kubernetes.NewQueuedEventHandler(processor.logger, processor.queue)
+
+	// Add a namespace and config to kubernetes
+	require.NoError(t, nsIndexer.Add(ns))
+	require.NoError(t, configIndexer.Add(crd))
+	eventHandler.OnAdd(crd, false)
+
+	// Wait for the receivers to be added to mimir
+	require.Eventually(t, func() bool {
+		cfg, _ := client.get()
+		return cfg != nil && strings.Contains(cfg.AlertmanagerConfig, "team-a/alerts/webhook")
+	}, time.Second, 10*time.Millisecond)
+
+	cfg, creates := client.get()
+	require.Equal(t, processor.templateFiles, cfg.TemplateFiles)
+
+	// Syncing without changes in kubernetes doesn't update mimir
+	processor.enqueueSyncMimir()
+	require.Eventually(t, func() bool {
+		return processor.queue.Len() == 0
+	}, time.Second, 10*time.Millisecond)
+	_, actualCreates := client.get()
+	require.Equal(t, creates, actualCreates)
+
+	// Break the config in kubernetes, it is left out of the configuration
+	crd = crd.DeepCopy()
+	crd.Spec.Route.Receiver = "missing"
+	require.NoError(t, configIndexer.Update(crd))
+	eventHandler.OnUpdate(crd, crd)
+
+	require.Eventually(t, func() bool {
+		cfg, _ := client.get()
+		healthy, _ := health.get()
+		return !healthy && !strings.Contains(cfg.AlertmanagerConfig, "team-a/alerts/webhook")
+	}, time.Second, 10*time.Millisecond)
+	_, err := health.get()
+	require.ErrorContains(t, err, "team-a/alerts")
+	require.Contains(t, processor.getConfigErrors(), "team-a/alerts")
+
+	// Remove the config from kubernetes
+	require.NoError(t, configIndexer.Delete(crd))
+	eventHandler.OnDelete(crd)
+
+	require.Eventually(t, func() bool {
+		healthy, _ := health.get()
+		return healthy
+	}, time.Second, 10*time.Millisecond)
+	require.Empty(t, processor.getConfigErrors())
+}
@@ -0,0 +1,46 @@
+package alerts
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/grafana/alloy/internal/component/common/config"
+	"github.com/grafana/alloy/internal/component/common/kubernetes"
+	"github.com/grafana/alloy/syntax/alloytypes"
+)
+
+type Arguments struct {
+	Address          string                  `alloy:"address,attr"`
+	TenantID         string                  `alloy:"tenant_id,attr,optional"`
+	HTTPClientConfig config.HTTPClientConfig `alloy:",squash"`
+	SyncInterval     time.Duration           `alloy:"sync_interval,attr,optional"`
+
+	BaseConfig    alloytypes.Secret `alloy:"base_config,attr"`
+	TemplateFiles map[string]string `alloy:"template_files,attr,optional"`
+
+	AlertmanagerConfigSelector          kubernetes.LabelSelector `alloy:"alertmanagerconfig_selector,block,optional"`
+	AlertmanagerConfigNamespaceSelector kubernetes.LabelSelector `alloy:"alertmanagerconfig_namespace_selector,block,optional"`
+}
+
+var DefaultArguments = Arguments{
+	SyncInterval:     5 * time.Minute,
+	HTTPClientConfig: config.DefaultHTTPClientConfig,
+}
+
+// SetToDefault implements syntax.Defaulter.
+func (args *Arguments) SetToDefault() {
+	*args = DefaultArguments
+}
+
+// Validate implements syntax.Validator.
+func (args *Arguments) Validate() error {
+	if args.SyncInterval <= 0 {
+		return fmt.Errorf("sync_interval must be greater than 0")
+	}
+	if _, err := parseBaseConfig(string(args.BaseConfig)); err != nil {
+		return fmt.Errorf("invalid base_config: %w", err)
+	}
+
+	// We must explicitly Validate because HTTPClientConfig is squashed and it won't run otherwise
+	return args.HTTPClientConfig.Validate()
+}
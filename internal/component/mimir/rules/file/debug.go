@@ -0,0 +1,30 @@
+package file
+
+import (
+	"time"
+)
+
+type DebugInfo struct {
+	Error               string                `alloy:"error,attr,optional"`
+	LastSync            time.Time             `alloy:"last_sync,attr,optional"`
+	MimirRuleNamespaces []DebugMimirNamespace `alloy:"mimir_rule_namespace,block,optional"`
+}
+
+type DebugMimirNamespace struct {
+	Name                 string `alloy:"name,attr"`
+	NumRuleGroups        int    `alloy:"num_rule_groups,attr"`
+	NumDesiredRuleGroups int    `alloy:"num_desired_rule_groups,attr"`
+}
+
+func (c *Component) DebugInfo() interface{} {
+	status := c.runner.Status()
+	output := DebugInfo{
+		Error:    status.Error,
+		LastSync: status.LastSync,
+	}
+
+	for _, ns := range status.Namespaces() {
+		output.MimirRuleNamespaces = append(output.MimirRuleNamespaces, DebugMimirNamespace(ns))
+	}
+	return output
+}
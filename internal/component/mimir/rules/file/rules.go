@@ -0,0 +1,87 @@
+package file
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/grafana/alloy/internal/component"
+	"github.com/grafana/alloy/internal/component/common/rulesync"
+	"github.com/grafana/alloy/internal/featuregate"
+	mimirClient "github.com/grafana/alloy/internal/mimir/client"
+)
+
+func init() {
+	component.Register(component.Registration{
+		Name:      "mimir.rules.file",
+		Stability: featuregate.StabilityExperimental,
+		Args:      Arguments{},
+		Exports:   nil,
+		Build: func(o component.Options, c component.Arguments) (component.Component, error) {
+			return New(o, c.(Arguments))
+		},
+	})
+}
+
+// Component syncs rule groups read from files or strings to the Mimir Ruler.
+type Component struct {
+	opts    component.Options
+	metrics *rulesync.Metrics
+	runner  *rulesync.Runner
+}
+
+var _ component.Component = (*Component)(nil)
+var _ component.DebugComponent = (*Component)(nil)
+var _ component.HealthComponent = (*Component)(nil)
+
+// New creates a new Component.
+func New(o component.Options, args Arguments) (*Component, error) {
+	m := rulesync.NewMetrics("mimir_rules_file", "Mimir")
+	if err := m.Register(o.Registerer); err != nil {
+		return nil, fmt.Errorf("registering metrics failed: %w", err)
+	}
+
+	c := &Component{
+		opts:    o,
+		metrics: m,
+		runner:  rulesync.NewRunner(o.Logger, m),
+	}
+	if err := c.Update(args); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *Component) Run(ctx context.Context) error {
+	return c.runner.Run(ctx)
+}
+
+func (c *Component) Update(newConfig component.Arguments) error {
+	args := newConfig.(Arguments)
+
+	httpClient := args.HTTPClientConfig.Convert()
+	client, err := mimirClient.New(c.opts.Logger, mimirClient.Config{
+		ID:                   args.TenantID,
+		Address:              args.Address,
+		UseLegacyRoutes:      args.UseLegacyRoutes,
+		PrometheusHTTPPrefix: args.PrometheusHTTPPrefix,
+		HTTPClientConfig:     *httpClient,
+	}, c.metrics.ClientTiming)
+	if err != nil {
+		return err
+	}
+
+	c.runner.Update(rulesync.Config{
+		Client:       client,
+		SyncInterval: args.SyncInterval,
+		Namespacer:   mimirNamespacer(args.MimirNameSpacePrefix, c.opts.ID),
+		ValidateExpr: true,
+		Targets:      args.Targets,
+		Contents:     args.contents(),
+	})
+	return nil
+}
+
+// CurrentHealth implements component.HealthComponent.
+func (c *Component) CurrentHealth() component.Health {
+	return c.runner.CurrentHealth()
+}
@@ -0,0 +1,51 @@
+package file
+
+import (
+	"testing"
+
+	"github.com/grafana/alloy/syntax"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAlloyConfig(t *testing.T) {
+	var exampleAlloyConfig = `
+	address = "GRAFANA_CLOUD_METRICS_URL"
+	targets = [{"__path__" = "/etc/rules/team-a.yaml"}]
+	contents = {
+		"team-b" = "groups: []",
+	}
+	basic_auth {
+		username = "GRAFANA_CLOUD_USER"
+		password = "GRAFANA_CLOUD_API_KEY"
+	}
+`
+
+	var args Arguments
+	err := syntax.Unmarshal([]byte(exampleAlloyConfig), &args)
+	require.NoError(t, err)
+	require.Equal(t, map[string]string{"team-b": "groups: []"}, args.contents())
+}
+
+func TestBadAlloyConfig(t *testing.T) {
+	var exampleAlloyConfig = `
+	address = "GRAFANA_CLOUD_METRICS_URL"
+	contents = {
+		"team/b" = "groups: []",
+	}
+`
+
+	var args Arguments
+	err := syntax.Unmarshal([]byte(exampleAlloyConfig), &args)
+	require.ErrorContains(t, err, `invalid namespace "team/b" in contents`)
+}
+
+func TestManagedNamespaces(t *testing.T) {
+	n := mimirNamespacer("alloy-file", "mimir.rules.file.default")
+	ns := n.Namespace("team-a")
+	require.Equal(t, "alloy-file/mimir.rules.file.default/team-a", ns)
+	require.True(t, n.IsManaged(ns))
+
+	// Components using the same prefix don't manage each other's namespaces.
+	require.False(t, n.IsManaged("alloy-file/mimir.rules.file.other/team-a"))
+	require.False(t, n.IsManaged("alloy-file/team-a"))
+}
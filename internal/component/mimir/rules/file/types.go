@@ -0,0 +1,73 @@
+package file
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/grafana/alloy/internal/component/common/config"
+	"github.com/grafana/alloy/internal/component/common/rulesync"
+	"github.com/grafana/alloy/internal/component/discovery"
+	"github.com/grafana/alloy/syntax/alloytypes"
+)
+
+type Arguments struct {
+	Address              string                  `alloy:"address,attr"`
+	TenantID             string                  `alloy:"tenant_id,attr,optional"`
+	UseLegacyRoutes      bool                    `alloy:"use_legacy_routes,attr,optional"`
+	PrometheusHTTPPrefix string                  `alloy:"prometheus_http_prefix,attr,optional"`
+	HTTPClientConfig     config.HTTPClientConfig `alloy:",squash"`
+	SyncInterval         time.Duration           `alloy:"sync_interval,attr,optional"`
+	MimirNameSpacePrefix string                  `alloy:"mimir_namespace_prefix,attr,optional"`
+
+	// Targets are rule files, usually exported by local.file_match.
+	Targets []discovery.Target `alloy:"targets,attr,optional"`
+	// Contents are the contents of rule files keyed by namespace.
+	Contents map[string]alloytypes.OptionalSecret `alloy:"contents,attr,optional"`
+}
+
+var DefaultArguments = Arguments{
+	SyncInterval:         time.Minute,
+	MimirNameSpacePrefix: "alloy-file",
+	HTTPClientConfig:     config.DefaultHTTPClientConfig,
+	PrometheusHTTPPrefix: "/prometheus",
+}
+
+// SetToDefault implements syntax.Defaulter.
+func (args *Arguments) SetToDefault() {
+	*args = DefaultArguments
+}
+
+// Validate implements syntax.Validator.
+func (args *Arguments) Validate() error {
+	if args.SyncInterval <= 0 {
+		return fmt.Errorf("sync_interval must be greater than 0")
+	}
+	if args.MimirNameSpacePrefix == "" {
+		return fmt.Errorf("mimir_namespace_prefix must not be empty")
+	}
+	for namespace := range args.Contents {
+		if namespace == "" || strings.Contains(namespace, "/") {
+			return fmt.Errorf("invalid namespace %q in contents, namespaces must not be empty or contain a slash", namespace)
+		}
+	}
+
+	// We must explicitly Validate because HTTPClientConfig is squashed and it won't run otherwise
+	return args.HTTPClientConfig.Validate()
+}
+
+// contents returns the contents of rule files keyed by namespace.
+func (args *Arguments) contents() map[string]string {
+	out := make(map[string]string, len(args.Contents))
+	for namespace, content := range args.Contents {
+		out[namespace] = content.Value
+	}
+	return out
+}
+
+// mimirNamespacer returns the Namespacer of the rule groups of the component
+// with the ID componentID. Mimir namespaces look like
+// <prefix>/<component ID>/<namespace>.
+func mimirNamespacer(prefix, componentID string) rulesync.Namespacer {
+	return rulesync.NewNamespacer(prefix, componentID, "/")
+}
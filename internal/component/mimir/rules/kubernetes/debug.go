@@ -4,6 +4,7 @@ import "fmt"
 
 type DebugInfo struct {
 	Error               string                   `alloy:"error,attr,optional"`
+	IsLeader            bool                     `alloy:"is_leader,attr"`
 	PrometheusRules     []DebugK8sPrometheusRule `alloy:"prometheus_rule,block,optional"`
 	MimirRuleNamespaces []DebugMimirNamespace    `alloy:"mimir_rule_namespace,block,optional"`
 }
@@ -21,7 +22,12 @@ type DebugMimirNamespace struct {
 }
 
 func (c *Component) DebugInfo() interface{} {
-	var output DebugInfo
+	output := DebugInfo{IsLeader: c.leader.IsLeader()}
+
+	// Rules are only loaded by the leader of the cluster.
+	if c.eventProcessor == nil {
+		return output
+	}
 
 	currentState := c.eventProcessor.getMimirState()
 	for namespace := range currentState {
@@ -38,7 +44,7 @@ func (c *Component) DebugInfo() interface{} {
 	// This should load from the informer cache, so it shouldn't fail under normal circumstances.
 	rulesByNamespace, err := c.eventProcessor.getKubernetesState()
 	if err != nil {
-		return DebugInfo{Error: fmt.Sprintf("failed to list rules: %v", err)}
+		return DebugInfo{IsLeader: output.IsLeader, Error: fmt.Sprintf("failed to list rules: %v", err)}
 	}
 
 	for namespace, rules := range rulesByNamespace {
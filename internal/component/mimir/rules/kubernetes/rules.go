@@ -8,14 +8,12 @@ import (
 	"time"
 
 	"github.com/go-kit/log"
-	"github.com/grafana/ckit/shard"
 	"github.com/grafana/dskit/backoff"
 	"github.com/grafana/dskit/instrument"
 	promExternalVersions "github.com/prometheus-operator/prometheus-operator/pkg/client/informers/externalversions"
 	promListers "github.com/prometheus-operator/prometheus-operator/pkg/client/listers/monitoring/v1"
 	promVersioned "github.com/prometheus-operator/prometheus-operator/pkg/client/versioned"
 	"github.com/prometheus/client_golang/prometheus"
-	"go.uber.org/atomic"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/client-go/informers"
@@ -179,7 +177,7 @@ func newNoInit(o component.Options, args Arguments) (*Component, error) {
 		log:            o.Logger,
 		opts:           o,
 		args:           args,
-		leader:         newComponentLeadership(o.ID, o.Logger, clusterSvc.(cluster.Cluster)),
+		leader:         commonK8s.NewComponentLeadership(o.ID, o.Logger, clusterSvc.(cluster.Cluster)),
 		configUpdates:  make(chan ConfigUpdate),
 		clusterUpdates: make(chan struct{}, 1),
 		ticker:         time.NewTicker(args.SyncInterval),
@@ -235,7 +233,7 @@ func (c *Component) startupWithRetries(ctx context.Context, leader leadership, s
 	)
 	for {
 		// Repeatedly check if we are the leader and attempt to start the component
-		_, err := leader.update()
+		_, err := leader.Update()
 		if err != nil {
 			level.Error(c.log).Log("msg", "checking leadership during starting failed, will retry", "err", err)
 			health.reportUnhealthy(err)
@@ -262,7 +260,7 @@ func (c *Component) iteration(ctx context.Context, leader leadership, state life
 	case <-c.clusterUpdates:
 		c.metrics.clusterUpdatesTotal.Inc()
 
-		changed, err := leader.update()
+		changed, err := leader.Update()
 		if err != nil {
 			level.Error(c.log).Log("msg", "checking leadership failed", "trigger", clusterUpdate, "err", err)
 			health.reportUnhealthy(err)
@@ -302,8 +300,9 @@ func (c *Component) restart(ctx context.Context) error {
 // startup launches the informers and starts the event loop if this instance is
 // the leader. If it is not the leader, startup does nothing.
 func (c *Component) startup(ctx context.Context) error {
-	if !c.leader.isLeader() {
+	if !c.leader.IsLeader() {
 		level.Info(c.log).Log("msg", "skipping startup because we are not the leader")
+		c.reportStandby()
 		return nil
 	}
 
@@ -327,6 +326,7 @@ func (c *Component) startup(ctx context.Context) error {
 	}
 
 	go c.eventProcessor.run(ctx)
+	c.reportHealthy()
 	return nil
 }
 
@@ -486,47 +486,13 @@ type lifecycle interface {
 // leadership encapsulates the logic for checking if this instance of the Component
 // is the leader among all instances to avoid conflicting updates of the Mimir API.
 type leadership interface {
-	// update checks if this component instance is still the leader, stores the result,
-	// and returns true if the leadership status has changed since the last time update
+	// Update checks if this component instance is still the leader, stores the result,
+	// and returns true if the leadership status has changed since the last time Update
 	// was called.
-	update() (bool, error)
+	Update() (bool, error)
 
-	// isLeader returns true if this component instance is the leader, false otherwise.
-	isLeader() bool
+	// IsLeader returns true if this component instance is the leader, false otherwise.
+	IsLeader() bool
 }
 
-// componentLeadership implements leadership based on checking ownership of a specific
-// key using a cluster.Cluster service.
-type componentLeadership struct {
-	id      string
-	logger  log.Logger
-	cluster cluster.Cluster
-	leader  atomic.Bool
-}
-
-func newComponentLeadership(id string, logger log.Logger, cluster cluster.Cluster) *componentLeadership {
-	return &componentLeadership{
-		id:      id,
-		logger:  logger,
-		cluster: cluster,
-	}
-}
-
-func (l *componentLeadership) update() (bool, error) {
-	peers, err := l.cluster.Lookup(shard.StringKey(l.id), 1, shard.OpReadWrite)
-	if err != nil {
-		return false, fmt.Errorf("unable to determine leader for %s: %w", l.id, err)
-	}
-
-	if len(peers) != 1 {
-		return false, fmt.Errorf("unexpected peers from leadership check: %+v", peers)
-	}
-
-	isLeader := peers[0].Self
-	level.Info(l.logger).Log("msg", "checked leadership of component", "is_leader", isLeader)
-	return l.leader.Swap(isLeader) != isLeader, nil
-}
-
-func (l *componentLeadership) isLeader() bool {
-	return l.leader.Load()
-}
+var _ leadership = (*commonK8s.ComponentLeadership)(nil)
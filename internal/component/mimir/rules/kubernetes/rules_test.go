@@ -16,6 +16,7 @@ import (
 	"go.uber.org/atomic"
 
 	"github.com/grafana/alloy/internal/component"
+	commonK8s "github.com/grafana/alloy/internal/component/common/kubernetes"
 	"github.com/grafana/alloy/internal/service/cluster"
 	"github.com/grafana/alloy/syntax"
 )
@@ -63,11 +64,11 @@ type fakeLeadership struct {
 	updateErr error
 }
 
-func (f *fakeLeadership) update() (bool, error) {
+func (f *fakeLeadership) Update() (bool, error) {
 	return f.changed, f.updateErr
 }
 
-func (f *fakeLeadership) isLeader() bool {
+func (f *fakeLeadership) IsLeader() bool {
 	return f.leader
 }
 
@@ -350,3 +351,14 @@ func TestIterationHandlesTick(t *testing.T) {
 	require.NoError(t, health.getErr())
 	require.True(t, state.syncStateCalled.Load())
 }
+
+func TestStartupOnStandby(t *testing.T) {
+	c := newComponentForTesting(t, prometheus.NewPedanticRegistry(), log.NewNopLogger())
+	c.leader = &fakeLeadership{leader: false}
+
+	require.NoError(t, c.startup(context.Background()))
+	require.Nil(t, c.eventProcessor)
+	require.Equal(t, component.HealthTypeHealthy, c.CurrentHealth().Health)
+	require.Equal(t, commonK8s.StandbyMessage, c.CurrentHealth().Message)
+	require.Equal(t, DebugInfo{IsLeader: false}, c.DebugInfo())
+}
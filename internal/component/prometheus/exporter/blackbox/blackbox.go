@@ -40,14 +40,15 @@ func buildBlackboxTargets(baseTarget discovery.Target, args component.Arguments)
 
 	a := args.(Arguments)
 	for _, tgt := range a.Targets {
-		target := make(discovery.Target)
+		target := make(map[string]string, len(tgt.Labels)+baseTarget.Len())
 		// Set extra labels first, meaning that any other labels will override
 		for k, v := range tgt.Labels {
 			target[k] = v
 		}
-		for k, v := range baseTarget {
+		baseTarget.ForEachLabel(func(k, v string) bool {
 			target[k] = v
-		}
+			return true
+		})
 
 		target["job"] = target["job"] + "/" + tgt.Name
 		target["__param_target"] = tgt.Target
@@ -55,7 +56,7 @@ func buildBlackboxTargets(baseTarget discovery.Target, args component.Arguments)
 			target["__param_module"] = tgt.Module
 		}
 
-		targets = append(targets, target)
+		targets = append(targets, discovery.NewTargetFromMap(target))
 	}
 
 	return targets
@@ -224,20 +224,20 @@ func TestBuildBlackboxTargets(t *testing.T) {
 		Targets:            TargetBlock{{Name: "target_a", Target: "http://example.com", Module: "http_2xx"}},
 		ProbeTimeoutOffset: 1.0,
 	}
-	baseTarget := discovery.Target{
+	baseTarget := discovery.NewTargetFromMap(map[string]string{
 		model.SchemeLabel:                   "http",
 		model.MetricsPathLabel:              "component/prometheus.exporter.blackbox.default/metrics",
 		"instance":                          "prometheus.exporter.blackbox.default",
 		"job":                               "integrations/blackbox",
 		"__meta_agent_integration_name":     "blackbox",
 		"__meta_agent_integration_instance": "prometheus.exporter.blackbox.default",
-	}
+	})
 	args := component.Arguments(baseArgs)
 	targets := buildBlackboxTargets(baseTarget, args)
 	require.Equal(t, 1, len(targets))
-	require.Equal(t, "integrations/blackbox/target_a", targets[0]["job"])
-	require.Equal(t, "http://example.com", targets[0]["__param_target"])
-	require.Equal(t, "http_2xx", targets[0]["__param_module"])
+	require.Equal(t, "integrations/blackbox/target_a", targets[0].AsMap()["job"])
+	require.Equal(t, "http://example.com", targets[0].AsMap()["__param_target"])
+	require.Equal(t, "http_2xx", targets[0].AsMap()["__param_module"])
 }
 
 func TestBuildBlackboxTargetsWithExtraLabels(t *testing.T) {
@@ -254,23 +254,23 @@ func TestBuildBlackboxTargetsWithExtraLabels(t *testing.T) {
 		}},
 		ProbeTimeoutOffset: 1.0,
 	}
-	baseTarget := discovery.Target{
+	baseTarget := discovery.NewTargetFromMap(map[string]string{
 		model.SchemeLabel:                   "http",
 		model.MetricsPathLabel:              "component/prometheus.exporter.blackbox.default/metrics",
 		"instance":                          "prometheus.exporter.blackbox.default",
 		"job":                               "integrations/blackbox",
 		"__meta_agent_integration_name":     "blackbox",
 		"__meta_agent_integration_instance": "prometheus.exporter.blackbox.default",
-	}
+	})
 	args := component.Arguments(baseArgs)
 	targets := buildBlackboxTargets(baseTarget, args)
 	require.Equal(t, 1, len(targets))
-	require.Equal(t, "integrations/blackbox/target_a", targets[0]["job"])
-	require.Equal(t, "http://example.com", targets[0]["__param_target"])
-	require.Equal(t, "http_2xx", targets[0]["__param_module"])
+	require.Equal(t, "integrations/blackbox/target_a", targets[0].AsMap()["job"])
+	require.Equal(t, "http://example.com", targets[0].AsMap()["__param_target"])
+	require.Equal(t, "http_2xx", targets[0].AsMap()["__param_module"])
 
-	require.Equal(t, "test", targets[0]["env"])
-	require.Equal(t, "bar", targets[0]["foo"])
+	require.Equal(t, "test", targets[0].AsMap()["env"])
+	require.Equal(t, "bar", targets[0].AsMap()["foo"])
 
 	// Check that the extra labels do not override existing labels
 	baseArgs.Targets[0].Labels = map[string]string{
@@ -280,6 +280,6 @@ func TestBuildBlackboxTargetsWithExtraLabels(t *testing.T) {
 	args = component.Arguments(baseArgs)
 	targets = buildBlackboxTargets(baseTarget, args)
 	require.Equal(t, 1, len(targets))
-	require.Equal(t, "integrations/blackbox/target_a", targets[0]["job"])
-	require.Equal(t, "prometheus.exporter.blackbox.default", targets[0]["instance"])
+	require.Equal(t, "integrations/blackbox/target_a", targets[0].AsMap()["job"])
+	require.Equal(t, "prometheus.exporter.blackbox.default", targets[0].AsMap()["instance"])
 }
@@ -92,13 +92,13 @@ func (a *Arguments) Validate() error {
 
 func customizeTarget(baseTarget discovery.Target, args component.Arguments) []discovery.Target {
 	a := args.(Arguments)
-	target := baseTarget
+	target := baseTarget.AsMap()
 	if len(a.KafkaURIs) > 1 {
 		target["instance"] = a.Instance
 	} else {
 		target["instance"] = a.KafkaURIs[0]
 	}
-	return []discovery.Target{target}
+	return []discovery.Target{discovery.NewTargetFromMap(target)}
 }
 
 func createExporter(opts component.Options, args component.Arguments, defaultInstanceKey string) (integrations.Integration, string, error) {
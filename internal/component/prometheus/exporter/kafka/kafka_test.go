@@ -113,10 +113,10 @@ func TestCustomizeTarget(t *testing.T) {
 		KafkaURIs: []string{"localhost:9200", "localhost:19200"},
 	}
 
-	baseTarget := discovery.Target{}
+	baseTarget := discovery.EmptyTarget
 	newTargets := customizeTarget(baseTarget, args)
 	require.Equal(t, 1, len(newTargets))
-	require.Equal(t, "example", newTargets[0]["instance"])
+	require.Equal(t, "example", newTargets[0].AsMap()["instance"])
 }
 
 func TestSASLPassword(t *testing.T) { // #6044
@@ -43,10 +43,7 @@ func buildSNMPTargets(baseTarget discovery.Target, args component.Arguments) []d
 	}
 
 	for _, tgt := range snmpTargets {
-		target := make(discovery.Target)
-		for k, v := range baseTarget {
-			target[k] = v
-		}
+		target := baseTarget.AsMap()
 
 		target["job"] = target["job"] + "/" + tgt.Name
 		target["__param_target"] = tgt.Target
@@ -63,7 +60,7 @@ func buildSNMPTargets(baseTarget discovery.Target, args component.Arguments) []d
 			target["__param_auth"] = tgt.Auth
 		}
 
-		targets = append(targets, target)
+		targets = append(targets, discovery.NewTargetFromMap(target))
 	}
 
 	return targets
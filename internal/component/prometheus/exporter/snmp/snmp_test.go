@@ -300,22 +300,22 @@ func TestBuildSNMPTargets(t *testing.T) {
 			WalkParams: "public", Auth: "public_v2"}},
 		WalkParams: WalkParams{{Name: "public", Retries: 2}},
 	}
-	baseTarget := discovery.Target{
+	baseTarget := discovery.NewTargetFromMap(map[string]string{
 		model.SchemeLabel:                   "http",
 		model.MetricsPathLabel:              "component/prometheus.exporter.snmp.default/metrics",
 		"instance":                          "prometheus.exporter.snmp.default",
 		"job":                               "integrations/snmp",
 		"__meta_agent_integration_name":     "snmp",
 		"__meta_agent_integration_instance": "prometheus.exporter.snmp.default",
-	}
+	})
 	args := component.Arguments(baseArgs)
 	targets := buildSNMPTargets(baseTarget, args)
 	require.Equal(t, 1, len(targets))
-	require.Equal(t, "integrations/snmp/network_switch_1", targets[0]["job"])
-	require.Equal(t, "192.168.1.2", targets[0]["__param_target"])
-	require.Equal(t, "if_mib", targets[0]["__param_module"])
-	require.Equal(t, "public", targets[0]["__param_walk_params"])
-	require.Equal(t, "public_v2", targets[0]["__param_auth"])
+	require.Equal(t, "integrations/snmp/network_switch_1", targets[0].AsMap()["job"])
+	require.Equal(t, "192.168.1.2", targets[0].AsMap()["__param_target"])
+	require.Equal(t, "if_mib", targets[0].AsMap()["__param_module"])
+	require.Equal(t, "public", targets[0].AsMap()["__param_walk_params"])
+	require.Equal(t, "public_v2", targets[0].AsMap()["__param_auth"])
 }
 
 func TestUnmarshalAlloyWithInlineConfig(t *testing.T) {
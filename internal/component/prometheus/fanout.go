@@ -2,6 +2,9 @@ package prometheus
 
 import (
 	"context"
+	"fmt"
+	"reflect"
+	"strconv"
 	"sync"
 	"time"
 
@@ -14,20 +17,26 @@ import (
 	"github.com/prometheus/prometheus/scrape"
 	"github.com/prometheus/prometheus/storage"
 
+	"github.com/grafana/alloy/internal/component"
 	"github.com/grafana/alloy/internal/service/labelstore"
 )
 
 var _ storage.Appendable = (*Fanout)(nil)
 
 // Fanout supports the default Alloy style of appendables since it can go to multiple outputs. It also allows the intercepting of appends.
+//
+// By default, appends and commits are sent to all children synchronously. UpdateIsolation can be used to buffer
+// commits per child and to detach children whose commits keep failing.
 type Fanout struct {
 	mut sync.RWMutex
 	// children is where to fan out.
-	children []storage.Appendable
+	children  []*fanoutChild
+	isolation *IsolationArguments
 	// ComponentID is what component this belongs to.
 	componentID    string
 	writeLatency   prometheus.Histogram
 	samplesCounter prometheus.Counter
+	childMetrics   *fanoutMetrics
 	ls             labelstore.LabelStore
 }
 
@@ -46,20 +55,105 @@ func NewFanout(children []storage.Appendable, componentID string, register prome
 	})
 	_ = register.Register(s)
 
-	return &Fanout{
-		children:       children,
+	f := &Fanout{
 		componentID:    componentID,
 		writeLatency:   wl,
 		samplesCounter: s,
+		childMetrics:   newFanoutMetrics(register),
 		ls:             ls,
 	}
+	f.updateChildren(children, nil)
+	return f
 }
 
 // UpdateChildren allows changing of the children of the fanout.
 func (f *Fanout) UpdateChildren(children []storage.Appendable) {
 	f.mut.Lock()
 	defer f.mut.Unlock()
-	f.children = children
+	f.updateChildren(children, f.isolation)
+}
+
+// UpdateIsolation changes how the children of the fanout are isolated from each other. A nil args sends appends and
+// commits to all children synchronously.
+func (f *Fanout) UpdateIsolation(args *IsolationArguments) {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+	f.updateChildren(f.appendables(), args)
+}
+
+// Close stops sending buffered commits to the children once they have been sent.
+func (f *Fanout) Close() {
+	f.mut.Lock()
+	defer f.mut.Unlock()
+	for _, c := range f.children {
+		c.close()
+	}
+	f.children = nil
+}
+
+// CurrentHealth returns the health of the fanout, which is unhealthy while a child is detached by its circuit
+// breaker.
+func (f *Fanout) CurrentHealth() component.Health {
+	f.mut.RLock()
+	defer f.mut.RUnlock()
+
+	for _, c := range f.children {
+		if open, failures, lastErr := c.breaker.state(); open {
+			return component.Health{
+				Health:     component.HealthTypeUnhealthy,
+				Message:    fmt.Sprintf("forward_to[%s] is detached after %d consecutive failed commits: %s", c.label, failures, lastErr),
+				UpdateTime: time.Now(),
+			}
+		}
+	}
+	return component.Health{
+		Health:     component.HealthTypeHealthy,
+		Message:    "all downstream components are attached",
+		UpdateTime: time.Now(),
+	}
+}
+
+func (f *Fanout) appendables() []storage.Appendable {
+	// Children are indexed by their position in the list of appendables, which may contain nil entries.
+	var appendables []storage.Appendable
+	for _, c := range f.children {
+		i, _ := strconv.Atoi(c.label)
+		for len(appendables) <= i {
+			appendables = append(appendables, nil)
+		}
+		appendables[i] = c.appendable
+	}
+	return appendables
+}
+
+// updateChildren must be called with f.mut held. Children which are unchanged keep their buffer and circuit breaker.
+func (f *Fanout) updateChildren(children []storage.Appendable, isolation *IsolationArguments) {
+	isolationChanged := !reflect.DeepEqual(f.isolation, isolation)
+
+	oldChildren := make(map[string]*fanoutChild, len(f.children))
+	for _, c := range f.children {
+		oldChildren[c.label] = c
+	}
+
+	newChildren := make([]*fanoutChild, 0, len(children))
+	for i, appendable := range children {
+		if appendable == nil {
+			continue
+		}
+		label := strconv.Itoa(i)
+		if old, ok := oldChildren[label]; ok && !isolationChanged && sameAppendable(old.appendable, appendable) {
+			newChildren = append(newChildren, old)
+			delete(oldChildren, label)
+			continue
+		}
+		newChildren = append(newChildren, newFanoutChild(i, appendable, isolation, f.childMetrics))
+	}
+
+	for _, old := range oldChildren {
+		old.close()
+	}
+	f.children = newChildren
+	f.isolation = isolation
 }
 
 // Appender satisfies the Appendable interface.
@@ -85,16 +179,29 @@ func (f *Fanout) Appender(ctx context.Context) storage.Appender {
 	}
 
 	for _, x := range f.children {
-		if x == nil {
-			continue
+		switch {
+		case x.queue != nil:
+			// Buffered children receive a replay of the appends once the appender is committed.
+			app.buffered = append(app.buffered, x)
+		case x.breaker.allow():
+			app.children = append(app.children, x.appendable.Appender(ctx))
+			app.syncChildren = append(app.syncChildren, x)
+		default:
+			app.detached = append(app.detached, x)
 		}
-		app.children = append(app.children, x.Appender(ctx))
+	}
+	if len(app.buffered) > 0 || len(app.detached) > 0 {
+		app.batch = &commitBatch{}
 	}
 	return app
 }
 
 type appender struct {
 	children          []storage.Appender
+	syncChildren      []*fanoutChild // The children the appenders in children belong to.
+	buffered          []*fanoutChild
+	detached          []*fanoutChild
+	batch             *commitBatch // Appends to replay on buffered children; nil if there are none.
 	componentID       string
 	writeLatency      prometheus.Histogram
 	samplesCounter    prometheus.Counter
@@ -119,7 +226,12 @@ func (a *appender) Append(ref storage.SeriesRef, l labels.Labels, t int64, v flo
 		Value:       v,
 	})
 	var multiErr error
-	updated := false
+	a.record(func(app storage.Appender) error {
+		_, err := app.Append(ref, l, t, v)
+		return err
+	}, true)
+	// Samples are sent to buffered children once the appender is committed.
+	updated := len(a.buffered) > 0
 	for _, x := range a.children {
 		_, err := x.Append(ref, l, t, v)
 		if err != nil {
@@ -139,12 +251,19 @@ func (a *appender) Commit() error {
 	defer a.recordLatency()
 	var multiErr error
 	a.ls.TrackStaleness(a.stalenessTrackers)
-	for _, x := range a.children {
+	for i, x := range a.children {
 		err := x.Commit()
+		a.syncChildren[i].onCommit(err)
 		if err != nil {
 			multiErr = multierror.Append(multiErr, err)
 		}
 	}
+	for _, x := range a.buffered {
+		x.enqueue(a.batch)
+	}
+	for _, x := range a.detached {
+		x.drop(a.batch, "circuit_open")
+	}
 	return multiErr
 }
 
@@ -162,6 +281,13 @@ func (a *appender) Rollback() error {
 	return multiErr
 }
 
+// record records op to be replayed on the buffered children once the appender is committed.
+func (a *appender) record(op func(storage.Appender) error, sample bool) {
+	if a.batch != nil {
+		a.batch.record(op, sample)
+	}
+}
+
 func (a *appender) recordLatency() {
 	if a.start.IsZero() {
 		return
@@ -178,6 +304,10 @@ func (a *appender) AppendExemplar(ref storage.SeriesRef, l labels.Labels, e exem
 	if ref == 0 {
 		ref = storage.SeriesRef(a.ls.GetOrAddGlobalRefID(l))
 	}
+	a.record(func(app storage.Appender) error {
+		_, err := app.AppendExemplar(ref, l, e)
+		return err
+	}, false)
 	var multiErr error
 	for _, x := range a.children {
 		_, err := x.AppendExemplar(ref, l, e)
@@ -196,6 +326,10 @@ func (a *appender) UpdateMetadata(ref storage.SeriesRef, l labels.Labels, m meta
 	if ref == 0 {
 		ref = storage.SeriesRef(a.ls.GetOrAddGlobalRefID(l))
 	}
+	a.record(func(app storage.Appender) error {
+		_, err := app.UpdateMetadata(ref, l, m)
+		return err
+	}, false)
 	var multiErr error
 	for _, x := range a.children {
 		_, err := x.UpdateMetadata(ref, l, m)
@@ -213,6 +347,10 @@ func (a *appender) AppendHistogram(ref storage.SeriesRef, l labels.Labels, t int
 	if ref == 0 {
 		ref = storage.SeriesRef(a.ls.GetOrAddGlobalRefID(l))
 	}
+	a.record(func(app storage.Appender) error {
+		_, err := app.AppendHistogram(ref, l, t, h, fh)
+		return err
+	}, true)
 	var multiErr error
 	for _, x := range a.children {
 		_, err := x.AppendHistogram(ref, l, t, h, fh)
@@ -230,6 +368,10 @@ func (a *appender) AppendCTZeroSample(ref storage.SeriesRef, l labels.Labels, t,
 	if ref == 0 {
 		ref = storage.SeriesRef(a.ls.GetOrAddGlobalRefID(l))
 	}
+	a.record(func(app storage.Appender) error {
+		_, err := app.AppendCTZeroSample(ref, l, t, ct)
+		return err
+	}, false)
 	var multiErr error
 	for _, x := range a.children {
 		_, err := x.AppendCTZeroSample(ref, l, t, ct)
@@ -0,0 +1,377 @@
+package prometheus
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/prometheus/scrape"
+	"github.com/prometheus/prometheus/storage"
+)
+
+// Policies applied when the buffer of a child of the fanout is full.
+const (
+	FullPolicyBlock      = "block"       // Wait for the child to make room in the buffer.
+	FullPolicyDropOldest = "drop_oldest" // Drop the oldest buffered commit.
+	FullPolicyDropNewest = "drop_newest" // Drop the commit being added.
+)
+
+// IsolationArguments configures how a Fanout isolates its children from each
+// other, so that a slow or failing child doesn't stall the others.
+type IsolationArguments struct {
+	// BufferSize is the number of commits buffered per child. Commits are sent
+	// to the child asynchronously when BufferSize is greater than zero.
+	BufferSize int `alloy:"buffer_size,attr,optional"`
+	// FullPolicy is the policy applied when the buffer of a child is full.
+	FullPolicy string `alloy:"full_policy,attr,optional"`
+
+	CircuitBreaker *CircuitBreakerArguments `alloy:"circuit_breaker,block,optional"`
+}
+
+// SetToDefault implements syntax.Defaulter.
+func (args *IsolationArguments) SetToDefault() {
+	*args = IsolationArguments{
+		BufferSize: 100,
+		FullPolicy: FullPolicyDropOldest,
+	}
+}
+
+// Validate implements syntax.Validator.
+func (args *IsolationArguments) Validate() error {
+	if args.BufferSize < 0 {
+		return fmt.Errorf("buffer_size must not be negative")
+	}
+	switch args.FullPolicy {
+	case FullPolicyBlock, FullPolicyDropOldest, FullPolicyDropNewest:
+	default:
+		return fmt.Errorf("unknown full_policy %q, must be one of %q, %q or %q", args.FullPolicy, FullPolicyBlock, FullPolicyDropOldest, FullPolicyDropNewest)
+	}
+	return nil
+}
+
+// CircuitBreakerArguments configures when a child of a Fanout is detached
+// because its commits keep failing.
+type CircuitBreakerArguments struct {
+	// FailureThreshold is the number of consecutive failed commits after which
+	// the child is detached.
+	FailureThreshold int `alloy:"failure_threshold,attr,optional"`
+	// OpenDuration is how long the child stays detached before a commit is
+	// tried again.
+	OpenDuration time.Duration `alloy:"open_duration,attr,optional"`
+}
+
+// SetToDefault implements syntax.Defaulter.
+func (args *CircuitBreakerArguments) SetToDefault() {
+	*args = CircuitBreakerArguments{
+		FailureThreshold: 5,
+		OpenDuration:     30 * time.Second,
+	}
+}
+
+// Validate implements syntax.Validator.
+func (args *CircuitBreakerArguments) Validate() error {
+	if args.FailureThreshold <= 0 {
+		return fmt.Errorf("failure_threshold must be greater than 0")
+	}
+	if args.OpenDuration <= 0 {
+		return fmt.Errorf("open_duration must be greater than 0")
+	}
+	return nil
+}
+
+// fanoutMetrics are the metrics reported for the children of a Fanout. The
+// children are identified by their index in the list of children.
+type fanoutMetrics struct {
+	bufferedCommits *prometheus.GaugeVec
+	droppedSamples  *prometheus.CounterVec
+	commitFailures  *prometheus.CounterVec
+	circuitOpen     *prometheus.GaugeVec
+}
+
+func newFanoutMetrics(register prometheus.Registerer) *fanoutMetrics {
+	m := &fanoutMetrics{
+		bufferedCommits: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "prometheus_fanout_child_buffered_commits",
+			Help: "Number of commits waiting in the buffer of a downstream component.",
+		}, []string{"child"}),
+		droppedSamples: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "prometheus_fanout_child_dropped_samples_total",
+			Help: "Total number of samples which were not sent to a downstream component.",
+		}, []string{"child", "reason"}),
+		commitFailures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "prometheus_fanout_child_commit_failures_total",
+			Help: "Total number of commits to a downstream component which failed.",
+		}, []string{"child"}),
+		circuitOpen: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "prometheus_fanout_child_circuit_open",
+			Help: "Whether a downstream component is detached because its commits keep failing.",
+		}, []string{"child"}),
+	}
+	for _, c := range []prometheus.Collector{m.bufferedCommits, m.droppedSamples, m.commitFailures, m.circuitOpen} {
+		_ = register.Register(c)
+	}
+	return m
+}
+
+// fanoutChild wraps a child of a Fanout with its circuit breaker and, if
+// commits are buffered, the queue of commits waiting to be sent.
+type fanoutChild struct {
+	appendable storage.Appendable
+	label      string
+	breaker    *circuitBreaker
+	queue      *commitQueue // Nil if commits are sent synchronously.
+	metrics    *fanoutMetrics
+}
+
+func newFanoutChild(index int, appendable storage.Appendable, args *IsolationArguments, metrics *fanoutMetrics) *fanoutChild {
+	c := &fanoutChild{
+		appendable: appendable,
+		label:      strconv.Itoa(index),
+		breaker:    newCircuitBreaker(nil),
+		metrics:    metrics,
+	}
+	if args == nil {
+		return c
+	}
+
+	c.breaker = newCircuitBreaker(args.CircuitBreaker)
+	if args.BufferSize > 0 {
+		c.queue = newCommitQueue(args.BufferSize, args.FullPolicy)
+		go c.run()
+	}
+	return c
+}
+
+// run sends the buffered commits to the child until the queue is closed.
+func (c *fanoutChild) run() {
+	ctx := scrape.ContextWithTarget(context.Background(), &scrape.Target{})
+	ctx = scrape.ContextWithMetricMetadataStore(ctx, NoopMetadataStore{})
+
+	for {
+		b, ok := c.queue.pop()
+		if !ok {
+			return
+		}
+		c.metrics.bufferedCommits.WithLabelValues(c.label).Set(float64(c.queue.len()))
+
+		if !c.breaker.allow() {
+			c.drop(b, "circuit_open")
+			continue
+		}
+
+		app := c.appendable.Appender(ctx)
+		for _, op := range b.ops {
+			// Errors of individual appends, such as out of order samples, are
+			// returned to the caller in the synchronous case and don't prevent the
+			// commit, so they are ignored here too.
+			_ = op(app)
+		}
+		c.onCommit(app.Commit())
+	}
+}
+
+// enqueue adds b to the commits waiting to be sent to the child.
+func (c *fanoutChild) enqueue(b *commitBatch) {
+	if !c.breaker.allow() {
+		c.drop(b, "circuit_open")
+		return
+	}
+	if dropped := c.queue.push(b); dropped != nil {
+		c.drop(dropped, "buffer_full")
+	}
+	c.metrics.bufferedCommits.WithLabelValues(c.label).Set(float64(c.queue.len()))
+}
+
+func (c *fanoutChild) onCommit(err error) {
+	if err != nil {
+		c.metrics.commitFailures.WithLabelValues(c.label).Inc()
+	}
+	if c.breaker.record(err) {
+		c.metrics.circuitOpen.WithLabelValues(c.label).Set(1)
+	} else if err == nil {
+		c.metrics.circuitOpen.WithLabelValues(c.label).Set(0)
+	}
+}
+
+func (c *fanoutChild) drop(b *commitBatch, reason string) {
+	c.metrics.droppedSamples.WithLabelValues(c.label, reason).Add(float64(b.samples))
+}
+
+// close stops sending commits to the child once the buffered commits have
+// been sent.
+func (c *fanoutChild) close() {
+	if c.queue != nil {
+		c.queue.close()
+	}
+	c.metrics.bufferedCommits.DeleteLabelValues(c.label)
+	c.metrics.circuitOpen.DeleteLabelValues(c.label)
+}
+
+// sameAppendable reports whether a and b are the same Appendable without
+// panicking on values which aren't comparable.
+func sameAppendable(a, b storage.Appendable) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	if reflect.TypeOf(a) != reflect.TypeOf(b) || !reflect.TypeOf(a).Comparable() {
+		return false
+	}
+	return a == b
+}
+
+// commitBatch records the calls made on an appender so that they can be
+// replayed on the appender of a child once the appender is committed.
+type commitBatch struct {
+	ops     []func(storage.Appender) error
+	samples int
+}
+
+func (b *commitBatch) record(op func(storage.Appender) error, sample bool) {
+	b.ops = append(b.ops, op)
+	if sample {
+		b.samples++
+	}
+}
+
+// commitQueue is a bounded FIFO queue of commits.
+type commitQueue struct {
+	mut     sync.Mutex
+	cond    *sync.Cond
+	batches []*commitBatch
+	size    int
+	policy  string
+	closed  bool
+}
+
+func newCommitQueue(size int, policy string) *commitQueue {
+	q := &commitQueue{size: size, policy: policy}
+	q.cond = sync.NewCond(&q.mut)
+	return q
+}
+
+// push adds b to the queue, applying the full policy of the queue if it's
+// full. It returns the batch which was dropped to make room, if any.
+func (q *commitQueue) push(b *commitBatch) (dropped *commitBatch) {
+	q.mut.Lock()
+	defer q.mut.Unlock()
+
+	for len(q.batches) >= q.size && !q.closed {
+		switch q.policy {
+		case FullPolicyDropNewest:
+			return b
+		case FullPolicyDropOldest:
+			dropped = q.batches[0]
+			q.batches[0] = nil
+			q.batches = q.batches[1:]
+		default:
+			q.cond.Wait()
+		}
+	}
+	if q.closed {
+		return b
+	}
+
+	q.batches = append(q.batches, b)
+	q.cond.Broadcast()
+	return dropped
+}
+
+// pop removes the oldest batch from the queue, waiting for one to be pushed
+// if the queue is empty. It returns false once the queue is closed and empty.
+func (q *commitQueue) pop() (*commitBatch, bool) {
+	q.mut.Lock()
+	defer q.mut.Unlock()
+
+	for len(q.batches) == 0 {
+		if q.closed {
+			return nil, false
+		}
+		q.cond.Wait()
+	}
+
+	b := q.batches[0]
+	q.batches[0] = nil
+	q.batches = q.batches[1:]
+	q.cond.Broadcast()
+	return b, true
+}
+
+func (q *commitQueue) len() int {
+	q.mut.Lock()
+	defer q.mut.Unlock()
+	return len(q.batches)
+}
+
+func (q *commitQueue) close() {
+	q.mut.Lock()
+	defer q.mut.Unlock()
+	q.closed = true
+	q.cond.Broadcast()
+}
+
+// circuitBreaker detaches a child after a number of consecutive failed
+// commits. Once the open duration has elapsed, commits are tried again: the
+// child is attached again after a successful commit, and detached again after
+// a failed one.
+type circuitBreaker struct {
+	threshold    int
+	openDuration time.Duration
+
+	mut       sync.Mutex
+	failures  int
+	lastErr   error
+	openUntil time.Time
+}
+
+// newCircuitBreaker creates a circuitBreaker. A nil args creates a breaker
+// which never opens.
+func newCircuitBreaker(args *CircuitBreakerArguments) *circuitBreaker {
+	if args == nil {
+		return &circuitBreaker{}
+	}
+	return &circuitBreaker{
+		threshold:    args.FailureThreshold,
+		openDuration: args.OpenDuration,
+	}
+}
+
+// allow reports whether a commit should be sent to the child.
+func (b *circuitBreaker) allow() bool {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+	return b.openUntil.IsZero() || !time.Now().Before(b.openUntil)
+}
+
+// record records the result of a commit. It returns true if the breaker was
+// opened.
+func (b *circuitBreaker) record(err error) (opened bool) {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+
+	if err == nil {
+		b.failures = 0
+		b.lastErr = nil
+		b.openUntil = time.Time{}
+		return false
+	}
+
+	b.failures++
+	b.lastErr = err
+	if b.threshold > 0 && b.failures >= b.threshold {
+		b.openUntil = time.Now().Add(b.openDuration)
+		return true
+	}
+	return false
+}
+
+// state returns whether the breaker is open, and if so, the number of
+// consecutive failures and the last error.
+func (b *circuitBreaker) state() (open bool, failures int, lastErr error) {
+	b.mut.Lock()
+	defer b.mut.Unlock()
+	return !b.openUntil.IsZero(), b.failures, b.lastErr
+}
@@ -1,10 +1,15 @@
 package prometheus
 
 import (
+	"fmt"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/grafana/alloy/internal/component"
 	"github.com/grafana/alloy/internal/service/labelstore"
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/prometheus/model/labels"
 
 	"github.com/prometheus/prometheus/storage"
 
@@ -28,3 +33,134 @@ func TestCommit(t *testing.T) {
 	err := app.Commit()
 	require.NoError(t, err)
 }
+
+func TestFanout_CircuitBreaker(t *testing.T) {
+	ls := labelstore.New(nil, prometheus.NewRegistry())
+	failing := &countingAppendable{commitErr: fmt.Errorf("wal is broken")}
+	healthy := &countingAppendable{}
+
+	fanout := NewFanout([]storage.Appendable{failing, healthy}, "", prometheus.NewRegistry(), ls)
+	fanout.UpdateIsolation(&IsolationArguments{
+		CircuitBreaker: &CircuitBreakerArguments{FailureThreshold: 2, OpenDuration: time.Hour},
+	})
+
+	for i := 0; i < 4; i++ {
+		app := fanout.Appender(context.Background())
+		_, err := app.Append(0, labels.FromStrings("__name__", "up"), int64(i), 1)
+		require.NoError(t, err)
+		_ = app.Commit()
+	}
+
+	// The failing child is detached after two commits, the healthy child keeps receiving samples.
+	require.Equal(t, 2, failing.commits())
+	require.Equal(t, 4, healthy.samples())
+	require.Equal(t, component.HealthTypeUnhealthy, fanout.CurrentHealth().Health)
+	require.Contains(t, fanout.CurrentHealth().Message, "wal is broken")
+
+	// Detached children are attached again once they are updated.
+	fanout.UpdateIsolation(nil)
+	require.Equal(t, component.HealthTypeHealthy, fanout.CurrentHealth().Health)
+}
+
+func TestFanout_BufferedChildren(t *testing.T) {
+	ls := labelstore.New(nil, prometheus.NewRegistry())
+	blocked := &countingAppendable{block: make(chan struct{})}
+	healthy := &countingAppendable{}
+
+	fanout := NewFanout([]storage.Appendable{blocked, healthy}, "", prometheus.NewRegistry(), ls)
+	defer fanout.Close()
+	fanout.UpdateIsolation(&IsolationArguments{BufferSize: 1, FullPolicy: FullPolicyDropNewest})
+
+	// Commits don't wait for the blocked child.
+	for i := 0; i < 10; i++ {
+		app := fanout.Appender(context.Background())
+		_, err := app.Append(0, labels.FromStrings("__name__", "up"), int64(i), 1)
+		require.NoError(t, err)
+		require.NoError(t, app.Commit())
+
+		// Wait for the commit to be sent to the healthy child so that it doesn't
+		// fill its buffer.
+		require.Eventually(t, func() bool { return healthy.samples() == i+1 }, 5*time.Second, 10*time.Millisecond)
+		if i == 0 {
+			// Wait for the blocked child to pick up the first commit.
+			require.Eventually(t, func() bool { return fanout.children[0].queue.len() == 0 }, 5*time.Second, 10*time.Millisecond)
+		}
+	}
+
+	// The blocked child receives the commit it was blocked on and the buffered
+	// one; the others were dropped.
+	close(blocked.block)
+	require.Eventually(t, func() bool { return blocked.commits() == 2 }, 5*time.Second, 10*time.Millisecond)
+	require.Equal(t, 2, blocked.samples())
+}
+
+func TestCommitQueue_DropOldest(t *testing.T) {
+	q := newCommitQueue(2, FullPolicyDropOldest)
+	b1, b2, b3 := &commitBatch{}, &commitBatch{}, &commitBatch{}
+	require.Nil(t, q.push(b1))
+	require.Nil(t, q.push(b2))
+	require.Same(t, b1, q.push(b3))
+
+	next, ok := q.pop()
+	require.True(t, ok)
+	require.Same(t, b2, next)
+
+	q.close()
+	next, ok = q.pop()
+	require.True(t, ok)
+	require.Same(t, b3, next)
+	_, ok = q.pop()
+	require.False(t, ok)
+}
+
+// countingAppendable counts the samples and commits it receives.
+type countingAppendable struct {
+	commitErr error
+	block     chan struct{} // If not nil, commits wait for block to be closed.
+
+	mut         sync.Mutex
+	sampleCount int
+	commitCount int
+}
+
+func (c *countingAppendable) Appender(context.Context) storage.Appender {
+	return &countingAppender{parent: c}
+}
+
+func (c *countingAppendable) samples() int {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	return c.sampleCount
+}
+
+func (c *countingAppendable) commits() int {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	return c.commitCount
+}
+
+type countingAppender struct {
+	storage.Appender
+	parent  *countingAppendable
+	pending int
+}
+
+func (a *countingAppender) Append(storage.SeriesRef, labels.Labels, int64, float64) (storage.SeriesRef, error) {
+	a.pending++
+	return 0, nil
+}
+
+func (a *countingAppender) Commit() error {
+	if a.parent.block != nil {
+		<-a.parent.block
+	}
+
+	a.parent.mut.Lock()
+	defer a.parent.mut.Unlock()
+	a.parent.commitCount++
+	if a.parent.commitErr != nil {
+		return a.parent.commitErr
+	}
+	a.parent.sampleCount += a.pending
+	return nil
+}
@@ -0,0 +1,16 @@
+// Package writev2 holds the types of the Prometheus Remote-Write 2.0 protocol.
+//
+// This code is copied from the prompb/io/prometheus/write/v2 package of
+// Prometheus v0.54.1 (https://github.com/prometheus/prometheus/tree/v0.54.1/prompb/io/prometheus/write/v2),
+// which is copyrighted by the Prometheus Authors and licensed under the Apache
+// License, Version 2.0. It can be replaced by the upstream package once
+// github.com/prometheus/prometheus is upgraded to v0.54 or later.
+//
+// The following changes have been made, since the histogram model of the
+// Prometheus version in use doesn't support custom buckets:
+//   - the conversions between Histogram and the histogram model don't copy
+//     custom bucket boundaries; histograms with custom buckets are handled by
+//     the callers of the package.
+//   - the names of the Remote-Write messages, which are defined in the config
+//     package of Prometheus, are defined in protocol.go.
+package writev2
@@ -0,0 +1,12 @@
+package writev2
+
+// Fully qualified names of the Remote-Write messages, used as the proto
+// parameter of the Content-Type of Remote-Write requests.
+const (
+	ProtoMessageV1 = "prometheus.WriteRequest"
+	ProtoMessageV2 = "io.prometheus.write.v2.Request"
+)
+
+// CustomBucketsSchema is the schema of native histograms with custom bucket
+// boundaries.
+const CustomBucketsSchema = -53
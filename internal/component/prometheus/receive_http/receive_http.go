@@ -17,7 +17,6 @@ import (
 	"github.com/grafana/alloy/internal/util"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/prometheus/storage"
-	"github.com/prometheus/prometheus/storage/remote"
 )
 
 func init() {
@@ -35,6 +34,9 @@ func init() {
 type Arguments struct {
 	Server    *fnet.ServerConfig   `alloy:",squash"`
 	ForwardTo []storage.Appendable `alloy:"forward_to,attr"`
+
+	// How the components in ForwardTo are isolated from each other.
+	Isolation *alloyprom.IsolationArguments `alloy:"isolation,block,optional"`
 }
 
 // SetToDefault implements syntax.Defaulter.
@@ -68,7 +70,7 @@ func New(opts component.Options, args Arguments) (*Component, error) {
 
 	c := &Component{
 		opts:               opts,
-		handler:            remote.NewWriteHandler(opts.Logger, opts.Registerer, fanout),
+		handler:            newWriteHandler(opts.Logger, opts.Registerer, fanout),
 		fanout:             fanout,
 		uncheckedCollector: uncheckedCollector,
 	}
@@ -81,6 +83,7 @@ func New(opts component.Options, args Arguments) (*Component, error) {
 
 // Run satisfies the Component interface.
 func (c *Component) Run(ctx context.Context) error {
+	defer c.fanout.Close()
 	defer func() {
 		c.updateMut.Lock()
 		defer c.updateMut.Unlock()
@@ -92,10 +95,16 @@ func (c *Component) Run(ctx context.Context) error {
 	return nil
 }
 
+// CurrentHealth satisfies the HealthComponent interface.
+func (c *Component) CurrentHealth() component.Health {
+	return c.fanout.CurrentHealth()
+}
+
 // Update satisfies the Component interface.
 func (c *Component) Update(args component.Arguments) error {
 	newArgs := args.(Arguments)
 	c.fanout.UpdateChildren(newArgs.ForwardTo)
+	c.fanout.UpdateIsolation(newArgs.Isolation)
 
 	c.updateMut.Lock()
 	defer c.updateMut.Unlock()
@@ -114,7 +123,7 @@ func (c *Component) Update(args component.Arguments) error {
 	c.server = s
 
 	err = c.server.MountAndRun(func(router *mux.Router) {
-		router.Path("/api/v1/metrics/write").Methods("POST").Handler(c.handler)
+		router.Path("/api/v1/metrics/write").Methods("POST", "HEAD").Handler(c.handler)
 	})
 	if err != nil {
 		return err
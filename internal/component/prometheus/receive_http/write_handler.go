@@ -0,0 +1,464 @@
+package receive_http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-kit/log"
+	"github.com/golang/snappy"
+	"github.com/grafana/alloy/internal/component/prometheus/internal/writev2"
+	"github.com/grafana/alloy/internal/runtime/logging/level"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/metadata"
+	"github.com/prometheus/prometheus/model/value"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/prometheus/prometheus/storage/remote"
+)
+
+const (
+	contentTypeProtobuf = "application/x-protobuf"
+
+	headerSamplesWritten    = "X-Prometheus-Remote-Write-Samples-Written"
+	headerHistogramsWritten = "X-Prometheus-Remote-Write-Histograms-Written"
+	headerExemplarsWritten  = "X-Prometheus-Remote-Write-Exemplars-Written"
+)
+
+// supportedContentTypes are the content types of the accepted Remote-Write
+// messages, advertised in the Accept-Post header of responses.
+var supportedContentTypes = strings.Join([]string{
+	contentTypeProtobuf + ";proto=" + writev2.ProtoMessageV1,
+	contentTypeProtobuf + ";proto=" + writev2.ProtoMessageV2,
+}, ", ")
+
+// writeHandler accepts Remote-Write 1.0 and 2.0 requests. The protocol of a
+// request is selected by the proto parameter of its Content-Type. Requests
+// without the parameter are Remote-Write 1.0 requests.
+type writeHandler struct {
+	logger     log.Logger
+	appendable storage.Appendable
+	v1         http.Handler
+}
+
+func newWriteHandler(logger log.Logger, reg prometheus.Registerer, appendable storage.Appendable) *writeHandler {
+	return &writeHandler{
+		logger:     logger,
+		appendable: appendable,
+		v1:         remote.NewWriteHandler(logger, reg, appendable),
+	}
+}
+
+func (h *writeHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Accept-Post", supportedContentTypes)
+	if r.Method == http.MethodHead {
+		w.WriteHeader(http.StatusOK)
+		return
+	}
+
+	msg, err := protoMessage(r.Header.Get("Content-Type"))
+	if err != nil {
+		level.Debug(h.logger).Log("msg", "unsupported remote write content type", "err", err)
+		http.Error(w, err.Error(), http.StatusUnsupportedMediaType)
+		return
+	}
+
+	switch msg {
+	case writev2.ProtoMessageV1:
+		h.v1.ServeHTTP(w, r)
+	case writev2.ProtoMessageV2:
+		h.serveV2(w, r)
+	}
+}
+
+// protoMessage returns the Remote-Write message sent with the given
+// Content-Type.
+func protoMessage(contentType string) (string, error) {
+	if contentType == "" {
+		return writev2.ProtoMessageV1, nil
+	}
+	mediaType, params, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return "", fmt.Errorf("parsing content type: %w", err)
+	}
+	if mediaType != contentTypeProtobuf {
+		return "", fmt.Errorf("unsupported content type %q, expected %q", mediaType, contentTypeProtobuf)
+	}
+
+	switch proto := params["proto"]; proto {
+	case "", writev2.ProtoMessageV1:
+		return writev2.ProtoMessageV1, nil
+	case writev2.ProtoMessageV2:
+		return writev2.ProtoMessageV2, nil
+	default:
+		return "", fmt.Errorf("unsupported remote write message %q", proto)
+	}
+}
+
+func (h *writeHandler) serveV2(w http.ResponseWriter, r *http.Request) {
+	if enc := r.Header.Get("Content-Encoding"); enc != "" && enc != "snappy" {
+		http.Error(w, fmt.Sprintf("unsupported content encoding %q, expected \"snappy\"", enc), http.StatusUnsupportedMediaType)
+		return
+	}
+
+	compressed, err := io.ReadAll(r.Body)
+	if err != nil {
+		level.Error(h.logger).Log("msg", "error reading remote write request", "err", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	raw, err := snappy.Decode(nil, compressed)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("decompressing request: %s", err), http.StatusBadRequest)
+		return
+	}
+	var req writev2.Request
+	if err := req.Unmarshal(raw); err != nil {
+		level.Error(h.logger).Log("msg", "error decoding remote write request", "err", err)
+		http.Error(w, fmt.Sprintf("decoding request: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	stats, err := h.writeV2(r.Context(), &req)
+
+	// The written counts are reported even on failure so that clients don't
+	// retry data which has been written.
+	w.Header().Set(headerSamplesWritten, strconv.Itoa(stats.samples))
+	w.Header().Set(headerHistogramsWritten, strconv.Itoa(stats.histograms))
+	w.Header().Set(headerExemplarsWritten, strconv.Itoa(stats.exemplars))
+
+	switch {
+	case err == nil:
+		w.WriteHeader(http.StatusNoContent)
+	case errors.As(err, new(badRequestError)), isOutOfOrder(err):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		level.Error(h.logger).Log("msg", "error appending remote write", "err", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
+
+type writeStats struct {
+	samples, histograms, exemplars int
+}
+
+// badRequestError is returned when the content of a request is invalid.
+type badRequestError struct{ error }
+
+func (e badRequestError) Unwrap() error { return e.error }
+
+func isOutOfOrder(err error) bool {
+	return errors.Is(err, storage.ErrOutOfOrderSample) ||
+		errors.Is(err, storage.ErrOutOfBounds) ||
+		errors.Is(err, storage.ErrDuplicateSampleForTimestamp) ||
+		errors.Is(err, storage.ErrTooOldSample)
+}
+
+// writeV2 appends the content of req. Series with invalid content are skipped
+// and reported as a badRequestError once the other series have been written.
+func (h *writeHandler) writeV2(ctx context.Context, req *writev2.Request) (stats writeStats, err error) {
+	app := h.appendable.Appender(ctx)
+	defer func() {
+		if err != nil && !errors.As(err, new(badRequestError)) {
+			_ = app.Rollback()
+			stats = writeStats{}
+			return
+		}
+		if commitErr := app.Commit(); commitErr != nil {
+			stats, err = writeStats{}, commitErr
+		}
+	}()
+
+	var (
+		badRequestErrs []error
+		b              labels.ScratchBuilder
+	)
+	for _, ts := range req.Timeseries {
+		// The conversions of the writev2 package don't check references.
+		if err := checkRefs(ts, len(req.Symbols)); err != nil {
+			badRequestErrs = append(badRequestErrs, err)
+			continue
+		}
+		l := ts.ToLabels(&b, req.Symbols)
+		if !l.IsValid() {
+			badRequestErrs = append(badRequestErrs, fmt.Errorf("invalid metric name or labels: %s", l))
+			continue
+		}
+
+		var m *metadata.Metadata
+		if ts.Metadata.Type != writev2.Metadata_METRIC_TYPE_UNSPECIFIED || ts.Metadata.HelpRef != 0 || ts.Metadata.UnitRef != 0 {
+			md := ts.ToMetadata(req.Symbols)
+			m = &md
+		}
+
+		var (
+			nativeHistograms []writev2.Histogram
+			classicSeries    []classicSample
+		)
+		for _, hp := range ts.Histograms {
+			if hp.Schema != writev2.CustomBucketsSchema {
+				nativeHistograms = append(nativeHistograms, hp)
+				continue
+			}
+			samples, err := customBucketsToClassic(l, hp)
+			if err != nil {
+				badRequestErrs = append(badRequestErrs, fmt.Errorf("series %s: %w", l, err))
+				continue
+			}
+			classicSeries = append(classicSeries, samples...)
+			stats.histograms++
+		}
+
+		// Native histograms with custom buckets are written as the series of
+		// the equivalent classic histogram, as the histogram model in use
+		// doesn't support custom buckets. The metadata and the created
+		// timestamp are written before the samples.
+		var written []labels.Labels
+		if len(ts.Samples) > 0 || len(nativeHistograms) > 0 || len(classicSeries) == 0 {
+			written = append(written, l)
+		}
+		written = append(written, classicSeriesLabels(classicSeries)...)
+		for _, sl := range written {
+			h.writeSeriesInfo(app, sl, m, ts)
+		}
+
+		var ref storage.SeriesRef
+		for _, s := range ts.Samples {
+			ref, err = app.Append(ref, l, s.Timestamp, s.Value)
+			if err != nil {
+				if isOutOfOrder(err) {
+					level.Error(h.logger).Log("msg", "out of order sample from remote write", "err", err, "series", l, "timestamp", s.Timestamp)
+				}
+				return stats, err
+			}
+			stats.samples++
+		}
+
+		for _, cs := range classicSeries {
+			if _, err := app.Append(0, cs.labels, cs.t, cs.v); err != nil {
+				if isOutOfOrder(err) {
+					level.Error(h.logger).Log("msg", "out of order histogram from remote write", "err", err, "series", cs.labels, "timestamp", cs.t)
+				}
+				return stats, err
+			}
+		}
+
+		for _, hp := range nativeHistograms {
+			if hp.IsFloatHistogram() {
+				_, err = app.AppendHistogram(0, l, hp.Timestamp, nil, hp.ToFloatHistogram())
+			} else {
+				_, err = app.AppendHistogram(0, l, hp.Timestamp, hp.ToIntHistogram(), nil)
+			}
+			if err != nil {
+				if isOutOfOrder(err) {
+					level.Error(h.logger).Log("msg", "out of order histogram from remote write", "err", err, "series", l, "timestamp", hp.Timestamp)
+				}
+				return stats, err
+			}
+			stats.histograms++
+		}
+
+		for _, ep := range ts.Exemplars {
+			e := ep.ToExemplar(&b, req.Symbols)
+			el := l
+			if len(classicSeries) > 0 {
+				el = exemplarBucket(classicSeries, e.Value)
+			}
+			// Exemplar ingestion errors don't fail the request, as in Remote-Write 1.0.
+			if _, err := app.AppendExemplar(0, el, e); err != nil {
+				level.Debug(h.logger).Log("msg", "error appending exemplar", "series", el, "err", err)
+				continue
+			}
+			stats.exemplars++
+		}
+	}
+
+	if len(badRequestErrs) > 0 {
+		return stats, badRequestError{errors.Join(badRequestErrs...)}
+	}
+	return stats, nil
+}
+
+// writeSeriesInfo updates the metadata and created timestamp of the series l,
+// which holds the samples or histograms of ts. Errors are only logged, as
+// they don't prevent samples from being written.
+func (h *writeHandler) writeSeriesInfo(app storage.Appender, l labels.Labels, m *metadata.Metadata, ts writev2.TimeSeries) {
+	if m != nil {
+		if _, err := app.UpdateMetadata(0, l, *m); err != nil {
+			level.Debug(h.logger).Log("msg", "error updating metadata", "series", l, "err", err)
+		}
+	}
+
+	if ts.CreatedTimestamp != 0 {
+		if t, ok := firstTimestamp(ts); ok {
+			_, err := app.AppendCTZeroSample(0, l, t, ts.CreatedTimestamp)
+			if err != nil && !errors.Is(err, storage.ErrOutOfOrderCT) {
+				level.Debug(h.logger).Log("msg", "error appending created timestamp", "series", l, "err", err)
+			}
+		}
+	}
+}
+
+// checkRefs returns an error if ts references symbols which don't exist.
+func checkRefs(ts writev2.TimeSeries, symbols int) error {
+	check := func(refs ...uint32) error {
+		for _, ref := range refs {
+			if int(ref) >= symbols {
+				return fmt.Errorf("symbol reference %d out of range, the request has %d symbols", ref, symbols)
+			}
+		}
+		return nil
+	}
+
+	if len(ts.LabelsRefs)%2 != 0 {
+		return fmt.Errorf("odd number of label references: %d", len(ts.LabelsRefs))
+	}
+	if err := check(ts.LabelsRefs...); err != nil {
+		return err
+	}
+	if err := check(ts.Metadata.HelpRef, ts.Metadata.UnitRef); err != nil {
+		return err
+	}
+	for _, e := range ts.Exemplars {
+		if len(e.LabelsRefs)%2 != 0 {
+			return fmt.Errorf("odd number of exemplar label references: %d", len(e.LabelsRefs))
+		}
+		if err := check(e.LabelsRefs...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// classicSample is a sample of a series of a classic histogram.
+type classicSample struct {
+	labels labels.Labels
+	t      int64
+	v      float64
+	// le is the upper bound of the bucket of _bucket series.
+	le float64
+}
+
+// customBucketsToClassic converts a native histogram with custom buckets of
+// the series l to the samples of the equivalent classic histogram: a
+// cumulative _bucket series for each bucket boundary and for +Inf, a _count
+// and a _sum series.
+func customBucketsToClassic(l labels.Labels, hp writev2.Histogram) ([]classicSample, error) {
+	bounds := hp.CustomValues
+	for i, b := range bounds {
+		if math.IsNaN(b) || math.IsInf(b, 0) || (i > 0 && b <= bounds[i-1]) {
+			return nil, fmt.Errorf("custom bucket boundaries must be finite and increasing")
+		}
+	}
+	if len(hp.NegativeSpans) > 0 {
+		return nil, fmt.Errorf("histograms with custom buckets can't have negative buckets")
+	}
+
+	// The last bucket is the +Inf bucket.
+	counts := make([]float64, len(bounds)+1)
+	var (
+		idx       int32
+		bucket    int
+		delta     int64
+		numDeltas = len(hp.PositiveDeltas)
+	)
+	for _, span := range hp.PositiveSpans {
+		idx += span.Offset
+		for j := uint32(0); j < span.Length; j++ {
+			if idx < 0 || int(idx) >= len(counts) {
+				return nil, fmt.Errorf("bucket index %d out of range for %d custom bucket boundaries", idx, len(bounds))
+			}
+			switch {
+			case hp.IsFloatHistogram() && bucket < len(hp.PositiveCounts):
+				counts[idx] = hp.PositiveCounts[bucket]
+			case !hp.IsFloatHistogram() && bucket < numDeltas:
+				delta += hp.PositiveDeltas[bucket]
+				counts[idx] = float64(delta)
+			default:
+				return nil, fmt.Errorf("histogram spans don't match its buckets")
+			}
+			bucket++
+			idx++
+		}
+	}
+
+	count := float64(hp.GetCountInt())
+	if hp.IsFloatHistogram() {
+		count = hp.GetCountFloat()
+	}
+	stale := value.IsStaleNaN(hp.Sum)
+	sample := func(lb *labels.Builder, v float64, le float64) classicSample {
+		if stale {
+			v = math.Float64frombits(value.StaleNaN)
+		}
+		return classicSample{labels: lb.Labels(), t: hp.Timestamp, v: v, le: le}
+	}
+
+	name := l.Get(labels.MetricName)
+	lb := labels.NewBuilder(l)
+	samples := make([]classicSample, 0, len(counts)+2)
+
+	lb.Set(labels.MetricName, name+"_bucket")
+	var cumulative float64
+	for i, b := range bounds {
+		cumulative += counts[i]
+		lb.Set(labels.BucketLabel, strconv.FormatFloat(b, 'g', -1, 64))
+		samples = append(samples, sample(lb, cumulative, b))
+	}
+	lb.Set(labels.BucketLabel, "+Inf")
+	samples = append(samples, sample(lb, count, math.Inf(1)))
+
+	lb.Del(labels.BucketLabel)
+	lb.Set(labels.MetricName, name+"_count")
+	samples = append(samples, sample(lb, count, 0))
+	lb.Set(labels.MetricName, name+"_sum")
+	samples = append(samples, sample(lb, hp.Sum, 0))
+	return samples, nil
+}
+
+// classicSeriesLabels returns the distinct series of samples.
+func classicSeriesLabels(samples []classicSample) []labels.Labels {
+	var (
+		res  []labels.Labels
+		seen = make(map[uint64]struct{})
+	)
+	for _, s := range samples {
+		h := s.labels.Hash()
+		if _, ok := seen[h]; ok {
+			continue
+		}
+		seen[h] = struct{}{}
+		res = append(res, s.labels)
+	}
+	return res
+}
+
+// exemplarBucket returns the _bucket series of samples in which an exemplar
+// with the value v falls, as exemplars of classic histograms are attached to
+// their bucket.
+func exemplarBucket(samples []classicSample, v float64) labels.Labels {
+	for _, s := range samples {
+		if s.labels.Has(labels.BucketLabel) && v <= s.le {
+			return s.labels
+		}
+	}
+	return samples[0].labels
+}
+
+// firstTimestamp returns the timestamp of the first sample or histogram of
+// ts.
+func firstTimestamp(ts writev2.TimeSeries) (int64, bool) {
+	switch {
+	case len(ts.Samples) > 0:
+		return ts.Samples[0].Timestamp, true
+	case len(ts.Histograms) > 0:
+		return ts.Histograms[0].Timestamp, true
+	}
+	return 0, false
+}
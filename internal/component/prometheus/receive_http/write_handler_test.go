@@ -0,0 +1,222 @@
+package receive_http
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/golang/snappy"
+	"github.com/grafana/alloy/internal/component/prometheus/internal/writev2"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/prometheus/model/exemplar"
+	"github.com/prometheus/prometheus/model/histogram"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/metadata"
+	"github.com/prometheus/prometheus/storage"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteHandler_V2(t *testing.T) {
+	app := &recordingAppender{}
+	handler := newWriteHandler(log.NewNopLogger(), prometheus.NewRegistry(), app)
+
+	st := writev2.NewSymbolTable()
+	h := &histogram.Histogram{Count: 2, Sum: 3, Schema: 0, PositiveSpans: []histogram.Span{{Offset: 0, Length: 1}}, PositiveBuckets: []int64{2}}
+	req := writev2.Request{
+		Timeseries: []writev2.TimeSeries{
+			{
+				LabelsRefs: st.SymbolizeLabels(labels.FromStrings("__name__", "requests_total"), nil),
+				Samples:    []writev2.Sample{{Value: 7, Timestamp: 2000}},
+				Exemplars:  []writev2.Exemplar{{LabelsRefs: st.SymbolizeLabels(labels.FromStrings("trace_id", "abc"), nil), Value: 7, Timestamp: 2000}},
+				Metadata: writev2.Metadata{
+					Type:    writev2.Metadata_METRIC_TYPE_COUNTER,
+					HelpRef: st.Symbolize("Number of requests."),
+					UnitRef: st.Symbolize("requests"),
+				},
+				CreatedTimestamp: 1000,
+			},
+			{
+				LabelsRefs: st.SymbolizeLabels(labels.FromStrings("__name__", "latency_seconds"), nil),
+				Histograms: []writev2.Histogram{writev2.FromIntHistogram(2000, h)},
+			},
+		},
+	}
+	req.Symbols = st.Symbols()
+
+	resp := serveV2(t, handler, &req)
+	require.Equal(t, http.StatusNoContent, resp.Code, resp.Body.String())
+	require.Equal(t, "1", resp.Header().Get(headerSamplesWritten))
+	require.Equal(t, "1", resp.Header().Get(headerHistogramsWritten))
+	require.Equal(t, "1", resp.Header().Get(headerExemplarsWritten))
+
+	require.True(t, app.committed)
+	require.Equal(t, []string{
+		`metadata {__name__="requests_total"} counter "Number of requests." requests`,
+		`ct {__name__="requests_total"} 1000`,
+		`sample {__name__="requests_total"} 2000 7`,
+		`exemplar {__name__="requests_total"} {trace_id="abc"}`,
+		`histogram {__name__="latency_seconds"} 2000 count=2`,
+	}, app.calls)
+}
+
+func TestWriteHandler_V2CustomBuckets(t *testing.T) {
+	app := &recordingAppender{}
+	handler := newWriteHandler(log.NewNopLogger(), prometheus.NewRegistry(), app)
+
+	st := writev2.NewSymbolTable()
+	req := writev2.Request{
+		Timeseries: []writev2.TimeSeries{
+			{
+				LabelsRefs: st.SymbolizeLabels(labels.FromStrings("__name__", "nhcb", "job", "test"), nil),
+				Histograms: []writev2.Histogram{{
+					Count:         &writev2.Histogram_CountInt{CountInt: 6},
+					Sum:           10,
+					Schema:        writev2.CustomBucketsSchema,
+					PositiveSpans: []writev2.BucketSpan{{Offset: 0, Length: 1}, {Offset: 1, Length: 2}},
+					// Buckets 0, 2 and 3 (+Inf) hold 1, 3 and 2 observations.
+					PositiveDeltas: []int64{1, 2, -1},
+					CustomValues:   []float64{0.5, 1, 2.5},
+					Timestamp:      1000,
+				}},
+				Exemplars: []writev2.Exemplar{{LabelsRefs: st.SymbolizeLabels(labels.FromStrings("trace_id", "abc"), nil), Value: 2, Timestamp: 1000}},
+			},
+			{
+				LabelsRefs: st.SymbolizeLabels(labels.FromStrings("__name__", "invalid"), nil),
+				Histograms: []writev2.Histogram{{
+					Count:        &writev2.Histogram_CountInt{CountInt: 1},
+					Schema:       writev2.CustomBucketsSchema,
+					CustomValues: []float64{2, 1},
+					Timestamp:    1000,
+				}},
+			},
+		},
+	}
+	req.Symbols = st.Symbols()
+
+	// Native histograms with custom buckets are written as classic
+	// histograms, and valid series are written even though the request is
+	// rejected.
+	resp := serveV2(t, handler, &req)
+	require.Equal(t, http.StatusBadRequest, resp.Code)
+	require.Contains(t, resp.Body.String(), "custom bucket boundaries must be finite and increasing")
+	require.Equal(t, "1", resp.Header().Get(headerHistogramsWritten))
+	require.Equal(t, "1", resp.Header().Get(headerExemplarsWritten))
+	require.True(t, app.committed)
+	require.Equal(t, []string{
+		`sample {__name__="nhcb_bucket", job="test", le="0.5"} 1000 1`,
+		`sample {__name__="nhcb_bucket", job="test", le="1"} 1000 1`,
+		`sample {__name__="nhcb_bucket", job="test", le="2.5"} 1000 4`,
+		`sample {__name__="nhcb_bucket", job="test", le="+Inf"} 1000 6`,
+		`sample {__name__="nhcb_count", job="test"} 1000 6`,
+		`sample {__name__="nhcb_sum", job="test"} 1000 10`,
+		`exemplar {__name__="nhcb_bucket", job="test", le="2.5"} {trace_id="abc"}`,
+	}, app.calls)
+}
+
+func TestWriteHandler_V2InvalidReferences(t *testing.T) {
+	app := &recordingAppender{}
+	handler := newWriteHandler(log.NewNopLogger(), prometheus.NewRegistry(), app)
+
+	req := writev2.Request{
+		Symbols: []string{"", "__name__", "up"},
+		Timeseries: []writev2.TimeSeries{
+			{LabelsRefs: []uint32{1, 3}, Samples: []writev2.Sample{{Value: 1, Timestamp: 1000}}},
+			{LabelsRefs: []uint32{1}, Samples: []writev2.Sample{{Value: 1, Timestamp: 1000}}},
+			{LabelsRefs: []uint32{1, 2}, Samples: []writev2.Sample{{Value: 1, Timestamp: 1000}}, Metadata: writev2.Metadata{HelpRef: 7}},
+		},
+	}
+
+	resp := serveV2(t, handler, &req)
+	require.Equal(t, http.StatusBadRequest, resp.Code)
+	require.Contains(t, resp.Body.String(), "out of range")
+	require.Empty(t, app.calls)
+}
+
+func TestWriteHandler_ContentNegotiation(t *testing.T) {
+	handler := newWriteHandler(log.NewNopLogger(), prometheus.NewRegistry(), &recordingAppender{})
+
+	resp := httptest.NewRecorder()
+	handler.ServeHTTP(resp, httptest.NewRequest(http.MethodHead, "/api/v1/metrics/write", nil))
+	require.Equal(t, http.StatusOK, resp.Code)
+	require.Contains(t, resp.Header().Get("Accept-Post"), "proto=io.prometheus.write.v2.Request")
+
+	for _, contentType := range []string{"application/json", "application/x-protobuf;proto=io.prometheus.write.v3.Request"} {
+		req := httptest.NewRequest(http.MethodPost, "/api/v1/metrics/write", nil)
+		req.Header.Set("Content-Type", contentType)
+		resp := httptest.NewRecorder()
+		handler.ServeHTTP(resp, req)
+		require.Equal(t, http.StatusUnsupportedMediaType, resp.Code, contentType)
+		require.Contains(t, resp.Header().Get("Accept-Post"), "proto=prometheus.WriteRequest")
+	}
+}
+
+func serveV2(t *testing.T, handler http.Handler, req *writev2.Request) *httptest.ResponseRecorder {
+	t.Helper()
+
+	raw, err := req.Marshal()
+	require.NoError(t, err)
+	httpReq := httptest.NewRequest(http.MethodPost, "/api/v1/metrics/write", bytes.NewReader(snappy.Encode(nil, raw)))
+	httpReq.Header.Set("Content-Type", "application/x-protobuf;proto=io.prometheus.write.v2.Request")
+	httpReq.Header.Set("Content-Encoding", "snappy")
+	httpReq.Header.Set("X-Prometheus-Remote-Write-Version", "2.0.0")
+
+	resp := httptest.NewRecorder()
+	handler.ServeHTTP(resp, httpReq)
+	return resp
+}
+
+// recordingAppender records the calls it receives as strings.
+type recordingAppender struct {
+	mut       sync.Mutex
+	calls     []string
+	committed bool
+}
+
+var _ storage.Appender = (*recordingAppender)(nil)
+
+func (a *recordingAppender) Appender(context.Context) storage.Appender { return a }
+
+func (a *recordingAppender) record(format string, args ...any) {
+	a.mut.Lock()
+	defer a.mut.Unlock()
+	a.calls = append(a.calls, fmt.Sprintf(format, args...))
+}
+
+func (a *recordingAppender) Append(_ storage.SeriesRef, l labels.Labels, t int64, v float64) (storage.SeriesRef, error) {
+	a.record("sample %s %d %g", l, t, v)
+	return 0, nil
+}
+
+func (a *recordingAppender) AppendExemplar(_ storage.SeriesRef, l labels.Labels, e exemplar.Exemplar) (storage.SeriesRef, error) {
+	a.record("exemplar %s %s", l, e.Labels)
+	return 0, nil
+}
+
+func (a *recordingAppender) AppendHistogram(_ storage.SeriesRef, l labels.Labels, t int64, h *histogram.Histogram, _ *histogram.FloatHistogram) (storage.SeriesRef, error) {
+	a.record("histogram %s %d count=%d", l, t, h.Count)
+	return 0, nil
+}
+
+func (a *recordingAppender) UpdateMetadata(_ storage.SeriesRef, l labels.Labels, m metadata.Metadata) (storage.SeriesRef, error) {
+	a.record("metadata %s %s %q %s", l, m.Type, m.Help, m.Unit)
+	return 0, nil
+}
+
+func (a *recordingAppender) AppendCTZeroSample(_ storage.SeriesRef, l labels.Labels, _, ct int64) (storage.SeriesRef, error) {
+	a.record("ct %s %d", l, ct)
+	return 0, nil
+}
+
+func (a *recordingAppender) Commit() error {
+	a.mut.Lock()
+	defer a.mut.Unlock()
+	a.committed = true
+	return nil
+}
+
+func (a *recordingAppender) Rollback() error { return nil }
@@ -49,6 +49,9 @@ type Arguments struct {
 
 	// Cache size to use for LRU cache.
 	CacheSize int `alloy:"max_cache_size,attr,optional"`
+
+	// How the components in ForwardTo are isolated from each other.
+	Isolation *prometheus.IsolationArguments `alloy:"isolation,block,optional"`
 }
 
 // SetToDefault implements syntax.Defaulter.
@@ -95,8 +98,9 @@ type Component struct {
 }
 
 var (
-	_ component.Component     = (*Component)(nil)
-	_ component.LiveDebugging = (*Component)(nil)
+	_ component.Component       = (*Component)(nil)
+	_ component.HealthComponent = (*Component)(nil)
+	_ component.LiveDebugging   = (*Component)(nil)
 )
 
 // New creates a new prometheus.relabel component.
@@ -219,6 +223,7 @@ func New(o component.Options, args Arguments) (*Component, error) {
 // Run implements component.Component.
 func (c *Component) Run(ctx context.Context) error {
 	defer c.exited.Store(true)
+	defer c.fanout.Close()
 
 	<-ctx.Done()
 	return nil
@@ -233,12 +238,18 @@ func (c *Component) Update(args component.Arguments) error {
 	c.clearCache(newArgs.CacheSize)
 	c.mrc = alloy_relabel.ComponentToPromRelabelConfigs(newArgs.MetricRelabelConfigs)
 	c.fanout.UpdateChildren(newArgs.ForwardTo)
+	c.fanout.UpdateIsolation(newArgs.Isolation)
 
 	c.opts.OnStateChange(Exports{Receiver: c.receiver, Rules: newArgs.MetricRelabelConfigs})
 
 	return nil
 }
 
+// CurrentHealth implements component.HealthComponent.
+func (c *Component) CurrentHealth() component.Health {
+	return c.fanout.CurrentHealth()
+}
+
 func (c *Component) relabel(val float64, lbls labels.Labels) labels.Labels {
 	c.mut.RLock()
 	defer c.mut.RUnlock()
@@ -0,0 +1,568 @@
+package remotewrite
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/subtle"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/golang/snappy"
+	"github.com/grafana/alloy/internal/component/prometheus/internal/writev2"
+	"github.com/grafana/alloy/internal/runtime/logging/level"
+	common "github.com/prometheus/common/config"
+	"github.com/prometheus/common/sigv4"
+	"github.com/prometheus/prometheus/config"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/prompb"
+	"github.com/prometheus/prometheus/storage/remote"
+	"github.com/prometheus/prometheus/storage/remote/azuread"
+	"go.uber.org/atomic"
+	"gopkg.in/yaml.v2"
+)
+
+const (
+	contentTypeV1 = "application/x-protobuf"
+	contentTypeV2 = "application/x-protobuf;proto=" + writev2.ProtoMessageV2
+
+	versionV1 = "0.1.0"
+	versionV2 = "2.0.0"
+
+	// maxErrMsgLen is the maximum length of the response bodies relayed to the
+	// queue manager, which only logs their first line.
+	maxErrMsgLen = 1024
+)
+
+// v2ProbeInterval is how long a forwarder keeps using the Remote-Write 1.0
+// protocol after the endpoint rejected a Remote-Write 2.0 request, before
+// trying the Remote-Write 2.0 protocol again.
+const v2ProbeInterval = 5 * time.Minute
+
+// forwarder sends the requests of a queue manager of the remote storage to an
+// endpoint using the Remote-Write 2.0 protocol.
+//
+// The remote storage of the Prometheus version in use only supports the
+// Remote-Write 1.0 protocol. The queue manager of an endpoint using the 2.0
+// protocol keeps the URL of the endpoint, so that its metrics are labelled
+// with it, but is configured to send its requests through a forwarder
+// listening on the loopback interface as an HTTP proxy. The forwarder
+// converts the requests and sends them to the endpoint. Requests to HTTPS
+// endpoints are tunneled through the proxy and terminated by the forwarder
+// with a certificate only the queue manager trusts. The status of the
+// response of the endpoint is returned to the queue manager, which keeps
+// handling retries and backoff.
+//
+// Remote-Write 2.0 sends the metadata and the created timestamp of a series
+// along with its samples. The forwarder looks them up in a seriesCache shared
+// by the forwarders of a component.
+//
+// If the endpoint rejects Remote-Write 2.0 requests with a 415 Unsupported
+// Media Type status, the forwarder falls back to the Remote-Write 1.0
+// protocol, and tries the Remote-Write 2.0 protocol again after
+// v2ProbeInterval.
+type forwarder struct {
+	log       log.Logger
+	proxyAuth string // expected Proxy-Authorization header of requests
+	proxyURL  *url.URL
+	cache     *seriesCache
+
+	clientMut sync.RWMutex
+	url       string
+	client    *http.Client
+
+	// tunnelHost is the host:port of the tunnels the forwarder accepts, and
+	// tunnelCA the PEM encoded certificate it terminates them with.
+	tunnelHost string
+	tunnelTLS  *tls.Config
+	tunnelCA   string
+
+	listener net.Listener
+	srv      *http.Server
+	tunnels  *connListener
+	tunnel   *http.Server
+
+	// downgradedAt is the Unix time in nanoseconds at which the endpoint last
+	// rejected a Remote-Write 2.0 request, or 0.
+	downgradedAt atomic.Int64
+}
+
+// newForwarder creates a forwarder sending to the endpoint of cfg, and starts
+// listening for requests.
+func newForwarder(logger log.Logger, cfg *config.RemoteWriteConfig, cache *seriesCache) (*forwarder, error) {
+	token := make([]byte, 16)
+	if _, err := rand.Read(token); err != nil {
+		return nil, err
+	}
+
+	cert, ca, err := newTunnelCertificate(cfg.URL.Hostname())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create forwarder certificate: %w", err)
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen for remote_write requests: %w", err)
+	}
+
+	const user = "alloy"
+	f := &forwarder{
+		log:        log.With(logger, "subcomponent", "forwarder", "url", cfg.URL.Redacted()),
+		proxyAuth:  "Basic " + base64.StdEncoding.EncodeToString([]byte(user+":"+hex.EncodeToString(token))),
+		proxyURL:   &url.URL{Scheme: "http", User: url.UserPassword(user, hex.EncodeToString(token)), Host: listener.Addr().String()},
+		cache:      cache,
+		tunnelHost: canonicalHost(cfg.URL.URL),
+		tunnelTLS: &tls.Config{
+			Certificates: []tls.Certificate{cert},
+			NextProtos:   []string{"http/1.1"},
+		},
+		tunnelCA: ca,
+		listener: listener,
+		tunnels:  newConnListener(listener.Addr()),
+	}
+	f.srv = &http.Server{Handler: http.HandlerFunc(f.serveProxy)}
+	// Tunnels are authorized when they're established.
+	f.tunnel = &http.Server{Handler: http.HandlerFunc(f.serveWrite)}
+	if err := f.ApplyConfig(cfg); err != nil {
+		_ = listener.Close()
+		return nil, err
+	}
+
+	go func() {
+		if err := f.srv.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			level.Error(f.log).Log("msg", "forwarder stopped", "err", err)
+		}
+	}()
+	go func() {
+		if err := f.tunnel.Serve(f.tunnels); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			level.Error(f.log).Log("msg", "forwarder stopped", "err", err)
+		}
+	}()
+	return f, nil
+}
+
+// ApplyConfig updates the client used to send requests to the endpoint of
+// cfg. Secrets aren't part of the hash identifying endpoints, so the client is
+// recreated on every update of the component. Updating the forwarder makes it
+// try the Remote-Write 2.0 protocol again.
+func (f *forwarder) ApplyConfig(cfg *config.RemoteWriteConfig) error {
+	client, err := newForwarderClient(cfg)
+	if err != nil {
+		return err
+	}
+
+	f.clientMut.Lock()
+	defer f.clientMut.Unlock()
+	f.url = cfg.URL.String()
+	f.client = client
+	f.downgradedAt.Store(0)
+	return nil
+}
+
+// newForwarderClient returns the HTTP client used to send requests to the
+// endpoint of cfg, built the same way as the client of the remote storage.
+func newForwarderClient(cfg *config.RemoteWriteConfig) (*http.Client, error) {
+	client, err := common.NewClientFromConfig(cfg.HTTPClientConfig, "remote_storage_write_client")
+	if err != nil {
+		return nil, err
+	}
+	t := client.Transport
+
+	if len(cfg.Headers) > 0 {
+		t = &headersRoundTripper{headers: cfg.Headers, next: t}
+	}
+	if cfg.SigV4Config != nil {
+		t, err = sigv4.NewSigV4RoundTripper(cfg.SigV4Config, t)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if cfg.AzureADConfig != nil {
+		t, err = azuread.NewAzureADRoundTripper(cfg.AzureADConfig, t)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	client.Transport = t
+	return client, nil
+}
+
+// loopbackConfig returns a copy of cfg sending requests through the
+// forwarder. The name of the copy is the name the remote storage gives to
+// cfg, so that the metrics of the queue don't depend on the address of the
+// forwarder.
+func (f *forwarder) loopbackConfig(cfg *config.RemoteWriteConfig) (*config.RemoteWriteConfig, error) {
+	name := cfg.Name
+	if name == "" {
+		hash, err := configHash(cfg)
+		if err != nil {
+			return nil, err
+		}
+		name = hash[:6]
+	}
+
+	loopback := *cfg
+	loopback.Name = name
+	loopback.Headers = nil
+	loopback.SigV4Config = nil
+	loopback.AzureADConfig = nil
+	loopback.HTTPClientConfig = common.DefaultHTTPClientConfig
+	loopback.HTTPClientConfig.ProxyURL = common.URL{URL: f.proxyURL}
+	loopback.HTTPClientConfig.TLSConfig = common.TLSConfig{CA: f.tunnelCA}
+	return &loopback, nil
+}
+
+// Close stops the forwarder.
+func (f *forwarder) Close() error {
+	return errors.Join(f.srv.Close(), f.tunnel.Close())
+}
+
+// serveProxy handles the requests the queue manager sends to the forwarder as
+// an HTTP proxy: CONNECT requests establishing tunnels to HTTPS endpoints, and
+// the requests to HTTP endpoints.
+func (f *forwarder) serveProxy(w http.ResponseWriter, r *http.Request) {
+	auth := r.Header.Get("Proxy-Authorization")
+	if subtle.ConstantTimeCompare([]byte(auth), []byte(f.proxyAuth)) != 1 {
+		http.Error(w, "proxy authentication required", http.StatusProxyAuthRequired)
+		return
+	}
+
+	if r.Method == http.MethodConnect {
+		f.serveConnect(w, r)
+		return
+	}
+	f.serveWrite(w, r)
+}
+
+// serveConnect establishes a tunnel to the endpoint and terminates it.
+func (f *forwarder) serveConnect(w http.ResponseWriter, r *http.Request) {
+	if r.Host != f.tunnelHost {
+		http.Error(w, fmt.Sprintf("tunnels to %s aren't allowed", r.Host), http.StatusForbidden)
+		return
+	}
+
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "tunnels aren't supported", http.StatusInternalServerError)
+		return
+	}
+	conn, rw, err := hijacker.Hijack()
+	if err != nil {
+		level.Warn(f.log).Log("msg", "failed to establish tunnel", "err", err)
+		return
+	}
+	// Clients wait for the response before starting the TLS handshake.
+	if rw.Reader.Buffered() > 0 {
+		_ = conn.Close()
+		return
+	}
+	if _, err := io.WriteString(conn, "HTTP/1.1 200 Connection established\r\n\r\n"); err != nil {
+		_ = conn.Close()
+		return
+	}
+	f.tunnels.push(tls.Server(conn, f.tunnelTLS))
+}
+
+// serveWrite converts the Remote-Write 1.0 request r of the queue manager
+// and sends it to the endpoint.
+func (f *forwarder) serveWrite(w http.ResponseWriter, r *http.Request) {
+	compressed, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if at := f.downgradedAt.Load(); at != 0 && time.Since(time.Unix(0, at)) < v2ProbeInterval {
+		f.send(w, r, compressed, contentTypeV1, versionV1)
+		return
+	}
+
+	raw, err := snappy.Decode(nil, compressed)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	var req prompb.WriteRequest
+	if err := req.Unmarshal(raw); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	f.cache.StoreMetadataProtos(req.Metadata)
+	if len(req.Timeseries) == 0 {
+		// Metadata is sent along with the series in Remote-Write 2.0.
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	data, err := f.convert(&req).Marshal()
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	resp, err := f.post(r, snappy.Encode(nil, data), contentTypeV2, versionV2)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	if resp.StatusCode == http.StatusUnsupportedMediaType {
+		_ = drain(resp)
+		level.Warn(f.log).Log("msg", "endpoint doesn't support the Remote-Write 2.0 protocol, falling back to the Remote-Write 1.0 protocol", "retry_in", v2ProbeInterval)
+		f.downgradedAt.Store(time.Now().UnixNano())
+		f.send(w, r, compressed, contentTypeV1, versionV1)
+		return
+	}
+	if f.downgradedAt.Swap(0) != 0 {
+		level.Info(f.log).Log("msg", "endpoint supports the Remote-Write 2.0 protocol again")
+	}
+	relay(w, resp)
+}
+
+// send sends body to the endpoint and relays its response to w.
+func (f *forwarder) send(w http.ResponseWriter, r *http.Request, body []byte, contentType, version string) {
+	resp, err := f.post(r, body, contentType, version)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadGateway)
+		return
+	}
+	relay(w, resp)
+}
+
+// post sends body to the endpoint on behalf of the request r of the queue
+// manager. Errors are network errors, which the queue manager retries.
+func (f *forwarder) post(r *http.Request, body []byte, contentType, version string) (*http.Response, error) {
+	f.clientMut.RLock()
+	endpoint, client := f.url, f.client
+	f.clientMut.RUnlock()
+
+	req, err := http.NewRequestWithContext(r.Context(), http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Encoding", "snappy")
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("User-Agent", r.Header.Get("User-Agent"))
+	req.Header.Set("X-Prometheus-Remote-Write-Version", version)
+	if attempt := r.Header.Get("Retry-Attempt"); attempt != "" {
+		req.Header.Set("Retry-Attempt", attempt)
+	}
+	return client.Do(req)
+}
+
+// relay writes the status and the beginning of the body of resp to w.
+func relay(w http.ResponseWriter, resp *http.Response) {
+	defer drain(resp)
+
+	if retryAfter := resp.Header.Get("Retry-After"); retryAfter != "" {
+		w.Header().Set("Retry-After", retryAfter)
+	}
+	w.WriteHeader(resp.StatusCode)
+	_, _ = io.Copy(w, io.LimitReader(resp.Body, maxErrMsgLen))
+}
+
+func drain(resp *http.Response) error {
+	_, _ = io.Copy(io.Discard, resp.Body)
+	return resp.Body.Close()
+}
+
+// convert converts a Remote-Write 1.0 request to a Remote-Write 2.0 request.
+func (f *forwarder) convert(req *prompb.WriteRequest) *writev2.Request {
+	var (
+		symbols = writev2.NewSymbolTable()
+		b       = labels.NewScratchBuilder(0)
+		out     = make([]writev2.TimeSeries, 0, len(req.Timeseries))
+	)
+
+	for _, ts := range req.Timeseries {
+		lbls := labelProtosToLabels(&b, ts.Labels)
+		series := writev2.TimeSeries{
+			LabelsRefs: symbols.SymbolizeLabels(lbls, nil),
+			Samples:    make([]writev2.Sample, 0, len(ts.Samples)),
+		}
+
+		for _, s := range ts.Samples {
+			series.Samples = append(series.Samples, writev2.Sample{Value: s.Value, Timestamp: s.Timestamp})
+		}
+		for _, hp := range ts.Histograms {
+			if hp.IsFloatHistogram() {
+				series.Histograms = append(series.Histograms, writev2.FromFloatHistogram(hp.Timestamp, remote.FloatHistogramProtoToFloatHistogram(hp)))
+			} else {
+				series.Histograms = append(series.Histograms, writev2.FromIntHistogram(hp.Timestamp, remote.HistogramProtoToHistogram(hp)))
+			}
+		}
+		for _, e := range ts.Exemplars {
+			series.Exemplars = append(series.Exemplars, writev2.Exemplar{
+				LabelsRefs: symbols.SymbolizeLabels(labelProtosToLabels(&b, e.Labels), nil),
+				Value:      e.Value,
+				Timestamp:  e.Timestamp,
+			})
+		}
+
+		if m, ok := f.cache.LookupMetadata(lbls.Get(labels.MetricName)); ok {
+			series.Metadata = writev2.Metadata{
+				Type:    m.typ,
+				HelpRef: symbols.Symbolize(m.help),
+				UnitRef: symbols.Symbolize(m.unit),
+			}
+		}
+		if ct, ok := f.cache.LookupCreatedTimestamp(lbls.Hash()); ok {
+			series.CreatedTimestamp = ct
+		}
+
+		out = append(out, series)
+	}
+
+	return &writev2.Request{
+		Symbols:    symbols.Symbols(),
+		Timeseries: out,
+	}
+}
+
+func labelProtosToLabels(b *labels.ScratchBuilder, lps []prompb.Label) labels.Labels {
+	b.Reset()
+	for _, l := range lps {
+		b.Add(l.Name, l.Value)
+	}
+	b.Sort()
+	return b.Labels()
+}
+
+// configHash returns the hash the remote storage uses to identify cfg.
+func configHash(cfg *config.RemoteWriteConfig) (string, error) {
+	data, err := yaml.Marshal(cfg)
+	if err != nil {
+		return "", err
+	}
+	hash := md5.Sum(data)
+	return hex.EncodeToString(hash[:]), nil
+}
+
+// headersRoundTripper sets the configured headers of the endpoint on
+// requests.
+type headersRoundTripper struct {
+	headers map[string]string
+	next    http.RoundTripper
+}
+
+// RoundTrip implements http.RoundTripper.
+func (rt *headersRoundTripper) RoundTrip(req *http.Request) (*http.Response, error) {
+	req = req.Clone(req.Context())
+	for k, v := range rt.headers {
+		req.Header.Set(k, v)
+	}
+	return rt.next.RoundTrip(req)
+}
+
+// canonicalHost returns the host:port u is sent to, including the default
+// port of its scheme.
+func canonicalHost(u *url.URL) string {
+	port := u.Port()
+	if port == "" {
+		port = "80"
+		if u.Scheme == "https" {
+			port = "443"
+		}
+	}
+	return net.JoinHostPort(u.Hostname(), port)
+}
+
+// newTunnelCertificate returns a self-signed certificate for host, and its
+// PEM encoding.
+func newTunnelCertificate(host string) (tls.Certificate, string, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return tls.Certificate{}, "", err
+	}
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return tls.Certificate{}, "", err
+	}
+
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: "alloy remote_write forwarder"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().AddDate(100, 0, 0),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	if ip := net.ParseIP(host); ip != nil {
+		template.IPAddresses = []net.IP{ip}
+	} else {
+		template.DNSNames = []string{host}
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return tls.Certificate{}, "", err
+	}
+	cert := tls.Certificate{Certificate: [][]byte{der}, PrivateKey: key}
+	return cert, string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})), nil
+}
+
+// connListener is a net.Listener accepting the connections pushed to it.
+type connListener struct {
+	addr      net.Addr
+	conns     chan net.Conn
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+func newConnListener(addr net.Addr) *connListener {
+	return &connListener{
+		addr:  addr,
+		conns: make(chan net.Conn),
+		done:  make(chan struct{}),
+	}
+}
+
+// push hands conn to the next call to Accept, or closes it if the listener is
+// closed.
+func (l *connListener) push(conn net.Conn) {
+	select {
+	case l.conns <- conn:
+	case <-l.done:
+		_ = conn.Close()
+	}
+}
+
+// Accept implements net.Listener.
+func (l *connListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.conns:
+		return conn, nil
+	case <-l.done:
+		return nil, net.ErrClosed
+	}
+}
+
+// Close implements net.Listener.
+func (l *connListener) Close() error {
+	l.closeOnce.Do(func() { close(l.done) })
+	return nil
+}
+
+// Addr implements net.Listener.
+func (l *connListener) Addr() net.Addr {
+	return l.addr
+}
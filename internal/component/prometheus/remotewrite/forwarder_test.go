@@ -0,0 +1,244 @@
+package remotewrite
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/golang/snappy"
+	"github.com/grafana/alloy/internal/component/prometheus/internal/writev2"
+	"github.com/grafana/alloy/internal/util"
+	common "github.com/prometheus/common/config"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/config"
+	"github.com/prometheus/prometheus/model/labels"
+	"github.com/prometheus/prometheus/model/metadata"
+	"github.com/prometheus/prometheus/prompb"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/atomic"
+)
+
+type receivedRequest struct {
+	contentType string
+	body        []byte
+}
+
+func newTestForwarder(t *testing.T, handler func(w http.ResponseWriter, r receivedRequest)) (*forwarder, *config.RemoteWriteConfig, chan receivedRequest) {
+	t.Helper()
+	return newTestForwarderWithServer(t, httptest.NewServer, handler)
+}
+
+// newTestForwarderWithServer creates a forwarder sending requests to an
+// endpoint started with newServer.
+func newTestForwarderWithServer(t *testing.T, newServer func(http.Handler) *httptest.Server, handler func(w http.ResponseWriter, r receivedRequest)) (*forwarder, *config.RemoteWriteConfig, chan receivedRequest) {
+	t.Helper()
+
+	received := make(chan receivedRequest, 10)
+	srv := newServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		compressed, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		body, err := snappy.Decode(nil, compressed)
+		require.NoError(t, err)
+
+		req := receivedRequest{contentType: r.Header.Get("Content-Type"), body: body}
+		received <- req
+		handler(w, req)
+	}))
+	t.Cleanup(srv.Close)
+
+	u, err := url.Parse(srv.URL)
+	require.NoError(t, err)
+	cfg := &config.RemoteWriteConfig{
+		URL:              &common.URL{URL: u},
+		HTTPClientConfig: common.DefaultHTTPClientConfig,
+	}
+	cfg.HTTPClientConfig.TLSConfig.InsecureSkipVerify = true
+
+	f, err := newForwarder(util.TestLogger(t), cfg, newSeriesCache())
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = f.Close() })
+
+	loopback, err := f.loopbackConfig(cfg)
+	require.NoError(t, err)
+	return f, loopback, received
+}
+
+// store sends req to the forwarder the way the remote storage does, and
+// returns the status of the response.
+func store(t *testing.T, loopback *config.RemoteWriteConfig, req *prompb.WriteRequest) int {
+	t.Helper()
+
+	data, err := req.Marshal()
+	require.NoError(t, err)
+	httpReq, err := http.NewRequest(http.MethodPost, loopback.URL.String(), bytes.NewReader(snappy.Encode(nil, data)))
+	require.NoError(t, err)
+
+	client, err := common.NewClientFromConfig(loopback.HTTPClientConfig, "test")
+	require.NoError(t, err)
+	resp, err := client.Do(httpReq)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	return resp.StatusCode
+}
+
+var testSeries = prompb.TimeSeries{
+	Labels:  []prompb.Label{{Name: "__name__", Value: "requests_total"}, {Name: "job", Value: "test"}},
+	Samples: []prompb.Sample{{Value: 1, Timestamp: 1000}},
+}
+
+func TestForwarder(t *testing.T) {
+	_, loopback, received := newTestForwarder(t, func(w http.ResponseWriter, _ receivedRequest) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	// Metadata is cached and sent along with the series.
+	require.Equal(t, http.StatusNoContent, store(t, loopback, &prompb.WriteRequest{
+		Metadata: []prompb.MetricMetadata{{
+			Type:             prompb.MetricMetadata_COUNTER,
+			MetricFamilyName: "requests",
+			Help:             "Number of requests.",
+		}},
+	}))
+	require.Empty(t, received)
+
+	require.Equal(t, http.StatusNoContent, store(t, loopback, &prompb.WriteRequest{
+		Timeseries: []prompb.TimeSeries{testSeries},
+	}))
+	req := <-received
+	require.Equal(t, contentTypeV2, req.contentType)
+
+	var v2 writev2.Request
+	require.NoError(t, v2.Unmarshal(req.body))
+	require.Len(t, v2.Timeseries, 1)
+
+	b := labels.NewScratchBuilder(0)
+	ts := v2.Timeseries[0]
+	require.Equal(t, labels.FromStrings("__name__", "requests_total", "job", "test"), ts.ToLabels(&b, v2.Symbols))
+	require.Equal(t, []writev2.Sample{{Value: 1, Timestamp: 1000}}, ts.Samples)
+	require.Equal(t, writev2.Metadata_METRIC_TYPE_COUNTER, ts.Metadata.Type)
+	require.Equal(t, "Number of requests.", v2.Symbols[ts.Metadata.HelpRef])
+}
+
+func TestForwarder_Unauthorized(t *testing.T) {
+	_, loopback, received := newTestForwarder(t, func(w http.ResponseWriter, _ receivedRequest) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	proxyURL := *loopback.HTTPClientConfig.ProxyURL.URL
+	proxyURL.User = url.UserPassword("alloy", "other")
+	loopback.HTTPClientConfig.ProxyURL = common.URL{URL: &proxyURL}
+	require.Equal(t, http.StatusProxyAuthRequired, store(t, loopback, &prompb.WriteRequest{
+		Timeseries: []prompb.TimeSeries{testSeries},
+	}))
+	require.Empty(t, received)
+}
+
+func TestForwarder_FallbackToV1(t *testing.T) {
+	f, loopback, received := newTestForwarder(t, func(w http.ResponseWriter, r receivedRequest) {
+		if r.contentType == contentTypeV2 {
+			w.WriteHeader(http.StatusUnsupportedMediaType)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	write := &prompb.WriteRequest{Timeseries: []prompb.TimeSeries{testSeries}}
+	require.Equal(t, http.StatusNoContent, store(t, loopback, write))
+	require.Equal(t, contentTypeV2, (<-received).contentType)
+
+	req := <-received
+	require.Equal(t, contentTypeV1, req.contentType)
+	var v1 prompb.WriteRequest
+	require.NoError(t, v1.Unmarshal(req.body))
+	require.Equal(t, write.Timeseries, v1.Timeseries)
+	require.NotZero(t, f.downgradedAt.Load())
+
+	// Following requests are sent using the Remote-Write 1.0 protocol.
+	require.Equal(t, http.StatusNoContent, store(t, loopback, write))
+	require.Equal(t, contentTypeV1, (<-received).contentType)
+	require.Empty(t, received)
+}
+
+func TestForwarder_ProbesV2(t *testing.T) {
+	var supportsV2 atomic.Bool
+	f, loopback, received := newTestForwarder(t, func(w http.ResponseWriter, r receivedRequest) {
+		if r.contentType == contentTypeV2 && !supportsV2.Load() {
+			w.WriteHeader(http.StatusUnsupportedMediaType)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	write := &prompb.WriteRequest{Timeseries: []prompb.TimeSeries{testSeries}}
+	require.Equal(t, http.StatusNoContent, store(t, loopback, write))
+	require.Equal(t, contentTypeV2, (<-received).contentType)
+	require.Equal(t, contentTypeV1, (<-received).contentType)
+
+	// The Remote-Write 2.0 protocol is tried again once v2ProbeInterval passed.
+	supportsV2.Store(true)
+	f.downgradedAt.Store(time.Now().Add(-v2ProbeInterval).UnixNano())
+	require.Equal(t, http.StatusNoContent, store(t, loopback, write))
+	require.Equal(t, contentTypeV2, (<-received).contentType)
+	require.Zero(t, f.downgradedAt.Load())
+
+	// Updating the forwarder tries the Remote-Write 2.0 protocol again.
+	f.downgradedAt.Store(time.Now().UnixNano())
+	require.NoError(t, f.ApplyConfig(&config.RemoteWriteConfig{
+		URL:              &common.URL{URL: loopback.URL.URL},
+		HTTPClientConfig: common.DefaultHTTPClientConfig,
+	}))
+	require.Equal(t, http.StatusNoContent, store(t, loopback, write))
+	require.Equal(t, contentTypeV2, (<-received).contentType)
+	require.Empty(t, received)
+}
+
+func TestForwarder_SeriesCache(t *testing.T) {
+	f, loopback, received := newTestForwarder(t, func(w http.ResponseWriter, _ receivedRequest) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+
+	lbls := labels.FromStrings("__name__", "requests_total", "job", "test")
+	f.cache.StoreMetadata("requests_total", metadata.Metadata{Type: model.MetricTypeCounter, Help: "Requests.", Unit: "requests"})
+	f.cache.StoreCreatedTimestamp(lbls.Hash(), 500)
+
+	require.Equal(t, http.StatusNoContent, store(t, loopback, &prompb.WriteRequest{
+		Timeseries: []prompb.TimeSeries{testSeries},
+	}))
+	var v2 writev2.Request
+	require.NoError(t, v2.Unmarshal((<-received).body))
+	require.Len(t, v2.Timeseries, 1)
+
+	ts := v2.Timeseries[0]
+	require.Equal(t, int64(500), ts.CreatedTimestamp)
+	require.Equal(t, writev2.Metadata_METRIC_TYPE_COUNTER, ts.Metadata.Type)
+	require.Equal(t, "Requests.", v2.Symbols[ts.Metadata.HelpRef])
+	require.Equal(t, "requests", v2.Symbols[ts.Metadata.UnitRef])
+}
+
+func TestForwarder_TLS(t *testing.T) {
+	_, loopback, received := newTestForwarderWithServer(t, httptest.NewTLSServer, func(w http.ResponseWriter, _ receivedRequest) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	require.Equal(t, "https", loopback.URL.Scheme)
+
+	require.Equal(t, http.StatusNoContent, store(t, loopback, &prompb.WriteRequest{
+		Timeseries: []prompb.TimeSeries{testSeries},
+	}))
+	require.Equal(t, contentTypeV2, (<-received).contentType)
+}
+
+func TestForwarder_RelaysStatus(t *testing.T) {
+	_, loopback, received := newTestForwarder(t, func(w http.ResponseWriter, _ receivedRequest) {
+		w.Header().Set("Retry-After", "5")
+		w.WriteHeader(http.StatusTooManyRequests)
+	})
+
+	require.Equal(t, http.StatusTooManyRequests, store(t, loopback, &prompb.WriteRequest{
+		Timeseries: []prompb.TimeSeries{testSeries},
+	}))
+	require.Len(t, received, 1)
+}
@@ -18,6 +18,7 @@ import (
 	"github.com/grafana/alloy/internal/service/labelstore"
 	"github.com/grafana/alloy/internal/static/metrics/wal"
 	"github.com/grafana/alloy/internal/useragent"
+	"github.com/prometheus/prometheus/config"
 	"github.com/prometheus/prometheus/model/exemplar"
 	"github.com/prometheus/prometheus/model/histogram"
 	"github.com/prometheus/prometheus/model/labels"
@@ -61,6 +62,15 @@ type Component struct {
 	mut sync.RWMutex
 	cfg Arguments
 
+	// forwarders holds the forwarders of the endpoints using the Remote-Write
+	// 2.0 protocol, indexed by the hash of their configuration.
+	forwarders map[string]*forwarder
+
+	// seriesCache holds the metadata and created timestamps of series for the
+	// forwarders. It's only filled while there are forwarders.
+	seriesCache   *seriesCache
+	hasForwarders atomic.Bool
+
 	receiver *prometheus.Interceptor
 }
 
@@ -98,6 +108,7 @@ func New(o component.Options, c Arguments) (*Component, error) {
 		walStore:    walStorage,
 		remoteStore: remoteStore,
 		storage:     storage.NewFanout(o.Logger, walStorage, remoteStore),
+		seriesCache: newSeriesCache(),
 	}
 	res.receiver = prometheus.NewInterceptor(
 		res.storage,
@@ -138,6 +149,10 @@ func New(o component.Options, c Arguments) (*Component, error) {
 				return 0, fmt.Errorf("%s has exited", o.ID)
 			}
 
+			if res.hasForwarders.Load() {
+				res.seriesCache.StoreMetadata(l.Get(labels.MetricName), m)
+			}
+
 			localID := ls.GetLocalRefID(res.opts.ID, uint64(globalRef))
 			newRef, nextErr := next.UpdateMetadata(storage.SeriesRef(localID), l, m)
 			if localID == 0 {
@@ -157,6 +172,22 @@ func New(o component.Options, c Arguments) (*Component, error) {
 			}
 			return globalRef, nextErr
 		}),
+		prometheus.WithCTZeroSampleHook(func(globalRef storage.SeriesRef, l labels.Labels, t, ct int64, next storage.Appender) (storage.SeriesRef, error) {
+			if res.exited.Load() {
+				return 0, fmt.Errorf("%s has exited", o.ID)
+			}
+
+			if res.hasForwarders.Load() {
+				res.seriesCache.StoreCreatedTimestamp(l.Hash(), ct)
+			}
+
+			localID := ls.GetLocalRefID(res.opts.ID, uint64(globalRef))
+			newRef, nextErr := next.AppendCTZeroSample(storage.SeriesRef(localID), l, t, ct)
+			if localID == 0 {
+				ls.GetOrAddLink(res.opts.ID, uint64(newRef), l)
+			}
+			return globalRef, nextErr
+		}),
 	)
 
 	// Immediately export the receiver which remains the same for the component
@@ -184,6 +215,11 @@ func (c *Component) Run(ctx context.Context) error {
 		if err != nil {
 			level.Error(c.log).Log("msg", "error when closing storage", "err", err)
 		}
+
+		c.mut.Lock()
+		defer c.mut.Unlock()
+		closeForwarders(c.log, c.forwarders)
+		c.forwarders = nil
 	}()
 
 	// Track the last timestamp we truncated for to prevent segments from getting
@@ -267,11 +303,76 @@ func (c *Component) Update(newConfig component.Arguments) error {
 		cfg.Headers[alloyseed.LegacyHeaderName] = uid
 		cfg.Headers[alloyseed.HeaderName] = uid
 	}
+
+	forwarders, created, err := c.applyForwarders(cfg, convertedConfig)
+	if err != nil {
+		closeForwarders(c.log, created)
+		return err
+	}
 	err = c.remoteStore.ApplyConfig(convertedConfig)
 	if err != nil {
+		closeForwarders(c.log, created)
 		return err
 	}
 
+	for hash, f := range c.forwarders {
+		if _, ok := forwarders[hash]; !ok {
+			closeForwarders(c.log, map[string]*forwarder{hash: f})
+		}
+	}
+	c.forwarders = forwarders
+	c.hasForwarders.Store(len(forwarders) > 0)
 	c.cfg = cfg
 	return nil
 }
+
+// applyForwarders makes the endpoints of convertedConfig which use the
+// Remote-Write 2.0 protocol send their requests through their forwarder. Forwarders are reused across
+// updates, so that the queues of the remote storage aren't recreated.
+//
+// applyForwarders returns the forwarders in use and the ones it created. mut
+// must be held when called.
+func (c *Component) applyForwarders(cfg Arguments, convertedConfig *config.Config) (inUse, created map[string]*forwarder, err error) {
+	inUse = make(map[string]*forwarder)
+	created = make(map[string]*forwarder)
+
+	for i, endpoint := range cfg.Endpoints {
+		if endpoint.Protocol != ProtocolV2 {
+			continue
+		}
+
+		rwConfig := convertedConfig.RemoteWriteConfigs[i]
+		hash, err := configHash(rwConfig)
+		if err != nil {
+			return nil, created, err
+		}
+
+		f, ok := c.forwarders[hash]
+		if ok {
+			err = f.ApplyConfig(rwConfig)
+		} else if f, ok = created[hash]; !ok {
+			f, err = newForwarder(c.log, rwConfig, c.seriesCache)
+			if err == nil {
+				created[hash] = f
+			}
+		}
+		if err != nil {
+			return nil, created, err
+		}
+		inUse[hash] = f
+
+		convertedConfig.RemoteWriteConfigs[i], err = f.loopbackConfig(rwConfig)
+		if err != nil {
+			return nil, created, err
+		}
+	}
+	return inUse, created, nil
+}
+
+func closeForwarders(logger log.Logger, forwarders map[string]*forwarder) {
+	for _, f := range forwarders {
+		if err := f.Close(); err != nil {
+			level.Error(logger).Log("msg", "error when closing remote_write forwarder", "err", err)
+		}
+	}
+}
@@ -3,11 +3,14 @@ package remotewrite_test
 import (
 	"context"
 	"fmt"
+	"io"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 	"time"
 
+	"github.com/golang/snappy"
+	"github.com/grafana/alloy/internal/component/prometheus/internal/writev2"
 	"github.com/grafana/alloy/internal/component/prometheus/remotewrite"
 	"github.com/grafana/alloy/internal/runtime/componenttest"
 	"github.com/grafana/alloy/internal/util"
@@ -89,6 +92,63 @@ func Test(t *testing.T) {
 	}
 }
 
+// TestProtocolV2 ensures that metrics can get sent to an endpoint using the
+// Remote-Write 2.0 protocol.
+func TestProtocolV2(t *testing.T) {
+	writeResult := make(chan labels.Labels, 10)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "application/x-protobuf;proto=io.prometheus.write.v2.Request", r.Header.Get("Content-Type"))
+		require.Equal(t, "2.0.0", r.Header.Get("X-Prometheus-Remote-Write-Version"))
+		require.Equal(t, "secret", r.Header.Get("X-Scope-OrgID"))
+
+		compressed, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		raw, err := snappy.Decode(nil, compressed)
+		require.NoError(t, err)
+		var req writev2.Request
+		require.NoError(t, req.Unmarshal(raw))
+
+		b := labels.NewScratchBuilder(0)
+		for _, ts := range req.Timeseries {
+			writeResult <- ts.ToLabels(&b, req.Symbols)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	args := testArgsForConfig(t, fmt.Sprintf(`
+		endpoint {
+			url            = "%s/api/v1/write"
+			remote_timeout = "100ms"
+			protocol       = "2.0"
+			headers        = {
+				"X-Scope-OrgID" = "secret",
+			}
+
+			queue_config {
+				batch_send_deadline = "100ms"
+			}
+		}
+	`, srv.URL))
+	tc, err := componenttest.NewControllerFromID(util.TestLogger(t), "prometheus.remote_write")
+	require.NoError(t, err)
+	go func() {
+		err = tc.Run(componenttest.TestContext(t), args)
+		require.NoError(t, err)
+	}()
+	require.NoError(t, tc.WaitRunning(5*time.Second))
+
+	sendMetric(t, tc, labels.FromStrings("foo", "bar"), time.Now().Add(time.Minute).UnixMilli(), 12)
+
+	select {
+	case <-time.After(time.Minute):
+		require.FailNow(t, "timed out waiting for metrics")
+	case res := <-writeResult:
+		require.Equal(t, labels.FromStrings("foo", "bar"), res)
+	}
+}
+
 func TestUpdate(t *testing.T) {
 	writeResult := make(chan *prompb.WriteRequest)
 
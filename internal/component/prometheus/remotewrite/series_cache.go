@@ -0,0 +1,145 @@
+package remotewrite
+
+import (
+	"strings"
+	"time"
+
+	"github.com/grafana/alloy/internal/component/prometheus/internal/writev2"
+	lru "github.com/hashicorp/golang-lru/v2"
+	"github.com/prometheus/prometheus/model/metadata"
+	"github.com/prometheus/prometheus/prompb"
+)
+
+const (
+	// seriesCacheTTL is how long the entries of a seriesCache are kept once
+	// they stop being updated.
+	seriesCacheTTL = 10 * time.Minute
+
+	// maxCachedMetadata and maxCachedCreatedTimestamps are the maximum numbers
+	// of entries of a seriesCache, past which the least recently used entries
+	// are evicted.
+	maxCachedMetadata          = 10_000
+	maxCachedCreatedTimestamps = 100_000
+)
+
+// metadataSuffixes are the suffixes of the series of a metric family which
+// don't appear in the name of the family.
+var metadataSuffixes = []string{"_bucket", "_count", "_sum", "_total", "_created", "_info"}
+
+// seriesCache holds the information about series which the Remote-Write 1.0
+// requests of the queue managers don't carry along with the samples of the
+// series: the metadata of metric families and the created timestamps of
+// series. Forwarders use it to build Remote-Write 2.0 requests.
+//
+// The cache is bounded: the least recently used entries are evicted once it's
+// full, and entries which weren't updated for seriesCacheTTL are ignored and
+// removed when they're looked up.
+type seriesCache struct {
+	now func() time.Time
+
+	metadata *lru.Cache[string, cachedMetadata]
+	created  *lru.Cache[uint64, cachedCreatedTimestamp]
+}
+
+type cachedMetadata struct {
+	typ       writev2.Metadata_MetricType
+	help      string
+	unit      string
+	updatedAt time.Time
+}
+
+type cachedCreatedTimestamp struct {
+	ct        int64
+	updatedAt time.Time
+}
+
+func newSeriesCache() *seriesCache {
+	// lru.New only fails for non-positive sizes.
+	md, _ := lru.New[string, cachedMetadata](maxCachedMetadata)
+	created, _ := lru.New[uint64, cachedCreatedTimestamp](maxCachedCreatedTimestamps)
+	return &seriesCache{
+		now:      time.Now,
+		metadata: md,
+		created:  created,
+	}
+}
+
+// StoreMetadata caches the metadata of the series or metric family called
+// name.
+func (c *seriesCache) StoreMetadata(name string, m metadata.Metadata) {
+	if name == "" {
+		return
+	}
+	c.metadata.Add(name, cachedMetadata{
+		typ:       writev2.FromMetadataType(m.Type),
+		help:      m.Help,
+		unit:      m.Unit,
+		updatedAt: c.now(),
+	})
+}
+
+// StoreMetadataProtos caches the metadata of metric families sent in a
+// Remote-Write 1.0 request.
+func (c *seriesCache) StoreMetadataProtos(mm []prompb.MetricMetadata) {
+	now := c.now()
+	for _, m := range mm {
+		if m.MetricFamilyName == "" {
+			continue
+		}
+		c.metadata.Add(m.MetricFamilyName, cachedMetadata{
+			// The metric types of both protocols have the same values.
+			typ:       writev2.Metadata_MetricType(m.Type),
+			help:      m.Help,
+			unit:      m.Unit,
+			updatedAt: now,
+		})
+	}
+}
+
+// LookupMetadata returns the cached metadata of the series called name, or of
+// its metric family.
+func (c *seriesCache) LookupMetadata(name string) (cachedMetadata, bool) {
+	if m, ok := c.lookupMetadata(name); ok {
+		return m, true
+	}
+	for _, suffix := range metadataSuffixes {
+		if family, ok := strings.CutSuffix(name, suffix); ok {
+			if m, ok := c.lookupMetadata(family); ok {
+				return m, true
+			}
+		}
+	}
+	return cachedMetadata{}, false
+}
+
+func (c *seriesCache) lookupMetadata(name string) (cachedMetadata, bool) {
+	m, ok := c.metadata.Get(name)
+	if !ok {
+		return cachedMetadata{}, false
+	}
+	if c.now().Sub(m.updatedAt) > seriesCacheTTL {
+		c.metadata.Remove(name)
+		return cachedMetadata{}, false
+	}
+	return m, true
+}
+
+// StoreCreatedTimestamp caches the created timestamp ct of the series with
+// the labels hash.
+func (c *seriesCache) StoreCreatedTimestamp(hash uint64, ct int64) {
+	c.created.Add(hash, cachedCreatedTimestamp{ct: ct, updatedAt: c.now()})
+}
+
+// LookupCreatedTimestamp returns the cached created timestamp of the series
+// with the labels hash.
+func (c *seriesCache) LookupCreatedTimestamp(hash uint64) (int64, bool) {
+	e, ok := c.created.Get(hash)
+	if !ok {
+		return 0, false
+	}
+	if c.now().Sub(e.updatedAt) > seriesCacheTTL {
+		c.created.Remove(hash)
+		return 0, false
+	}
+	return e.ct, true
+}
@@ -0,0 +1,50 @@
+package remotewrite
+
+import (
+	"testing"
+	"time"
+
+	"github.com/grafana/alloy/internal/component/prometheus/internal/writev2"
+	"github.com/prometheus/common/model"
+	"github.com/prometheus/prometheus/model/metadata"
+	"github.com/prometheus/prometheus/prompb"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSeriesCache(t *testing.T) {
+	now := time.Now()
+	c := newSeriesCache()
+	c.now = func() time.Time { return now }
+
+	c.StoreMetadataProtos([]prompb.MetricMetadata{{
+		Type:             prompb.MetricMetadata_HISTOGRAM,
+		MetricFamilyName: "latency_seconds",
+	}})
+	c.StoreMetadata("requests_total", metadata.Metadata{Type: model.MetricTypeCounter})
+	c.StoreCreatedTimestamp(1, 500)
+
+	// Series are matched with the metadata of their metric family.
+	m, ok := c.LookupMetadata("latency_seconds_bucket")
+	require.True(t, ok)
+	require.Equal(t, writev2.Metadata_METRIC_TYPE_HISTOGRAM, m.typ)
+	m, ok = c.LookupMetadata("requests_total")
+	require.True(t, ok)
+	require.Equal(t, writev2.Metadata_METRIC_TYPE_COUNTER, m.typ)
+	_, ok = c.LookupMetadata("other")
+	require.False(t, ok)
+
+	ct, ok := c.LookupCreatedTimestamp(1)
+	require.True(t, ok)
+	require.Equal(t, int64(500), ct)
+
+	// Entries expire once they weren't updated for seriesCacheTTL.
+	now = now.Add(seriesCacheTTL + time.Second)
+	_, ok = c.LookupMetadata("requests_total")
+	require.False(t, ok)
+	_, ok = c.LookupMetadata("latency_seconds_bucket")
+	require.False(t, ok)
+	_, ok = c.LookupCreatedTimestamp(1)
+	require.False(t, ok)
+	require.Zero(t, c.metadata.Len())
+	require.Zero(t, c.created.Len())
+}
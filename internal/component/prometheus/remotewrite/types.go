@@ -64,6 +64,13 @@ func (rc *Arguments) SetToDefault() {
 	*rc = DefaultArguments
 }
 
+// Versions of the Remote-Write protocol which can be used to send data to an
+// endpoint.
+const (
+	ProtocolV1 = "1.0"
+	ProtocolV2 = "2.0"
+)
+
 // EndpointOptions describes an individual location for where metrics in the WAL
 // should be delivered to using the remote_write protocol.
 type EndpointOptions struct {
@@ -79,6 +86,7 @@ type EndpointOptions struct {
 	WriteRelabelConfigs  []*alloy_relabel.Config `alloy:"write_relabel_config,block,optional"`
 	SigV4                *SigV4Config            `alloy:"sigv4,block,optional"`
 	AzureAD              *AzureADConfig          `alloy:"azuread,block,optional"`
+	Protocol             string                  `alloy:"protocol,attr,optional"`
 }
 
 // SetToDefault implements syntax.Defaulter.
@@ -87,6 +95,7 @@ func (r *EndpointOptions) SetToDefault() {
 		RemoteTimeout:    30 * time.Second,
 		SendExemplars:    true,
 		HTTPClientConfig: types.CloneDefaultHTTPClientConfig(),
+		Protocol:         ProtocolV1,
 	}
 }
 
@@ -121,6 +130,12 @@ func (r *EndpointOptions) Validate() error {
 		}
 	}
 
+	switch r.Protocol {
+	case ProtocolV1, ProtocolV2:
+	default:
+		return fmt.Errorf("unsupported protocol %q, must be %q or %q", r.Protocol, ProtocolV1, ProtocolV2)
+	}
+
 	if r.WriteRelabelConfigs != nil {
 		for _, relabelConfig := range r.WriteRelabelConfigs {
 			if err := relabelConfig.Validate(); err != nil {
@@ -258,6 +258,15 @@ func TestAlloyConfig(t *testing.T) {
 			}`,
 			errorMsg: "at most one of basic_auth, authorization, oauth2, bearer_token & bearer_token_file must be configured",
 		},
+		{
+			testName: "UnsupportedProtocol",
+			cfg: `
+			endpoint {
+				url      = "http://0.0.0.0:11111/api/v1/write"
+				protocol = "1.1"
+			}`,
+			errorMsg: `unsupported protocol "1.1", must be "1.0" or "2.0"`,
+		},
 	}
 
 	for _, tc := range tests {
@@ -112,6 +112,9 @@ type Arguments struct {
 	EnableProtobufNegotiation bool `alloy:"enable_protobuf_negotiation,attr,optional"`
 
 	Clustering cluster.ComponentBlock `alloy:"clustering,block,optional"`
+
+	// How the components in ForwardTo are isolated from each other.
+	Isolation *prometheus.IsolationArguments `alloy:"isolation,block,optional"`
 }
 
 // SetToDefault implements syntax.Defaulter.
@@ -191,7 +194,8 @@ type Component struct {
 }
 
 var (
-	_ component.Component = (*Component)(nil)
+	_ component.Component       = (*Component)(nil)
+	_ component.HealthComponent = (*Component)(nil)
 )
 
 // New creates a new prometheus.scrape component.
@@ -269,6 +273,7 @@ func New(o component.Options, args Arguments) (*Component, error) {
 
 // Run implements component.Component.
 func (c *Component) Run(ctx context.Context) error {
+	defer c.appendable.Close()
 	defer c.scraper.Stop()
 	defer c.unregisterer.UnregisterAll()
 
@@ -353,6 +358,7 @@ func (c *Component) Update(args component.Arguments) error {
 	c.args = newArgs
 
 	c.appendable.UpdateChildren(newArgs.ForwardTo)
+	c.appendable.UpdateIsolation(newArgs.Isolation)
 
 	sc := getPromScrapeConfigs(c.opts.ID, newArgs)
 	err := c.scraper.ApplyConfig(&config.Config{
@@ -371,6 +377,11 @@ func (c *Component) Update(args component.Arguments) error {
 	return nil
 }
 
+// CurrentHealth implements component.HealthComponent.
+func (c *Component) CurrentHealth() component.Health {
+	return c.appendable.CurrentHealth()
+}
+
 // NotifyClusterChange implements component.ClusterComponent.
 func (c *Component) NotifyClusterChange() {
 	c.mut.RLock()
@@ -482,7 +493,7 @@ func (c *Component) DebugInfo() interface{} {
 func (c *Component) componentTargetsToPromTargetGroups(jobName string, tgs []discovery.Target) map[string][]*targetgroup.Group {
 	promGroup := &targetgroup.Group{Source: jobName}
 	for _, tg := range tgs {
-		promGroup.Targets = append(promGroup.Targets, convertLabelSet(tg))
+		promGroup.Targets = append(promGroup.Targets, tg.LabelSet())
 	}
 
 	return map[string][]*targetgroup.Group{jobName: {promGroup}}
@@ -502,11 +513,3 @@ func (c *Component) populatePromLabels(targets []discovery.Target, jobName strin
 	}
 	return promTargets
 }
-
-func convertLabelSet(tg discovery.Target) model.LabelSet {
-	lset := make(model.LabelSet, len(tg))
-	for k, v := range tg {
-		lset[model.LabelName(k)] = model.LabelValue(v)
-	}
-	return lset
-}
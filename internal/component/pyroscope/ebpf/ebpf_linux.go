@@ -221,7 +221,7 @@ func (c *Component) updateDebugInfo() {
 func targetsOptionFromArgs(args Arguments) sd.TargetsOptions {
 	targets := make([]sd.DiscoveryTarget, 0, len(args.Targets))
 	for _, t := range args.Targets {
-		targets = append(targets, sd.DiscoveryTarget(t))
+		targets = append(targets, sd.DiscoveryTarget(t.AsMap()))
 	}
 	return sd.TargetsOptions{
 		Targets:            targets,
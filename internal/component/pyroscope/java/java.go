@@ -83,7 +83,8 @@ func (j *javaComponent) updateTargets(args Arguments) {
 
 	active := make(map[int]struct{})
 	for _, target := range args.Targets {
-		pid, err := strconv.Atoi(target[labelProcessID])
+		pidLabel, _ := target.Get(labelProcessID)
+		pid, err := strconv.Atoi(pidLabel)
 		_ = level.Debug(j.opts.Logger).Log("msg", "active target",
 			"target", fmt.Sprintf("%+v", target),
 			"pid", pid)
@@ -12,23 +12,23 @@ const (
 )
 
 func inferServiceName(target discovery.Target) string {
-	k8sServiceName := target[labelServiceNameK8s]
+	k8sServiceName, _ := target.Get(labelServiceNameK8s)
 	if k8sServiceName != "" {
 		return k8sServiceName
 	}
-	k8sNamespace := target["__meta_kubernetes_namespace"]
-	k8sContainer := target["__meta_kubernetes_pod_container_name"]
+	k8sNamespace, _ := target.Get("__meta_kubernetes_namespace")
+	k8sContainer, _ := target.Get("__meta_kubernetes_pod_container_name")
 	if k8sNamespace != "" && k8sContainer != "" {
 		return fmt.Sprintf("java/%s/%s", k8sNamespace, k8sContainer)
 	}
-	dockerContainer := target["__meta_docker_container_name"]
+	dockerContainer, _ := target.Get("__meta_docker_container_name")
 	if dockerContainer != "" {
 		return dockerContainer
 	}
-	if swarmService := target["__meta_dockerswarm_container_label_service_name"]; swarmService != "" {
+	if swarmService, _ := target.Get("__meta_dockerswarm_container_label_service_name"); swarmService != "" {
 		return swarmService
 	}
-	if swarmService := target["__meta_dockerswarm_service_name"]; swarmService != "" {
+	if swarmService, _ := target.Get("__meta_dockerswarm_service_name"); swarmService != "" {
 		return swarmService
 	}
 	return "unspecified"
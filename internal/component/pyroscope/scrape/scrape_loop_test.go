@@ -28,7 +28,7 @@ func TestScrapePool(t *testing.T) {
 
 	args := NewDefaultArguments()
 	args.Targets = []discovery.Target{
-		{"instance": "foo"},
+		discovery.NewTargetFromMap(map[string]string{"instance": "foo"}),
 	}
 	args.ProfilingConfig.Block.Enabled = false
 	args.ProfilingConfig.Goroutine.Enabled = false
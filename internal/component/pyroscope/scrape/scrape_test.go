@@ -50,14 +50,14 @@ func TestComponent(t *testing.T) {
 
 	arg.ForwardTo = []pyroscope.Appendable{pyroscope.NoopAppendable}
 	arg.Targets = []discovery.Target{
-		{
+		discovery.NewTargetFromMap(map[string]string{
 			model.AddressLabel: "foo",
 			serviceNameLabel:   "s",
-		},
-		{
+		}),
+		discovery.NewTargetFromMap(map[string]string{
 			model.AddressLabel:  "bar",
 			serviceNameK8SLabel: "k",
-		},
+		}),
 	}
 	c.Update(arg)
 
@@ -99,10 +99,10 @@ func TestUnmarshalConfig(t *testing.T) {
 			expected: func() Arguments {
 				r := NewDefaultArguments()
 				r.Targets = []discovery.Target{
-					{
+					discovery.NewTargetFromMap(map[string]string{
 						"__address__": "localhost:9090",
 						"foo":         "bar",
-					},
+					}),
 				}
 				return r
 			},
@@ -131,14 +131,14 @@ func TestUnmarshalConfig(t *testing.T) {
 			expected: func() Arguments {
 				r := NewDefaultArguments()
 				r.Targets = []discovery.Target{
-					{
+					discovery.NewTargetFromMap(map[string]string{
 						"__address__": "localhost:9090",
 						"foo":         "bar",
-					},
-					{
+					}),
+					discovery.NewTargetFromMap(map[string]string{
 						"__address__": "localhost:8080",
 						"foo":         "buzz",
-					},
+					}),
 				}
 				r.ProfilingConfig.Block.Enabled = false
 				r.ProfilingConfig.Custom = append(r.ProfilingConfig.Custom, CustomProfilingTarget{
@@ -266,16 +266,16 @@ func TestUpdateWhileScraping(t *testing.T) {
 	go c.Run(ctx)
 
 	args.Targets = []discovery.Target{
-		{
+		discovery.NewTargetFromMap(map[string]string{
 			model.AddressLabel: address,
 			serviceNameLabel:   "s",
 			"foo":              "bar",
-		},
-		{
+		}),
+		discovery.NewTargetFromMap(map[string]string{
 			model.AddressLabel:  address,
 			serviceNameK8SLabel: "k",
 			"foo":               "buz",
-		},
+		}),
 	}
 
 	c.Update(args)
@@ -290,11 +290,11 @@ func TestUpdateWhileScraping(t *testing.T) {
 	go func() {
 		for i := 0; i < 100; i++ {
 			args.Targets = []discovery.Target{
-				{
+				discovery.NewTargetFromMap(map[string]string{
 					model.AddressLabel: address,
 					serviceNameLabel:   "s",
 					"foo":              fmt.Sprintf("%d", i),
-				},
+				}),
 			}
 			require.NoError(t, c.Update(args))
 			c.scraper.reload()
@@ -5,6 +5,7 @@ import (
 	"context"
 	"fmt"
 	"reflect"
+	"sort"
 	"sync"
 	"time"
 
@@ -15,6 +16,7 @@ import (
 	"github.com/grafana/alloy/syntax/alloytypes"
 
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	client_go "k8s.io/client-go/kubernetes"
 )
 
@@ -25,10 +27,21 @@ const (
 	TypeConfigMap ResourceType = "configmap"
 )
 
+// Modes to keep the exports up to date.
+const (
+	ModePoll  = "poll"
+	ModeWatch = "watch"
+)
+
 // Arguments control the component.
 type Arguments struct {
-	Namespace     string        `alloy:"namespace,attr"`
-	Name          string        `alloy:"name,attr"`
+	Namespace string `alloy:"namespace,attr"`
+	Name      string `alloy:"name,attr,optional"`
+	// Selector selects the resources to export instead of Name. The data of all
+	// the selected resources is merged.
+	Selector *kubernetes.LabelSelector `alloy:"selector,block,optional"`
+
+	Mode          string        `alloy:"mode,attr,optional"`
 	PollFrequency time.Duration `alloy:"poll_frequency,attr,optional"`
 	PollTimeout   time.Duration `alloy:"poll_timeout,attr,optional"`
 
@@ -38,6 +51,7 @@ type Arguments struct {
 
 // DefaultArguments holds default settings for Arguments.
 var DefaultArguments = Arguments{
+	Mode:          ModePoll,
 	PollFrequency: 1 * time.Minute,
 	PollTimeout:   15 * time.Second,
 }
@@ -55,9 +69,34 @@ func (args *Arguments) Validate() error {
 	if args.PollTimeout <= 0 {
 		return fmt.Errorf("poll_timeout must not be greater than 0")
 	}
+	if args.Mode != ModePoll && args.Mode != ModeWatch {
+		return fmt.Errorf("mode must be %q or %q, got %q", ModePoll, ModeWatch, args.Mode)
+	}
+
+	if args.Name == "" && args.Selector == nil {
+		return fmt.Errorf("one of name or selector must be set")
+	} else if args.Name != "" && args.Selector != nil {
+		return fmt.Errorf("name and selector can't both be set")
+	}
+	if args.Selector != nil {
+		if _, err := kubernetes.ConvertSelectorToListOptions(*args.Selector); err != nil {
+			return fmt.Errorf("invalid selector: %w", err)
+		}
+	}
 	return nil
 }
 
+// labelSelector returns the selector of the resources to export, which
+// matches everything when they're selected by name.
+func (args *Arguments) labelSelector() labels.Selector {
+	if args.Selector == nil {
+		return labels.Everything()
+	}
+	// The selector was checked by Validate.
+	selector, _ := kubernetes.ConvertSelectorToListOptions(*args.Selector)
+	return selector
+}
+
 // Exports holds settings exported by this component.
 type Exports struct {
 	Data map[string]alloytypes.OptionalSecret `alloy:"data,attr"`
@@ -71,8 +110,13 @@ type Component struct {
 	mut  sync.Mutex
 	args Arguments
 
-	client *client_go.Clientset
-	kind   ResourceType
+	buildClient func(Arguments) (client_go.Interface, error)
+	client      client_go.Interface
+	kind        ResourceType
+
+	// updateCh is written to when the arguments changed, which restarts the
+	// poll or watch loop.
+	updateCh chan struct{}
 
 	lastPoll    time.Time
 	lastExports Exports // Used for determining whether exports should be updated
@@ -88,11 +132,27 @@ var (
 
 // New returns a new, unstarted remote.kubernetes.* component.
 func New(opts component.Options, args Arguments, rType ResourceType) (*Component, error) {
+	return newComponent(opts, args, rType, func(args Arguments) (client_go.Interface, error) {
+		restConfig, err := args.Client.BuildRESTConfig(opts.Logger)
+		if err != nil {
+			return nil, err
+		}
+		client, err := client_go.NewForConfig(restConfig)
+		if err != nil {
+			return nil, fmt.Errorf("creating kubernetes client: %w", err)
+		}
+		return client, nil
+	})
+}
+
+func newComponent(opts component.Options, args Arguments, rType ResourceType, buildClient func(Arguments) (client_go.Interface, error)) (*Component, error) {
 	c := &Component{
 		log:  opts.Logger,
 		opts: opts,
 
-		kind: rType,
+		buildClient: buildClient,
+		kind:        rType,
+		updateCh:    make(chan struct{}, 1),
 		health: component.Health{
 			Health:     component.HealthTypeUnknown,
 			Message:    "component started",
@@ -108,10 +168,32 @@ func New(opts component.Options, args Arguments, rType ResourceType) (*Component
 
 // Run starts the remote.kubernetes.* component.
 func (c *Component) Run(ctx context.Context) error {
+	for {
+		c.mut.Lock()
+		mode := c.args.Mode
+		c.mut.Unlock()
+
+		if mode == ModeWatch {
+			c.watch(ctx)
+		} else {
+			c.pollLoop(ctx)
+		}
+
+		if ctx.Err() != nil {
+			return nil
+		}
+	}
+}
+
+// pollLoop polls the Kubernetes API until ctx is canceled or the arguments
+// are updated.
+func (c *Component) pollLoop(ctx context.Context) {
 	for {
 		select {
 		case <-ctx.Done():
-			return nil
+			return
+		case <-c.updateCh:
+			return
 		case <-time.After(c.nextPoll()):
 			c.poll()
 		}
@@ -139,10 +221,11 @@ func (c *Component) nextPoll() time.Duration {
 // with the success or failure status.
 func (c *Component) poll() {
 	err := c.pollError()
-	c.updatePollHealth(err)
+	c.updateHealth("polling", err)
 }
 
-func (c *Component) updatePollHealth(err error) {
+// updateHealth reports the result of the latest poll or watch event.
+func (c *Component) updateHealth(action string, err error) {
 	c.healthMut.Lock()
 	defer c.healthMut.Unlock()
 
@@ -155,7 +238,7 @@ func (c *Component) updatePollHealth(err error) {
 	} else {
 		c.health = component.Health{
 			Health:     component.HealthTypeUnhealthy,
-			Message:    fmt.Sprintf("polling failed: %s", err),
+			Message:    fmt.Sprintf("%s failed: %s", action, err),
 			UpdateTime: time.Now(),
 		}
 	}
@@ -171,27 +254,69 @@ func (c *Component) pollError() error {
 	ctx, cancel := context.WithTimeout(context.Background(), c.args.PollTimeout)
 	defer cancel()
 
-	data := map[string]alloytypes.OptionalSecret{}
+	var resources []resource
 	if c.kind == TypeSecret {
-		secret, err := c.client.CoreV1().Secrets(c.args.Namespace).Get(ctx, c.args.Name, v1.GetOptions{})
-		if err != nil {
-			return err
-		}
-		for k, v := range secret.Data {
-			data[k] = alloytypes.OptionalSecret{
-				Value:    string(v),
-				IsSecret: true,
+		if c.args.Name != "" {
+			secret, err := c.client.CoreV1().Secrets(c.args.Namespace).Get(ctx, c.args.Name, v1.GetOptions{})
+			if err != nil {
+				return err
+			}
+			resources = append(resources, secretResource(secret))
+		} else {
+			list, err := c.client.CoreV1().Secrets(c.args.Namespace).List(ctx, v1.ListOptions{
+				LabelSelector: c.args.labelSelector().String(),
+			})
+			if err != nil {
+				return err
+			}
+			for i := range list.Items {
+				resources = append(resources, secretResource(&list.Items[i]))
 			}
 		}
 	} else if c.kind == TypeConfigMap {
-		cmap, err := c.client.CoreV1().ConfigMaps(c.args.Namespace).Get(ctx, c.args.Name, v1.GetOptions{})
-		if err != nil {
-			return err
+		if c.args.Name != "" {
+			cmap, err := c.client.CoreV1().ConfigMaps(c.args.Namespace).Get(ctx, c.args.Name, v1.GetOptions{})
+			if err != nil {
+				return err
+			}
+			resources = append(resources, configMapResource(cmap))
+		} else {
+			list, err := c.client.CoreV1().ConfigMaps(c.args.Namespace).List(ctx, v1.ListOptions{
+				LabelSelector: c.args.labelSelector().String(),
+			})
+			if err != nil {
+				return err
+			}
+			for i := range list.Items {
+				resources = append(resources, configMapResource(&list.Items[i]))
+			}
 		}
-		for k, v := range cmap.Data {
+	}
+
+	c.exportResources(resources)
+	return nil
+}
+
+// resource holds the name and data of a Secret or ConfigMap.
+type resource struct {
+	name string
+	data map[string]string
+}
+
+// exportResources merges the data of resources and exports it if it changed.
+// When several resources hold the same key, the value of the resource whose
+// name sorts last is used. c.mut must be held when calling.
+func (c *Component) exportResources(resources []resource) {
+	sort.Slice(resources, func(i, j int) bool {
+		return resources[i].name < resources[j].name
+	})
+
+	data := map[string]alloytypes.OptionalSecret{}
+	for _, r := range resources {
+		for k, v := range r.data {
 			data[k] = alloytypes.OptionalSecret{
 				Value:    v,
-				IsSecret: false,
+				IsSecret: c.kind == TypeSecret,
 			}
 		}
 	}
@@ -207,7 +332,6 @@ func (c *Component) pollError() error {
 	}
 
 	c.lastExports = newExports
-	return nil
 }
 
 // Update updates the remote.kubernetes.* component. After the update completes, a
@@ -222,7 +346,13 @@ func (c *Component) Update(args component.Arguments) (err error) {
 		// occurred during Update, we don't bother to do anything.
 		// It is important to set err and the health so startup works correctly
 		err = c.pollError()
-		c.updatePollHealth(err)
+		c.updateHealth("polling", err)
+
+		// Restart the poll or watch loop with the new arguments.
+		select {
+		case c.updateCh <- struct{}{}:
+		default:
+		}
 	}()
 
 	c.mut.Lock()
@@ -231,15 +361,7 @@ func (c *Component) Update(args component.Arguments) (err error) {
 	newArgs := args.(Arguments)
 	c.args = newArgs
 
-	restConfig, err := c.args.Client.BuildRESTConfig(c.log)
-	if err != nil {
-		return err
-	}
-	c.client, err = client_go.NewForConfig(restConfig)
-	if err != nil {
-		return fmt.Errorf("creating kubernetes client: %w", err)
-	}
-
+	c.client, err = c.buildClient(c.args)
 	return err
 }
 
@@ -1,12 +1,25 @@
 package kubernetes
 
 import (
+	"context"
+	"reflect"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/grafana/alloy/internal/component"
+	"github.com/grafana/alloy/internal/component/common/kubernetes"
+	"github.com/grafana/alloy/internal/util"
 	"github.com/grafana/alloy/syntax"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/stretchr/testify/require"
 	"gotest.tools/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+	client_go "k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+	clienttesting "k8s.io/client-go/testing"
 )
 
 func TestAlloyUnmarshal(t *testing.T) {
@@ -41,3 +54,203 @@ func TestValidate(t *testing.T) {
 		require.ErrorContains(t, err, "poll_timeout must not be greater than 0")
 	})
 }
+
+func TestValidate_NameOrSelector(t *testing.T) {
+	t.Run("neither", func(t *testing.T) {
+		args := Arguments{}
+		args.SetToDefault()
+		require.ErrorContains(t, args.Validate(), "one of name or selector must be set")
+	})
+	t.Run("both", func(t *testing.T) {
+		args := Arguments{}
+		args.SetToDefault()
+		args.Name = "foo"
+		args.Selector = &kubernetes.LabelSelector{}
+		require.ErrorContains(t, args.Validate(), "name and selector can't both be set")
+	})
+	t.Run("unknown mode", func(t *testing.T) {
+		args := Arguments{}
+		args.SetToDefault()
+		args.Name = "foo"
+		args.Mode = "stream"
+		require.ErrorContains(t, args.Validate(), `mode must be "poll" or "watch", got "stream"`)
+	})
+}
+
+// testExports records the exports of a component under test.
+type testExports struct {
+	mut     sync.Mutex
+	exports Exports
+}
+
+func (te *testExports) onStateChange(e component.Exports) {
+	te.mut.Lock()
+	defer te.mut.Unlock()
+	te.exports = e.(Exports)
+}
+
+func (te *testExports) data() map[string]string {
+	te.mut.Lock()
+	defer te.mut.Unlock()
+
+	out := make(map[string]string, len(te.exports.Data))
+	for k, v := range te.exports.Data {
+		out[k] = v.Value
+	}
+	return out
+}
+
+// newTestComponent runs a component using client. It returns once the
+// component watches the API server if args.Mode is watch.
+func newTestComponent(t *testing.T, client *fake.Clientset, args Arguments, kind ResourceType) (*Component, *testExports) {
+	watching := make(chan struct{}, 1)
+	// The fake clientset doesn't support resource versions; wait for the watch
+	// to be established so that no event is missed.
+	client.PrependWatchReactor("*", func(action clienttesting.Action) (bool, watch.Interface, error) {
+		w, err := client.Tracker().Watch(action.GetResource(), action.GetNamespace())
+		if err != nil {
+			return false, nil, err
+		}
+		select {
+		case watching <- struct{}{}:
+		default:
+		}
+		return true, w, nil
+	})
+
+	exports := &testExports{}
+	opts := component.Options{
+		Logger:        util.TestLogger(t),
+		Registerer:    prometheus.NewRegistry(),
+		OnStateChange: exports.onStateChange,
+	}
+	c, err := newComponent(opts, args, kind, func(Arguments) (client_go.Interface, error) {
+		return client, nil
+	})
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		require.NoError(t, c.Run(ctx))
+	}()
+	t.Cleanup(func() {
+		cancel()
+		<-done
+	})
+
+	if args.Mode == ModeWatch {
+		select {
+		case <-watching:
+		case <-time.After(10 * time.Second):
+			t.Fatal("timed out waiting for the watch to start")
+		}
+	}
+	return c, exports
+}
+
+func TestWatch_ConfigMap(t *testing.T) {
+	ctx := context.Background()
+	client := fake.NewSimpleClientset(
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "monitoring", Name: "endpoint"},
+			Data:       map[string]string{"url": "http://mimir:9009"},
+		},
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "monitoring", Name: "other"},
+			Data:       map[string]string{"other": "value"},
+		},
+	)
+
+	args := DefaultArguments
+	args.Namespace = "monitoring"
+	args.Name = "endpoint"
+	args.Mode = ModeWatch
+	// Polling would not pick up changes during the test.
+	args.PollFrequency = time.Hour
+
+	c, exports := newTestComponent(t, client, args, TypeConfigMap)
+	require.Equal(t, map[string]string{"url": "http://mimir:9009"}, exports.data())
+
+	_, err := client.CoreV1().ConfigMaps("monitoring").Update(ctx, &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "monitoring", Name: "endpoint"},
+		Data:       map[string]string{"url": "http://mimir:8080"},
+	}, metav1.UpdateOptions{})
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		return exports.data()["url"] == "http://mimir:8080"
+	}, 10*time.Second, 10*time.Millisecond)
+	require.Equal(t, component.HealthTypeHealthy, c.CurrentHealth().Health)
+
+	// The exports are kept when the ConfigMap is deleted.
+	require.NoError(t, client.CoreV1().ConfigMaps("monitoring").Delete(ctx, "endpoint", metav1.DeleteOptions{}))
+	require.Eventually(t, func() bool {
+		return c.CurrentHealth().Health == component.HealthTypeUnhealthy
+	}, 10*time.Second, 10*time.Millisecond)
+	require.Equal(t, map[string]string{"url": "http://mimir:8080"}, exports.data())
+}
+
+func TestWatch_SecretSelector(t *testing.T) {
+	ctx := context.Background()
+	newSecret := func(name string, labels map[string]string, data map[string]string) *corev1.Secret {
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "monitoring", Name: name, Labels: labels},
+			Data:       map[string][]byte{},
+		}
+		for k, v := range data {
+			secret.Data[k] = []byte(v)
+		}
+		return secret
+	}
+	client := fake.NewSimpleClientset(
+		newSecret("a", map[string]string{"alloy": "yes"}, map[string]string{"user": "a", "password": "a"}),
+		newSecret("b", map[string]string{"alloy": "yes"}, map[string]string{"password": "b"}),
+		newSecret("c", map[string]string{"alloy": "no"}, map[string]string{"token": "c"}),
+	)
+
+	args := DefaultArguments
+	args.Namespace = "monitoring"
+	args.Selector = &kubernetes.LabelSelector{MatchLabels: map[string]string{"alloy": "yes"}}
+	args.Mode = ModeWatch
+	args.PollFrequency = time.Hour
+
+	_, exports := newTestComponent(t, client, args, TypeSecret)
+	// b sorts after a, so its password is used.
+	require.Equal(t, map[string]string{"user": "a", "password": "b"}, exports.data())
+
+	_, err := client.CoreV1().Secrets("monitoring").Create(ctx,
+		newSecret("d", map[string]string{"alloy": "yes"}, map[string]string{"token": "d"}),
+		metav1.CreateOptions{},
+	)
+	require.NoError(t, err)
+	require.Eventually(t, func() bool {
+		return exports.data()["token"] == "d"
+	}, 10*time.Second, 10*time.Millisecond)
+
+	require.NoError(t, client.CoreV1().Secrets("monitoring").Delete(ctx, "b", metav1.DeleteOptions{}))
+	require.Eventually(t, func() bool {
+		return reflect.DeepEqual(exports.data(), map[string]string{"user": "a", "password": "a", "token": "d"})
+	}, 10*time.Second, 10*time.Millisecond)
+}
+
+func TestPoll_ConfigMapSelector(t *testing.T) {
+	client := fake.NewSimpleClientset(
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "monitoring", Name: "a", Labels: map[string]string{"alloy": "yes"}},
+			Data:       map[string]string{"a": "1"},
+		},
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "monitoring", Name: "b", Labels: map[string]string{"alloy": "no"}},
+			Data:       map[string]string{"b": "2"},
+		},
+	)
+
+	args := DefaultArguments
+	args.Namespace = "monitoring"
+	args.Selector = &kubernetes.LabelSelector{MatchLabels: map[string]string{"alloy": "yes"}}
+
+	_, exports := newTestComponent(t, client, args, TypeConfigMap)
+	require.Equal(t, map[string]string{"a": "1"}, exports.data())
+}
@@ -0,0 +1,152 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+
+	"github.com/grafana/alloy/internal/runtime/logging/level"
+
+	corev1 "k8s.io/api/core/v1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/client-go/informers"
+	"k8s.io/client-go/tools/cache"
+)
+
+// watch keeps the exports up to date with an informer until ctx is canceled
+// or the arguments are updated. The exports are recomputed from the informer
+// cache on each change event, instead of polling the API server.
+func (c *Component) watch(ctx context.Context) {
+	ctx, cancel := context.WithCancel(ctx)
+
+	c.mut.Lock()
+	client, args := c.client, c.args
+	c.mut.Unlock()
+
+	factory := informers.NewSharedInformerFactoryWithOptions(client, 0,
+		informers.WithNamespace(args.Namespace),
+		informers.WithTweakListOptions(func(opts *v1.ListOptions) {
+			if args.Name != "" {
+				opts.FieldSelector = fields.OneTermEqualSelector("metadata.name", args.Name).String()
+			} else {
+				opts.LabelSelector = args.labelSelector().String()
+			}
+		}),
+	)
+	defer func() {
+		// Shutdown waits for the informers, which stop once ctx is canceled.
+		cancel()
+		factory.Shutdown()
+	}()
+
+	var (
+		informer cache.SharedIndexInformer
+		list     func() ([]resource, error)
+	)
+	switch c.kind {
+	case TypeSecret:
+		secrets := factory.Core().V1().Secrets()
+		informer = secrets.Informer()
+		list = func() ([]resource, error) {
+			items, err := secrets.Lister().Secrets(args.Namespace).List(args.labelSelector())
+			if err != nil {
+				return nil, err
+			}
+			var resources []resource
+			for _, item := range items {
+				if args.Name == "" || item.Name == args.Name {
+					resources = append(resources, secretResource(item))
+				}
+			}
+			return resources, nil
+		}
+	case TypeConfigMap:
+		configMaps := factory.Core().V1().ConfigMaps()
+		informer = configMaps.Informer()
+		list = func() ([]resource, error) {
+			items, err := configMaps.Lister().ConfigMaps(args.Namespace).List(args.labelSelector())
+			if err != nil {
+				return nil, err
+			}
+			var resources []resource
+			for _, item := range items {
+				if args.Name == "" || item.Name == args.Name {
+					resources = append(resources, configMapResource(item))
+				}
+			}
+			return resources, nil
+		}
+	}
+
+	changeCh := make(chan struct{}, 1)
+	notify := func(interface{}) {
+		select {
+		case changeCh <- struct{}{}:
+		default:
+		}
+	}
+	_, err := informer.AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc:    notify,
+		UpdateFunc: func(_, newObj interface{}) { notify(newObj) },
+		DeleteFunc: notify,
+	})
+	if err != nil {
+		level.Error(c.log).Log("msg", "failed to add event handler", "err", err)
+	}
+	err = informer.SetWatchErrorHandler(func(_ *cache.Reflector, err error) {
+		level.Warn(c.log).Log("msg", "watching failed, will retry", "err", err)
+		c.updateHealth("watching", err)
+	})
+	if err != nil {
+		level.Error(c.log).Log("msg", "failed to set watch error handler", "err", err)
+	}
+
+	factory.Start(ctx.Done())
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-c.updateCh:
+			return
+		case <-changeCh:
+			c.updateHealth("watching", c.exportWatched(args, list))
+		}
+	}
+}
+
+// exportWatched exports the resources found in the informer cache.
+func (c *Component) exportWatched(args Arguments, list func() ([]resource, error)) error {
+	resources, err := list()
+	if err != nil {
+		return err
+	}
+	if args.Name != "" && len(resources) == 0 {
+		// Keep the last exports, as polling does when the resource is missing.
+		return fmt.Errorf("%s %s/%s not found", c.kind, args.Namespace, args.Name)
+	}
+
+	c.mut.Lock()
+	defer c.mut.Unlock()
+
+	if !reflect.DeepEqual(c.args, args) {
+		// The arguments were updated and the loop is about to restart; don't
+		// override the exports of the new arguments.
+		return nil
+	}
+	c.exportResources(resources)
+	return nil
+}
+
+func secretResource(secret *corev1.Secret) resource {
+	data := make(map[string]string, len(secret.Data))
+	for k, v := range secret.Data {
+		data[k] = string(v)
+	}
+	return resource{name: secret.Name, data: data}
+}
+
+func configMapResource(cmap *corev1.ConfigMap) resource {
+	return resource{name: cmap.Name, data: cmap.Data}
+}
@@ -14,8 +14,8 @@ type secretStore interface {
 	Read(ctx context.Context, args *Arguments) (*vault.Secret, error)
 }
 
-// TODO(rfratto): support logical stores.
-
+// kvStore reads secrets from a KV v2 secrets engine, whose mount is the first
+// element of the path.
 type kvStore struct{ c *vault.Client }
 
 func (ks *kvStore) Read(ctx context.Context, args *Arguments) (*vault.Secret, error) {
@@ -36,3 +36,31 @@ func (ks *kvStore) Read(ctx context.Context, args *Arguments) (*vault.Secret, er
 	kvSecret.Raw.Data = kvSecret.Data
 	return kvSecret.Raw, nil
 }
+
+// logicalStore sends raw requests to the path, which is needed by secrets
+// engines other than KV v2, such as the ones generating dynamic secrets.
+type logicalStore struct{ c *vault.Client }
+
+func (ls *logicalStore) Read(ctx context.Context, args *Arguments) (*vault.Secret, error) {
+	var (
+		secret *vault.Secret
+		err    error
+	)
+	if args.WriteData != nil {
+		// Endpoints issuing credentials, like pki/issue/<role>, expect a write
+		// request carrying the parameters of the credentials.
+		data := make(map[string]any, len(args.WriteData))
+		for key, value := range args.WriteData {
+			data[key] = value
+		}
+		secret, err = ls.c.Logical().WriteWithContext(ctx, args.Path, data)
+	} else {
+		secret, err = ls.c.Logical().ReadWithContext(ctx, args.Path)
+	}
+	if err != nil {
+		return nil, err
+	} else if secret == nil {
+		return nil, fmt.Errorf("no secret found at %q", args.Path)
+	}
+	return secret, nil
+}
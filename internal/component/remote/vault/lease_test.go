@@ -0,0 +1,264 @@
+package vault
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/grafana/alloy/internal/runtime/componenttest"
+	"github.com/grafana/alloy/internal/util"
+	"github.com/grafana/alloy/syntax"
+	"github.com/grafana/alloy/syntax/alloytypes"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeVault is a minimal Vault server issuing leased secrets.
+type fakeVault struct {
+	*httptest.Server
+
+	leaseDuration int
+	renewable     bool
+
+	mut          sync.Mutex
+	issued       int
+	renewals     int
+	rejectRenew  bool
+	lastWriteReq map[string]any
+}
+
+func newFakeVault(t *testing.T, leaseDuration int, renewable bool) *fakeVault {
+	fv := &fakeVault{leaseDuration: leaseDuration, renewable: renewable}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/v1/database/creds/app", func(w http.ResponseWriter, r *http.Request) {
+		fv.issue(w, "database/creds/app", map[string]any{})
+	})
+	mux.HandleFunc("/v1/pki/issue/web", func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]any
+		if r.Method != http.MethodPut && r.Method != http.MethodPost {
+			http.Error(w, `{"errors":["unsupported operation"]}`, http.StatusMethodNotAllowed)
+			return
+		} else if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, `{"errors":["invalid request"]}`, http.StatusBadRequest)
+			return
+		}
+		fv.issue(w, "pki/issue/web", req)
+	})
+	mux.HandleFunc("/v1/sys/leases/renew", func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			LeaseID string `json:"lease_id"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+
+		fv.mut.Lock()
+		defer fv.mut.Unlock()
+		if fv.rejectRenew {
+			http.Error(w, `{"errors":["lease not found"]}`, http.StatusBadRequest)
+			return
+		}
+		fv.renewals++
+		writeJSON(w, map[string]any{
+			"request_id":     fmt.Sprintf("renew-%d", fv.renewals),
+			"lease_id":       req.LeaseID,
+			"lease_duration": fv.leaseDuration,
+			"renewable":      fv.renewable,
+		})
+	})
+
+	fv.Server = httptest.NewServer(mux)
+	t.Cleanup(fv.Close)
+	return fv
+}
+
+func (fv *fakeVault) issue(w http.ResponseWriter, path string, req map[string]any) {
+	fv.mut.Lock()
+	defer fv.mut.Unlock()
+
+	fv.issued++
+	fv.lastWriteReq = req
+	writeJSON(w, map[string]any{
+		"request_id":     fmt.Sprintf("issue-%d", fv.issued),
+		"lease_id":       fmt.Sprintf("%s/%d", path, fv.issued),
+		"lease_duration": fv.leaseDuration,
+		"renewable":      fv.renewable,
+		"data": map[string]any{
+			"username": fmt.Sprintf("user-%d", fv.issued),
+		},
+	})
+}
+
+func (fv *fakeVault) counts() (issued, renewals int) {
+	fv.mut.Lock()
+	defer fv.mut.Unlock()
+	return fv.issued, fv.renewals
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func runLeaseTestComponent(t *testing.T, cfg string) *componenttest.Controller {
+	var args Arguments
+	require.NoError(t, syntax.Unmarshal([]byte(cfg), &args))
+
+	ctrl, err := componenttest.NewControllerFromID(util.TestLogger(t), "remote.vault")
+	require.NoError(t, err)
+
+	ctx := componenttest.TestContext(t)
+	go func() {
+		require.NoError(t, ctrl.Run(ctx, args))
+	}()
+	require.NoError(t, ctrl.WaitRunning(time.Minute))
+	require.NoError(t, ctrl.WaitExports(time.Minute))
+	return ctrl
+}
+
+func Test_Lease_Renewal(t *testing.T) {
+	fv := newFakeVault(t, 2, true)
+
+	ctrl := runLeaseTestComponent(t, fmt.Sprintf(`
+		server = "%s"
+		path   = "database/creds/app"
+		store  = "logical"
+
+		lease_renewal_ratio = 0.5
+
+		auth.token {
+			token = "test"
+		}
+	`, fv.URL))
+
+	exports := ctrl.Exports().(Exports)
+	require.Equal(t, alloytypes.Secret("user-1"), exports.Data["username"])
+	initialExpiry, err := time.Parse(time.RFC3339, exports.LeaseExpiresAt)
+	require.NoError(t, err)
+	require.WithinDuration(t, time.Now().Add(2*time.Second), initialExpiry, 2*time.Second)
+
+	// The lease is renewed after half its duration, without reading the secret
+	// again.
+	require.Eventually(t, func() bool {
+		_, renewals := fv.counts()
+		return renewals >= 2
+	}, 10*time.Second, 50*time.Millisecond)
+
+	issued, _ := fv.counts()
+	require.Equal(t, 1, issued)
+
+	exports = ctrl.Exports().(Exports)
+	require.Equal(t, alloytypes.Secret("user-1"), exports.Data["username"])
+	renewedExpiry, err := time.Parse(time.RFC3339, exports.LeaseExpiresAt)
+	require.NoError(t, err)
+	require.True(t, renewedExpiry.After(initialExpiry), "expiry %s should be after %s", renewedExpiry, initialExpiry)
+}
+
+func Test_Lease_RereadOnRevocation(t *testing.T) {
+	fv := newFakeVault(t, 2, true)
+	fv.rejectRenew = true
+
+	ctrl := runLeaseTestComponent(t, fmt.Sprintf(`
+		server = "%s"
+		path   = "database/creds/app"
+		store  = "logical"
+
+		lease_renewal_ratio = 0.5
+
+		auth.token {
+			token = "test"
+		}
+	`, fv.URL))
+
+	// Renewing the revoked lease fails, so new credentials are read.
+	require.Eventually(t, func() bool {
+		return ctrl.Exports().(Exports).Data["username"] == alloytypes.Secret("user-2")
+	}, 10*time.Second, 50*time.Millisecond)
+
+	_, renewals := fv.counts()
+	require.Equal(t, 0, renewals)
+}
+
+func Test_Lease_ReissueNonRenewable(t *testing.T) {
+	fv := newFakeVault(t, 1, false)
+
+	ctrl := runLeaseTestComponent(t, fmt.Sprintf(`
+		server     = "%s"
+		path       = "pki/issue/web"
+		store      = "logical"
+		write_data = {
+			common_name = "web.example.com",
+		}
+
+		lease_renewal_ratio = 0.5
+
+		auth.token {
+			token = "test"
+		}
+	`, fv.URL))
+
+	fv.mut.Lock()
+	require.Equal(t, map[string]any{"common_name": "web.example.com"}, fv.lastWriteReq)
+	fv.mut.Unlock()
+
+	// Non-renewable leases are replaced before they expire.
+	require.Eventually(t, func() bool {
+		return ctrl.Exports().(Exports).Data["username"] == alloytypes.Secret("user-2")
+	}, 10*time.Second, 50*time.Millisecond)
+
+	_, renewals := fv.counts()
+	require.Equal(t, 0, renewals)
+}
+
+func TestArguments_Validate_Store(t *testing.T) {
+	tt := []struct {
+		name      string
+		cfg       string
+		expectErr string
+	}{
+		{
+			name: "logical store with write data",
+			cfg: `
+				store      = "logical"
+				write_data = { common_name = "example.com" }
+			`,
+		},
+		{
+			name:      "write data with kv store",
+			cfg:       `write_data = { common_name = "example.com" }`,
+			expectErr: `write_data requires store to be "logical"`,
+		},
+		{
+			name:      "unknown store",
+			cfg:       `store = "cubbyhole"`,
+			expectErr: `unsupported store "cubbyhole", expected "kv" or "logical"`,
+		},
+		{
+			name:      "renewal ratio out of range",
+			cfg:       `lease_renewal_ratio = 1`,
+			expectErr: "lease_renewal_ratio must be between 0 and 1, exclusive",
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			cfg := `
+				server = "http://localhost:8200"
+				path   = "pki/issue/web"
+				auth.token {
+					token = "test"
+				}
+			` + tc.cfg
+
+			var args Arguments
+			err := syntax.Unmarshal([]byte(cfg), &args)
+			if tc.expectErr == "" {
+				require.NoError(t, err)
+			} else {
+				require.ErrorContains(t, err, tc.expectErr)
+			}
+		})
+	}
+}
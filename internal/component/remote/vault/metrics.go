@@ -8,6 +8,9 @@ type metrics struct {
 
 	authLeaseRenewalTotal   prometheus.Counter
 	secretLeaseRenewalTotal prometheus.Counter
+
+	authLeaseExpiry   prometheus.Gauge
+	secretLeaseExpiry prometheus.Gauge
 }
 
 func newMetrics(r prometheus.Registerer) *metrics {
@@ -31,6 +34,15 @@ func newMetrics(r prometheus.Registerer) *metrics {
 		Help: "Total number of times this component renewed its secret lease",
 	})
 
+	m.authLeaseExpiry = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "remote_vault_auth_lease_expiry_timestamp_seconds",
+		Help: "Unix timestamp at which the lease of the auth token expires, or 0 if it isn't leased",
+	})
+	m.secretLeaseExpiry = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "remote_vault_secret_lease_expiry_timestamp_seconds",
+		Help: "Unix timestamp at which the lease of the secret expires, or 0 if it isn't leased",
+	})
+
 	if r != nil {
 		r.MustRegister(
 			m.authTotal,
@@ -38,6 +50,9 @@ func newMetrics(r prometheus.Registerer) *metrics {
 
 			m.authLeaseRenewalTotal,
 			m.secretLeaseRenewalTotal,
+
+			m.authLeaseExpiry,
+			m.secretLeaseExpiry,
 		)
 	}
 	return &m
@@ -2,6 +2,7 @@ package vault
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sync"
 	"time"
@@ -15,6 +16,10 @@ import (
 
 const tokenManagerInitializeTimeout = time.Minute
 
+// leaseRetryInterval is how long to wait before retrying a failed lease
+// renewal or token retrieval.
+const leaseRetryInterval = 10 * time.Second
+
 type getTokenFunc func(ctx context.Context, client *vault.Client) (*vault.Secret, error)
 
 // A tokenManager retrieves and manages the lifecycle of tokens. tokenManager,
@@ -24,14 +29,18 @@ type tokenManager struct {
 	log           log.Logger
 	refreshTicker *ticker
 	getter        getTokenFunc
+	onToken       func(token *vault.Secret, leaseExpiry time.Time)
 	onStateChange chan struct{} // Written to when cli or token changes.
 
 	readCounter    prometheus.Counter
 	refreshCounter prometheus.Counter
+	expiryGauge    prometheus.Gauge
 
-	mut   sync.RWMutex
-	cli   *vault.Client
-	token *vault.Secret
+	mut          sync.RWMutex
+	cli          *vault.Client
+	token        *vault.Secret
+	leaseExpiry  time.Time
+	renewalRatio float64
 
 	healthMut sync.RWMutex
 	health    component.Health
@@ -43,11 +52,16 @@ type tokenManager struct {
 type tokenManagerOptions struct {
 	Log    log.Logger
 	Getter getTokenFunc
+	// OnToken, if set, is called whenever a token is retrieved or its lease is
+	// renewed.
+	OnToken func(token *vault.Secret, leaseExpiry time.Time)
 
 	ReadCounter, RefreshCounter prometheus.Counter
+	ExpiryGauge                 prometheus.Gauge
 
 	Client          *vault.Client
 	RefreshInterval time.Duration
+	RenewalRatio    float64
 }
 
 // newTokenManager creates a new, unstarted tokenManager. tokenManager will
@@ -60,12 +74,18 @@ func newTokenManager(opts tokenManagerOptions) (*tokenManager, error) {
 		log:           opts.Log,
 		refreshTicker: newTicker(opts.RefreshInterval),
 		getter:        opts.Getter,
+		onToken:       opts.OnToken,
 		onStateChange: make(chan struct{}, 1),
 
 		readCounter:    opts.ReadCounter,
 		refreshCounter: opts.RefreshCounter,
+		expiryGauge:    opts.ExpiryGauge,
 
-		cli: opts.Client,
+		cli:          opts.Client,
+		renewalRatio: opts.RenewalRatio,
+	}
+	if tm.renewalRatio == 0 {
+		tm.renewalRatio = DefaultArguments.LeaseRenewalRatio
 	}
 	if err := tm.updateToken(ctx); err != nil {
 		return nil, fmt.Errorf("failed to get token: %w", err)
@@ -105,7 +125,7 @@ func (tm *tokenManager) updateToken(ctx context.Context) (err error) {
 
 	tm.readCounter.Inc()
 
-	tm.token = token
+	tm.setToken(token, time.Now())
 
 	select {
 	case tm.onStateChange <- struct{}{}:
@@ -115,12 +135,33 @@ func (tm *tokenManager) updateToken(ctx context.Context) (err error) {
 	return nil
 }
 
+// setToken stores token and the expiry of its lease, which started at
+// leaseStart. mut must be held when called.
+func (tm *tokenManager) setToken(token *vault.Secret, leaseStart time.Time) {
+	tm.token = token
+	tm.leaseExpiry = time.Time{}
+	if duration, _ := leaseOf(token); duration > 0 {
+		tm.leaseExpiry = leaseStart.UTC().Add(duration)
+	}
+
+	if tm.expiryGauge != nil {
+		if tm.leaseExpiry.IsZero() {
+			tm.expiryGauge.Set(0)
+		} else {
+			tm.expiryGauge.Set(float64(tm.leaseExpiry.Unix()))
+		}
+	}
+	if tm.onToken != nil {
+		tm.onToken(token, tm.leaseExpiry)
+	}
+}
+
 // Run runs the tokenManager, blocking until the provided context is canceled.
 func (tm *tokenManager) Run(ctx context.Context) {
-	var cancelLifecycleWatcher context.CancelFunc
+	var cancelLeaseWatcher context.CancelFunc
 	defer func() {
-		if cancelLifecycleWatcher != nil {
-			cancelLifecycleWatcher()
+		if cancelLeaseWatcher != nil {
+			cancelLeaseWatcher()
 		}
 	}()
 
@@ -135,14 +176,14 @@ func (tm *tokenManager) Run(ctx context.Context) {
 			_ = tm.updateToken(ctx)
 
 		case <-tm.onStateChange:
-			if cancelLifecycleWatcher != nil {
-				cancelLifecycleWatcher()
+			if cancelLeaseWatcher != nil {
+				cancelLeaseWatcher()
 			}
 
 			ctx, cancel := context.WithCancel(ctx)
-			cancelLifecycleWatcher = cancel
+			cancelLeaseWatcher = cancel
 
-			tm.updateLifecycleWatcher(ctx)
+			go tm.watchLease(ctx)
 		}
 	}
 }
@@ -156,69 +197,194 @@ func (tm *tokenManager) updateHealth(h component.Health) {
 
 func (tm *tokenManager) updateDebugInfo(updateTime time.Time) {
 	tm.mut.RLock()
-	token := tm.token
+	token, leaseExpiry := tm.token, tm.leaseExpiry
 	tm.mut.RUnlock()
 
 	tm.debugMut.Lock()
 	defer tm.debugMut.Unlock()
 
-	tm.debugInfo = getSecretInfo(token, updateTime)
+	tm.debugInfo = getSecretInfo(token, leaseExpiry, updateTime)
 }
 
-func (tm *tokenManager) updateLifecycleWatcher(ctx context.Context) {
+// watchLease keeps the lease of the current token alive until ctx is
+// canceled. The lease is renewed once the renewal ratio of its duration has
+// elapsed, so that the rest of the lease is left to retry failed renewals.
+//
+// A new token is retrieved instead when the lease can't be extended anymore:
+// because it isn't renewable, it reached its maximum TTL, or Vault rejected
+// the renewal, which happens when the lease was revoked.
+func (tm *tokenManager) watchLease(ctx context.Context) {
 	tm.mut.RLock()
-	defer tm.mut.RUnlock()
+	token, expiry := tm.token, tm.leaseExpiry
+	tm.mut.RUnlock()
 
-	if !needsLifecycleWatcher(tm.token) {
+	duration, renewable := leaseOf(token)
+	if duration <= 0 {
 		return
 	}
+	// Renewals ask for the duration of the original lease.
+	increment := duration
+	next := tm.renewalTime(expiry, duration)
 
-	lw, err := tm.cli.NewLifetimeWatcher(&vault.LifetimeWatcherInput{
-		Secret:        tm.token,
-		RenewBehavior: vault.RenewBehaviorIgnoreErrors,
-	})
+	for {
+		if !waitUntil(ctx, next) {
+			return
+		}
+
+		if !renewable {
+			level.Info(tm.log).Log("msg", "lease can't be renewed, retrieving a new token", "lease_expiry", expiry)
+			tm.replaceToken(ctx)
+			return
+		}
+
+		renewed, err := tm.renewLease(ctx, token, increment)
+		var respErr *vault.ResponseError
+		switch {
+		case errors.As(err, &respErr) && respErr.StatusCode >= 400 && respErr.StatusCode < 500:
+			level.Warn(tm.log).Log("msg", "lease renewal was rejected, retrieving a new token", "err", err)
+			tm.replaceToken(ctx)
+			return
+
+		case err != nil:
+			next = time.Now().Add(leaseRetryInterval)
+			if !next.Before(expiry) {
+				level.Warn(tm.log).Log("msg", "failed to renew lease before its expiry, retrieving a new token", "err", err)
+				tm.replaceToken(ctx)
+				return
+			}
+			level.Warn(tm.log).Log("msg", "failed to renew lease, will retry", "retry_in", leaseRetryInterval, "err", err)
+			continue
+		}
+
+		tm.refreshCounter.Inc()
+
+		var ok bool
+		token, expiry, ok = tm.setRenewedToken(token, renewed)
+		if !ok {
+			// The token was replaced in the meantime, which cancels ctx.
+			return
+		}
+		level.Debug(tm.log).Log("msg", "lease has been renewed", "lease_expiry", expiry)
+
+		duration, renewable = leaseOf(token)
+		if duration <= 0 {
+			tm.replaceToken(ctx)
+			return
+		}
+		if duration < increment {
+			// Vault capped the lease to its maximum TTL; further renewals can't
+			// extend it.
+			renewable = false
+		}
+		next = tm.renewalTime(expiry, duration)
+	}
+}
+
+// renewalTime returns when a lease of the given duration expiring at expiry
+// should be renewed.
+func (tm *tokenManager) renewalTime(expiry time.Time, duration time.Duration) time.Time {
+	tm.mut.RLock()
+	ratio := tm.renewalRatio
+	tm.mut.RUnlock()
+
+	return expiry.Add(-time.Duration(float64(duration) * (1 - ratio)))
+}
+
+// renewLease renews the lease of token for increment.
+func (tm *tokenManager) renewLease(ctx context.Context, token *vault.Secret, increment time.Duration) (*vault.Secret, error) {
+	tm.mut.RLock()
+	cli := tm.cli
+	tm.mut.RUnlock()
+
+	var (
+		renewed *vault.Secret
+		err     error
+	)
+	if token.Auth != nil {
+		renewed, err = cli.Auth().Token().RenewSelfWithContext(ctx, int(increment.Seconds()))
+	} else {
+		renewed, err = cli.Sys().RenewWithContext(ctx, token.LeaseID, int(increment.Seconds()))
+	}
 	if err != nil {
-		level.Error(tm.log).Log("msg", "failed to create lifetime watcher, lease will not renew automatically", "err", err)
-		return
+		return nil, err
+	} else if renewed == nil {
+		return nil, fmt.Errorf("empty response renewing lease")
 	}
+	return renewed, nil
+}
 
-	go lw.Start()
+// setRenewedToken updates the lease of token with the one returned by its
+// renewal, and returns the updated token and its lease expiry. ok is false if
+// token was replaced in the meantime.
+func (tm *tokenManager) setRenewedToken(token, renewed *vault.Secret) (updated *vault.Secret, expiry time.Time, ok bool) {
+	// Renewals don't return the data of the secret; keep the data of the
+	// original token.
+	updated = new(vault.Secret)
+	*updated = *token
+	updated.RequestID = renewed.RequestID
+	updated.Warnings = renewed.Warnings
+
+	if token.Auth != nil && renewed.Auth != nil {
+		auth := *token.Auth
+		auth.LeaseDuration = renewed.Auth.LeaseDuration
+		auth.Renewable = renewed.Auth.Renewable
+		updated.Auth = &auth
+	} else {
+		updated.LeaseDuration = renewed.LeaseDuration
+		updated.Renewable = renewed.Renewable
+	}
 
-	go func() {
-		for {
-			select {
-			case <-ctx.Done():
-				lw.Stop()
-				return
+	now := time.Now()
 
-			case <-lw.DoneCh():
-				if ctx.Err() != nil {
-					return
-				}
-				// Error is logged as health and debug info.
-				_ = tm.updateToken(ctx)
-
-			case output := <-lw.RenewCh():
-				tm.refreshCounter.Inc()
-				level.Debug(tm.log).Log("msg", "token has renewed")
-				tm.updateDebugInfo(output.RenewedAt)
-			}
+	tm.mut.Lock()
+	if tm.token != token {
+		tm.mut.Unlock()
+		return nil, time.Time{}, false
+	}
+	tm.setToken(updated, now)
+	expiry = tm.leaseExpiry
+	tm.mut.Unlock()
+
+	tm.updateDebugInfo(now)
+	return updated, expiry, true
+}
+
+// replaceToken retrieves a new token, retrying until it succeeds or ctx is
+// canceled.
+func (tm *tokenManager) replaceToken(ctx context.Context) {
+	// Errors are logged as health and debug info.
+	for tm.updateToken(ctx) != nil {
+		if !waitUntil(ctx, time.Now().Add(leaseRetryInterval)) {
+			return
 		}
-	}()
+	}
 }
 
-// needsLifecycleWatcher determines if a secret needs a lifecycle watcher.
-// Secrets only need a lifecycle watcher if they are renewable or have a lease
-// duration.
-func needsLifecycleWatcher(secret *vault.Secret) bool {
-	if secret == nil {
+// waitUntil blocks until t, returning false if ctx was canceled first.
+func waitUntil(ctx context.Context, t time.Time) bool {
+	timer := time.NewTimer(time.Until(t))
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
 		return false
+	case <-timer.C:
+		return true
 	}
+}
 
-	if secret.Auth != nil {
-		return secret.Auth.Renewable || secret.Auth.LeaseDuration > 0
+// leaseOf returns the duration of the lease of a secret and whether it can be
+// renewed. The lease of tokens returned by auth methods is held in the auth
+// information of the secret.
+func leaseOf(secret *vault.Secret) (duration time.Duration, renewable bool) {
+	switch {
+	case secret == nil:
+		return 0, false
+	case secret.Auth != nil:
+		return time.Duration(secret.Auth.LeaseDuration) * time.Second, secret.Auth.Renewable
+	default:
+		return time.Duration(secret.LeaseDuration) * time.Second, secret.Renewable
 	}
-	return secret.Renewable || secret.LeaseDuration > 0
 }
 
 // SetClient updates the client associated with the tokenManager. This will
@@ -241,6 +407,15 @@ func (tm *tokenManager) SetRefreshInterval(interval time.Duration) {
 	tm.refreshTicker.Reset(interval)
 }
 
+// SetRenewalRatio sets the ratio of the lease duration after which leases are
+// renewed. It applies from the next renewal onwards.
+func (tm *tokenManager) SetRenewalRatio(ratio float64) {
+	tm.mut.Lock()
+	defer tm.mut.Unlock()
+
+	tm.renewalRatio = ratio
+}
+
 // CurrentHealth returns the health of the tokenManager.
 func (tm *tokenManager) CurrentHealth() component.Health {
 	tm.healthMut.RLock()
@@ -265,7 +440,7 @@ type secretInfo struct {
 	Warnings         []string  `alloy:"warnings,attr"`
 }
 
-func getSecretInfo(secret *vault.Secret, updateTime time.Time) secretInfo {
+func getSecretInfo(secret *vault.Secret, leaseExpiry, updateTime time.Time) secretInfo {
 	if secret == nil {
 		return secretInfo{
 			LastUpdateTime: updateTime,
@@ -273,20 +448,12 @@ func getSecretInfo(secret *vault.Secret, updateTime time.Time) secretInfo {
 		}
 	}
 
+	_, renewable := leaseOf(secret)
 	return secretInfo{
 		LatestRequestID:  secret.RequestID,
 		LastUpdateTime:   updateTime,
-		SecretExpireTime: secretExpireTime(secret),
-		Renewable:        secret.Renewable,
+		SecretExpireTime: leaseExpiry,
+		Renewable:        renewable,
 		Warnings:         secret.Warnings,
 	}
 }
-
-func secretExpireTime(secret *vault.Secret) time.Time {
-	ttl, err := secret.TokenTTL()
-	if err != nil || ttl == 0 {
-		return time.Time{}
-	}
-
-	return time.Now().UTC().Add(ttl)
-}
@@ -35,8 +35,15 @@ type Arguments struct {
 	Namespace string `alloy:"namespace,attr,optional"`
 
 	Path string `alloy:"path,attr"`
+	// Store selects how the secret at Path is read. Dynamic secrets engines
+	// require the logical store.
+	Store string `alloy:"store,attr,optional"`
+	// WriteData makes the logical store retrieve the secret with a write
+	// request holding these parameters.
+	WriteData map[string]string `alloy:"write_data,attr,optional"`
 
-	RereadFrequency time.Duration `alloy:"reread_frequency,attr,optional"`
+	RereadFrequency   time.Duration `alloy:"reread_frequency,attr,optional"`
+	LeaseRenewalRatio float64       `alloy:"lease_renewal_ratio,attr,optional"`
 
 	ClientOptions ClientOptions `alloy:"client_options,block,optional"`
 
@@ -48,8 +55,16 @@ type Arguments struct {
 	Auth []AuthArguments `alloy:"auth,enum,optional"`
 }
 
+// Stores supported by remote.vault.
+const (
+	storeKV      = "kv"
+	storeLogical = "logical"
+)
+
 // DefaultArguments holds default settings for Arguments.
 var DefaultArguments = Arguments{
+	Store:             storeKV,
+	LeaseRenewalRatio: 0.67,
 	ClientOptions: ClientOptions{
 		MinRetryWait: 1000 * time.Millisecond,
 		MaxRetryWait: 1500 * time.Millisecond,
@@ -95,6 +110,20 @@ func (a *Arguments) Validate() error {
 		return fmt.Errorf("client_options.timeout must be greater than 0")
 	}
 
+	switch a.Store {
+	case storeKV:
+		if a.WriteData != nil {
+			return fmt.Errorf("write_data requires store to be %q", storeLogical)
+		}
+	case storeLogical:
+	default:
+		return fmt.Errorf("unsupported store %q, expected %q or %q", a.Store, storeKV, storeLogical)
+	}
+
+	if a.LeaseRenewalRatio <= 0 || a.LeaseRenewalRatio >= 1 {
+		return fmt.Errorf("lease_renewal_ratio must be between 0 and 1, exclusive")
+	}
+
 	return nil
 }
 
@@ -106,7 +135,9 @@ func (a *Arguments) authMethod() authMethod {
 }
 
 func (a *Arguments) secretStore(cli *vault.Client) secretStore {
-	// TODO(rfratto): support different stores (like a logical store).
+	if a.Store == storeLogical {
+		return &logicalStore{c: cli}
+	}
 	return &kvStore{c: cli}
 }
 
@@ -127,6 +158,11 @@ type Exports struct {
 	// However, it seems that most secrets engines don't actually return
 	// arbitrary data, so this limitation shouldn't cause any issues in practice.
 	Data map[string]alloytypes.Secret `alloy:"data,attr"`
+
+	// LeaseExpiresAt is the RFC 3339 time at which the lease of the secret
+	// expires, or empty if the secret isn't leased. It moves forward each time
+	// the lease is renewed.
+	LeaseExpiresAt string `alloy:"lease_expires_at,attr"`
 }
 
 // Component implements the remote.vault component.
@@ -217,12 +253,16 @@ func (c *Component) Update(args component.Arguments) error {
 
 			ReadCounter:    c.metrics.authTotal,
 			RefreshCounter: c.metrics.authLeaseRenewalTotal,
+			ExpiryGauge:    c.metrics.authLeaseExpiry,
+
+			RenewalRatio: newArgs.LeaseRenewalRatio,
 		})
 		if err != nil {
 			return err
 		}
 		c.authManager = mgr
 	} else {
+		c.authManager.SetRenewalRatio(newArgs.LeaseRenewalRatio)
 		c.authManager.SetClient(newClient)
 	}
 
@@ -231,16 +271,20 @@ func (c *Component) Update(args component.Arguments) error {
 			Log:             log.With(c.log, "token_type", "secret"),
 			Client:          newClient,
 			Getter:          c.getSecret,
+			OnToken:         c.exportSecret,
 			RefreshInterval: newArgs.RereadFrequency,
+			RenewalRatio:    newArgs.LeaseRenewalRatio,
 
 			ReadCounter:    c.metrics.secretReadTotal,
 			RefreshCounter: c.metrics.secretLeaseRenewalTotal,
+			ExpiryGauge:    c.metrics.secretLeaseExpiry,
 		})
 		if err != nil {
 			return err
 		}
 		c.secretManager = mgr
 	} else {
+		c.secretManager.SetRenewalRatio(newArgs.LeaseRenewalRatio)
 		c.secretManager.SetClient(newClient)
 		c.secretManager.SetRefreshInterval(newArgs.RereadFrequency)
 	}
@@ -260,24 +304,21 @@ func (c *Component) getSecret(ctx context.Context, cli *vault.Client) (*vault.Se
 	c.mut.RLock()
 	defer c.mut.RUnlock()
 
+	// The secret is exported by the secret manager once stored, as its lease
+	// is needed by the exports too.
 	store := c.args.secretStore(cli)
-	secret, err := store.Read(ctx, &c.args)
-	if err != nil {
-		return nil, err
-	}
-
-	// Export the secret so other components can use it.
-	c.exportSecret(secret)
-
-	return secret, nil
+	return store.Read(ctx, &c.args)
 }
 
 // exportSecret converts the secret into exports and exports it to the
-// controller.
-func (c *Component) exportSecret(secret *vault.Secret) {
+// controller. It's called whenever the secret is read or its lease renewed.
+func (c *Component) exportSecret(secret *vault.Secret, leaseExpiry time.Time) {
 	newExports := Exports{
 		Data: make(map[string]alloytypes.Secret),
 	}
+	if !leaseExpiry.IsZero() {
+		newExports.LeaseExpiresAt = leaseExpiry.Format(time.RFC3339)
+	}
 
 	for key, value := range secret.Data {
 		switch value := value.(type) {
@@ -25,14 +25,14 @@ func TestOptionalSecret_Write(t *testing.T) {
 		{
 			name: "empty",
 			value: common.ConvertTargets{
-				Targets: []discovery.Target{{}},
+				Targets: []discovery.Target{discovery.EmptyTarget},
 			},
 			expect: ``,
 		},
 		{
 			name: "__address__ key",
 			value: common.ConvertTargets{
-				Targets: []discovery.Target{{"__address__": "testing"}},
+				Targets: []discovery.Target{discovery.NewTargetFromMap(map[string]string{"__address__": "testing"})},
 			},
 			expect: `[{
 	__address__ = "testing",
@@ -41,7 +41,7 @@ func TestOptionalSecret_Write(t *testing.T) {
 		{
 			name: "__address__ key label",
 			value: common.ConvertTargets{
-				Targets: []discovery.Target{{"__address__": "testing", "label": "value"}},
+				Targets: []discovery.Target{discovery.NewTargetFromMap(map[string]string{"__address__": "testing", "label": "value"})},
 			},
 			expect: `[{
 	__address__ = "testing",
@@ -52,8 +52,8 @@ func TestOptionalSecret_Write(t *testing.T) {
 			name: "multiple __address__ key label",
 			value: common.ConvertTargets{
 				Targets: []discovery.Target{
-					{"__address__": "testing", "label": "value"},
-					{"__address__": "testing2", "label": "value"},
+					discovery.NewTargetFromMap(map[string]string{"__address__": "testing", "label": "value"}),
+					discovery.NewTargetFromMap(map[string]string{"__address__": "testing2", "label": "value"}),
 				},
 			},
 			expect: `concat(
@@ -70,14 +70,14 @@ func TestOptionalSecret_Write(t *testing.T) {
 		{
 			name: "__expr__ key",
 			value: common.ConvertTargets{
-				Targets: []discovery.Target{{"__expr__": "testing"}},
+				Targets: []discovery.Target{discovery.NewTargetFromMap(map[string]string{"__expr__": "testing"})},
 			},
 			expect: `testing`,
 		},
 		{
 			name: "multiple __expr__ key",
 			value: common.ConvertTargets{
-				Targets: []discovery.Target{{"__expr__": "testing"}, {"__expr__": "testing2"}},
+				Targets: []discovery.Target{discovery.NewTargetFromMap(map[string]string{"__expr__": "testing"}), discovery.NewTargetFromMap(map[string]string{"__expr__": "testing2"})},
 			},
 			expect: `concat(
 	testing,
@@ -87,7 +87,7 @@ func TestOptionalSecret_Write(t *testing.T) {
 		{
 			name: "both key types",
 			value: common.ConvertTargets{
-				Targets: []discovery.Target{{"__address__": "testing", "label": "value"}, {"__expr__": "testing2"}},
+				Targets: []discovery.Target{discovery.NewTargetFromMap(map[string]string{"__address__": "testing", "label": "value"}), discovery.NewTargetFromMap(map[string]string{"__expr__": "testing2"})},
 			},
 			expect: `concat(
 	[{
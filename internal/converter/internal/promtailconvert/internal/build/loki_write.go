@@ -41,18 +41,20 @@ func toLokiWriteArguments(config *client.Config, diags *diag.Diagnostics) *lokiw
 	return &lokiwrite.Arguments{
 		Endpoints: []lokiwrite.EndpointOptions{
 			{
-				Name:              config.Name,
-				URL:               config.URL.String(),
-				BatchWait:         config.BatchWait,
-				BatchSize:         batchSize,
-				HTTPClientConfig:  common.ToHttpClientConfig(&config.Client),
-				Headers:           config.Headers,
-				MinBackoff:        config.BackoffConfig.MinBackoff,
-				MaxBackoff:        config.BackoffConfig.MaxBackoff,
-				MaxBackoffRetries: config.BackoffConfig.MaxRetries,
-				RemoteTimeout:     config.Timeout,
-				TenantID:          config.TenantID,
-				RetryOnHTTP429:    !config.DropRateLimitedBatches,
+				Name:               config.Name,
+				URL:                config.URL.String(),
+				BatchWait:          config.BatchWait,
+				BatchSize:          batchSize,
+				HTTPClientConfig:   common.ToHttpClientConfig(&config.Client),
+				Headers:            config.Headers,
+				MinBackoff:         config.BackoffConfig.MinBackoff,
+				MaxBackoff:         config.BackoffConfig.MaxBackoff,
+				MaxBackoffRetries:  config.BackoffConfig.MaxRetries,
+				RemoteTimeout:      config.Timeout,
+				TenantID:           config.TenantID,
+				RetryOnHTTP429:     !config.DropRateLimitedBatches,
+				StructuredMetadata: lokiwrite.GetDefaultEndpointOptions().StructuredMetadata,
+				TenantQueues:       lokiwrite.GetDefaultEndpointOptions().TenantQueues,
 			},
 		},
 		ExternalLabels: convertFlagLabels(config.ExternalLabels),
@@ -4,6 +4,9 @@ import (
 	"context"
 	"encoding"
 	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
 	"sync"
 	"time"
 
@@ -80,6 +83,10 @@ type FSNotifyOptions struct {
 	Filename      string
 	ReloadFile    func()        // Callback to request file reload.
 	PollFrequency time.Duration // How often to do fallback polling
+
+	// Recursive watches every directory below Filename, which must be a
+	// directory, so that changes anywhere in the tree are detected.
+	Recursive bool
 }
 
 // newFSNotify creates a new fsnotify detector which uses filesystem events to
@@ -89,12 +96,6 @@ func NewFSNotify(opts FSNotifyOptions) (*FSNotify, error) {
 	if err != nil {
 		return nil, err
 	}
-	if err := w.Add(opts.Filename); err != nil {
-		// It's possible that the file already got deleted by the time our fsnotify
-		// was created. We'll log the error and wait for our polling fallback for
-		// the file to be recreated.
-		level.Warn(opts.Logger).Log("msg", "failed to watch file", "err", err)
-	}
 
 	ctx, cancel := context.WithCancel(context.Background())
 
@@ -104,6 +105,13 @@ func NewFSNotify(opts FSNotifyOptions) (*FSNotify, error) {
 		cancel:  cancel,
 	}
 
+	if err := wd.watch(opts.Filename); err != nil {
+		// It's possible that the file already got deleted by the time our fsnotify
+		// was created. We'll log the error and wait for our polling fallback for
+		// the file to be recreated.
+		level.Warn(opts.Logger).Log("msg", "failed to watch file", "err", err)
+	}
+
 	go wd.wait(ctx)
 	return wd, nil
 }
@@ -122,11 +130,7 @@ func (fsn *FSNotify) wait(ctx context.Context) {
 			//
 			// We'll use the poll period to re-establish the watch in case it was
 			// stopped. This is a no-op if the watch is already active.
-			fsn.watcherMut.Lock()
-			err := fsn.watcher.Add(fsn.opts.Filename)
-			fsn.watcherMut.Unlock()
-
-			if err != nil {
+			if err := fsn.watch(fsn.opts.Filename); err != nil {
 				level.Warn(fsn.opts.Logger).Log("msg", "failed re-watch file", "err", err)
 			}
 
@@ -145,11 +149,48 @@ func (fsn *FSNotify) wait(ctx context.Context) {
 			}
 		case ev := <-fsn.watcher.Events:
 			level.Debug(fsn.opts.Logger).Log("msg", "got fsnotify event", "op", ev.Op.String())
+
+			// Directories created in a recursively watched tree must be watched
+			// as well, otherwise changes to their files would go unnoticed
+			// until the next poll.
+			if fsn.opts.Recursive && ev.Has(fsnotify.Create) {
+				if fi, err := os.Stat(ev.Name); err == nil && fi.IsDir() {
+					if err := fsn.watch(ev.Name); err != nil {
+						level.Warn(fsn.opts.Logger).Log("msg", "failed to watch directory", "path", ev.Name, "err", err)
+					}
+				}
+			}
+
 			fsn.opts.ReloadFile()
 		}
 	}
 }
 
+// watch adds a watch for path, and for every directory below it if the
+// detector is recursive. Adding a path which is already watched is a no-op.
+func (fsn *FSNotify) watch(path string) error {
+	fsn.watcherMut.Lock()
+	defer fsn.watcherMut.Unlock()
+
+	if err := fsn.watcher.Add(path); err != nil || !fsn.opts.Recursive {
+		return err
+	}
+
+	return filepath.WalkDir(path, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			// Entries can disappear while walking the tree; the next poll
+			// catches up with them.
+			return nil
+		}
+		if d.IsDir() && p != path {
+			if err := fsn.watcher.Add(p); err != nil {
+				level.Warn(fsn.opts.Logger).Log("msg", "failed to watch directory", "path", p, "err", err)
+			}
+		}
+		return nil
+	})
+}
+
 func (fsn *FSNotify) Close() error {
 	fsn.watcherMut.Lock()
 	defer fsn.watcherMut.Unlock()
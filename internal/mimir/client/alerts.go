@@ -0,0 +1,69 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"gopkg.in/yaml.v3"
+)
+
+// alertmanagerAPIPath is the path of the Alertmanager configuration API of
+// Mimir.
+const alertmanagerAPIPath = "/api/v1/alerts"
+
+// AlertmanagerConfig is the Alertmanager configuration of a tenant.
+type AlertmanagerConfig struct {
+	TemplateFiles      map[string]string `yaml:"template_files"`
+	AlertmanagerConfig string            `yaml:"alertmanager_config"`
+}
+
+// AlertmanagerInterface is the part of MimirClient managing the Alertmanager
+// configuration of a tenant.
+type AlertmanagerInterface interface {
+	GetAlertmanagerConfig(ctx context.Context) (*AlertmanagerConfig, error)
+	CreateAlertmanagerConfig(ctx context.Context, cfg AlertmanagerConfig) error
+}
+
+var _ AlertmanagerInterface = (*MimirClient)(nil)
+
+// GetAlertmanagerConfig retrieves the Alertmanager configuration of the
+// tenant. It returns nil if the tenant has no configuration.
+func (r *MimirClient) GetAlertmanagerConfig(ctx context.Context) (*AlertmanagerConfig, error) {
+	res, err := r.doRequest(alertmanagerAPIPath, alertmanagerAPIPath, "GET", nil)
+	if errors.Is(err, ErrNotFound) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	defer res.Body.Close()
+	body, err := io.ReadAll(res.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	var cfg AlertmanagerConfig
+	if err := yaml.Unmarshal(body, &cfg); err != nil {
+		return nil, err
+	}
+	return &cfg, nil
+}
+
+// CreateAlertmanagerConfig replaces the Alertmanager configuration of the
+// tenant.
+func (r *MimirClient) CreateAlertmanagerConfig(ctx context.Context, cfg AlertmanagerConfig) error {
+	payload, err := yaml.Marshal(&cfg)
+	if err != nil {
+		return err
+	}
+
+	res, err := r.doRequest(alertmanagerAPIPath, alertmanagerAPIPath, "POST", payload)
+	if err != nil {
+		return err
+	}
+
+	res.Body.Close()
+
+	return nil
+}
@@ -0,0 +1,60 @@
+package client
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/dskit/instrument"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMimirClient_AlertmanagerConfig(t *testing.T) {
+	var stored []byte
+
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/api/v1/alerts", r.URL.Path)
+
+		switch r.Method {
+		case http.MethodGet:
+			if stored == nil {
+				http.Error(w, "the Alertmanager is not configured", http.StatusNotFound)
+				return
+			}
+			_, _ = w.Write(stored)
+		case http.MethodPost:
+			body, err := io.ReadAll(r.Body)
+			require.NoError(t, err)
+			stored = body
+			w.WriteHeader(http.StatusCreated)
+		default:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}))
+	defer ts.Close()
+
+	client, err := New(log.NewNopLogger(), Config{
+		Address: ts.URL,
+	}, prometheus.NewHistogramVec(prometheus.HistogramOpts{}, instrument.HistogramCollectorBuckets))
+	require.NoError(t, err)
+
+	ctx := context.Background()
+
+	actual, err := client.GetAlertmanagerConfig(ctx)
+	require.NoError(t, err)
+	require.Nil(t, actual)
+
+	expected := AlertmanagerConfig{
+		TemplateFiles:      map[string]string{"default.tmpl": `{{ define "x" }}x{{ end }}`},
+		AlertmanagerConfig: "route:\n  receiver: default\nreceivers:\n  - name: default\n",
+	}
+	require.NoError(t, client.CreateAlertmanagerConfig(ctx, expected))
+
+	actual, err = client.GetAlertmanagerConfig(ctx)
+	require.NoError(t, err)
+	require.Equal(t, &expected, actual)
+}
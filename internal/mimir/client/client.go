@@ -23,6 +23,7 @@ import (
 
 var (
 	ErrUnrecoverable = errors.New("unrecoverable error response")
+	ErrNotFound      = errors.New("not found")
 )
 
 // IsRecoverable returns true for errors from API requests that can be retried, false otherwise.
@@ -127,6 +128,9 @@ func checkResponse(r *http.Response) error {
 		errMsg = fmt.Sprintf("server returned HTTP status %s: %s", r.Status, msg)
 	}
 
+	if r.StatusCode == http.StatusNotFound {
+		return fmt.Errorf("%w: %w: %s", ErrUnrecoverable, ErrNotFound, errMsg)
+	}
 	if r.StatusCode/100 == 4 && r.StatusCode != http.StatusTooManyRequests {
 		return fmt.Errorf("%w: %s", ErrUnrecoverable, errMsg)
 	}
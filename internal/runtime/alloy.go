@@ -145,6 +145,9 @@ type controllerOptions struct {
 	IsModule          bool                         // Whether this controller is for a module.
 	// A worker pool to evaluate components asynchronously. A default one will be created if this is nil.
 	WorkerPool worker.Pool
+	// The policy used to prioritise and coalesce evaluations. It is configured by the evaluation block of the root
+	// controller and shared with its modules. A default one will be created if this is nil.
+	EvaluationPolicy *controller.EvaluationPolicy
 }
 
 // newController creates a new, unstarted Alloy controller with a specific
@@ -152,9 +155,10 @@ type controllerOptions struct {
 // given modReg.
 func newController(o controllerOptions) *Runtime {
 	var (
-		log        = o.Logger
-		tracer     = o.Tracer
-		workerPool = o.WorkerPool
+		log              = o.Logger
+		tracer           = o.Tracer
+		workerPool       = o.WorkerPool
+		evaluationPolicy = o.EvaluationPolicy
 	)
 
 	if tracer == nil {
@@ -171,6 +175,10 @@ func newController(o controllerOptions) *Runtime {
 		workerPool = worker.NewDefaultWorkerPool()
 	}
 
+	if evaluationPolicy == nil {
+		evaluationPolicy = controller.NewEvaluationPolicy()
+	}
+
 	f := &Runtime{
 		log:    log,
 		tracer: tracer,
@@ -194,7 +202,7 @@ func newController(o controllerOptions) *Runtime {
 			MinStability:  o.MinStability,
 			OnBlockNodeUpdate: func(cn controller.BlockNode) {
 				// Changed node should be queued for reevaluation.
-				f.updateQueue.Enqueue(&controller.QueuedNode{Node: cn, LastUpdatedTime: time.Now()})
+				f.loader.EnqueueUpdate(f.updateQueue, &controller.QueuedNode{Node: cn, LastUpdatedTime: time.Now()})
 			},
 			OnExportsChange: o.OnExportsChange,
 			Registerer:      o.Reg,
@@ -211,6 +219,7 @@ func newController(o controllerOptions) *Runtime {
 					ID:                id,
 					ServiceMap:        serviceMap,
 					WorkerPool:        workerPool,
+					EvaluationPolicy:  evaluationPolicy,
 				})
 			},
 			GetServiceData: func(name string) (interface{}, error) {
@@ -220,6 +229,7 @@ func newController(o controllerOptions) *Runtime {
 				}
 				return svc.Data(), nil
 			},
+			EvaluationPolicy: evaluationPolicy,
 		},
 
 		Services:          o.Services,
@@ -236,6 +246,7 @@ func newController(o controllerOptions) *Runtime {
 func (f *Runtime) Run(ctx context.Context) {
 	defer func() { _ = f.sched.Close() }()
 	defer f.loader.Cleanup(!f.opts.IsModule)
+	defer f.updateQueue.Stop()
 	defer level.Debug(f.log).Log("msg", "Alloy controller exiting")
 
 	for {
@@ -0,0 +1,146 @@
+package controller
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/grafana/alloy/internal/runtime/internal/dag"
+	"github.com/grafana/alloy/internal/runtime/internal/worker"
+)
+
+// EvaluationArguments configures how nodes are scheduled for evaluation. It
+// is decoded from the evaluation config block.
+type EvaluationArguments struct {
+	// CoalesceWindow is the default window during which the updates of a node
+	// are merged before its dependants are evaluated.
+	CoalesceWindow time.Duration `alloy:"coalesce_window,attr,optional"`
+
+	Nodes []EvaluationNodeArguments `alloy:"node,block,optional"`
+}
+
+// EvaluationNodeArguments overrides the scheduling of a single node.
+type EvaluationNodeArguments struct {
+	// ID is the global ID of the node, which includes the IDs of the modules
+	// it runs in.
+	ID string `alloy:"id,attr"`
+
+	// Priority of the evaluations of the node. Defaults to "high" for config
+	// and service nodes, and to "normal" for components.
+	Priority string `alloy:"priority,attr,optional"`
+
+	// CoalesceWindow overrides the default coalescing window for the updates
+	// of the node. Nil uses the default.
+	CoalesceWindow *time.Duration `alloy:"coalesce_window,attr,optional"`
+}
+
+// Validate implements syntax.Validator.
+func (args *EvaluationArguments) Validate() error {
+	if args.CoalesceWindow < 0 {
+		return fmt.Errorf("coalesce_window must not be negative")
+	}
+
+	seen := make(map[string]struct{}, len(args.Nodes))
+	for _, n := range args.Nodes {
+		if _, ok := seen[n.ID]; ok {
+			return fmt.Errorf("node %q is configured more than once", n.ID)
+		}
+		seen[n.ID] = struct{}{}
+
+		if n.Priority != "" {
+			if _, err := worker.ParsePriority(n.Priority); err != nil {
+				return fmt.Errorf("node %q: %w", n.ID, err)
+			}
+		}
+		if n.CoalesceWindow != nil && *n.CoalesceWindow < 0 {
+			return fmt.Errorf("node %q: coalesce_window must not be negative", n.ID)
+		}
+	}
+	return nil
+}
+
+// EvaluationPolicy decides the priority of node evaluations and how long the
+// updates of a node are coalesced. It is shared between the root controller
+// and the controllers of its modules, and is updated by the evaluation config
+// block of the root controller.
+type EvaluationPolicy struct {
+	mut            sync.RWMutex
+	coalesceWindow time.Duration
+	nodes          map[string]nodeEvaluationPolicy
+}
+
+type nodeEvaluationPolicy struct {
+	priority       *worker.Priority
+	coalesceWindow *time.Duration
+}
+
+// NewEvaluationPolicy returns an EvaluationPolicy using the default settings.
+func NewEvaluationPolicy() *EvaluationPolicy {
+	return &EvaluationPolicy{}
+}
+
+// Update replaces the settings of the policy.
+func (p *EvaluationPolicy) Update(args EvaluationArguments) error {
+	nodes := make(map[string]nodeEvaluationPolicy, len(args.Nodes))
+	for _, n := range args.Nodes {
+		var np nodeEvaluationPolicy
+		if n.Priority != "" {
+			priority, err := worker.ParsePriority(n.Priority)
+			if err != nil {
+				return fmt.Errorf("node %q: %w", n.ID, err)
+			}
+			np.priority = &priority
+		}
+		np.coalesceWindow = n.CoalesceWindow
+		nodes[n.ID] = np
+	}
+
+	p.mut.Lock()
+	defer p.mut.Unlock()
+	p.coalesceWindow = args.CoalesceWindow
+	p.nodes = nodes
+	return nil
+}
+
+// Priority returns the priority of the evaluations of node n, identified by
+// its globalID. A nil EvaluationPolicy uses the default priorities.
+func (p *EvaluationPolicy) Priority(globalID string, n dag.Node) worker.Priority {
+	if np, ok := p.node(globalID); ok && np.priority != nil {
+		return *np.priority
+	}
+
+	switch n.(type) {
+	case ComponentNode:
+		return worker.PriorityNormal
+	default:
+		// Config, service and declare nodes are cheap to evaluate and usually
+		// have many dependants, so they are evaluated first.
+		return worker.PriorityHigh
+	}
+}
+
+// CoalesceWindow returns how long the updates of the node identified by
+// globalID are merged before its dependants are evaluated. A nil
+// EvaluationPolicy never coalesces updates.
+func (p *EvaluationPolicy) CoalesceWindow(globalID string) time.Duration {
+	if p == nil {
+		return 0
+	}
+	if np, ok := p.node(globalID); ok && np.coalesceWindow != nil {
+		return *np.coalesceWindow
+	}
+
+	p.mut.RLock()
+	defer p.mut.RUnlock()
+	return p.coalesceWindow
+}
+
+func (p *EvaluationPolicy) node(globalID string) (nodeEvaluationPolicy, bool) {
+	if p == nil {
+		return nodeEvaluationPolicy{}, false
+	}
+	p.mut.RLock()
+	defer p.mut.RUnlock()
+	np, ok := p.nodes[globalID]
+	return np, ok
+}
@@ -0,0 +1,85 @@
+package controller
+
+import (
+	"testing"
+	"time"
+
+	"github.com/grafana/alloy/internal/runtime/internal/worker"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEvaluationPolicy(t *testing.T) {
+	var (
+		component = &BuiltinComponentNode{}
+		config    = &LoggingConfigNode{}
+		window    = 5 * time.Second
+	)
+
+	var nilPolicy *EvaluationPolicy
+	require.Equal(t, worker.PriorityNormal, nilPolicy.Priority("prometheus.scrape.default", component))
+	require.Equal(t, worker.PriorityHigh, nilPolicy.Priority("logging", config))
+	require.Zero(t, nilPolicy.CoalesceWindow("prometheus.scrape.default"))
+
+	p := NewEvaluationPolicy()
+	err := p.Update(EvaluationArguments{
+		CoalesceWindow: time.Second,
+		Nodes: []EvaluationNodeArguments{
+			{ID: "discovery.kubernetes.pods", Priority: "low", CoalesceWindow: &window},
+			{ID: "module.file.mod/prometheus.scrape.default", Priority: "high"},
+		},
+	})
+	require.NoError(t, err)
+
+	require.Equal(t, worker.PriorityLow, p.Priority("discovery.kubernetes.pods", component))
+	require.Equal(t, worker.PriorityHigh, p.Priority("module.file.mod/prometheus.scrape.default", component))
+	require.Equal(t, worker.PriorityNormal, p.Priority("prometheus.scrape.default", component))
+
+	require.Equal(t, window, p.CoalesceWindow("discovery.kubernetes.pods"))
+	require.Equal(t, time.Second, p.CoalesceWindow("prometheus.scrape.default"))
+}
+
+func TestEvaluationArguments_Validate(t *testing.T) {
+	negative := -time.Second
+
+	tt := []struct {
+		name        string
+		args        EvaluationArguments
+		expectedErr string
+	}{
+		{
+			name: "valid",
+			args: EvaluationArguments{Nodes: []EvaluationNodeArguments{{ID: "a", Priority: "high"}}},
+		},
+		{
+			name:        "negative window",
+			args:        EvaluationArguments{CoalesceWindow: negative},
+			expectedErr: "coalesce_window must not be negative",
+		},
+		{
+			name:        "negative node window",
+			args:        EvaluationArguments{Nodes: []EvaluationNodeArguments{{ID: "a", CoalesceWindow: &negative}}},
+			expectedErr: `node "a": coalesce_window must not be negative`,
+		},
+		{
+			name:        "unknown priority",
+			args:        EvaluationArguments{Nodes: []EvaluationNodeArguments{{ID: "a", Priority: "urgent"}}},
+			expectedErr: `node "a": unknown priority "urgent"`,
+		},
+		{
+			name:        "duplicate node",
+			args:        EvaluationArguments{Nodes: []EvaluationNodeArguments{{ID: "a"}, {ID: "a"}}},
+			expectedErr: `node "a" is configured more than once`,
+		},
+	}
+
+	for _, tc := range tt {
+		t.Run(tc.name, func(t *testing.T) {
+			err := tc.args.Validate()
+			if tc.expectedErr == "" {
+				require.NoError(t, err)
+			} else {
+				require.ErrorContains(t, err, tc.expectedErr)
+			}
+		})
+	}
+}
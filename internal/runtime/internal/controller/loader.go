@@ -536,6 +536,12 @@ func (l *Loader) populateConfigBlockNodes(args map[string]any, g *dag.Graph, con
 		g.Add(c)
 	}
 
+	// If an evaluation config block is not provided, we create an empty node which uses defaults.
+	if nodeMap.evaluation == nil && l.isRootController() {
+		c := NewDefaultEvaluationConfigNode(l.globals)
+		g.Add(c)
+	}
+
 	l.importConfigNodes = nodeMap.importMap
 
 	return diags
@@ -684,6 +690,17 @@ func (l *Loader) OriginalGraph() *dag.Graph {
 	return l.originalGraph.Clone()
 }
 
+// EnqueueUpdate adds the updated node to q so that its dependants are
+// evaluated. Updates are delayed by the coalescing window of the node, if
+// any, so that frequent updates result in a single evaluation of the
+// dependants.
+func (l *Loader) EnqueueUpdate(q *Queue, updated *QueuedNode) {
+	globalID := path.Join(l.globals.ControllerID, updated.Node.NodeID())
+	if q.EnqueueAfter(updated, l.globals.EvaluationPolicy.CoalesceWindow(globalID)) {
+		l.cm.evaluationsMerged.WithLabelValues("coalesced").Inc()
+	}
+}
+
 // EvaluateDependants sends nodes which depend directly on nodes in updatedNodes for evaluation to the
 // workerPool. It should be called whenever nodes update their exports.
 // It is beneficial to call EvaluateDependants with a batch of nodes, as it will enqueue the entire batch before
@@ -738,11 +755,20 @@ func (l *Loader) EvaluateDependants(ctx context.Context, updatedNodes []*QueuedN
 			retryBackoff       = backoff.New(ctx, l.backoffConfig)
 			err                error
 		)
+		globalUniqueKey := path.Join(l.globals.ControllerID, nodeRef.NodeID())
+		priority := l.globals.EvaluationPolicy.Priority(globalUniqueKey, nodeRef)
 		for retryBackoff.Ongoing() {
-			globalUniqueKey := path.Join(l.globals.ControllerID, nodeRef.NodeID())
-			err = l.workerPool.SubmitWithKey(globalUniqueKey, func() {
+			var (
+				queued      bool
+				submittedAt = time.Now()
+			)
+			queued, err = l.workerPool.SubmitWithPriority(globalUniqueKey, priority, func() {
+				l.cm.evaluationQueueWaitTime.Observe(time.Since(submittedAt).Seconds())
 				l.concurrentEvalFn(nodeRef, dependantCtx, tracer, parentRef)
 			})
+			if err == nil && !queued {
+				l.cm.evaluationsMerged.WithLabelValues("queued").Inc()
+			}
 			if err != nil {
 				level.Error(l.log).Log(
 					"msg", "failed to submit node for evaluation - Alloy is likely overloaded "+
@@ -759,6 +785,7 @@ func (l *Loader) EvaluateDependants(ctx context.Context, updatedNodes []*QueuedN
 		}
 		span.SetAttributes(attribute.Int("retries", retryBackoff.NumRetries()))
 		if err != nil {
+			l.cm.evaluationsDropped.Inc()
 			span.SetStatus(codes.Error, err.Error())
 		} else {
 			span.SetStatus(codes.Ok, "node submitted for evaluation")
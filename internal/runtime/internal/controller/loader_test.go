@@ -62,6 +62,7 @@ func TestLoader(t *testing.T) {
 			"testcomponents.passthrough.forwarded",
 			"logging",
 			"tracing",
+			"evaluation",
 		},
 		OutEdges: []edge{
 			{From: "testcomponents.passthrough.ticker", To: "testcomponents.tick.ticker"},
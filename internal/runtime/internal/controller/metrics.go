@@ -13,6 +13,9 @@ type controllerMetrics struct {
 	componentEvaluationTime     prometheus.Histogram
 	dependenciesWaitTime        prometheus.Histogram
 	evaluationQueueSize         prometheus.Gauge
+	evaluationQueueWaitTime     prometheus.Histogram
+	evaluationsMerged           *prometheus.CounterVec
+	evaluationsDropped          prometheus.Counter
 	slowComponentThreshold      time.Duration
 	slowComponentEvaluationTime *prometheus.CounterVec
 }
@@ -63,6 +66,30 @@ func newControllerMetrics(parent, id string) *controllerMetrics {
 		ConstLabels: map[string]string{"controller_path": parent, "controller_id": id},
 	})
 
+	cm.evaluationQueueWaitTime = prometheus.NewHistogram(
+		prometheus.HistogramOpts{
+			Name:                            "alloy_component_evaluation_queue_wait_seconds",
+			Help:                            "Time spent by components in the worker pool queue before being evaluated.",
+			ConstLabels:                     map[string]string{"controller_path": parent, "controller_id": id},
+			Buckets:                         evaluationTimesBuckets,
+			NativeHistogramBucketFactor:     1.1,
+			NativeHistogramMaxBucketNumber:  100,
+			NativeHistogramMinResetDuration: 1 * time.Hour,
+		},
+	)
+
+	cm.evaluationsMerged = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name:        "alloy_component_evaluation_merged_total",
+		Help:        "Number of updates merged into an update or evaluation which was already waiting. The reason is \"queued\" if the evaluation was already waiting in the worker pool, and \"coalesced\" if the update was merged during a coalescing window.",
+		ConstLabels: map[string]string{"controller_path": parent, "controller_id": id},
+	}, []string{"reason"})
+
+	cm.evaluationsDropped = prometheus.NewCounter(prometheus.CounterOpts{
+		Name:        "alloy_component_evaluation_dropped_total",
+		Help:        "Number of evaluations which could not be submitted to the worker pool before the controller stopped.",
+		ConstLabels: map[string]string{"controller_path": parent, "controller_id": id},
+	})
+
 	cm.slowComponentEvaluationTime = prometheus.NewCounterVec(prometheus.CounterOpts{
 		Name:        "alloy_component_evaluation_slow_seconds",
 		Help:        fmt.Sprintf("Number of seconds spent evaluating components that take longer than %v to evaluate", cm.slowComponentThreshold),
@@ -84,6 +111,9 @@ func (cm *controllerMetrics) Collect(ch chan<- prometheus.Metric) {
 	cm.controllerEvaluation.Collect(ch)
 	cm.dependenciesWaitTime.Collect(ch)
 	cm.evaluationQueueSize.Collect(ch)
+	cm.evaluationQueueWaitTime.Collect(ch)
+	cm.evaluationsMerged.Collect(ch)
+	cm.evaluationsDropped.Collect(ch)
 	cm.slowComponentEvaluationTime.Collect(ch)
 }
 
@@ -92,6 +122,9 @@ func (cm *controllerMetrics) Describe(ch chan<- *prometheus.Desc) {
 	cm.controllerEvaluation.Describe(ch)
 	cm.dependenciesWaitTime.Describe(ch)
 	cm.evaluationQueueSize.Describe(ch)
+	cm.evaluationQueueWaitTime.Describe(ch)
+	cm.evaluationsMerged.Describe(ch)
+	cm.evaluationsDropped.Describe(ch)
 	cm.slowComponentEvaluationTime.Describe(ch)
 }
 
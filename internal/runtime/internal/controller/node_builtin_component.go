@@ -17,6 +17,7 @@ import (
 	"github.com/grafana/alloy/internal/featuregate"
 	"github.com/grafana/alloy/internal/runtime/logging"
 	"github.com/grafana/alloy/internal/runtime/logging/level"
+	"github.com/grafana/alloy/internal/runtime/profiling"
 	"github.com/grafana/alloy/internal/runtime/tracing"
 	"github.com/grafana/alloy/syntax/ast"
 	"github.com/grafana/alloy/syntax/vm"
@@ -73,6 +74,7 @@ type ComponentGlobals struct {
 	ControllerID        string                                 // ID of controller.
 	NewModuleController func(id string) ModuleController       // Func to generate a module controller.
 	GetServiceData      func(name string) (interface{}, error) // Get data for a service.
+	EvaluationPolicy    *EvaluationPolicy                      // Policy used to prioritise and coalesce evaluations.
 }
 
 // BuiltinComponentNode is a controller node which manages a builtin component.
@@ -244,7 +246,12 @@ func (cn *BuiltinComponentNode) UpdateBlock(b *ast.BlockStmt) {
 // Evaluate will return an error if the Alloy block cannot be evaluated or if
 // decoding to arguments fails.
 func (cn *BuiltinComponentNode) Evaluate(scope *vm.Scope) error {
-	err := cn.evaluate(scope)
+	// Label the evaluation so that the cost of building or updating the
+	// managed component, and any goroutine it starts, is attributed to it.
+	var err error
+	profiling.Do(context.Background(), cn.globalID, func(context.Context) {
+		err = cn.evaluate(scope)
+	})
 
 	switch err {
 	case nil:
@@ -313,7 +320,10 @@ func (cn *BuiltinComponentNode) Run(ctx context.Context) error {
 	}
 
 	cn.setRunHealth(component.HealthTypeHealthy, "started component")
-	err := cn.managed.Run(ctx)
+	var err error
+	profiling.Do(ctx, cn.globalID, func(ctx context.Context) {
+		err = cn.managed.Run(ctx)
+	})
 
 	var exitMsg string
 	logger := cn.managedOpts.Logger
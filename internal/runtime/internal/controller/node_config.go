@@ -9,10 +9,11 @@ import (
 )
 
 const (
-	argumentBlockID = "argument"
-	exportBlockID   = "export"
-	loggingBlockID  = "logging"
-	tracingBlockID  = "tracing"
+	argumentBlockID   = "argument"
+	evaluationBlockID = "evaluation"
+	exportBlockID     = "export"
+	loggingBlockID    = "logging"
+	tracingBlockID    = "tracing"
 )
 
 // NewConfigNode creates a new ConfigNode from an initial ast.BlockStmt.
@@ -21,6 +22,8 @@ func NewConfigNode(block *ast.BlockStmt, globals ComponentGlobals) (BlockNode, d
 	switch block.GetBlockName() {
 	case argumentBlockID:
 		return NewArgumentConfigNode(block, globals), nil
+	case evaluationBlockID:
+		return NewEvaluationConfigNode(block, globals), nil
 	case exportBlockID:
 		return NewExportConfigNode(block, globals), nil
 	case loggingBlockID:
@@ -47,6 +50,7 @@ func NewConfigNode(block *ast.BlockStmt, globals ComponentGlobals) (BlockNode, d
 type ConfigNodeMap struct {
 	logging     *LoggingConfigNode
 	tracing     *TracingConfigNode
+	evaluation  *EvaluationConfigNode
 	argumentMap map[string]*ArgumentConfigNode
 	exportMap   map[string]*ExportConfigNode
 	importMap   map[string]*ImportConfigNode
@@ -58,6 +62,7 @@ func NewConfigNodeMap() *ConfigNodeMap {
 	return &ConfigNodeMap{
 		logging:     nil,
 		tracing:     nil,
+		evaluation:  nil,
 		argumentMap: map[string]*ArgumentConfigNode{},
 		exportMap:   map[string]*ExportConfigNode{},
 		importMap:   map[string]*ImportConfigNode{},
@@ -78,6 +83,8 @@ func (nodeMap *ConfigNodeMap) Append(configNode BlockNode) diag.Diagnostics {
 		nodeMap.logging = n
 	case *TracingConfigNode:
 		nodeMap.tracing = n
+	case *EvaluationConfigNode:
+		nodeMap.evaluation = n
 	case *ImportConfigNode:
 		nodeMap.importMap[n.Label()] = n
 	default:
@@ -128,6 +135,15 @@ func (nodeMap *ConfigNodeMap) ValidateModuleConstraints(isInModule bool) diag.Di
 				EndPos:   ast.EndPos(nodeMap.tracing.Block()).Position(),
 			})
 		}
+
+		if nodeMap.evaluation != nil {
+			diags.Add(diag.Diagnostic{
+				Severity: diag.SeverityLevelError,
+				Message:  "evaluation block not allowed inside a module",
+				StartPos: ast.StartPos(nodeMap.evaluation.Block()).Position(),
+				EndPos:   ast.EndPos(nodeMap.evaluation.Block()).Position(),
+			})
+		}
 		return diags
 	}
 
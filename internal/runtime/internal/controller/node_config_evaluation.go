@@ -0,0 +1,101 @@
+package controller
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/grafana/alloy/syntax/ast"
+	"github.com/grafana/alloy/syntax/vm"
+)
+
+var _ BlockNode = (*EvaluationConfigNode)(nil)
+
+// EvaluationConfigNode configures the EvaluationPolicy shared by the
+// controller and its modules.
+type EvaluationConfigNode struct {
+	nodeID        string
+	componentName string
+	policy        *EvaluationPolicy
+
+	mut   sync.RWMutex
+	block *ast.BlockStmt // Current Alloy blocks to derive config from
+	eval  *vm.Evaluator
+}
+
+// NewEvaluationConfigNode creates a new EvaluationConfigNode from an initial ast.BlockStmt.
+// The underlying config isn't applied until Evaluate is called.
+func NewEvaluationConfigNode(block *ast.BlockStmt, globals ComponentGlobals) *EvaluationConfigNode {
+	return &EvaluationConfigNode{
+		nodeID:        BlockComponentID(block).String(),
+		componentName: block.GetBlockName(),
+		policy:        globals.EvaluationPolicy,
+
+		block: block,
+		eval:  vm.New(block.Body),
+	}
+}
+
+// NewDefaultEvaluationConfigNode creates a new EvaluationConfigNode with nil block and eval.
+// This will force evaluate to use the default evaluation settings for this node.
+func NewDefaultEvaluationConfigNode(globals ComponentGlobals) *EvaluationConfigNode {
+	return &EvaluationConfigNode{
+		nodeID:        evaluationBlockID,
+		componentName: evaluationBlockID,
+		policy:        globals.EvaluationPolicy,
+
+		block: nil,
+		eval:  nil,
+	}
+}
+
+// Evaluate implements BlockNode and updates the evaluation policy by
+// re-evaluating its Alloy block with the provided scope.
+//
+// Evaluate will return an error if the Alloy block cannot be evaluated or if
+// decoding to arguments fails.
+func (cn *EvaluationConfigNode) Evaluate(scope *vm.Scope) error {
+	cn.mut.RLock()
+	defer cn.mut.RUnlock()
+
+	var args EvaluationArguments
+	if cn.eval != nil {
+		if err := cn.eval.Evaluate(scope, &args); err != nil {
+			return fmt.Errorf("decoding configuration: %w", err)
+		}
+	}
+
+	if cn.policy == nil {
+		return nil
+	}
+	if err := cn.policy.Update(args); err != nil {
+		return fmt.Errorf("could not update evaluation policy: %w", err)
+	}
+	return nil
+}
+
+// Block implements BlockNode and returns the current block of the managed config node.
+func (cn *EvaluationConfigNode) Block() *ast.BlockStmt {
+	cn.mut.RLock()
+	defer cn.mut.RUnlock()
+	return cn.block
+}
+
+// NodeID implements dag.Node and returns the unique ID for the config node.
+func (cn *EvaluationConfigNode) NodeID() string { return cn.nodeID }
+
+// UpdateBlock updates the Alloy block used to construct arguments.
+// The new block isn't used until the next time Evaluate is invoked.
+//
+// UpdateBlock will panic if the block does not match the component ID of the
+// EvaluationConfigNode.
+func (cn *EvaluationConfigNode) UpdateBlock(b *ast.BlockStmt) {
+	if !BlockComponentID(b).Equals(strings.Split(cn.nodeID, ".")) {
+		panic("UpdateBlock called with an Alloy block with a different ID")
+	}
+
+	cn.mut.Lock()
+	defer cn.mut.Unlock()
+	cn.block = b
+	cn.eval = vm.New(b.Body)
+}
@@ -12,6 +12,7 @@ import (
 	"github.com/go-kit/log"
 	"github.com/grafana/alloy/internal/component"
 	"github.com/grafana/alloy/internal/runtime/logging/level"
+	"github.com/grafana/alloy/internal/runtime/profiling"
 	"github.com/grafana/alloy/syntax/ast"
 	"github.com/grafana/alloy/syntax/vm"
 )
@@ -219,7 +220,10 @@ func (cn *CustomComponentNode) Run(ctx context.Context) error {
 	}
 
 	cn.setRunHealth(component.HealthTypeHealthy, "started custom component")
-	err := managed.Run(ctx)
+	var err error
+	profiling.Do(ctx, cn.globalID, func(ctx context.Context) {
+		err = managed.Run(ctx)
+	})
 	if err != nil {
 		level.Error(logger).Log("msg", "error running custom component", "id", cn.nodeID, "err", err)
 	}
@@ -12,6 +12,8 @@ type Queue struct {
 	mut         sync.Mutex
 	queuedSet   map[*QueuedNode]struct{}
 	queuedOrder []*QueuedNode
+	delayed     map[BlockNode]*time.Timer // Timers of the nodes waiting for their coalescing window to elapse.
+	stopped     bool
 
 	updateCh chan struct{}
 }
@@ -27,6 +29,7 @@ func NewQueue() *Queue {
 		updateCh:    make(chan struct{}, 1),
 		queuedSet:   make(map[*QueuedNode]struct{}),
 		queuedOrder: make([]*QueuedNode, 0),
+		delayed:     make(map[BlockNode]*time.Timer),
 	}
 }
 
@@ -49,6 +52,56 @@ func (q *Queue) Enqueue(c *QueuedNode) {
 	}
 }
 
+// EnqueueAfter inserts c into the Queue once delay has elapsed. Updates of
+// the same BlockNode made while c is waiting are merged into c, which keeps
+// the time of the first update. EnqueueAfter returns true if c was merged into
+// an update which is already waiting.
+//
+// EnqueueAfter is equivalent to Enqueue if delay is not positive. Updates
+// are discarded once the Queue is stopped.
+func (q *Queue) EnqueueAfter(c *QueuedNode, delay time.Duration) (merged bool) {
+	if delay <= 0 {
+		q.Enqueue(c)
+		return false
+	}
+
+	q.mut.Lock()
+	defer q.mut.Unlock()
+
+	if q.stopped {
+		return false
+	}
+	if _, ok := q.delayed[c.Node]; ok {
+		return true
+	}
+
+	q.delayed[c.Node] = time.AfterFunc(delay, func() {
+		q.mut.Lock()
+		if q.stopped {
+			q.mut.Unlock()
+			return
+		}
+		delete(q.delayed, c.Node)
+		q.mut.Unlock()
+
+		q.Enqueue(c)
+	})
+	return false
+}
+
+// Stop discards the updates waiting for their coalescing window to elapse,
+// and stops their timers.
+func (q *Queue) Stop() {
+	q.mut.Lock()
+	defer q.mut.Unlock()
+
+	q.stopped = true
+	for node, timer := range q.delayed {
+		timer.Stop()
+		delete(q.delayed, node)
+	}
+}
+
 // Chan returns a channel which is written to when the queue is non-empty.
 func (q *Queue) Chan() <-chan struct{} { return q.updateCh }
 
@@ -89,3 +89,47 @@ func TestEnqueue_ChannelNotification(t *testing.T) {
 		}, 3*time.Second, 5*time.Millisecond)
 	}
 }
+
+func TestEnqueueAfter_Coalesces(t *testing.T) {
+	n1, n2 := &EvaluationConfigNode{nodeID: "a"}, &EvaluationConfigNode{nodeID: "b"}
+	first := &QueuedNode{Node: n1, LastUpdatedTime: time.Now()}
+
+	q := NewQueue()
+	require.False(t, q.EnqueueAfter(first, 50*time.Millisecond))
+	require.True(t, q.EnqueueAfter(&QueuedNode{Node: n1, LastUpdatedTime: time.Now()}, 50*time.Millisecond))
+	require.False(t, q.EnqueueAfter(&QueuedNode{Node: n2}, 0))
+
+	// Updates without a delay are queued immediately.
+	<-q.Chan()
+	all := q.DequeueAll()
+	require.Len(t, all, 1)
+	require.Same(t, n2, all[0].Node)
+
+	select {
+	case <-q.Chan():
+	case <-time.After(5 * time.Second):
+		require.FailNow(t, "coalesced update was never queued")
+	}
+	all = q.DequeueAll()
+	require.Len(t, all, 1)
+	require.Same(t, first, all[0])
+
+	// Once the window has elapsed, updates start a new window.
+	require.False(t, q.EnqueueAfter(&QueuedNode{Node: n1}, time.Millisecond))
+}
+
+func TestQueue_StopDiscardsDelayedUpdates(t *testing.T) {
+	q := NewQueue()
+	require.False(t, q.EnqueueAfter(&QueuedNode{Node: &EvaluationConfigNode{nodeID: "a"}}, 20*time.Millisecond))
+	q.Stop()
+	require.Empty(t, q.delayed)
+
+	// Neither the waiting update nor later ones are queued.
+	require.False(t, q.EnqueueAfter(&QueuedNode{Node: &EvaluationConfigNode{nodeID: "b"}}, time.Millisecond))
+	select {
+	case <-q.Chan():
+		require.FailNow(t, "delayed update was queued after the queue was stopped")
+	case <-time.After(100 * time.Millisecond):
+	}
+	require.Empty(t, q.DequeueAll())
+}
@@ -0,0 +1,90 @@
+package importsource
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// cacheFileName is the name of the file, relative to the data path of the
+// import source, which holds the last successfully fetched module content.
+const cacheFileName = "module-cache.json"
+
+// moduleCache persists the last known good content of a remote import source
+// so that the module can still be loaded when the remote is unreachable.
+//
+// The content is stored along with a key identifying where it was fetched
+// from, so that the content of a previous source is never loaded once the
+// arguments of the import point to another one.
+type moduleCache struct {
+	path string
+}
+
+// cachedModule is the on-disk representation of the cache.
+type cachedModule struct {
+	Source  string            `json:"source"`
+	Content map[string]string `json:"content"`
+}
+
+// cacheSource returns the key of the source identified by parts.
+func cacheSource(parts ...string) string {
+	h := sha256.New()
+	for _, p := range parts {
+		// Prefix each part with its length so that parts can't be shifted
+		// from one to the next.
+		fmt.Fprintf(h, "%d:%s", len(p), p)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func newModuleCache(dataPath string) *moduleCache {
+	return &moduleCache{path: filepath.Join(dataPath, cacheFileName)}
+}
+
+// Load returns the content cached for source. It returns a nil map and no
+// error if no content has been cached yet for source.
+func (c *moduleCache) Load(source string) (map[string]string, error) {
+	bb, err := os.ReadFile(c.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	} else if err != nil {
+		return nil, fmt.Errorf("reading module cache: %w", err)
+	}
+
+	var m cachedModule
+	if err := json.Unmarshal(bb, &m); err != nil {
+		return nil, fmt.Errorf("decoding module cache: %w", err)
+	}
+	if m.Source != source {
+		return nil, nil
+	}
+	return m.Content, nil
+}
+
+// Store atomically replaces the cached content with the content of source.
+func (c *moduleCache) Store(source string, content map[string]string) error {
+	bb, err := json.Marshal(cachedModule{Source: source, Content: content})
+	if err != nil {
+		return fmt.Errorf("encoding module cache: %w", err)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(c.path), 0750); err != nil {
+		return fmt.Errorf("creating module cache directory: %w", err)
+	}
+
+	// Write to a temporary file first so that a crash never leaves a partially
+	// written cache behind.
+	tmp := c.path + ".tmp"
+	if err := os.WriteFile(tmp, bb, 0640); err != nil {
+		return fmt.Errorf("writing module cache: %w", err)
+	}
+	if err := os.Rename(tmp, c.path); err != nil {
+		return fmt.Errorf("writing module cache: %w", err)
+	}
+	return nil
+}
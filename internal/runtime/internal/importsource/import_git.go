@@ -20,6 +20,10 @@ import (
 
 // ImportGit imports a module from a git repository.
 // There are currently no remote.git component, the logic is implemented here.
+//
+// The last module content which was successfully read and verified is cached
+// in the data path, so that the module can be loaded even if the repository
+// cannot be cloned when the import is first evaluated.
 type ImportGit struct {
 	opts            component.Options
 	log             log.Logger
@@ -28,6 +32,8 @@ type ImportGit struct {
 	repo            *vcs.GitRepo
 	repoOpts        vcs.GitRepoOptions
 	args            GitArguments
+	cache           *moduleCache
+	delivered       bool // whether content has been sent to onContentChange
 	onContentChange func(map[string]string)
 
 	argsChanged chan struct{}
@@ -48,6 +54,8 @@ type GitArguments struct {
 	Path          string            `alloy:"path,attr"`
 	PullFrequency time.Duration     `alloy:"pull_frequency,attr,optional"`
 	GitAuthConfig vcs.GitAuthConfig `alloy:",squash"`
+
+	Verify *VerifyArguments `alloy:"verify,block,optional"`
 }
 
 var DefaultGitArguments = GitArguments{
@@ -60,12 +68,18 @@ func (args *GitArguments) SetToDefault() {
 	*args = DefaultGitArguments
 }
 
+// cacheSource returns the key of the module cache for the source of args.
+func (args GitArguments) cacheSource() string {
+	return cacheSource(args.Repository, args.Revision, args.Path)
+}
+
 func NewImportGit(managedOpts component.Options, eval *vm.Evaluator, onContentChange func(map[string]string)) *ImportGit {
 	return &ImportGit{
 		opts:            managedOpts,
 		log:             managedOpts.Logger,
 		eval:            eval,
 		argsChanged:     make(chan struct{}, 1),
+		cache:           newModuleCache(managedOpts.DataPath),
 		onContentChange: onContentChange,
 	}
 }
@@ -170,7 +184,15 @@ func (im *ImportGit) updateHealth(err error) {
 // vcs.UpdateFailedError; vcs.UpdateFailedError means that the Git repo
 // exists, but we were unable to update it. It makes sense to retry on the next poll and it may succeed.
 func (im *ImportGit) Update(args component.Arguments) (err error) {
+	// cacheErr is set when the loaded module content is kept or the module
+	// was loaded from the cache. It is only reported through the health of
+	// the source.
+	var cacheErr error
 	defer func() {
+		if err == nil && cacheErr != nil {
+			im.updateHealth(cacheErr)
+			return
+		}
 		im.updateHealth(err)
 	}()
 	im.mut.Lock()
@@ -197,7 +219,7 @@ func (im *ImportGit) Update(args component.Arguments) (err error) {
 			if errors.As(err, &vcs.UpdateFailedError{}) {
 				level.Error(im.log).Log("msg", "failed to update repository", "err", err)
 				im.updateHealth(err)
-			} else {
+			} else if cacheErr = im.fallback(newArgs, "failed to clone repository", err); cacheErr == nil {
 				return err
 			}
 		}
@@ -205,14 +227,18 @@ func (im *ImportGit) Update(args component.Arguments) (err error) {
 		im.repoOpts = repoOpts
 	}
 
-	if err := im.pollFile(context.Background(), newArgs); err != nil {
-		if errors.As(err, &vcs.UpdateFailedError{}) {
-			level.Error(im.log).Log("msg", "failed to poll file from repository", "err", err)
-			// We don't update the health here because it will be updated via the defer call.
-			// This is not very good because if we reassign the err before exiting the function it will not update the health correctly.
-			// TODO improve the error  health handling.
-		} else {
-			return err
+	// The repository is nil if it could not be cloned, in which case it's
+	// cloned again on the next poll.
+	if im.repo != nil {
+		if err := im.pollFile(context.Background(), newArgs); err != nil {
+			if errors.As(err, &vcs.UpdateFailedError{}) {
+				level.Error(im.log).Log("msg", "failed to poll file from repository", "err", err)
+				// We don't update the health here because it will be updated via the defer call.
+				// This is not very good because if we reassign the err before exiting the function it will not update the health correctly.
+				// TODO improve the error  health handling.
+			} else if cacheErr = im.fallback(newArgs, "failed to read module from repository", err); cacheErr == nil {
+				return err
+			}
 		}
 	}
 
@@ -229,6 +255,16 @@ func (im *ImportGit) Update(args component.Arguments) (err error) {
 // pollFile fetches the latest content from the repository and updates the
 // controller. pollFile must only be called with im.mut held.
 func (im *ImportGit) pollFile(ctx context.Context, args GitArguments) error {
+	// The repository may not have been cloned yet if the module was loaded
+	// from the cache.
+	if im.repo == nil {
+		r, err := vcs.NewGitRepo(ctx, filepath.Join(im.opts.DataPath, "repo"), im.repoOpts)
+		if err != nil && !errors.As(err, &vcs.UpdateFailedError{}) {
+			return err
+		}
+		im.repo = r
+	}
+
 	// Make sure our repo is up-to-date.
 	if err := im.repo.Update(ctx); err != nil {
 		return err
@@ -239,17 +275,77 @@ func (im *ImportGit) pollFile(ctx context.Context, args GitArguments) error {
 		return err
 	}
 
+	var content map[string]string
 	if info.IsDir() {
-		return im.handleDirectory(args.Path)
+		content, err = im.handleDirectory(args.Path)
+	} else {
+		content, err = im.handleFile(args.Path)
+	}
+	if err != nil {
+		return err
+	}
+	return im.deliver(args, content, false)
+}
+
+// deliver verifies content and forwards it to onContentChange. Content which
+// was not loaded from the cache is written to the cache. deliver must only be
+// called with im.mut held.
+func (im *ImportGit) deliver(args GitArguments, content map[string]string, fromCache bool) error {
+	if err := args.Verify.Verify(content, isDirectoryContent(args, content)); err != nil {
+		return fmt.Errorf("module verification failed: %w", err)
 	}
 
-	return im.handleFile(args.Path)
+	if !fromCache {
+		if err := im.cache.Store(args.cacheSource(), content); err != nil {
+			level.Warn(im.log).Log("msg", "failed to cache module content", "err", err)
+		}
+	}
+
+	im.delivered = true
+	im.onContentChange(content)
+	return nil
+}
+
+// fallback is called when the module can't be read from the repository
+// because of err. It keeps the module content which was already delivered, or
+// delivers the cached content if there's none. It returns the error to report
+// in the health of the import, or nil if no module content is available.
+// fallback must only be called with im.mut held.
+func (im *ImportGit) fallback(args GitArguments, msg string, err error) error {
+	if im.delivered {
+		level.Warn(im.log).Log("msg", msg+", keeping the loaded module content", "err", err)
+		return fmt.Errorf("keeping the loaded module content: %w", err)
+	}
+	if !im.loadCache(args) {
+		return nil
+	}
+	level.Warn(im.log).Log("msg", msg+", using cached module content", "err", err)
+	return fmt.Errorf("using cached module content: %w", err)
+}
+
+// loadCache delivers the cached module content. It reports whether the
+// cached content was delivered. loadCache must only be called with im.mut
+// held.
+func (im *ImportGit) loadCache(args GitArguments) bool {
+	content, err := im.cache.Load(args.cacheSource())
+	if err != nil {
+		level.Warn(im.log).Log("msg", "failed to load cached module content", "err", err)
+		return false
+	} else if content == nil {
+		return false
+	}
+
+	if err := im.deliver(args, content, true); err != nil {
+		level.Error(im.log).Log("msg", "rejected cached module content", "err", err)
+		return false
+	}
+	return true
 }
 
-func (im *ImportGit) handleDirectory(path string) error {
+func (im *ImportGit) handleDirectory(path string) (map[string]string, error) {
 	filesInfo, err := im.repo.ReadDir(path)
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	content := make(map[string]string)
@@ -259,21 +355,26 @@ func (im *ImportGit) handleDirectory(path string) error {
 		}
 		bb, err := im.repo.ReadFile(filepath.Join(path, fi.Name()))
 		if err != nil {
-			return err
+			return nil, err
 		}
 		content[fi.Name()] = string(bb)
 	}
-	im.onContentChange(content)
-	return nil
+	return content, nil
+}
+
+// isDirectoryContent reports whether content was read from a directory of the
+// repository rather than from the single file at args.Path.
+func isDirectoryContent(args GitArguments, content map[string]string) bool {
+	_, ok := content[args.Path]
+	return len(content) != 1 || !ok
 }
 
-func (im *ImportGit) handleFile(path string) error {
+func (im *ImportGit) handleFile(path string) (map[string]string, error) {
 	bb, err := im.repo.ReadFile(path)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	im.onContentChange(map[string]string{path: string(bb)})
-	return nil
+	return map[string]string{path: string(bb)}, nil
 }
 
 // CurrentHealth implements component.HealthComponent.
@@ -5,33 +5,70 @@ import (
 	"fmt"
 	"net/http"
 	"reflect"
+	"sort"
+	"sync"
 	"time"
 
+	"github.com/go-kit/log"
+	"go.uber.org/atomic"
+
 	"github.com/grafana/alloy/internal/component"
 	common_config "github.com/grafana/alloy/internal/component/common/config"
 	remote_http "github.com/grafana/alloy/internal/component/remote/http"
+	"github.com/grafana/alloy/internal/runtime/logging/level"
 	"github.com/grafana/alloy/syntax/vm"
 )
 
 // ImportHTTP imports a module from a HTTP server via the remote.http component.
+//
+// The last module content which was successfully fetched and verified is
+// cached in the data path. If the server cannot be reached when the import is
+// first evaluated, the cached content is loaded instead and the managed
+// remote.http component is created once the server becomes reachable.
 type ImportHTTP struct {
+	mut               sync.Mutex
 	managedRemoteHTTP *remote_http.Component
-	arguments         component.Arguments
+	arguments         HTTPArguments
 	managedOpts       component.Options
 	eval              *vm.Evaluator
+	log               log.Logger
+	cache             *moduleCache
+	onContentChange   func(map[string]string)
+
+	// delivered is set once content has been sent to onContentChange.
+	delivered atomic.Bool
+
+	stateMut sync.RWMutex
+	verify   *VerifyArguments
+	source   string // cache key of the source of the module
+	rejected error  // set when fetched content was rejected and no content is available
+	health   component.Health
+	running  *remote_http.Component // copy of managedRemoteHTTP for health reporting
 }
 
 var _ ImportSource = (*ImportHTTP)(nil)
 
 func NewImportHTTP(managedOpts component.Options, eval *vm.Evaluator, onContentChange func(map[string]string)) *ImportHTTP {
+	im := &ImportHTTP{
+		eval:            eval,
+		log:             managedOpts.Logger,
+		cache:           newModuleCache(managedOpts.DataPath),
+		onContentChange: onContentChange,
+	}
 	opts := managedOpts
 	opts.OnStateChange = func(e component.Exports) {
-		onContentChange(map[string]string{opts.ID: e.(remote_http.Exports).Content.Value})
-	}
-	return &ImportHTTP{
-		managedOpts: opts,
-		eval:        eval,
+		content := map[string]string{opts.ID: e.(remote_http.Exports).Content.Value}
+		if err := im.deliver(content, false); err != nil {
+			level.Error(im.log).Log("msg", "rejected module content", "err", err)
+			if !im.loadCache() {
+				im.stateMut.Lock()
+				im.rejected = err
+				im.stateMut.Unlock()
+			}
+		}
 	}
+	im.managedOpts = opts
+	return im
 }
 
 // HTTPArguments holds values which are used to configure the remote.http component.
@@ -45,6 +82,8 @@ type HTTPArguments struct {
 	Body    string            `alloy:"body,attr,optional"`
 
 	Client common_config.HTTPClientConfig `alloy:"client,block,optional"`
+
+	Verify *VerifyArguments `alloy:"verify,block,optional"`
 }
 
 // DefaultHTTPArguments holds default settings for HTTPArguments.
@@ -60,26 +99,67 @@ func (args *HTTPArguments) SetToDefault() {
 	*args = DefaultHTTPArguments
 }
 
+// cacheSource returns the key of the module cache for the source of args.
+func (args HTTPArguments) cacheSource() string {
+	keys := make([]string, 0, len(args.Headers))
+	for k := range args.Headers {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	parts := []string{args.URL, args.Method, args.Body}
+	for _, k := range keys {
+		parts = append(parts, k, args.Headers[k])
+	}
+	return cacheSource(parts...)
+}
+
+func (args HTTPArguments) remoteHTTPArguments() remote_http.Arguments {
+	return remote_http.Arguments{
+		URL:           args.URL,
+		PollFrequency: args.PollFrequency,
+		PollTimeout:   args.PollTimeout,
+		Method:        args.Method,
+		Headers:       args.Headers,
+		Body:          args.Body,
+		Client:        args.Client,
+	}
+}
+
 func (im *ImportHTTP) Evaluate(scope *vm.Scope) error {
 	var arguments HTTPArguments
 	if err := im.eval.Evaluate(scope, &arguments); err != nil {
 		return fmt.Errorf("decoding configuration: %w", err)
 	}
+
+	im.mut.Lock()
+	defer im.mut.Unlock()
+
+	// The verification settings must be known before the managed component
+	// performs its first poll.
+	im.stateMut.Lock()
+	im.verify = arguments.Verify
+	im.source = arguments.cacheSource()
+	im.rejected = nil
+	im.stateMut.Unlock()
+
 	if im.managedRemoteHTTP == nil {
-		var err error
-		im.managedRemoteHTTP, err = remote_http.New(im.managedOpts, remote_http.Arguments{
-			URL:           arguments.URL,
-			PollFrequency: arguments.PollFrequency,
-			PollTimeout:   arguments.PollTimeout,
-			Method:        arguments.Method,
-			Headers:       arguments.Headers,
-			Body:          arguments.Body,
-			Client:        arguments.Client,
-		})
-		if err != nil {
-			return fmt.Errorf("creating http component: %w", err)
-		}
 		im.arguments = arguments
+		if err := im.createManagedComponent(arguments); err != nil {
+			if !im.loadCache() {
+				return fmt.Errorf("creating http component: %w", err)
+			}
+			level.Warn(im.log).Log("msg", "failed to fetch module, using cached content", "err", err)
+		} else if err := im.rejectedErr(); err != nil {
+			// The fetched content was rejected and there's no cached content to
+			// fall back to. The managed component is created again by Run.
+			im.managedRemoteHTTP = nil
+			im.stateMut.Lock()
+			im.running = nil
+			im.stateMut.Unlock()
+			return fmt.Errorf("module verification failed: %w", err)
+		}
+		return nil
 	}
 
 	if reflect.DeepEqual(im.arguments, arguments) {
@@ -87,19 +167,137 @@ func (im *ImportHTTP) Evaluate(scope *vm.Scope) error {
 	}
 
 	// Update the existing managed component
-	if err := im.managedRemoteHTTP.Update(arguments); err != nil {
+	if err := im.managedRemoteHTTP.Update(arguments.remoteHTTPArguments()); err != nil {
 		return fmt.Errorf("updating component: %w", err)
 	}
 	im.arguments = arguments
 	return nil
 }
 
+// createManagedComponent creates the managed remote.http component. im.mut
+// must be held when calling.
+func (im *ImportHTTP) createManagedComponent(arguments HTTPArguments) error {
+	c, err := remote_http.New(im.managedOpts, arguments.remoteHTTPArguments())
+	if err != nil {
+		im.setHealth(component.HealthTypeUnhealthy, fmt.Sprintf("fetching module failed: %s", err))
+		return err
+	}
+	im.managedRemoteHTTP = c
+
+	im.stateMut.Lock()
+	im.running = c
+	im.stateMut.Unlock()
+	return nil
+}
+
 func (im *ImportHTTP) Run(ctx context.Context) error {
-	return im.managedRemoteHTTP.Run(ctx)
+	for {
+		im.mut.Lock()
+		managed, pollFrequency := im.managedRemoteHTTP, im.arguments.PollFrequency
+		im.mut.Unlock()
+
+		if managed != nil {
+			return managed.Run(ctx)
+		}
+
+		// The module was loaded from the cache; retry creating the managed
+		// component until the server is reachable again.
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(pollFrequency):
+			im.mut.Lock()
+			if im.managedRemoteHTTP == nil {
+				if err := im.createManagedComponent(im.arguments); err != nil {
+					level.Warn(im.log).Log("msg", "failed to fetch module, still using cached content", "err", err)
+				}
+			}
+			im.mut.Unlock()
+		}
+	}
+}
+
+// deliver verifies content and forwards it to onContentChange. Content which
+// was not loaded from the cache is written to the cache.
+func (im *ImportHTTP) deliver(content map[string]string, fromCache bool) error {
+	im.stateMut.RLock()
+	verify, source := im.verify, im.source
+	im.stateMut.RUnlock()
+
+	if err := verify.Verify(content, false); err != nil {
+		im.setHealth(component.HealthTypeUnhealthy, fmt.Sprintf("module verification failed: %s", err))
+		return err
+	}
+
+	if !fromCache {
+		if err := im.cache.Store(source, content); err != nil {
+			level.Warn(im.log).Log("msg", "failed to cache module content", "err", err)
+		}
+		im.setHealth(component.HealthTypeHealthy, "module updated")
+	}
+
+	im.stateMut.Lock()
+	im.rejected = nil
+	im.stateMut.Unlock()
+
+	im.delivered.Store(true)
+	im.onContentChange(content)
+	return nil
+}
+
+// rejectedErr returns the error for which fetched content was rejected, if no
+// module content is available.
+func (im *ImportHTTP) rejectedErr() error {
+	im.stateMut.RLock()
+	defer im.stateMut.RUnlock()
+	return im.rejected
+}
+
+// loadCache delivers the cached module content if no content has been
+// delivered yet. It reports whether module content is available.
+func (im *ImportHTTP) loadCache() bool {
+	if im.delivered.Load() {
+		return true
+	}
+
+	im.stateMut.RLock()
+	source := im.source
+	im.stateMut.RUnlock()
+
+	content, err := im.cache.Load(source)
+	if err != nil {
+		level.Warn(im.log).Log("msg", "failed to load cached module content", "err", err)
+		return false
+	} else if content == nil {
+		return false
+	}
+
+	if err := im.deliver(content, true); err != nil {
+		level.Error(im.log).Log("msg", "rejected cached module content", "err", err)
+		return false
+	}
+	return true
+}
+
+func (im *ImportHTTP) setHealth(t component.HealthType, msg string) {
+	im.stateMut.Lock()
+	defer im.stateMut.Unlock()
+
+	im.health = component.Health{
+		Health:     t,
+		Message:    msg,
+		UpdateTime: time.Now(),
+	}
 }
 
 func (im *ImportHTTP) CurrentHealth() component.Health {
-	return im.managedRemoteHTTP.CurrentHealth()
+	im.stateMut.RLock()
+	defer im.stateMut.RUnlock()
+
+	if im.running == nil {
+		return im.health
+	}
+	return component.LeastHealthy(im.running.CurrentHealth(), im.health)
 }
 
 // Update the evaluator.
@@ -0,0 +1,119 @@
+package importsource
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/grafana/alloy/internal/component"
+	"github.com/grafana/alloy/internal/util"
+	"github.com/grafana/alloy/syntax/parser"
+	"github.com/grafana/alloy/syntax/vm"
+	"github.com/stretchr/testify/require"
+)
+
+const testModule = `declare "a" {}`
+
+type contentRecorder struct {
+	mut     sync.Mutex
+	content map[string]string
+}
+
+func (r *contentRecorder) onContentChange(content map[string]string) {
+	r.mut.Lock()
+	defer r.mut.Unlock()
+	r.content = content
+}
+
+func (r *contentRecorder) get() map[string]string {
+	r.mut.Lock()
+	defer r.mut.Unlock()
+	return r.content
+}
+
+func newTestImportHTTP(t *testing.T, dataPath string, config string, r *contentRecorder) *ImportHTTP {
+	t.Helper()
+	file, err := parser.ParseFile("test", []byte(config))
+	require.NoError(t, err)
+
+	opts := component.Options{
+		ID:       "import.http.test",
+		Logger:   util.TestLogger(t),
+		DataPath: dataPath,
+	}
+	return NewImportHTTP(opts, vm.New(file), r.onContentChange)
+}
+
+func TestImportHTTP_FallbackToCache(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(testModule))
+	}))
+	config := fmt.Sprintf("url = %q", srv.URL)
+	dataPath := t.TempDir()
+
+	var online contentRecorder
+	im := newTestImportHTTP(t, dataPath, config, &online)
+	require.NoError(t, im.Evaluate(nil))
+	require.Equal(t, testModule, online.get()["import.http.test"])
+
+	// Once the server is gone, a new import source with the same data path
+	// must load the cached content instead of failing.
+	srv.Close()
+
+	var offline contentRecorder
+	im = newTestImportHTTP(t, dataPath, config, &offline)
+	require.NoError(t, im.Evaluate(nil))
+	require.Equal(t, testModule, offline.get()["import.http.test"])
+	require.Equal(t, component.HealthTypeUnhealthy, im.CurrentHealth().Health)
+
+	// Without a cache the evaluation fails.
+	var empty contentRecorder
+	im = newTestImportHTTP(t, t.TempDir(), config, &empty)
+	require.Error(t, im.Evaluate(nil))
+	require.Nil(t, empty.get())
+}
+
+func TestImportHTTP_ChangedSourceIgnoresCache(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(testModule))
+	}))
+	dataPath := t.TempDir()
+
+	var online contentRecorder
+	im := newTestImportHTTP(t, dataPath, fmt.Sprintf("url = %q", srv.URL), &online)
+	require.NoError(t, im.Evaluate(nil))
+	require.Equal(t, testModule, online.get()["import.http.test"])
+	srv.Close()
+
+	// The cached content of the previous URL must not be loaded for an
+	// unreachable new URL.
+	var changed contentRecorder
+	im = newTestImportHTTP(t, dataPath, fmt.Sprintf("url = %q", srv.URL+"/other.alloy"), &changed)
+	require.Error(t, im.Evaluate(nil))
+	require.Nil(t, changed.get())
+}
+
+func TestImportHTTP_Verify(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		_, _ = w.Write([]byte(testModule))
+	}))
+	defer srv.Close()
+
+	checksum := sha256.Sum256([]byte(testModule))
+
+	var valid contentRecorder
+	im := newTestImportHTTP(t, t.TempDir(), fmt.Sprintf("url = %q\nverify {\n sha256 = %q\n}", srv.URL, hex.EncodeToString(checksum[:])), &valid)
+	require.NoError(t, im.Evaluate(nil))
+	require.Equal(t, testModule, valid.get()["import.http.test"])
+
+	var tampered contentRecorder
+	other := sha256.Sum256([]byte("other"))
+	im = newTestImportHTTP(t, t.TempDir(), fmt.Sprintf("url = %q\nverify {\n sha256 = %q\n}", srv.URL, hex.EncodeToString(other[:])), &tampered)
+	require.ErrorContains(t, im.Evaluate(nil), "module verification failed")
+	require.Nil(t, tampered.get())
+	require.Equal(t, component.HealthTypeUnhealthy, im.CurrentHealth().Health)
+}
@@ -0,0 +1,142 @@
+package importsource
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/subtle"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/grafana/alloy/syntax/alloytypes"
+)
+
+// VerifyArguments configures an optional integrity check of the module
+// content retrieved by a remote import source. Content which fails the check
+// is rejected and the previously loaded module is kept.
+//
+// The checked payload is the module content itself when a single file is
+// imported. When a directory is imported, the payload holds, for each
+// imported file sorted by file name, the file name, a NUL byte, the size of
+// the file in bytes as a decimal number, a newline and the file content, so
+// that files can't be renamed and content can't be moved between files
+// without failing the check.
+type VerifyArguments struct {
+	// SHA256 is the expected hex-encoded SHA-256 checksum of the payload.
+	SHA256 string `alloy:"sha256,attr,optional"`
+
+	// PublicKey is a PEM-encoded Ed25519 public key used to check Signature.
+	PublicKey string `alloy:"public_key,attr,optional"`
+	// Signature is the base64-encoded detached Ed25519 signature of the
+	// payload.
+	Signature alloytypes.Secret `alloy:"signature,attr,optional"`
+}
+
+// Validate implements syntax.Validator.
+func (args *VerifyArguments) Validate() error {
+	if args.SHA256 == "" && args.PublicKey == "" && args.Signature == "" {
+		return fmt.Errorf("at least one of sha256 or public_key must be set")
+	}
+	if args.SHA256 != "" {
+		if _, err := decodeChecksum(args.SHA256); err != nil {
+			return err
+		}
+	}
+	if (args.PublicKey == "") != (args.Signature == "") {
+		return fmt.Errorf("public_key and signature must be set together")
+	}
+	if args.PublicKey != "" {
+		if _, err := parsePublicKey(args.PublicKey); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Verify checks content against the configured checksum and signature.
+// directory must be set if content was imported from a directory. A nil
+// VerifyArguments accepts any content.
+func (args *VerifyArguments) Verify(content map[string]string, directory bool) error {
+	if args == nil {
+		return nil
+	}
+	payload := verificationPayload(content, directory)
+
+	if args.SHA256 != "" {
+		expected, err := decodeChecksum(args.SHA256)
+		if err != nil {
+			return err
+		}
+		actual := sha256.Sum256(payload)
+		if subtle.ConstantTimeCompare(expected, actual[:]) != 1 {
+			return fmt.Errorf("sha256 checksum mismatch: expected %s, got %s", strings.ToLower(args.SHA256), hex.EncodeToString(actual[:]))
+		}
+	}
+
+	if args.PublicKey != "" {
+		key, err := parsePublicKey(args.PublicKey)
+		if err != nil {
+			return err
+		}
+		sig, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(args.Signature)))
+		if err != nil {
+			return fmt.Errorf("decoding signature: %w", err)
+		}
+		if !ed25519.Verify(key, payload, sig) {
+			return fmt.Errorf("signature verification failed")
+		}
+	}
+	return nil
+}
+
+func verificationPayload(content map[string]string, directory bool) []byte {
+	names := make([]string, 0, len(content))
+	for name := range content {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var sb strings.Builder
+	for _, name := range names {
+		if directory {
+			sb.WriteString(name)
+			sb.WriteByte(0)
+			sb.WriteString(strconv.Itoa(len(content[name])))
+			sb.WriteByte('\n')
+		}
+		sb.WriteString(content[name])
+	}
+	return []byte(sb.String())
+}
+
+func decodeChecksum(s string) ([]byte, error) {
+	bb, err := hex.DecodeString(strings.TrimSpace(s))
+	if err != nil {
+		return nil, fmt.Errorf("sha256 must be hex-encoded: %w", err)
+	}
+	if len(bb) != sha256.Size {
+		return nil, fmt.Errorf("sha256 must be %d bytes long, got %d", sha256.Size, len(bb))
+	}
+	return bb, nil
+}
+
+func parsePublicKey(s string) (ed25519.PublicKey, error) {
+	block, _ := pem.Decode([]byte(s))
+	if block == nil {
+		return nil, fmt.Errorf("public_key must be PEM-encoded")
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("parsing public_key: %w", err)
+	}
+	edKey, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("public_key must be an Ed25519 key, got %T", key)
+	}
+	return edKey, nil
+}
@@ -0,0 +1,93 @@
+package importsource
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/pem"
+	"testing"
+
+	"github.com/grafana/alloy/syntax/alloytypes"
+	"github.com/stretchr/testify/require"
+)
+
+func TestVerifyArguments(t *testing.T) {
+	content := map[string]string{
+		"b.alloy": "declare \"b\" {}",
+		"a.alloy": "declare \"a\" {}",
+	}
+	payload := []byte("a.alloy\x0014\n" + content["a.alloy"] + "b.alloy\x0014\n" + content["b.alloy"])
+	checksum := sha256.Sum256(payload)
+
+	pub, priv, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKIXPublicKey(pub)
+	require.NoError(t, err)
+	pemKey := string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+	signature := base64.StdEncoding.EncodeToString(ed25519.Sign(priv, payload))
+
+	t.Run("nil accepts everything", func(t *testing.T) {
+		var args *VerifyArguments
+		require.NoError(t, args.Verify(content, true))
+	})
+
+	t.Run("checksum", func(t *testing.T) {
+		args := VerifyArguments{SHA256: hex.EncodeToString(checksum[:])}
+		require.NoError(t, args.Validate())
+		require.NoError(t, args.Verify(content, true))
+		require.ErrorContains(t, args.Verify(map[string]string{"a.alloy": "tampered"}, true), "checksum mismatch")
+	})
+
+	t.Run("signature", func(t *testing.T) {
+		args := VerifyArguments{PublicKey: pemKey, Signature: alloytypes.Secret(signature)}
+		require.NoError(t, args.Validate())
+		require.NoError(t, args.Verify(content, true))
+		require.ErrorContains(t, args.Verify(map[string]string{"a.alloy": "tampered"}, true), "signature verification failed")
+	})
+
+	t.Run("renamed and moved content", func(t *testing.T) {
+		args := VerifyArguments{SHA256: hex.EncodeToString(checksum[:])}
+		renamed := map[string]string{"a.alloy": content["a.alloy"], "c.alloy": content["b.alloy"]}
+		require.ErrorContains(t, args.Verify(renamed, true), "checksum mismatch")
+		moved := map[string]string{"a.alloy": content["a.alloy"] + content["b.alloy"], "b.alloy": ""}
+		require.ErrorContains(t, args.Verify(moved, true), "checksum mismatch")
+	})
+
+	t.Run("single file", func(t *testing.T) {
+		single := sha256.Sum256([]byte(content["a.alloy"]))
+		args := VerifyArguments{SHA256: hex.EncodeToString(single[:])}
+		require.NoError(t, args.Verify(map[string]string{"module": content["a.alloy"]}, false))
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		require.Error(t, (&VerifyArguments{}).Validate())
+		require.Error(t, (&VerifyArguments{SHA256: "abc"}).Validate())
+		require.Error(t, (&VerifyArguments{PublicKey: pemKey}).Validate())
+		require.Error(t, (&VerifyArguments{PublicKey: "not a key", Signature: "c2ln"}).Validate())
+	})
+}
+
+func TestModuleCache(t *testing.T) {
+	cache := newModuleCache(t.TempDir())
+
+	source := cacheSource("https://example.com/module.alloy")
+	content, err := cache.Load(source)
+	require.NoError(t, err)
+	require.Nil(t, content)
+
+	expect := map[string]string{"module.alloy": "declare \"a\" {}"}
+	require.NoError(t, cache.Store(source, expect))
+
+	content, err = cache.Load(source)
+	require.NoError(t, err)
+	require.Equal(t, expect, content)
+
+	// The content of another source is never loaded.
+	content, err = cache.Load(cacheSource("https://example.com/other.alloy"))
+	require.NoError(t, err)
+	require.Nil(t, content)
+	require.NotEqual(t, cacheSource("ab", "c"), cacheSource("a", "bc"))
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"runtime"
 	"sync"
+	"time"
 )
 
 type Pool interface {
@@ -22,10 +23,56 @@ type Pool interface {
 	// Adding a job with a key that is already queued is a no-op (even if the submitted function is different).
 	// Error is returned if the pool is unable to accept extra work - the caller can decide how to handle this situation.
 	SubmitWithKey(string, func()) error
+	// SubmitWithPriority is like SubmitWithKey, but waiting tasks with a higher priority are run before waiting tasks
+	// with a lower priority. Tasks with the same priority are run in the order they were submitted. The priority of a
+	// waiting task is raised by one level every PriorityAgingInterval, so that a steady stream of tasks with a higher
+	// priority can't delay it forever.
+	//
+	// If a task with the same key is already waiting, the submission is merged into it: queued is false, and the
+	// priority of the waiting task is raised to priority if it was lower.
+	SubmitWithPriority(key string, priority Priority, f func()) (queued bool, err error)
 	// QueueSize returns the number of tasks currently queued or running.
 	QueueSize() int
 }
 
+// Priority is the priority of a task submitted to a Pool.
+type Priority int
+
+const (
+	PriorityLow    Priority = -1
+	PriorityNormal Priority = 0
+	PriorityHigh   Priority = 1
+)
+
+// PriorityAgingInterval is how long a task waits before its priority is raised by one level.
+const PriorityAgingInterval = time.Second
+
+// String returns the string representation of p.
+func (p Priority) String() string {
+	switch p {
+	case PriorityLow:
+		return "low"
+	case PriorityNormal:
+		return "normal"
+	case PriorityHigh:
+		return "high"
+	}
+	return fmt.Sprintf("Priority(%d)", int(p))
+}
+
+// ParsePriority parses the string representation of a Priority.
+func ParsePriority(s string) (Priority, error) {
+	switch s {
+	case "low":
+		return PriorityLow, nil
+	case "normal":
+		return PriorityNormal, nil
+	case "high":
+		return PriorityHigh, nil
+	}
+	return 0, fmt.Errorf("unknown priority %q, must be one of \"low\", \"normal\" or \"high\"", s)
+}
+
 // fixedWorkerPool is a Pool that distributes work across a fixed number of workers. It uses workQueue to ensure
 // that SubmitWithKey guarantees are met.
 type fixedWorkerPool struct {
@@ -60,10 +107,14 @@ func NewFixedWorkerPool(workersCount int, maxQueueSize int) Pool {
 }
 
 func (w *fixedWorkerPool) SubmitWithKey(key string, f func()) error {
-	_, err := w.workQueue.tryEnqueue(key, f)
+	_, err := w.workQueue.tryEnqueue(key, PriorityNormal, f)
 	return err
 }
 
+func (w *fixedWorkerPool) SubmitWithPriority(key string, priority Priority, f func()) (bool, error) {
+	return w.workQueue.tryEnqueue(key, priority, f)
+}
+
 // QueueSize returns the number of tasks in the queue - waiting or currently running.
 func (w *fixedWorkerPool) QueueSize() int {
 	return w.workQueue.queueSize()
@@ -80,11 +131,21 @@ func (w *fixedWorkerPool) start() {
 		go func() {
 			defer w.allStopped.Done()
 			for {
+				// Stop even if there are tasks waiting, as Stop doesn't drain the queue.
 				select {
 				case <-w.quit:
 					return
-				case f := <-w.workQueue.tasksToRun:
+				default:
+				}
+
+				if f, ok := w.workQueue.nextTask(); ok {
 					f()
+					continue
+				}
+				select {
+				case <-w.quit:
+					return
+				case <-w.workQueue.taskReady:
 				}
 			}
 		}()
@@ -92,30 +153,53 @@ func (w *fixedWorkerPool) start() {
 }
 
 type workQueue struct {
-	maxSize    int
-	tasksToRun chan func()
+	maxSize int
+	// taskReady is written to when a task may have become runnable. Workers pull tasks from the queue only when they
+	// are idle, so that the priority of waiting tasks is taken into account when picking the next task to run.
+	taskReady chan struct{}
+
+	// now returns the current time, used to age waiting tasks.
+	now func() time.Time
 
 	lock         sync.Mutex
 	waitingOrder []string
-	waiting      map[string]func()
+	waiting      map[string]*waitingTask
 	running      map[string]struct{}
 }
 
+type waitingTask struct {
+	f          func()
+	priority   Priority
+	enqueuedAt time.Time
+}
+
+// effectivePriority returns the priority of t raised by one level for every PriorityAgingInterval it waited.
+func (t *waitingTask) effectivePriority(now time.Time) Priority {
+	return t.priority + Priority(now.Sub(t.enqueuedAt)/PriorityAgingInterval)
+}
+
 func newWorkQueue(maxSize int) *workQueue {
+	if maxSize < 0 {
+		panic(fmt.Sprintf("maxSize must not be negative, got %d", maxSize))
+	}
 	return &workQueue{
-		maxSize:    maxSize,
-		tasksToRun: make(chan func(), maxSize),
-		waiting:    make(map[string]func()),
-		running:    make(map[string]struct{}),
+		maxSize:   maxSize,
+		taskReady: make(chan struct{}, 1),
+		now:       time.Now,
+		waiting:   make(map[string]*waitingTask),
+		running:   make(map[string]struct{}),
 	}
 }
 
-func (w *workQueue) tryEnqueue(key string, f func()) (bool, error) {
+func (w *workQueue) tryEnqueue(key string, priority Priority, f func()) (bool, error) {
 	w.lock.Lock()
 	defer w.lock.Unlock()
 
-	// Don't enqueue if same task already waiting
-	if _, exists := w.waiting[key]; exists {
+	// Don't enqueue if same task already waiting, but make sure it doesn't wait longer than the new submission would.
+	if task, exists := w.waiting[key]; exists {
+		if priority > task.priority {
+			task.priority = priority
+		}
 		return false, nil
 	}
 
@@ -127,10 +211,10 @@ func (w *workQueue) tryEnqueue(key string, f func()) (bool, error) {
 
 	// Else enqueue
 	w.waitingOrder = append(w.waitingOrder, key)
-	w.waiting[key] = f
+	w.waiting[key] = &waitingTask{f: f, priority: priority, enqueuedAt: w.now()}
 
-	// A task may have become runnable now, emit it
-	w.emitNextTask()
+	// A task may have become runnable now, wake up a worker
+	w.notifyTaskReady()
 
 	return true, nil
 }
@@ -139,31 +223,45 @@ func (w *workQueue) taskDone(key string) {
 	w.lock.Lock()
 	defer w.lock.Unlock()
 	delete(w.running, key)
-	// A task may have become runnable now, emit it
-	w.emitNextTask()
+	// A task may have become runnable now, wake up a worker
+	w.notifyTaskReady()
+}
+
+func (w *workQueue) notifyTaskReady() {
+	select {
+	case w.taskReady <- struct{}{}:
+	default:
+	}
 }
 
-// emitNextTask emits the next eligible task to be run if there is one. It must be called whenever the queue state
-// changes (e.g. a task is added or a task finishes). The lock must be held when calling this function.
-func (w *workQueue) emitNextTask() {
+// nextTask returns the next eligible task to be run if there is one, and marks it as running. The eligible task is
+// the first task in waitingOrder with the highest effective priority whose key is not already running.
+func (w *workQueue) nextTask() (func(), bool) {
+	w.lock.Lock()
+	defer w.lock.Unlock()
+
 	var (
-		task  func()
-		key   string
-		index int
-		found = false
+		key       string
+		index     int
+		priority  Priority
+		found     = false
+		runnables = 0
+		now       = w.now()
 	)
 
-	// Find the first key in waitingOrder that is not yet running
 	for i, k := range w.waitingOrder {
-		if _, alreadyRunning := w.running[k]; !alreadyRunning {
-			found, key, index = true, k, i
-			break
+		if _, alreadyRunning := w.running[k]; alreadyRunning {
+			continue
+		}
+		runnables++
+		if p := w.waiting[k].effectivePriority(now); !found || p > priority {
+			found, key, index, priority = true, k, i, p
 		}
 	}
 
 	// Return if we didn't find any task ready to run
 	if !found {
-		return
+		return nil, false
 	}
 
 	// Remove the task from waiting and add it to running set.
@@ -171,18 +269,20 @@ func (w *workQueue) emitNextTask() {
 	// This code is NOT identified as a performance hot spot and given that in large Alloy instances we observe max number of
 	// tasks queued to be ~10, the slice is actually faster because it does not allocate memory. See BenchmarkQueue.
 	w.waitingOrder = append(w.waitingOrder[:index], w.waitingOrder[index+1:]...)
-	task = w.waiting[key]
+	task := w.waiting[key].f
 	delete(w.waiting, key)
 	w.running[key] = struct{}{}
 
+	// Other tasks are still runnable; wake up another worker to pick them up.
+	if runnables > 1 {
+		w.notifyTaskReady()
+	}
+
 	// Wrap the actual task to make sure we mark it as done when it finishes
-	wrapped := func() {
+	return func() {
 		defer w.taskDone(key)
 		task()
-	}
-
-	// Emit the task to be run. There will always be space in this buffered channel, because we limit queue size.
-	w.tasksToRun <- wrapped
+	}, true
 }
 
 func (w *workQueue) queueSize() int {
@@ -3,6 +3,7 @@ package worker
 import (
 	"container/list"
 	"fmt"
+	"sync"
 	"testing"
 	"time"
 
@@ -198,6 +199,91 @@ func TestWorkerPool(t *testing.T) {
 			require.Equal(t, 2, pool.QueueSize())
 		})
 
+		t.Run("should run waiting tasks by priority", func(t *testing.T) {
+			defer goleak.VerifyNone(t)
+			pool := NewFixedWorkerPool(1, 10)
+			defer pool.Stop()
+
+			// First task will block the only worker, so that the next ones are waiting
+			blockFirstTask := make(chan struct{})
+			firstTaskRunning := make(chan struct{})
+			err := pool.SubmitWithKey("k-blocking", func() {
+				firstTaskRunning <- struct{}{}
+				<-blockFirstTask
+			})
+			require.NoError(t, err)
+			<-firstTaskRunning
+
+			var (
+				mut   sync.Mutex
+				order []string
+			)
+			record := func(key string) func() {
+				return func() {
+					mut.Lock()
+					defer mut.Unlock()
+					order = append(order, key)
+				}
+			}
+
+			for _, task := range []struct {
+				key      string
+				priority Priority
+			}{
+				{"low", PriorityLow},
+				{"normal-1", PriorityNormal},
+				{"high", PriorityHigh},
+				{"normal-2", PriorityNormal},
+			} {
+				queued, err := pool.SubmitWithPriority(task.key, task.priority, record(task.key))
+				require.NoError(t, err)
+				require.True(t, queued)
+			}
+
+			// Resubmitting a waiting task merges it, raising its priority.
+			queued, err := pool.SubmitWithPriority("normal-2", PriorityHigh, record("normal-2"))
+			require.NoError(t, err)
+			require.False(t, queued)
+
+			close(blockFirstTask)
+			require.Eventually(t, func() bool {
+				mut.Lock()
+				defer mut.Unlock()
+				return len(order) == 4
+			}, 3*time.Second, 5*time.Millisecond)
+			require.Equal(t, []string{"high", "normal-2", "normal-1", "low"}, order)
+		})
+
+		t.Run("should not run waiting tasks once stopped", func(t *testing.T) {
+			defer goleak.VerifyNone(t)
+			pool := NewFixedWorkerPool(1, 10)
+			tasksDone := atomic.Int32{}
+
+			blockFirstTask := make(chan struct{})
+			firstTaskRunning := make(chan struct{})
+			err := pool.SubmitWithKey("k-blocking", func() {
+				firstTaskRunning <- struct{}{}
+				<-blockFirstTask
+			})
+			require.NoError(t, err)
+			<-firstTaskRunning
+
+			for i := 0; i < 5; i++ {
+				require.NoError(t, pool.SubmitWithKey(fmt.Sprintf("t%d", i), func() { tasksDone.Inc() }))
+			}
+
+			stopped := make(chan struct{})
+			go func() {
+				pool.Stop()
+				close(stopped)
+			}()
+			<-pool.(*fixedWorkerPool).quit
+
+			close(blockFirstTask)
+			<-stopped
+			require.Equal(t, int32(0), tasksDone.Load())
+		})
+
 		t.Run("should not block when one task is stuck", func(t *testing.T) {
 			defer goleak.VerifyNone(t)
 			tasksCount := 1000
@@ -347,3 +433,35 @@ func BenchmarkQueue(b *testing.B) {
 		})
 	}
 }
+
+func TestWorkQueue_PriorityAging(t *testing.T) {
+	now := time.Now()
+	q := newWorkQueue(10)
+	q.now = func() time.Time { return now }
+
+	run := func() string {
+		f, ok := q.nextTask()
+		require.True(t, ok)
+		var key string
+		for k := range q.running {
+			key = k
+		}
+		f()
+		return key
+	}
+
+	queued, err := q.tryEnqueue("low", PriorityLow, func() {})
+	require.NoError(t, err)
+	require.True(t, queued)
+
+	// A steady stream of high priority tasks delays the low priority task
+	// until it has waited for two aging intervals.
+	var order []string
+	for i := 0; i < 5; i++ {
+		_, err := q.tryEnqueue(fmt.Sprintf("high-%d", i), PriorityHigh, func() {})
+		require.NoError(t, err)
+		order = append(order, run())
+		now = now.Add(PriorityAgingInterval)
+	}
+	require.Equal(t, []string{"high-0", "high-1", "low", "high-2", "high-3"}, order)
+}
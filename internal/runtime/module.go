@@ -135,6 +135,7 @@ func newModule(o *moduleOptions) *module {
 			ModuleRegistry:    o.ModuleRegistry,
 			ComponentRegistry: o.ComponentRegistry,
 			WorkerPool:        o.WorkerPool,
+			EvaluationPolicy:  o.EvaluationPolicy,
 			Options: Options{
 				ControllerID: o.ID,
 				Tracer:       o.Tracer,
@@ -226,4 +227,8 @@ type moduleControllerOptions struct {
 	// WorkerPool is a worker pool that can be used to run tasks asynchronously. A default pool will be created if this
 	// is nil.
 	WorkerPool worker.Pool
+
+	// EvaluationPolicy is the evaluation policy of the root controller. A default policy will be created if this is
+	// nil.
+	EvaluationPolicy *controller.EvaluationPolicy
 }
@@ -0,0 +1,68 @@
+// Package profiling attributes the work performed by components to the
+// components themselves. While enabled, the goroutines of each running
+// component are labelled with the ID of the component, so that Go profiles
+// can be broken down or filtered per component.
+package profiling
+
+import (
+	"context"
+	"runtime/pprof"
+	"strings"
+	"sync/atomic"
+
+	"github.com/google/pprof/profile"
+)
+
+// ComponentIDLabel is the pprof label which holds the global ID of the
+// component that started a goroutine.
+const ComponentIDLabel = "component_id"
+
+// enabled reports whether goroutines are labelled. Labelling is disabled by
+// default, as it adds overhead to every goroutine started by components.
+var enabled atomic.Bool
+
+// SetEnabled enables or disables the labelling of the goroutines of
+// components. Only the goroutines started by calls to Do made while labelling
+// is enabled are labelled.
+func SetEnabled(v bool) {
+	enabled.Store(v)
+}
+
+// Enabled reports whether the goroutines of components are labelled.
+func Enabled() bool {
+	return enabled.Load()
+}
+
+// Do calls f with a copy of ctx which has the pprof labels identifying
+// componentID, if labelling is enabled. Goroutines started by f inherit the
+// labels. Otherwise f is called with ctx.
+func Do(ctx context.Context, componentID string, f func(ctx context.Context)) {
+	if !enabled.Load() {
+		f(ctx)
+		return
+	}
+	pprof.Do(ctx, pprof.Labels(ComponentIDLabel, componentID), f)
+}
+
+// ComponentID returns the ID of the component which a profile sample is
+// attributed to, or an empty string if the sample isn't attributed to any
+// component.
+func ComponentID(s *profile.Sample) string {
+	if ids := s.Label[ComponentIDLabel]; len(ids) > 0 {
+		return ids[0]
+	}
+	return ""
+}
+
+// Filter removes all samples from p which are not attributed to the
+// component with the given ID or to one of the components of the modules it
+// runs.
+func Filter(p *profile.Profile, componentID string) {
+	samples := p.Sample[:0]
+	for _, s := range p.Sample {
+		if id := ComponentID(s); id == componentID || strings.HasPrefix(id, componentID+"/") {
+			samples = append(samples, s)
+		}
+	}
+	p.Sample = samples
+}
@@ -0,0 +1,26 @@
+package profiling
+
+import (
+	"context"
+	"runtime/pprof"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestDo(t *testing.T) {
+	defer SetEnabled(false)
+
+	// Goroutines aren't labelled unless labelling is enabled.
+	Do(context.Background(), "test.component", func(ctx context.Context) {
+		_, ok := pprof.Label(ctx, ComponentIDLabel)
+		require.False(t, ok)
+	})
+
+	SetEnabled(true)
+	Do(context.Background(), "test.component", func(ctx context.Context) {
+		id, ok := pprof.Label(ctx, ComponentIDLabel)
+		require.True(t, ok)
+		require.Equal(t, "test.component", id)
+	})
+}
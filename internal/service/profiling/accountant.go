@@ -0,0 +1,359 @@
+package profiling
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"runtime/pprof"
+	"sync"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/google/pprof/profile"
+	"github.com/grafana/alloy/internal/runtime/logging/level"
+	runtimeprofiling "github.com/grafana/alloy/internal/runtime/profiling"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Profile types which can be retrieved per component. Other profile types,
+// such as heap profiles, don't record pprof labels.
+const (
+	ProfileTypeCPU       = "cpu"
+	ProfileTypeGoroutine = "goroutine"
+)
+
+// Profiler retrieves the resources used by components. Components are
+// identified by their global ID, which includes the IDs of the modules they
+// run in.
+type Profiler interface {
+	// Usage returns the last accounted resource usage of a component. ok is
+	// false if accounting is disabled or if no usage was accounted for the
+	// component.
+	Usage(componentID string) (usage Usage, ok bool)
+
+	// WriteProfile writes a gzipped pprof profile of the given type which only
+	// includes the samples of a component and of the components of the
+	// modules it runs. CPU profiles are collected for the given duration,
+	// unless accounting is enabled, in which case the profile collected during
+	// the last accounting interval is used.
+	WriteProfile(ctx context.Context, w io.Writer, profileType string, componentID string, duration time.Duration) error
+}
+
+// Usage is the resource usage of a component.
+type Usage struct {
+	CPUSecondsTotal float64   `json:"cpuSecondsTotal"` // Estimated CPU time used since accounting started.
+	CPUUsage        float64   `json:"cpuUsage"`        // Average number of CPU cores used during the last interval.
+	Goroutines      int       `json:"goroutines"`      // Number of running goroutines.
+	UpdateTime      time.Time `json:"updatedTime"`
+}
+
+type accountant struct {
+	log     log.Logger
+	updated chan struct{}
+
+	mut      sync.RWMutex
+	args     Arguments
+	cpuTotal map[string]float64
+	usage    map[string]Usage
+	lastCPU  *profile.Profile
+
+	cpuSecondsDesc *prometheus.Desc
+	goroutinesDesc *prometheus.Desc
+}
+
+var (
+	_ Profiler             = (*accountant)(nil)
+	_ prometheus.Collector = (*accountant)(nil)
+)
+
+func newAccountant(l log.Logger) *accountant {
+	if l == nil {
+		l = log.NewNopLogger()
+	}
+	return &accountant{
+		log:      l,
+		updated:  make(chan struct{}, 1),
+		args:     DefaultArguments,
+		cpuTotal: make(map[string]float64),
+		usage:    make(map[string]Usage),
+
+		cpuSecondsDesc: prometheus.NewDesc(
+			"alloy_component_cpu_seconds_total",
+			"Estimated total CPU time used by the goroutines of a component while accounting is enabled.",
+			[]string{"component_id"}, nil,
+		),
+		goroutinesDesc: prometheus.NewDesc(
+			"alloy_component_goroutines",
+			"Number of goroutines started by a component.",
+			[]string{"component_id"}, nil,
+		),
+	}
+}
+
+// SetArguments updates the settings of the accountant.
+func (a *accountant) SetArguments(args Arguments) {
+	a.mut.Lock()
+	a.args = args
+	a.mut.Unlock()
+
+	select {
+	case a.updated <- struct{}{}:
+	default:
+	}
+}
+
+// Run collects profiles until ctx is canceled.
+func (a *accountant) Run(ctx context.Context) {
+	for {
+		a.mut.RLock()
+		args := a.args
+		a.mut.RUnlock()
+
+		if !args.Enabled {
+			a.reset()
+			select {
+			case <-ctx.Done():
+				return
+			case <-a.updated:
+				continue
+			}
+		}
+
+		a.collect(ctx, args.Interval)
+		if ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+// maxCPUWindow bounds the time during which the CPU profiler is held in each
+// interval, so that CPU profiles requested through /debug/pprof/profile can be
+// collected in between.
+const maxCPUWindow = 5 * time.Second
+
+// collect collects a CPU profile during the first part of interval, followed
+// by a goroutine profile at the end of interval, and records the usage of
+// every component found in the profiles. collect returns early if ctx is
+// canceled or if the arguments are updated.
+func (a *accountant) collect(ctx context.Context, interval time.Duration) {
+	start := time.Now()
+
+	var buf bytes.Buffer
+	cpuErr := pprof.StartCPUProfile(&buf)
+	if cpuErr != nil {
+		// Another CPU profile is already running, for example one requested
+		// through /debug/pprof/profile.
+		level.Debug(a.log).Log("msg", "skipping CPU accounting for this interval", "err", cpuErr)
+	}
+
+	completed := a.wait(ctx, min(interval/2, maxCPUWindow))
+	window := time.Since(start)
+
+	var cpuProfile *profile.Profile
+	if cpuErr == nil {
+		pprof.StopCPUProfile()
+
+		p, err := profile.Parse(&buf)
+		if err != nil {
+			level.Warn(a.log).Log("msg", "failed to parse CPU profile", "err", err)
+		} else {
+			cpuProfile = p
+		}
+	}
+
+	if completed {
+		a.wait(ctx, interval-window)
+	}
+	elapsed := time.Since(start)
+
+	goroutineProfile, err := lookupProfile(ProfileTypeGoroutine)
+	if err != nil {
+		level.Warn(a.log).Log("msg", "failed to collect goroutine profile", "err", err)
+		return
+	}
+
+	a.record(cpuProfile, window, goroutineProfile, elapsed)
+}
+
+// wait waits for d. It reports false if it returned early because ctx was
+// canceled or the arguments were updated.
+func (a *accountant) wait(ctx context.Context, d time.Duration) bool {
+	select {
+	case <-ctx.Done():
+		return false
+	case <-a.updated:
+		return false
+	case <-time.After(d):
+		return true
+	}
+}
+
+// record records the usage of components. cpuProfile, which is nil if CPU
+// accounting was skipped, was collected during window, and the CPU usage it
+// shows is extrapolated to the whole interval, which lasted elapsed.
+func (a *accountant) record(cpuProfile *profile.Profile, window time.Duration, goroutineProfile *profile.Profile, elapsed time.Duration) {
+	cpuSeconds := make(map[string]float64)
+	if cpuProfile != nil {
+		idx := sampleIndex(cpuProfile, "cpu")
+		for _, s := range cpuProfile.Sample {
+			if id := runtimeprofiling.ComponentID(s); id != "" && idx >= 0 {
+				cpuSeconds[id] += float64(s.Value[idx]) / float64(time.Second)
+			}
+		}
+	}
+
+	goroutines := make(map[string]int)
+	for _, s := range goroutineProfile.Sample {
+		if id := runtimeprofiling.ComponentID(s); id != "" {
+			goroutines[id] += int(s.Value[0])
+		}
+	}
+
+	a.mut.Lock()
+	defer a.mut.Unlock()
+
+	if cpuProfile != nil {
+		a.lastCPU = cpuProfile
+	}
+
+	// Components are accounted if they have goroutines or used CPU time, so
+	// that the CPU time of components whose goroutines exited before the end
+	// of the interval isn't lost.
+	ids := make(map[string]struct{}, len(goroutines))
+	for id := range goroutines {
+		ids[id] = struct{}{}
+	}
+	for id := range cpuSeconds {
+		ids[id] = struct{}{}
+	}
+
+	now := time.Now()
+	usage := make(map[string]Usage, len(ids))
+	for id := range ids {
+		// When CPU accounting was skipped, the last CPU usage is kept.
+		cpuUsage := a.usage[id].CPUUsage
+		if cpuProfile != nil {
+			cpuUsage = cpuSeconds[id] / window.Seconds()
+			a.cpuTotal[id] += cpuUsage * elapsed.Seconds()
+		}
+		usage[id] = Usage{
+			CPUSecondsTotal: a.cpuTotal[id],
+			CPUUsage:        cpuUsage,
+			Goroutines:      goroutines[id],
+			UpdateTime:      now,
+		}
+	}
+
+	// Forget about components which are no longer running.
+	for id := range a.cpuTotal {
+		if _, ok := usage[id]; !ok {
+			delete(a.cpuTotal, id)
+		}
+	}
+	a.usage = usage
+}
+
+func (a *accountant) reset() {
+	a.mut.Lock()
+	defer a.mut.Unlock()
+
+	a.cpuTotal = make(map[string]float64)
+	a.usage = make(map[string]Usage)
+	a.lastCPU = nil
+}
+
+// Usage implements Profiler.
+func (a *accountant) Usage(componentID string) (Usage, bool) {
+	a.mut.RLock()
+	defer a.mut.RUnlock()
+
+	u, ok := a.usage[componentID]
+	return u, ok
+}
+
+// WriteProfile implements Profiler.
+func (a *accountant) WriteProfile(ctx context.Context, w io.Writer, profileType string, componentID string, duration time.Duration) error {
+	if !runtimeprofiling.Enabled() {
+		return fmt.Errorf("component profiles require the profiling block to be enabled")
+	}
+
+	var (
+		p   *profile.Profile
+		err error
+	)
+
+	switch profileType {
+	case ProfileTypeCPU:
+		a.mut.RLock()
+		if a.args.Enabled && a.lastCPU != nil {
+			p = a.lastCPU.Copy()
+		}
+		a.mut.RUnlock()
+
+		if p == nil {
+			p, err = collectCPUProfile(ctx, duration)
+		}
+	case ProfileTypeGoroutine:
+		p, err = lookupProfile(ProfileTypeGoroutine)
+	default:
+		return fmt.Errorf("unsupported profile type %q", profileType)
+	}
+	if err != nil {
+		return err
+	}
+
+	runtimeprofiling.Filter(p, componentID)
+	return p.Write(w)
+}
+
+// Describe implements prometheus.Collector.
+func (a *accountant) Describe(ch chan<- *prometheus.Desc) {
+	ch <- a.cpuSecondsDesc
+	ch <- a.goroutinesDesc
+}
+
+// Collect implements prometheus.Collector.
+func (a *accountant) Collect(ch chan<- prometheus.Metric) {
+	a.mut.RLock()
+	defer a.mut.RUnlock()
+
+	for id, u := range a.usage {
+		ch <- prometheus.MustNewConstMetric(a.cpuSecondsDesc, prometheus.CounterValue, u.CPUSecondsTotal, id)
+		ch <- prometheus.MustNewConstMetric(a.goroutinesDesc, prometheus.GaugeValue, float64(u.Goroutines), id)
+	}
+}
+
+func collectCPUProfile(ctx context.Context, duration time.Duration) (*profile.Profile, error) {
+	var buf bytes.Buffer
+	if err := pprof.StartCPUProfile(&buf); err != nil {
+		return nil, err
+	}
+
+	select {
+	case <-ctx.Done():
+	case <-time.After(duration):
+	}
+	pprof.StopCPUProfile()
+
+	return profile.Parse(&buf)
+}
+
+func lookupProfile(name string) (*profile.Profile, error) {
+	var buf bytes.Buffer
+	if err := pprof.Lookup(name).WriteTo(&buf, 0); err != nil {
+		return nil, err
+	}
+	return profile.Parse(&buf)
+}
+
+// sampleIndex returns the index of the sample value of the given type, or -1
+// if the profile doesn't have such a sample type.
+func sampleIndex(p *profile.Profile, sampleType string) int {
+	for i, st := range p.SampleType {
+		if st.Type == sampleType {
+			return i
+		}
+	}
+	return -1
+}
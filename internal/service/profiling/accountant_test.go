@@ -0,0 +1,140 @@
+package profiling
+
+import (
+	"bytes"
+	"context"
+	"runtime/pprof"
+	"testing"
+	"time"
+
+	"github.com/google/pprof/profile"
+	runtimeprofiling "github.com/grafana/alloy/internal/runtime/profiling"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAccountant(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	runtimeprofiling.SetEnabled(true)
+	defer runtimeprofiling.SetEnabled(false)
+
+	// Start a fake component which burns CPU in two goroutines.
+	stopped := make(chan struct{})
+	runtimeprofiling.Do(ctx, "test.component", func(ctx context.Context) {
+		for i := 0; i < 2; i++ {
+			go func() {
+				for ctx.Err() == nil {
+					busyLoop()
+				}
+				stopped <- struct{}{}
+			}()
+		}
+	})
+
+	a := newAccountant(nil)
+	a.SetArguments(Arguments{Enabled: true, Interval: time.Second})
+	go a.Run(ctx)
+
+	require.Eventually(t, func() bool {
+		u, ok := a.Usage("test.component")
+		return ok && u.Goroutines == 2 && u.CPUSecondsTotal > 0
+	}, 10*time.Second, 100*time.Millisecond)
+
+	_, ok := a.Usage("unknown.component")
+	require.False(t, ok)
+
+	var buf bytes.Buffer
+	require.NoError(t, a.WriteProfile(ctx, &buf, ProfileTypeGoroutine, "test.component", 0))
+	p, err := profile.Parse(&buf)
+	require.NoError(t, err)
+	require.NotEmpty(t, p.Sample)
+	for _, s := range p.Sample {
+		require.Equal(t, "test.component", runtimeprofiling.ComponentID(s))
+	}
+
+	require.Error(t, a.WriteProfile(ctx, &buf, "heap", "test.component", 0))
+
+	// Disabling accounting forgets about the usage.
+	a.SetArguments(Arguments{Enabled: false, Interval: time.Second})
+	require.Eventually(t, func() bool {
+		_, ok := a.Usage("test.component")
+		return !ok
+	}, 5*time.Second, 100*time.Millisecond)
+
+	cancel()
+	<-stopped
+	<-stopped
+}
+
+func TestAccountant_Record(t *testing.T) {
+	newProfile := func(sampleType string, values map[string]int64) *profile.Profile {
+		p := &profile.Profile{SampleType: []*profile.ValueType{{Type: sampleType}}}
+		for id, v := range values {
+			p.Sample = append(p.Sample, &profile.Sample{
+				Value: []int64{v},
+				Label: map[string][]string{runtimeprofiling.ComponentIDLabel: {id}},
+			})
+		}
+		return p
+	}
+
+	a := newAccountant(nil)
+
+	// The CPU time of a component without goroutines at the end of the
+	// interval is still accounted, and extrapolated to the whole interval.
+	cpu := newProfile("cpu", map[string]int64{"a": int64(time.Second), "b": int64(time.Second / 2)})
+	goroutines := newProfile("goroutine", map[string]int64{"a": 2})
+	a.record(cpu, 2*time.Second, goroutines, 4*time.Second)
+
+	u, ok := a.Usage("a")
+	require.True(t, ok)
+	require.Equal(t, 2, u.Goroutines)
+	require.InDelta(t, 0.5, u.CPUUsage, 1e-9)
+	require.InDelta(t, 2.0, u.CPUSecondsTotal, 1e-9)
+
+	u, ok = a.Usage("b")
+	require.True(t, ok)
+	require.Equal(t, 0, u.Goroutines)
+	require.InDelta(t, 1.0, u.CPUSecondsTotal, 1e-9)
+
+	// When CPU accounting is skipped, the last CPU usage is kept.
+	a.record(nil, 0, goroutines, 4*time.Second)
+	u, ok = a.Usage("a")
+	require.True(t, ok)
+	require.InDelta(t, 0.5, u.CPUUsage, 1e-9)
+	require.InDelta(t, 2.0, u.CPUSecondsTotal, 1e-9)
+
+	_, ok = a.Usage("b")
+	require.False(t, ok)
+}
+
+func TestAccountant_ReleasesCPUProfiler(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	a := newAccountant(nil)
+	a.SetArguments(Arguments{Enabled: true, Interval: 2 * time.Second})
+	go a.Run(ctx)
+
+	cpuProfilerFree := func() bool {
+		var buf bytes.Buffer
+		if err := pprof.StartCPUProfile(&buf); err != nil {
+			return false
+		}
+		pprof.StopCPUProfile()
+		return true
+	}
+
+	// The CPU profiler is only held during the first half of the interval.
+	require.Eventually(t, func() bool { return !cpuProfilerFree() }, 5*time.Second, 10*time.Millisecond)
+	require.Eventually(t, cpuProfilerFree, 5*time.Second, 10*time.Millisecond)
+}
+
+func busyLoop() {
+	x := 0
+	for i := 0; i < 1_000_000; i++ {
+		x += i
+	}
+	_ = x
+}
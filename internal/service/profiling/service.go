@@ -0,0 +1,100 @@
+// Package profiling implements the profiling service, which accounts the
+// resources used by each running component.
+//
+// When enabled, the goroutines of running components are labelled with their
+// component ID (see [github.com/grafana/alloy/internal/runtime/profiling]),
+// and the service periodically collects CPU and goroutine profiles of the
+// process and aggregates their samples per component.
+package profiling
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/go-kit/log"
+	"github.com/grafana/alloy/internal/featuregate"
+	runtimeprofiling "github.com/grafana/alloy/internal/runtime/profiling"
+	"github.com/grafana/alloy/internal/service"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// ServiceName defines the name used for the profiling service.
+const ServiceName = "profiling"
+
+// Options are used to configure the profiling service. Options are constant
+// for the lifetime of the profiling service.
+type Options struct {
+	Logger  log.Logger            // Where to send logs.
+	Metrics prometheus.Registerer // Where to send metrics to.
+}
+
+// Arguments holds runtime settings for the profiling service.
+type Arguments struct {
+	Enabled  bool          `alloy:"enabled,attr,optional"`
+	Interval time.Duration `alloy:"interval,attr,optional"`
+}
+
+// DefaultArguments holds default settings for Arguments.
+var DefaultArguments = Arguments{
+	Interval: 15 * time.Second,
+}
+
+// SetToDefault implements syntax.Defaulter.
+func (a *Arguments) SetToDefault() {
+	*a = DefaultArguments
+}
+
+// Validate implements syntax.Validator.
+func (a *Arguments) Validate() error {
+	if a.Interval < time.Second {
+		return fmt.Errorf("interval must be at least \"1s\", got %q", a.Interval)
+	}
+	return nil
+}
+
+// Service implements the profiling service.
+type Service struct {
+	accountant *accountant
+}
+
+var _ service.Service = (*Service)(nil)
+
+// New returns a new, unstarted instance of the profiling service.
+func New(opts Options) *Service {
+	a := newAccountant(opts.Logger)
+	if opts.Metrics != nil {
+		opts.Metrics.MustRegister(a)
+	}
+	return &Service{accountant: a}
+}
+
+// Definition implements service.Service.
+func (*Service) Definition() service.Definition {
+	return service.Definition{
+		Name:       ServiceName,
+		ConfigType: Arguments{},
+		DependsOn:  []string{},
+		Stability:  featuregate.StabilityExperimental,
+	}
+}
+
+// Run implements service.Service.
+func (s *Service) Run(ctx context.Context, _ service.Host) error {
+	s.accountant.Run(ctx)
+	return nil
+}
+
+// Update implements service.Service.
+func (s *Service) Update(args any) error {
+	newArgs := args.(Arguments)
+	runtimeprofiling.SetEnabled(newArgs.Enabled)
+	s.accountant.SetArguments(newArgs)
+	return nil
+}
+
+// Data implements service.Service. It returns a [Profiler] to retrieve the
+// resource usage of components.
+func (s *Service) Data() any {
+	return s.accountant
+}
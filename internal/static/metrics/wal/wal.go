@@ -856,9 +856,29 @@ func (a *appender) AppendHistogram(ref storage.SeriesRef, l labels.Labels, t int
 	return storage.SeriesRef(series.ref), nil
 }
 
-func (a *appender) AppendCTZeroSample(_ storage.SeriesRef, _ labels.Labels, _ int64, _ int64) (storage.SeriesRef, error) {
-	// TODO(ptodev): implement this later
-	return 0, nil
+// AppendCTZeroSample appends a sample with a value of zero at the created
+// timestamp ct of the series, mirroring the TSDB head appender.
+func (a *appender) AppendCTZeroSample(ref storage.SeriesRef, l labels.Labels, t int64, ct int64) (storage.SeriesRef, error) {
+	if ct >= t {
+		return 0, storage.ErrOutOfOrderCT
+	}
+
+	series := a.w.series.GetByID(chunks.HeadSeriesRef(ref))
+	if series == nil {
+		series = a.w.series.GetByHash(l.Hash(), l)
+	}
+	if series != nil {
+		series.Lock()
+		lastTs := series.lastTs
+		series.Unlock()
+
+		// Skip the zero sample if the series already has samples after it.
+		if ct <= lastTs {
+			return 0, storage.ErrOutOfOrderCT
+		}
+	}
+
+	return a.Append(ref, l, ct, 0)
 }
 
 func (a *appender) UpdateMetadata(ref storage.SeriesRef, _ labels.Labels, m metadata.Metadata) (storage.SeriesRef, error) {
@@ -680,3 +680,40 @@ func (fn *fakeNotifier) Notify() {
 		fn.NotitfyFunc()
 	}
 }
+
+func TestStorage_AppendCTZeroSample(t *testing.T) {
+	walDir := t.TempDir()
+	s, err := NewStorage(log.NewNopLogger(), nil, walDir)
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		require.NoError(t, s.Close())
+	})
+
+	lbls := labels.FromStrings("__name__", "requests_total")
+
+	app := s.Appender(context.Background())
+	_, err = app.AppendCTZeroSample(0, lbls, 100, 100)
+	require.ErrorIs(t, err, storage.ErrOutOfOrderCT, "created timestamp must be before the sample")
+
+	ref, err := app.AppendCTZeroSample(0, lbls, 100, 50)
+	require.NoError(t, err)
+	_, err = app.Append(ref, lbls, 100, 7)
+	require.NoError(t, err)
+	require.NoError(t, app.Commit())
+
+	// The series already has samples after the created timestamp.
+	app = s.Appender(context.Background())
+	_, err = app.AppendCTZeroSample(ref, lbls, 200, 50)
+	require.ErrorIs(t, err, storage.ErrOutOfOrderCT)
+	require.NoError(t, app.Commit())
+
+	var collector walDataCollector
+	replayer := walReplayer{w: &collector}
+	require.NoError(t, replayer.Replay(s.wal.Dir()))
+
+	require.Len(t, collector.samples, 2)
+	require.Equal(t, int64(50), collector.samples[0].T)
+	require.Equal(t, 0.0, collector.samples[0].V)
+	require.Equal(t, int64(100), collector.samples[1].T)
+	require.Equal(t, 7.0, collector.samples[1].V)
+}
@@ -230,7 +230,7 @@ func (c *Consumer) getConnectionIP(ctx context.Context) string {
 }
 
 func GetHostFromLabels(labels discovery.Target) (string, error) {
-	address, ok := labels[model.AddressLabel]
+	address, ok := labels.Get(model.AddressLabel)
 	if !ok {
 		return "", fmt.Errorf("unable to find address in labels %q", labels.Labels())
 	}
@@ -248,11 +248,12 @@ func GetHostFromLabels(labels discovery.Target) (string, error) {
 }
 
 func NewTargetsWithNonInternalLabels(labels discovery.Target) discovery.Target {
-	res := make(discovery.Target)
-	for k, v := range labels {
+	res := make(map[string]string, labels.Len())
+	labels.ForEachLabel(func(k, v string) bool {
 		if !strings.HasPrefix(k, "__") {
 			res[k] = v
 		}
-	}
-	return res
+		return true
+	})
+	return discovery.NewTargetFromMap(res)
 }
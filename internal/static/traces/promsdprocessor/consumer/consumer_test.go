@@ -84,9 +84,9 @@ func TestOperationType(t *testing.T) {
 			}
 			consumerOpts := Options{
 				HostLabels: map[string]discovery.Target{
-					attrIP: {
+					attrIP: discovery.NewTargetFromMap(map[string]string{
 						attrKey: tc.newValue,
-					},
+					}),
 				},
 				OperationType:   tc.operationType,
 				PodAssociations: podAssociations,
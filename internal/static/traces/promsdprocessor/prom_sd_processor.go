@@ -178,10 +178,7 @@ func (p *promServiceDiscoProcessor) syncTargets(jobName string, group *targetgro
 			continue
 		}
 
-		var labels = make(discovery.Target)
-		for k, v := range processedLabels.Map() {
-			labels[k] = v
-		}
+		labels := discovery.NewTargetFromLabels(processedLabels)
 
 		host, err := promsdconsumer.GetHostFromLabels(labels)
 		if err != nil {
@@ -38,7 +38,7 @@ func TestSyncGroups(t *testing.T) {
 				},
 			},
 			expected: map[string]discovery.Target{
-				"127.0.0.1": {},
+				"127.0.0.1": discovery.EmptyTarget,
 			},
 		},
 		{
@@ -54,9 +54,9 @@ func TestSyncGroups(t *testing.T) {
 				},
 			},
 			expected: map[string]discovery.Target{
-				"127.0.0.1": {
+				"127.0.0.1": discovery.NewTargetFromMap(map[string]string{
 					"label": "val",
-				},
+				}),
 			},
 		},
 		{
@@ -72,9 +72,9 @@ func TestSyncGroups(t *testing.T) {
 				},
 			},
 			expected: map[string]discovery.Target{
-				"127.0.0.1": {
+				"127.0.0.1": discovery.NewTargetFromMap(map[string]string{
 					"label": "val",
-				},
+				}),
 			},
 		},
 		{
@@ -90,7 +90,7 @@ func TestSyncGroups(t *testing.T) {
 				},
 			},
 			expected: map[string]discovery.Target{
-				"127.0.0.1": {},
+				"127.0.0.1": discovery.EmptyTarget,
 			},
 		},
 	}
@@ -5,12 +5,15 @@
 package api
 
 import (
+	"bytes"
 	"encoding/json"
+	"fmt"
 	"math/rand"
 	"net/http"
 	"path"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/gorilla/mux"
@@ -18,6 +21,7 @@ import (
 	"github.com/grafana/alloy/internal/service"
 	"github.com/grafana/alloy/internal/service/cluster"
 	"github.com/grafana/alloy/internal/service/livedebugging"
+	"github.com/grafana/alloy/internal/service/profiling"
 	"github.com/prometheus/prometheus/util/httputil"
 )
 
@@ -43,6 +47,8 @@ func (a *AlloyAPI) RegisterRoutes(urlPrefix string, r *mux.Router) {
 	r.Handle(path.Join(urlPrefix, "/components/{id:.+}"), httputil.CompressionHandler{Handler: a.getComponentHandler()})
 	r.Handle(path.Join(urlPrefix, "/peers"), httputil.CompressionHandler{Handler: a.getClusteringPeersHandler()})
 	r.Handle(path.Join(urlPrefix, "/debug/{id:.+}"), a.liveDebugging())
+	r.Handle(path.Join(urlPrefix, "/usage/{id:.+}"), httputil.CompressionHandler{Handler: a.getComponentUsageHandler()})
+	r.Handle(path.Join(urlPrefix, "/profile/{id:.+}"), a.getComponentProfileHandler())
 }
 
 func (a *AlloyAPI) listComponentsHandler() http.HandlerFunc {
@@ -115,6 +121,73 @@ func (a *AlloyAPI) getClusteringPeersHandler() http.HandlerFunc {
 	}
 }
 
+func (a *AlloyAPI) getComponentUsageHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		profiler, ok := a.profiler()
+		if !ok {
+			http.Error(w, "profiling service not running", http.StatusInternalServerError)
+			return
+		}
+
+		usage, found := profiler.Usage(mux.Vars(r)["id"])
+		if !found {
+			http.NotFound(w, r)
+			return
+		}
+
+		bb, err := json.Marshal(usage)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		_, _ = w.Write(bb)
+	}
+}
+
+func (a *AlloyAPI) getComponentProfileHandler() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		profiler, ok := a.profiler()
+		if !ok {
+			http.Error(w, "profiling service not running", http.StatusInternalServerError)
+			return
+		}
+
+		profileType := r.URL.Query().Get("type")
+		if profileType == "" {
+			profileType = profiling.ProfileTypeGoroutine
+		}
+
+		duration := 30 * time.Second
+		if seconds := r.URL.Query().Get("seconds"); seconds != "" {
+			sec, err := strconv.ParseInt(seconds, 10, 64)
+			if err != nil || sec <= 0 {
+				http.Error(w, "Invalid seconds", http.StatusBadRequest)
+				return
+			}
+			duration = time.Duration(sec) * time.Second
+		}
+
+		var buf bytes.Buffer
+		if err := profiler.WriteProfile(r.Context(), &buf, profileType, mux.Vars(r)["id"], duration); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/octet-stream")
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", profileType+".pb.gz"))
+		_, _ = w.Write(buf.Bytes())
+	}
+}
+
+func (a *AlloyAPI) profiler() (profiling.Profiler, bool) {
+	svc, found := a.alloy.GetService(profiling.ServiceName)
+	if !found {
+		return nil, false
+	}
+	profiler, ok := svc.Data().(profiling.Profiler)
+	return profiler, ok
+}
+
 func (a *AlloyAPI) liveDebugging() http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		vars := mux.Vars(r)
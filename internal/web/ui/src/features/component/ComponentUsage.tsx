@@ -0,0 +1,52 @@
+import Table from './Table';
+import { ResourceUsage } from './types';
+
+import styles from './ComponentView.module.css';
+
+interface ComponentUsageProps {
+  usage: ResourceUsage;
+  // Global ID of the component, used to build the links to its profiles.
+  id: string;
+}
+
+const TABLEHEADERS = ['Name', 'Value'];
+
+const ComponentUsage = ({ usage, id }: ComponentUsageProps) => {
+  const rows = [
+    { name: 'CPU usage (cores)', value: usage.cpuUsage.toFixed(3) },
+    { name: 'CPU time (seconds)', value: usage.cpuSecondsTotal.toFixed(3) },
+    { name: 'Goroutines', value: usage.goroutines.toString() },
+  ];
+
+  const renderTableData = () => {
+    return rows.map(({ name, value }) => {
+      return (
+        <tr key={name}>
+          <td className={styles.nameColumn}>{name}</td>
+          <td>
+            <pre className={styles.pre}>{value}</pre>
+          </td>
+        </tr>
+      );
+    });
+  };
+
+  return (
+    <section id="usage">
+      <h2>Resource usage</h2>
+      <div className={styles.sectionContent}>
+        <div className={styles.list}>
+          <Table tableHeaders={TABLEHEADERS} renderTableData={renderTableData} style={{ width: '210px' }} />
+        </div>
+        <p>
+          <em className={styles.informative}>
+            Updated at {usage.updatedTime}. Download the <a href={`api/v0/web/profile/${id}?type=cpu`}>CPU</a> or{' '}
+            <a href={`api/v0/web/profile/${id}?type=goroutine`}>goroutine</a> profile of this component.
+          </em>
+        </p>
+      </div>
+    </section>
+  );
+};
+
+export default ComponentUsage;
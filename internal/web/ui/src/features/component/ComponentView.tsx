@@ -7,6 +7,7 @@ import { partitionBody } from '../../utils/partition';
 
 import ComponentBody from './ComponentBody';
 import ComponentList from './ComponentList';
+import ComponentUsage from './ComponentUsage';
 import { HealthLabel } from './HealthLabel';
 import { ComponentDetail, ComponentInfo, PartitionedBody } from './types';
 
@@ -58,6 +59,13 @@ export const ComponentView: FC<ComponentViewProps> = (props) => {
           {argsPartition && partitionTOC(argsPartition)}
           {exportsPartition && partitionTOC(exportsPartition)}
           {debugPartition && partitionTOC(debugPartition)}
+          {props.component.usage && (
+            <li>
+              <Link to="#usage" target="_top">
+                Resource usage
+              </Link>
+            </li>
+          )}
           {props.component.referencesTo.length > 0 && (
             <li>
               <Link to="#dependencies" target="_top">
@@ -121,6 +129,12 @@ export const ComponentView: FC<ComponentViewProps> = (props) => {
         <ComponentBody partition={argsPartition} />
         {exportsPartition && <ComponentBody partition={exportsPartition} />}
         {debugPartition && <ComponentBody partition={debugPartition} />}
+        {props.component.usage && (
+          <ComponentUsage
+            usage={props.component.usage}
+            id={pathJoin([props.component.moduleID, props.component.localID])}
+          />
+        )}
 
         {props.component.referencesTo.length > 0 && (
           <section id="dependencies">
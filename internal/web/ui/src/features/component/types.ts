@@ -110,6 +110,26 @@ export interface ComponentDetail extends ComponentInfo {
    * If a component is a module loader, the loaded components from the module are included here.
    */
   moduleInfo?: ComponentInfo[];
+
+  /**
+   * Resource usage of the component. Only set when the profiling service is
+   * enabled.
+   */
+  usage?: ResourceUsage;
+}
+
+/**
+ * ResourceUsage is the resource usage of a component, as accounted by the
+ * profiling service.
+ */
+export interface ResourceUsage {
+  /** Average number of CPU cores used during the last interval. */
+  cpuUsage: number;
+  /** CPU time used since accounting started. */
+  cpuSecondsTotal: number;
+  /** Number of running goroutines. */
+  goroutines: number;
+  updatedTime: string;
 }
 
 export interface PartitionedBody {
@@ -2,7 +2,7 @@ import { FC, useEffect, useState } from 'react';
 import { useParams } from 'react-router-dom';
 
 import { ComponentView } from '../features/component/ComponentView';
-import { ComponentDetail, ComponentInfo, componentInfoByID } from '../features/component/types';
+import { ComponentDetail, ComponentInfo, componentInfoByID, ResourceUsage } from '../features/component/types';
 import { useComponentInfo } from '../hooks/componentInfo';
 import { parseID } from '../utils/id';
 
@@ -39,6 +39,16 @@ const ComponentDetailPage: FC = () => {
           data.moduleInfo = (data.moduleInfo || []).concat(moduleComponents);
         }
 
+        // Resource usage is only available when the profiling service is
+        // enabled.
+        const usageResp = await fetch(`./api/v0/web/usage/${id}`, {
+          cache: 'no-cache',
+          credentials: 'same-origin',
+        });
+        if (usageResp.ok) {
+          data.usage = (await usageResp.json()) as ResourceUsage;
+        }
+
         setComponent(data);
       };
 
@@ -306,6 +306,9 @@ func buildJSONValue(v value.Value) jsonValue {
 		return jsonValue{Type: "function", Value: v.Describe()}
 
 	case value.TypeCapsule:
+		if obj, ok := v.TryConvertToObject(); ok {
+			return buildJSONValue(obj)
+		}
 		return jsonValue{Type: "capsule", Value: v.Describe()}
 
 	default:
@@ -390,6 +390,27 @@ func (v Value) Key(key string) (index Value, ok bool) {
 	return
 }
 
+// TryConvertToObject returns v as an object. Objects are returned as is, and
+// capsules which can be converted into a map[string]interface{} through
+// ConvertibleIntoCapsule are converted. ok is false for all other values.
+func (v Value) TryConvertToObject() (res Value, ok bool) {
+	switch v.ty {
+	case TypeObject:
+		return v, true
+	case TypeCapsule:
+		into, isConvertible := v.Interface().(ConvertibleIntoCapsule)
+		if !isConvertible {
+			return Null, false
+		}
+		var m map[string]interface{}
+		if err := into.ConvertInto(&m); err != nil {
+			return Null, false
+		}
+		return Encode(m), true
+	}
+	return Null, false
+}
+
 // Call invokes a function value with the provided arguments. It panics if v is
 // not a function. If v is a variadic function, args should be the full flat
 // list of arguments.
@@ -6,6 +6,7 @@ import (
 	"testing"
 	"time"
 
+	"github.com/grafana/alloy/syntax"
 	"github.com/grafana/alloy/syntax/parser"
 	"github.com/grafana/alloy/syntax/printer"
 	"github.com/grafana/alloy/syntax/token"
@@ -312,6 +313,36 @@ func (ct CustomTokenizer) AlloyTokenize() []builder.Token {
 	return []builder.Token{{Tok: token.LITERAL, Lit: "CUSTOM_TOKENS"}}
 }
 
+// objectCapsule is a capsule which can be converted into an object.
+type objectCapsule map[string]string
+
+func (objectCapsule) AlloyCapsule() {}
+
+func (oc objectCapsule) ConvertInto(dst interface{}) error {
+	if dst, ok := dst.(*map[string]interface{}); ok {
+		m := make(map[string]interface{}, len(oc))
+		for k, v := range oc {
+			m[k] = v
+		}
+		*dst = m
+		return nil
+	}
+	return syntax.ErrNoConversion
+}
+
+func TestBuilder_GoEncode_ObjectCapsule(t *testing.T) {
+	f := builder.NewFile()
+	f.Body().SetAttributeValue("target", objectCapsule{"job": "test", "__address__": "localhost"})
+
+	expect := format(t, `
+		target = {
+			__address__ = "localhost",
+			job         = "test",
+		}
+	`)
+	require.Equal(t, expect, string(f.Bytes()))
+}
+
 func TestBuilder_GoEncode_Tokenizer(t *testing.T) {
 	t.Run("Tokenizer", func(t *testing.T) {
 		f := builder.NewFile()
@@ -85,6 +85,9 @@ func valueTokens(v value.Value) []Token {
 		toks = append(toks, Token{token.LITERAL, v.Describe()})
 
 	case value.TypeCapsule:
+		if obj, ok := v.TryConvertToObject(); ok {
+			return valueTokens(obj)
+		}
 		toks = append(toks, Token{token.LITERAL, v.Describe()})
 
 	default:
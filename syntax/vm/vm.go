@@ -354,6 +354,11 @@ func (vm *Evaluator) evaluateExpr(scope *Scope, assoc map[value.Value]ast.Node,
 		if err != nil {
 			return value.Null, err
 		}
+		if obj, ok := val.TryConvertToObject(); ok {
+			// Capsules which convert into objects, like discovery targets, are
+			// accessed like objects.
+			val = obj
+		}
 
 		switch val.Type() {
 		case value.TypeObject:
@@ -383,6 +388,9 @@ func (vm *Evaluator) evaluateExpr(scope *Scope, assoc map[value.Value]ast.Node,
 		if err != nil {
 			return value.Null, err
 		}
+		if obj, ok := val.TryConvertToObject(); ok {
+			val = obj
+		}
 
 		switch val.Type() {
 		case value.TypeArray:
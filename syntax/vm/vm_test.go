@@ -6,6 +6,7 @@ import (
 	"testing"
 	"unicode"
 
+	"github.com/grafana/alloy/syntax"
 	"github.com/grafana/alloy/syntax/parser"
 	"github.com/grafana/alloy/syntax/scanner"
 	"github.com/grafana/alloy/syntax/token"
@@ -226,6 +227,23 @@ func TestVM_Evaluate_AccessExpr(t *testing.T) {
 		require.Equal(t, "", actual)
 	})
 
+	t.Run("Lookup field of capsule convertible into object", func(t *testing.T) {
+		scope := &vm.Scope{
+			Variables: map[string]interface{}{
+				"target": objectCapsule{"__address__": "localhost:9090"},
+			},
+		}
+
+		for _, input := range []string{`target.__address__`, `target["__address__"]`} {
+			expr, err := parser.ParseExpression(input)
+			require.NoError(t, err)
+
+			var actual string
+			require.NoError(t, vm.New(expr).Evaluate(scope, &actual))
+			require.Equal(t, "localhost:9090", actual)
+		}
+	})
+
 	t.Run("Invalid lookup 1", func(t *testing.T) {
 		expr, err := parser.ParseExpression(`{ a = 15 }.b`)
 		require.NoError(t, err)
@@ -253,6 +271,23 @@ func TestVM_Evaluate_AccessExpr(t *testing.T) {
 	})
 }
 
+// objectCapsule is a capsule which can be converted into an object.
+type objectCapsule map[string]string
+
+func (objectCapsule) AlloyCapsule() {}
+
+func (oc objectCapsule) ConvertInto(dst interface{}) error {
+	if dst, ok := dst.(*map[string]interface{}); ok {
+		m := make(map[string]interface{}, len(oc))
+		for k, v := range oc {
+			m[k] = v
+		}
+		*dst = m
+		return nil
+	}
+	return syntax.ErrNoConversion
+}
+
 func trimWhitespace(in string) string {
 	f := token.NewFile("")
 